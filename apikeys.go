@@ -0,0 +1,303 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"kozan/db"
+	"kozan/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// API anahtarı biçimi: kzn_<prefix>_<secret>
+const apiKeyMarker = "kzn_"
+
+func isAPIKey(token string) bool {
+	return strings.HasPrefix(token, apiKeyMarker)
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ipAllowed istemci IP'sinin izin listesinde (IP veya CIDR) olup olmadığını kontrol eder
+func ipAllowed(clientIP string, allowlist []string) bool {
+	if len(allowlist) == 0 {
+		return true
+	}
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return false
+	}
+	for _, entry := range allowlist {
+		if strings.Contains(entry, "/") {
+			if _, network, err := net.ParseCIDR(entry); err == nil && network.Contains(ip) {
+				return true
+			}
+		} else if allowed := net.ParseIP(entry); allowed != nil && allowed.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// trustedProxies X-Forwarded-For başlığına güvenilecek proxy adreslerini
+// TRUSTED_PROXIES (virgülle ayrılmış IP veya CIDR) ile okur. Varsayılan
+// hiçbiridir; aksi halde her istemci IP allowlist'ini ve audit kaydını
+// başlıkla atlatabilir.
+func trustedProxies() []string {
+	var proxies []string
+	for _, entry := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			proxies = append(proxies, entry)
+		}
+	}
+	return proxies
+}
+
+// authenticateAPIKey anahtarı doğrular ve isteğe kullanıcı bilgilerini ekler
+func authenticateAPIKey(c *gin.Context, key string) error {
+	parts := strings.SplitN(strings.TrimPrefix(key, apiKeyMarker), "_", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid key format")
+	}
+
+	var (
+		id         int
+		userID     int
+		username   string
+		roleID     int
+		keyHash    string
+		scopes     []string
+		allowedIPs []string
+		expiresAt  sql.NullTime
+	)
+	err := db.DB.QueryRow(`
+		SELECT k.id, k.user_id, u.username, u.role_id, k.key_hash, k.scopes, k.allowed_ips, k.expires_at
+		FROM api_keys k JOIN users u ON u.id = k.user_id
+		WHERE k.prefix = $1 AND k.revoked_at IS NULL`, parts[0]).
+		Scan(&id, &userID, &username, &roleID, &keyHash, pq.Array(&scopes), pq.Array(&allowedIPs), &expiresAt)
+	if err != nil {
+		return fmt.Errorf("unknown key")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashAPIKey(key)), []byte(keyHash)) != 1 {
+		return fmt.Errorf("unknown key")
+	}
+	if expiresAt.Valid && time.Now().After(expiresAt.Time) {
+		return fmt.Errorf("key expired")
+	}
+	if !ipAllowed(c.ClientIP(), allowedIPs) {
+		return fmt.Errorf("ip not allowed")
+	}
+
+	// Anahtar, sahibinin rolünde olmayan bir yetkiyi veremez
+	rolePerms := permissionSet(roleID)
+	perms := make(map[string]bool)
+	for _, scope := range scopes {
+		if rolePerms[scope] {
+			perms[scope] = true
+		}
+	}
+
+	// Her istekte yazmamak için dakikada bir güncelle
+	db.DB.Exec(`UPDATE api_keys SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`, id)
+
+	c.Set("username", username)
+	c.Set("user_id", userID)
+	c.Set("role_id", roleID)
+	c.Set("permissions", perms)
+	c.Set("api_key_id", id)
+	return nil
+}
+
+func scanAPIKey(scanner interface{ Scan(...interface{}) error }) (models.APIKey, error) {
+	var k models.APIKey
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	err := scanner.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, pq.Array(&k.Scopes), pq.Array(&k.AllowedIPs),
+		&expiresAt, &lastUsedAt, &revokedAt, &k.CreatedAt)
+	if expiresAt.Valid {
+		k.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		k.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		k.RevokedAt = &revokedAt.Time
+	}
+	return k, err
+}
+
+const apiKeyColumns = "id, user_id, name, prefix, scopes, allowed_ips, expires_at, last_used_at, revoked_at, created_at"
+
+// requireSession API anahtarı ile yapılan istekleri reddeder
+func requireSession(c *gin.Context) bool {
+	if _, ok := c.Get("api_key_id"); ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API anahtarları bu işlem için kullanılamaz"})
+		return false
+	}
+	return true
+}
+
+// API anahtarı işlemleri
+func getAPIKeysHandler(c *gin.Context) {
+	if !requireSession(c) {
+		return
+	}
+
+	query := "SELECT " + apiKeyColumns + " FROM api_keys WHERE user_id = $1 ORDER BY id"
+	args := []interface{}{currentUserID(c)}
+	// Kullanıcı yöneticileri başka bir kullanıcının anahtarlarını da görebilir
+	if userID := c.Query("user_id"); userID != "" && hasPermission(c, permUsersWrite) {
+		args[0] = userID
+	}
+
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		keys = append(keys, k)
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+func createAPIKeyHandler(c *gin.Context) {
+	if !requireSession(c) {
+		return
+	}
+
+	var input struct {
+		Name       string     `json:"name"`
+		Scopes     []string   `json:"scopes"`
+		AllowedIPs []string   `json:"allowed_ips"`
+		ExpiresAt  *time.Time `json:"expires_at"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.Name == "" || len(input.Scopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ad ve en az bir scope zorunludur"})
+		return
+	}
+	for _, scope := range input.Scopes {
+		if !isValidPermission(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz scope: " + scope})
+			return
+		}
+		if !hasPermission(c, scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Sahip olmadığınız bir yetki verilemez: " + scope})
+			return
+		}
+	}
+	for _, entry := range input.AllowedIPs {
+		if net.ParseIP(entry) == nil {
+			if _, _, err := net.ParseCIDR(entry); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz IP: " + entry})
+				return
+			}
+		}
+	}
+	if input.ExpiresAt != nil && input.ExpiresAt.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bitiş tarihi geçmişte olamaz"})
+		return
+	}
+
+	prefix, err := randomHex(4)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	secret, err := randomHex(24)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	key := apiKeyMarker + prefix + "_" + secret
+
+	row := db.DB.QueryRow(
+		"INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, allowed_ips, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING "+apiKeyColumns,
+		currentUserID(c), input.Name, prefix, hashAPIKey(key), pq.Array(input.Scopes), pq.Array(input.AllowedIPs), input.ExpiresAt,
+	)
+	apiKey, err := scanAPIKey(row)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, "api_key", apiKey.ID, auditCreate, nil, apiKey)
+
+	// Anahtarın tamamı sadece bir kez gösterilir
+	c.JSON(http.StatusCreated, gin.H{"key": key, "api_key": apiKey})
+}
+
+func revokeAPIKeyHandler(c *gin.Context) {
+	if !requireSession(c) {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var result sql.Result
+	if hasPermission(c, permUsersWrite) {
+		result, err = db.DB.Exec("UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL", id)
+	} else {
+		result, err = db.DB.Exec("UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL", id, currentUserID(c))
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	recordAudit(c, "api_key", id, auditUpdate, gin.H{"revoked": false}, gin.H{"revoked": true})
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestForgedForwardedForIgnored(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	allowlist := []string{"203.0.113.0/24"}
+
+	tests := []struct {
+		name    string
+		proxies string
+		remote  string
+		allowed bool
+	}{
+		{"proxy yok, sahte başlık", "", "198.51.100.7:5000", false},
+		{"güvenilmeyen kaynaktan sahte başlık", "10.0.0.1", "198.51.100.7:5000", false},
+		{"güvenilen proxy", "10.0.0.1", "10.0.0.1:5000", true},
+	}
+	for _, tt := range tests {
+		t.Setenv("TRUSTED_PROXIES", tt.proxies)
+		r := gin.New()
+		if err := r.SetTrustedProxies(trustedProxies()); err != nil {
+			t.Fatal(err)
+		}
+		r.GET("/", func(c *gin.Context) {
+			if !ipAllowed(c.ClientIP(), allowlist) {
+				c.Status(http.StatusForbidden)
+				return
+			}
+			c.Status(http.StatusOK)
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tt.remote
+		req.Header.Set("X-Forwarded-For", "203.0.113.10")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if got := w.Code == http.StatusOK; got != tt.allowed {
+			t.Errorf("%s: allowed = %v, want %v", tt.name, got, tt.allowed)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"kozan/db"
+	"kozan/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Audit aksiyonları
+const (
+	auditCreate    = "create"
+	auditUpdate    = "update"
+	auditDelete    = "delete"
+	auditPublish   = "publish"
+	auditUnpublish = "unpublish"
+	auditSchedule  = "schedule"
+)
+
+// toJSONMap bir modeli alan adı → değer haritasına çevirir
+func toJSONMap(v interface{}) map[string]interface{} {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var m map[string]interface{}
+	json.Unmarshal(data, &m)
+	return m
+}
+
+// jsonDiff değişen alanları {"alan": {"before": ..., "after": ...}} biçiminde döndürür
+func jsonDiff(before, after map[string]interface{}) map[string]interface{} {
+	diff := make(map[string]interface{})
+	for key, b := range before {
+		if a, ok := after[key]; !ok || !reflect.DeepEqual(a, b) {
+			diff[key] = gin.H{"before": b, "after": after[key]}
+		}
+	}
+	for key, a := range after {
+		if _, ok := before[key]; !ok {
+			diff[key] = gin.H{"before": nil, "after": a}
+		}
+	}
+	return diff
+}
+
+// auditRedactedKeys gizli değerler taşıyan alanlar; audit kaydı değiştirilemez
+// olduğu için bu alanlar hiç yazılmaz
+var auditRedactedKeys = []string{
+	"password", "secret", "token", "key_hash", "private_key", "api_key", "signature", "signature_file",
+}
+
+// redactAudit gizli alanları iç içe nesneler dahil siler. client_secret,
+// form_token gibi son ekle biten alanlar da eşleşir.
+func redactAudit(m map[string]interface{}) map[string]interface{} {
+	for key, value := range m {
+		if isRedactedAuditKey(key) {
+			delete(m, key)
+			continue
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			redactAudit(v)
+		case []interface{}:
+			for _, item := range v {
+				if nested, ok := item.(map[string]interface{}); ok {
+					redactAudit(nested)
+				}
+			}
+		}
+	}
+	return m
+}
+
+func isRedactedAuditKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range auditRedactedKeys {
+		if key == k || strings.HasSuffix(key, "_"+k) {
+			return true
+		}
+	}
+	return false
+}
+
+func nullableJSON(m map[string]interface{}) interface{} {
+	if m == nil {
+		return nil
+	}
+	data, _ := json.Marshal(m)
+	return string(data)
+}
+
+// auditActor değişikliği yapan taraftır (kullanıcı, API anahtarı veya sistem)
+type auditActor struct {
+	id        int
+	username  string
+	apiKeyID  interface{}
+	ip        string
+	userAgent string
+}
+
+// systemActor zamanlayıcı gibi arka plan işleri için kullanılır
+func systemActor(name string) auditActor {
+	return auditActor{username: name, userAgent: name}
+}
+
+func requestActor(c *gin.Context) auditActor {
+	actor := auditActor{
+		id:        currentUserID(c),
+		username:  c.GetString("username"),
+		ip:        c.ClientIP(),
+		userAgent: c.Request.UserAgent(),
+	}
+	if id, ok := c.Get("api_key_id"); ok {
+		actor.apiKeyID = id
+	}
+	return actor
+}
+
+// recordAudit bir admin değişikliğini audit_log tablosuna yazar.
+// Hata isteği bozmaz, sadece loglanır.
+func recordAudit(c *gin.Context, entityType string, entityID int, action string, before, after interface{}) {
+	writeAudit(requestActor(c), entityType, entityID, action, before, after)
+}
+
+func writeAudit(actor auditActor, entityType string, entityID int, action string, before, after interface{}) {
+	if err := insertAudit(db.DB, actor, entityType, entityID, action, before, after); err != nil {
+		log.Printf("audit log write failed (%s %s %d): %v", action, entityType, entityID, err)
+	}
+}
+
+// insertAudit audit kaydını verilen bağlantı veya işlemde yazar. Değişikliğin
+// kendisiyle aynı işlemde çağrıldığında hata işlemi geri almalıdır.
+func insertAudit(q execer, actor auditActor, entityType string, entityID int, action string, before, after interface{}) error {
+	beforeMap := redactAudit(toJSONMap(before))
+	afterMap := redactAudit(toJSONMap(after))
+	diff := jsonDiff(beforeMap, afterMap)
+	if action == auditUpdate && len(diff) == 0 {
+		return nil
+	}
+	diffJSON, _ := json.Marshal(diff)
+
+	_, err := q.Exec(
+		`INSERT INTO audit_log (actor_id, actor_username, api_key_id, ip, user_agent, entity_type, entity_id, action, before, after, diff)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		actor.id, actor.username, actor.apiKeyID, actor.ip, actor.userAgent,
+		entityType, entityID, action, nullableJSON(beforeMap), nullableJSON(afterMap), string(diffJSON),
+	)
+	return err
+}
+
+// Audit kayıtları
+func getAuditLogHandler(c *gin.Context) {
+	query := `SELECT id, actor_id, actor_username, api_key_id, ip, user_agent, entity_type, entity_id, action, before, after, diff, created_at
+		FROM audit_log WHERE 1=1`
+	var args []interface{}
+	addFilter := func(clause string, value interface{}) {
+		args = append(args, value)
+		query += fmt.Sprintf(" AND %s $%d", clause, len(args))
+	}
+
+	if v := c.Query("entity_type"); v != "" {
+		addFilter("entity_type =", v)
+	}
+	if v := c.Query("entity_id"); v != "" {
+		addFilter("entity_id =", v)
+	}
+	if v := c.Query("actor_id"); v != "" {
+		addFilter("actor_id =", v)
+	}
+	if v := c.Query("action"); v != "" {
+		addFilter("action =", v)
+	}
+	for param, clause := range map[string]string{"from": "created_at >=", "to": "created_at <"} {
+		if v := c.Query(param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz tarih: " + param})
+				return
+			}
+			addFilter(clause, t)
+		}
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if offset < 0 {
+		offset = 0
+	}
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT %d OFFSET %d", limit, offset)
+
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var e models.AuditEntry
+		var before, after []byte
+		var diff []byte
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.ActorUsername, &e.APIKeyID, &e.IP, &e.UserAgent,
+			&e.EntityType, &e.EntityID, &e.Action, &before, &after, &diff, &e.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		e.Before = json.RawMessage(before)
+		e.After = json.RawMessage(after)
+		e.Diff = json.RawMessage(diff)
+		entries = append(entries, e)
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJSONDiff(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after map[string]interface{}
+		want          []string
+	}{
+		{"unchanged", map[string]interface{}{"a": 1.0}, map[string]interface{}{"a": 1.0}, nil},
+		{"changed", map[string]interface{}{"a": 1.0}, map[string]interface{}{"a": 2.0}, []string{"a"}},
+		{"added", map[string]interface{}{}, map[string]interface{}{"b": "x"}, []string{"b"}},
+		{"removed", map[string]interface{}{"c": true}, map[string]interface{}{}, []string{"c"}},
+		{"create", nil, map[string]interface{}{"a": 1.0, "b": 2.0}, []string{"a", "b"}},
+		{"nested equal", map[string]interface{}{"n": []interface{}{"x"}}, map[string]interface{}{"n": []interface{}{"x"}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := jsonDiff(tt.before, tt.after)
+			if len(diff) != len(tt.want) {
+				t.Fatalf("got %v, want keys %v", diff, tt.want)
+			}
+			for _, key := range tt.want {
+				if _, ok := diff[key]; !ok {
+					t.Errorf("missing key %q in %v", key, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestRedactAudit(t *testing.T) {
+	got := redactAudit(map[string]interface{}{
+		"id":            1.0,
+		"key":           "maintenance_reminder",
+		"password":      "hash",
+		"secret":        "whsec",
+		"client_secret": "x",
+		"form_token":    "y",
+		"signature":     "sig",
+		"nested":        map[string]interface{}{"key_hash": "h", "name": "n"},
+		"items":         []interface{}{map[string]interface{}{"token": "t", "ok": true}},
+	})
+	want := map[string]interface{}{
+		"id":     1.0,
+		"key":    "maintenance_reminder",
+		"nested": map[string]interface{}{"name": "n"},
+		"items":  []interface{}{map[string]interface{}{"ok": true}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
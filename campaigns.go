@@ -0,0 +1,209 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"kozan/db"
+	"kozan/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Kampanya / banner işlemleri. Kampanyalar diğer içerikler gibi taslak olarak
+// oluşturulur, yayına alma ve kaldırma genellikle zamanlanır.
+const campaignColumns = "id, title, description, description_format, image, link, product_id, special_price"
+
+func scanCampaign(scanner interface{ Scan(...interface{}) error }) (models.Campaign, error) {
+	var campaign models.Campaign
+	var productID sql.NullInt64
+	var specialPrice sql.NullFloat64
+	err := scanner.Scan(&campaign.ID, &campaign.Title, &campaign.Description, &campaign.DescriptionFormat, &campaign.Image, &campaign.Link, &productID, &specialPrice)
+	if productID.Valid {
+		v := int(productID.Int64)
+		campaign.ProductID = &v
+	}
+	if specialPrice.Valid {
+		campaign.SpecialPrice = &specialPrice.Float64
+	}
+	return campaign, err
+}
+
+func getCampaign(id int) (models.Campaign, error) {
+	return scanCampaign(db.DB.QueryRow("SELECT "+campaignColumns+" FROM campaigns WHERE id = $1", id))
+}
+
+func listCampaigns() ([]models.Campaign, error) {
+	rows, err := db.DB.Query("SELECT " + campaignColumns + " FROM campaigns ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	campaigns := []models.Campaign{}
+	for rows.Next() {
+		campaign, err := scanCampaign(rows)
+		if err != nil {
+			return nil, err
+		}
+		campaigns = append(campaigns, campaign)
+	}
+	return campaigns, nil
+}
+
+// applyCampaignPrices yayındaki (önizlemede taslak) kampanyaların özel fiyatını
+// bağlı ürünlere uygular. Normal fiyat regularPrice alanında döner; bir ürüne
+// birden fazla kampanya bağlıysa en düşük fiyat geçerlidir.
+func applyCampaignPrices(c *gin.Context, products []map[string]interface{}) error {
+	campaigns, err := publicItems(c, "campaign")
+	if err != nil {
+		return err
+	}
+
+	type offer struct {
+		campaignID interface{}
+		price      float64
+	}
+	offers := map[float64]offer{}
+	for _, campaign := range campaigns {
+		productID, ok1 := campaign["productId"].(float64)
+		price, ok2 := campaign["specialPrice"].(float64)
+		if !ok1 || !ok2 || price <= 0 {
+			continue
+		}
+		if current, ok := offers[productID]; !ok || price < current.price {
+			offers[productID] = offer{campaign["id"], price}
+		}
+	}
+
+	for _, product := range products {
+		id, _ := product["id"].(float64)
+		o, ok := offers[id]
+		regular, _ := product["price"].(float64)
+		if !ok || (regular > 0 && o.price >= regular) {
+			continue
+		}
+		product["regularPrice"] = regular
+		product["price"] = o.price
+		product["campaignId"] = o.campaignID
+	}
+	return nil
+}
+
+func getCampaignsHandler(c *gin.Context) {
+	campaigns, err := listCampaigns()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, campaigns)
+}
+
+func getPublicCampaignsHandler(c *gin.Context) {
+	servePublic(c, "campaign")
+}
+
+func createCampaignHandler(c *gin.Context) {
+	var campaign models.Campaign
+	if err := c.BindJSON(&campaign); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateCampaign(&campaign); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := saveContentChange(c, "campaign", auditCreate, nil, 0, func(tx *sql.Tx) (int, interface{}, error) {
+		err := tx.QueryRow(
+			"INSERT INTO campaigns (title, description, description_format, image, link, product_id, special_price) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+			campaign.Title, campaign.Description, campaign.DescriptionFormat, campaign.Image, campaign.Link, campaign.ProductID, campaign.SpecialPrice,
+		).Scan(&campaign.ID)
+		return campaign.ID, campaign, err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, campaign)
+}
+
+func updateCampaignHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var campaign models.Campaign
+	if err := c.BindJSON(&campaign); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateCampaign(&campaign); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before, err := getCampaign(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+
+	campaign.ID = id
+	err = saveContentChange(c, "campaign", auditUpdate, before, 0, func(tx *sql.Tx) (int, interface{}, error) {
+		return id, campaign, updateCampaign(tx, campaign)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, campaign)
+}
+
+// validateCampaign başlığı zorunlu tutar ve açıklamayı biçimine göre temizler
+func validateCampaign(campaign *models.Campaign) error {
+	if err := normalizeRichText(&campaign.Description, &campaign.DescriptionFormat); err != nil {
+		return err
+	}
+	if campaign.Title == "" {
+		return fmt.Errorf("Başlık zorunludur")
+	}
+	return nil
+}
+
+func updateCampaign(tx *sql.Tx, campaign models.Campaign) error {
+	_, err := tx.Exec(
+		"UPDATE campaigns SET title = $1, description = $2, description_format = $3, image = $4, link = $5, product_id = $6, special_price = $7 WHERE id = $8",
+		campaign.Title, campaign.Description, campaign.DescriptionFormat, campaign.Image, campaign.Link, campaign.ProductID, campaign.SpecialPrice, campaign.ID,
+	)
+	return err
+}
+
+func deleteCampaignHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	before, err := getCampaign(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+
+	if _, err := db.DB.Exec("DELETE FROM campaigns WHERE id = $1", id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	unpublishEntity(db.DB, "campaign", id)
+	cancelSchedules("campaign", id)
+	recordAudit(c, "campaign", id, auditDelete, before, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Campaign deleted"})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// captchaVerifier public formlarda isteğe bağlı CAPTCHA doğrulamasıdır.
+// CAPTCHA_PROVIDER boşsa doğrulama yapılmaz.
+type captchaVerifier interface {
+	Verify(ctx context.Context, token, remoteIP string) error
+}
+
+// siteVerifyCaptcha Turnstile, hCaptcha ve reCAPTCHA'nın ortak siteverify API'si
+type siteVerifyCaptcha struct {
+	endpoint string
+	secret   string
+	client   *http.Client
+}
+
+var captchaEndpoints = map[string]string{
+	"turnstile": "https://challenges.cloudflare.com/turnstile/v0/siteverify",
+	"hcaptcha":  "https://api.hcaptcha.com/siteverify",
+	"recaptcha": "https://www.google.com/recaptcha/api/siteverify",
+}
+
+var captcha captchaVerifier
+
+func initCaptcha() {
+	provider := strings.ToLower(os.Getenv("CAPTCHA_PROVIDER"))
+	if provider == "" {
+		return
+	}
+	endpoint, ok := captchaEndpoints[provider]
+	if !ok {
+		log.Fatalf("Bilinmeyen CAPTCHA_PROVIDER: %s", provider)
+	}
+	if os.Getenv("CAPTCHA_SECRET") == "" {
+		log.Fatal("CAPTCHA_SECRET zorunludur")
+	}
+	captcha = &siteVerifyCaptcha{
+		endpoint: endpoint,
+		secret:   os.Getenv("CAPTCHA_SECRET"),
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (v *siteVerifyCaptcha) Verify(ctx context.Context, token, remoteIP string) error {
+	if token == "" {
+		return fmt.Errorf("captcha token missing")
+	}
+	form := url.Values{"secret": {v.secret}, "response": {token}, "remoteip": {remoteIP}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Success bool     `json:"success"`
+		Errors  []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	if !result.Success {
+		return fmt.Errorf("captcha rejected: %v", result.Errors)
+	}
+	return nil
+}
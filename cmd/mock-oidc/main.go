@@ -0,0 +1,40 @@
+// mock-oidc yerel geliştirme için OIDC sağlayıcısını başlatır. Her yetkilendirme
+// isteğini MOCK_OIDC_* ile tanımlanan kullanıcı adına onaylar.
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"kozan/mockoidc"
+)
+
+func main() {
+	addr := os.Getenv("MOCK_OIDC_ADDR")
+	if addr == "" {
+		addr = ":9000"
+	}
+	provider := &mockoidc.Provider{
+		Issuer: os.Getenv("MOCK_OIDC_ISSUER"),
+		Email:  os.Getenv("MOCK_OIDC_EMAIL"),
+		Groups: strings.Split(os.Getenv("MOCK_OIDC_GROUPS"), ","),
+	}
+	if provider.Issuer == "" {
+		provider.Issuer = "http://localhost:9000"
+	}
+	if provider.Email == "" {
+		provider.Email = "ofis@klimakozan.com"
+	}
+	if os.Getenv("MOCK_OIDC_GROUPS") == "" {
+		provider.Groups = []string{"ofis"}
+	}
+
+	handler, err := provider.Handler()
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Mock OIDC provider listening on %s (issuer %s)", addr, provider.Issuer)
+	log.Fatal(http.ListenAndServe(addr, handler))
+}
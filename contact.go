@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"kozan/models"
+)
+
+// İletişim kanalları: telefonlar E.164 biçiminde saklanır, harita sadece
+// bilinen sağlayıcılardan gömülebilir ve sosyal kanallar https olmalıdır.
+var phoneTypes = map[string]bool{"landline": true, "mobile": true, "whatsapp": true}
+
+var channelTypes = map[string]bool{
+	"instagram": true, "facebook": true, "x": true, "youtube": true,
+	"linkedin": true, "tiktok": true, "telegram": true, "google": true,
+}
+
+// Harita iframe'i için izin verilen adresler
+var mapEmbedHosts = map[string]bool{
+	"www.google.com":        true,
+	"maps.google.com":       true,
+	"www.openstreetmap.org": true,
+	"yandex.com.tr":         true,
+}
+
+var e164Re = regexp.MustCompile(`^\+[1-9]\d{7,14}$`)
+
+// normalizePhone Türkiye numaralarını E.164'e çevirir:
+// "0538 515 31 91", "538 515 3191", "0090 538..." → "+905385153191"
+func normalizePhone(raw string) (string, error) {
+	var b strings.Builder
+	for i, r := range strings.TrimSpace(raw) {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '+' && i == 0:
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '(' || r == ')' || r == '.':
+		default:
+			return "", fmt.Errorf("geçersiz telefon numarası: %s", raw)
+		}
+	}
+
+	number := b.String()
+	switch {
+	case strings.HasPrefix(number, "+"):
+	case strings.HasPrefix(number, "00"):
+		number = "+" + number[2:]
+	case strings.HasPrefix(number, "0") && len(number) == 11:
+		number = "+90" + number[1:]
+	case len(number) == 10:
+		number = "+90" + number
+	}
+
+	if !e164Re.MatchString(number) {
+		return "", fmt.Errorf("geçersiz telefon numarası: %s", raw)
+	}
+	return number, nil
+}
+
+// Türkiye'de cep numaraları +905 ile başlar
+func isMobileNumber(number string) bool {
+	return strings.HasPrefix(number, "+905")
+}
+
+func phoneHref(phone models.ContactPhone) string {
+	if phone.Type == "whatsapp" {
+		return "https://wa.me/" + strings.TrimPrefix(phone.Number, "+")
+	}
+	return "tel:" + phone.Number
+}
+
+// fillContactPhones eski tek phone alanıyla phones listesini eşitler ve linkleri üretir
+func fillContactPhones(contact *models.Contact) {
+	if len(contact.Phones) == 0 && contact.Phone != "" {
+		number, err := normalizePhone(contact.Phone)
+		if err != nil {
+			number = contact.Phone
+		}
+		phoneType := "landline"
+		if isMobileNumber(number) {
+			phoneType = "mobile"
+		}
+		contact.Phones = []models.ContactPhone{{Type: phoneType, Number: number}}
+	}
+	if contact.Phones == nil {
+		contact.Phones = []models.ContactPhone{}
+	}
+	for i := range contact.Phones {
+		contact.Phones[i].Href = phoneHref(contact.Phones[i])
+	}
+	if len(contact.Phones) > 0 {
+		contact.Phone = contact.Phones[0].Number
+	}
+}
+
+// validateContact admin panelinden gelen iletişim kaydını doğrular ve normalleştirir
+func validateContact(contact *models.Contact) error {
+	// Sadece phone gönderen eski istemciler için
+	if len(contact.Phones) == 0 && contact.Phone != "" {
+		contact.Phones = []models.ContactPhone{{Number: contact.Phone}}
+	}
+	for i := range contact.Phones {
+		phone := &contact.Phones[i]
+		number, err := normalizePhone(phone.Number)
+		if err != nil {
+			return err
+		}
+		phone.Number = number
+		if phone.Type == "" {
+			phone.Type = "landline"
+			if isMobileNumber(number) {
+				phone.Type = "mobile"
+			}
+		}
+		if !phoneTypes[phone.Type] {
+			return fmt.Errorf("geçersiz telefon tipi: %s", phone.Type)
+		}
+		if phone.Type == "whatsapp" && !isMobileNumber(number) && strings.HasPrefix(number, "+90") {
+			return fmt.Errorf("WhatsApp numarası cep telefonu olmalı: %s", number)
+		}
+	}
+	if len(contact.Phones) == 0 {
+		contact.Phone = ""
+	}
+
+	if contact.Latitude < -90 || contact.Latitude > 90 {
+		return fmt.Errorf("enlem -90 ile 90 arasında olmalı")
+	}
+	if contact.Longitude < -180 || contact.Longitude > 180 {
+		return fmt.Errorf("boylam -180 ile 180 arasında olmalı")
+	}
+	if (contact.Latitude == 0) != (contact.Longitude == 0) {
+		return fmt.Errorf("enlem ve boylam birlikte girilmeli")
+	}
+
+	if contact.MapEmbedURL != "" {
+		u, err := url.Parse(contact.MapEmbedURL)
+		if err != nil || u.Scheme != "https" || !mapEmbedHosts[u.Host] {
+			return fmt.Errorf("harita adresi Google Maps, OpenStreetMap veya Yandex gömme linki olmalı")
+		}
+	}
+
+	for _, ch := range contact.Channels {
+		if !channelTypes[ch.Type] {
+			return fmt.Errorf("geçersiz kanal tipi: %s", ch.Type)
+		}
+		u, err := url.Parse(ch.URL)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("%s adresi https ile başlamalı", ch.Type)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"0538 515 31 91", "+905385153191", false},
+		{"538 515 3191", "+905385153191", false},
+		{"0090 538 515 31 91", "+905385153191", false},
+		{"+90 (538) 515-31-91", "+905385153191", false},
+		{"0322.515.31.91", "+903225153191", false},
+		{"  05385153191  ", "+905385153191", false},
+		{"+49 30 1234567", "+49301234567", false},
+		{"0049 30 1234567", "+49301234567", false},
+		{"", "", true},
+		{"12345", "", true},
+		{"0538 515 31 9a", "", true},
+		{"538+5153191", "", true},
+		{"+0 538 515 31 91", "", true},
+		{"+90 538 515 31 91 12345678", "", true},
+	}
+	for _, tt := range tests {
+		got, err := normalizePhone(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("normalizePhone(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("normalizePhone(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
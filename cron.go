@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule beş alanlı cron ifadesidir: dakika saat gün ay haftanın-günü.
+// "*", "5", "1-5", "*/15", "0,30" ve "@hourly", "@daily", "@weekly" (Pazar 00:00) desteklenir.
+// Saatler businessLocation'a göre yorumlanır.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// Gün ve haftanın günü birlikte kısıtlıysa biri tutması yeterlidir (standart cron)
+	domRestricted, dowRestricted bool
+}
+
+var cronAliases = map[string]string{
+	"@hourly": "0 * * * *",
+	"@daily":  "0 0 * * *",
+	"@weekly": "0 0 * * 0",
+}
+
+func parseCron(spec string) (*cronSchedule, error) {
+	if alias, ok := cronAliases[spec]; ok {
+		spec = alias
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron ifadesi 5 alan olmalı: %q", spec)
+	}
+
+	s := &cronSchedule{}
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	// 0 ve 7 Pazar
+	if s.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domRestricted = fields[2] != "*"
+	s.dowRestricted = fields[4] != "*"
+	// Haftanın günü serbestken seçilen ayların hiçbirinde olmayan günler
+	// ("0 0 31 2 *") hiç çalışmaz
+	if s.domRestricted && !s.dowRestricted && !s.hasPossibleDay() {
+		return nil, fmt.Errorf("cron ifadesi hiçbir tarihte çalışmaz: %q", spec)
+	}
+	return s, nil
+}
+
+// cronMonthDays ayların en fazla gün sayısı (Şubat artık yıla göre 29)
+var cronMonthDays = [13]int{0, 31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+func (s *cronSchedule) hasPossibleDay() bool {
+	for month := 1; month <= 12; month++ {
+		if s.month&(1<<uint(month)) == 0 {
+			continue
+		}
+		for day := 1; day <= cronMonthDays[month]; day++ {
+			if s.dom&(1<<uint(day)) != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("geçersiz cron adımı: %q", part)
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = strconv.Atoi(from); err != nil {
+				return 0, fmt.Errorf("geçersiz cron değeri: %q", part)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(to); err != nil {
+					return 0, fmt.Errorf("geçersiz cron değeri: %q", part)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("cron değeri %d-%d aralığında olmalı: %q", min, max, part)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domOK := s.dom&(1<<uint(t.Day())) != 0
+	dowOK := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domRestricted && s.dowRestricted {
+		return domOK || dowOK
+	}
+	return domOK && dowOK
+}
+
+// next after'dan sonraki ilk çalışma zamanını döndürür
+func (s *cronSchedule) next(after time.Time) time.Time {
+	t := after.In(businessLocation).Truncate(time.Minute).Add(time.Minute)
+	// parseCron imkânsız günleri reddettiği için en seyrek ifade (29 Şubat)
+	// için bile birkaç yıl yeterlidir
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, businessLocation)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, businessLocation)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, businessLocation)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return limit
+}
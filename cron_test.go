@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		spec string
+		ok   bool
+	}{
+		{"* * * * *", true},
+		{"@hourly", true},
+		{"@daily", true},
+		{"*/15 9-17 * * 1-5", true},
+		{"0,30 3 1 * *", true},
+		{"0 0 29 2 *", true},
+		{"0 0 31 1,2 *", true},
+		{"0 0 * * 7", true},
+		{"0 0 31 2 *", false},
+		{"0 0 30,31 2 *", false},
+		{"0 0 31 4,6,9,11 *", false},
+		{"* * * *", false},
+		{"60 * * * *", false},
+		{"* 24 * * *", false},
+		{"* * 0 * *", false},
+		{"* * * 13 *", false},
+		{"* * * * 8", false},
+		{"*/0 * * * *", false},
+		{"5-1 * * * *", false},
+		{"a * * * *", false},
+		{"@yearly", false},
+	}
+	for _, tt := range tests {
+		_, err := parseCron(tt.spec)
+		if (err == nil) != tt.ok {
+			t.Errorf("parseCron(%q) error = %v, want ok = %v", tt.spec, err, tt.ok)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	at := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, businessLocation)
+	}
+	tests := []struct {
+		spec  string
+		after time.Time
+		want  time.Time
+	}{
+		{"* * * * *", at(2026, 3, 10, 14, 5), at(2026, 3, 10, 14, 6)},
+		{"@hourly", at(2026, 3, 10, 14, 0), at(2026, 3, 10, 15, 0)},
+		{"30 3 * * *", at(2026, 3, 10, 3, 30), at(2026, 3, 11, 3, 30)},
+		{"30 3 * * *", at(2026, 3, 10, 2, 59), at(2026, 3, 10, 3, 30)},
+		{"*/15 * * * *", at(2026, 3, 10, 14, 16), at(2026, 3, 10, 14, 30)},
+		{"0 0 1 * *", at(2026, 12, 15, 8, 0), at(2027, 1, 1, 0, 0)},
+		// 10 Mart 2026 Salı
+		{"0 9 * * 1-5", at(2026, 3, 13, 10, 0), at(2026, 3, 16, 9, 0)},
+		{"@weekly", at(2026, 3, 10, 0, 0), at(2026, 3, 15, 0, 0)},
+		{"0 0 * * 7", at(2026, 3, 10, 0, 0), at(2026, 3, 15, 0, 0)},
+		// Gün ve haftanın günü birlikteyse biri yeterli
+		{"0 0 20 * 0", at(2026, 3, 10, 0, 0), at(2026, 3, 15, 0, 0)},
+		{"0 0 29 2 *", at(2026, 3, 1, 0, 0), at(2028, 2, 29, 0, 0)},
+		{"0 0 31 * *", at(2026, 4, 1, 0, 0), at(2026, 5, 31, 0, 0)},
+	}
+	for _, tt := range tests {
+		s, err := parseCron(tt.spec)
+		if err != nil {
+			t.Fatalf("parseCron(%q): %v", tt.spec, err)
+		}
+		if got := s.next(tt.after); !got.Equal(tt.want) {
+			t.Errorf("%q.next(%v) = %v, want %v", tt.spec, tt.after, got, tt.want)
+		}
+	}
+}
@@ -59,6 +59,22 @@ func createTables() {
 		log.Fatal(err)
 	}
 
+	// SSO columns
+	_, err = DB.Exec(`
+		ALTER TABLE users
+			ADD COLUMN IF NOT EXISTS oidc_issuer TEXT,
+			ADD COLUMN IF NOT EXISTS oidc_subject TEXT,
+			ADD COLUMN IF NOT EXISTS oidc_provisioned BOOLEAN NOT NULL DEFAULT FALSE
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, err = DB.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS users_oidc_subject_key ON users (oidc_issuer, oidc_subject)`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// Services table
 	_, err = DB.Exec(`
 		CREATE TABLE IF NOT EXISTS services (
@@ -131,5 +147,934 @@ func createTables() {
 		log.Fatal(err)
 	}
 
+	// API keys table
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS api_keys (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			name VARCHAR(255) NOT NULL,
+			prefix VARCHAR(16) NOT NULL UNIQUE,
+			key_hash VARCHAR(64) NOT NULL,
+			scopes TEXT[] NOT NULL DEFAULT '{}',
+			allowed_ips TEXT[] NOT NULL DEFAULT '{}',
+			expires_at TIMESTAMP WITH TIME ZONE,
+			last_used_at TIMESTAMP WITH TIME ZONE,
+			revoked_at TIMESTAMP WITH TIME ZONE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Audit log table
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS audit_log (
+			id BIGSERIAL PRIMARY KEY,
+			actor_id INTEGER NOT NULL,
+			actor_username VARCHAR(255) NOT NULL,
+			api_key_id INTEGER,
+			ip VARCHAR(64) NOT NULL,
+			user_agent TEXT NOT NULL,
+			entity_type VARCHAR(50) NOT NULL,
+			entity_id INTEGER NOT NULL,
+			action VARCHAR(20) NOT NULL,
+			before JSONB,
+			after JSONB,
+			diff JSONB NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, err = DB.Exec(`CREATE INDEX IF NOT EXISTS audit_log_entity_idx ON audit_log (entity_type, entity_id)`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Append-only tables reject changes through this trigger function. The only
+	// exception is KVKK anonymization, which strips personal fields from rows
+	// inside a transaction that sets kozan.redaction; rows are never deleted.
+	_, err = DB.Exec(`
+		CREATE OR REPLACE FUNCTION append_only_table() RETURNS trigger AS $$
+		BEGIN
+			IF TG_OP = 'UPDATE' AND current_setting('kozan.redaction', true) = 'on' THEN
+				RETURN NEW;
+			END IF;
+			RAISE EXCEPTION '% is append-only', TG_TABLE_NAME;
+		END;
+		$$ LANGUAGE plpgsql;
+
+		DROP TRIGGER IF EXISTS audit_log_no_update ON audit_log;
+		CREATE TRIGGER audit_log_no_update BEFORE UPDATE OR DELETE ON audit_log
+			FOR EACH ROW EXECUTE FUNCTION append_only_table();
+
+		DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log;
+		CREATE TRIGGER audit_log_no_truncate BEFORE TRUNCATE ON audit_log
+			FOR EACH STATEMENT EXECUTE FUNCTION append_only_table();
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Content revisions table
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS content_revisions (
+			id BIGSERIAL PRIMARY KEY,
+			entity_type VARCHAR(50) NOT NULL,
+			entity_id INTEGER NOT NULL,
+			revision INTEGER NOT NULL,
+			data JSONB NOT NULL,
+			author_id INTEGER NOT NULL,
+			author_username VARCHAR(255) NOT NULL,
+			restored_from INTEGER,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (entity_type, entity_id, revision)
+		)
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Revisions are append-only, like the audit log
+	_, err = DB.Exec(`
+		DROP TRIGGER IF EXISTS content_revisions_no_update ON content_revisions;
+		CREATE TRIGGER content_revisions_no_update BEFORE UPDATE OR DELETE ON content_revisions
+			FOR EACH ROW EXECUTE FUNCTION append_only_table();
+
+		DROP TRIGGER IF EXISTS content_revisions_no_truncate ON content_revisions;
+		CREATE TRIGGER content_revisions_no_truncate BEFORE TRUNCATE ON content_revisions
+			FOR EACH STATEMENT EXECUTE FUNCTION append_only_table();
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Campaigns table
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS campaigns (
+			id SERIAL PRIMARY KEY,
+			title VARCHAR(255) NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			image TEXT NOT NULL DEFAULT '',
+			link TEXT NOT NULL DEFAULT '',
+			product_id INTEGER REFERENCES products(id) ON DELETE SET NULL,
+			special_price DECIMAL(10,2)
+		)
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Publish schedule table
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS publish_schedule (
+			id SERIAL PRIMARY KEY,
+			entity_type VARCHAR(50) NOT NULL,
+			entity_id INTEGER NOT NULL,
+			action VARCHAR(20) NOT NULL,
+			run_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			error TEXT,
+			created_by INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			executed_at TIMESTAMP WITH TIME ZONE
+		)
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, err = DB.Exec(`CREATE INDEX IF NOT EXISTS publish_schedule_due_idx ON publish_schedule (run_at) WHERE status = 'pending'`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Published content table
+	var publishedExists bool
+	err = DB.QueryRow(`SELECT to_regclass('published_content') IS NOT NULL`).Scan(&publishedExists)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS published_content (
+			entity_type VARCHAR(50) NOT NULL,
+			entity_id INTEGER NOT NULL,
+			data JSONB NOT NULL,
+			published_by INTEGER NOT NULL DEFAULT 0,
+			published_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (entity_type, entity_id)
+		)
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Yayın akışından önceki içerik zaten canlıydı, ilk kurulumda yayına al
+	if !publishedExists {
+		_, err = DB.Exec(`
+			INSERT INTO published_content (entity_type, entity_id, data)
+			SELECT 'product', id, jsonb_build_object('id', id, 'name', name, 'description', COALESCE(description, ''), 'price', COALESCE(price, 0), 'image', COALESCE(image, '')) FROM products;
+
+			INSERT INTO published_content (entity_type, entity_id, data)
+			SELECT 'service', id, jsonb_build_object('id', id, 'title', title, 'description', COALESCE(description, ''), 'image', COALESCE(image, '')) FROM services;
+
+			INSERT INTO published_content (entity_type, entity_id, data)
+			SELECT 'about', id, jsonb_build_object('id', id, 'title', title, 'content', COALESCE(content, ''), 'image', COALESCE(image, '')) FROM about ORDER BY id LIMIT 1;
+
+			INSERT INTO published_content (entity_type, entity_id, data)
+			SELECT 'hero', id, jsonb_build_object('id', id, 'subheading', COALESCE(subheading, ''), 'heading', COALESCE(heading, ''), 'buttonText', COALESCE(button_text, ''), 'backgroundImage', COALESCE(background_image, '')) FROM hero ORDER BY id LIMIT 1;
+
+			INSERT INTO published_content (entity_type, entity_id, data)
+			SELECT 'footer', id, jsonb_build_object('id', id, 'copyright', COALESCE(copyright, ''), 'socialLinks', social_links, 'links', links) FROM footer ORDER BY id LIMIT 1;
+		`)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Translations table
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS translations (
+			entity_type VARCHAR(50) NOT NULL,
+			entity_id INTEGER NOT NULL,
+			locale VARCHAR(10) NOT NULL,
+			field VARCHAR(50) NOT NULL,
+			value TEXT NOT NULL,
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (entity_type, entity_id, locale, field)
+		)
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Published content carries the translations it was published with, so
+	// translation edits stay in draft until the next publish. Rows published
+	// before the snapshot existed take the translations current at upgrade.
+	_, err = DB.Exec(`
+		ALTER TABLE published_content ADD COLUMN IF NOT EXISTS translations JSONB;
+
+		UPDATE published_content p SET translations = (
+			SELECT COALESCE(jsonb_object_agg(locale, fields), '{}'::jsonb) FROM (
+				SELECT locale, jsonb_object_agg(field, value) AS fields FROM translations t
+				WHERE t.entity_type = p.entity_type AND t.entity_id = p.entity_id AND value <> '' GROUP BY locale) s)
+		WHERE translations IS NULL;
+
+		ALTER TABLE published_content ALTER COLUMN translations SET DEFAULT '{}'::jsonb,
+			ALTER COLUMN translations SET NOT NULL;
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Preview links are bound to one record; bumping its version revokes them
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS preview_versions (
+			entity_type VARCHAR(50) NOT NULL,
+			entity_id INTEGER NOT NULL,
+			version INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (entity_type, entity_id)
+		)
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Rich text format columns (text, markdown, html)
+	_, err = DB.Exec(`
+		ALTER TABLE products ADD COLUMN IF NOT EXISTS description_format VARCHAR(10) NOT NULL DEFAULT 'text';
+		ALTER TABLE services ADD COLUMN IF NOT EXISTS description_format VARCHAR(10) NOT NULL DEFAULT 'text';
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS description_format VARCHAR(10) NOT NULL DEFAULT 'text';
+		ALTER TABLE about ADD COLUMN IF NOT EXISTS content_format VARCHAR(10) NOT NULL DEFAULT 'text';
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// SEO columns and slug redirects
+	_, err = DB.Exec(`
+		ALTER TABLE products
+			ADD COLUMN IF NOT EXISTS slug TEXT,
+			ADD COLUMN IF NOT EXISTS seo_title TEXT NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS seo_description TEXT NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS og_image TEXT NOT NULL DEFAULT '';
+		ALTER TABLE services
+			ADD COLUMN IF NOT EXISTS slug TEXT,
+			ADD COLUMN IF NOT EXISTS seo_title TEXT NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS seo_description TEXT NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS og_image TEXT NOT NULL DEFAULT '';
+		CREATE UNIQUE INDEX IF NOT EXISTS products_slug_key ON products (slug);
+		CREATE UNIQUE INDEX IF NOT EXISTS services_slug_key ON services (slug);
+
+		CREATE TABLE IF NOT EXISTS slug_redirects (
+			entity_type VARCHAR(50) NOT NULL,
+			old_slug TEXT NOT NULL,
+			entity_id INTEGER NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (entity_type, old_slug)
+		);
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Opening hours tables
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS opening_hours (
+			weekday SMALLINT PRIMARY KEY CHECK (weekday BETWEEN 1 AND 7),
+			intervals JSONB NOT NULL DEFAULT '[]',
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS opening_hours_exceptions (
+			id SERIAL PRIMARY KEY,
+			label VARCHAR(255) NOT NULL,
+			start_date DATE NOT NULL,
+			end_date DATE NOT NULL CHECK (end_date >= start_date),
+			closed BOOLEAN NOT NULL DEFAULT FALSE,
+			intervals JSONB NOT NULL DEFAULT '[]',
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Contact phones, map and channels
+	_, err = DB.Exec(`
+		ALTER TABLE contact
+			ADD COLUMN IF NOT EXISTS phones JSONB NOT NULL DEFAULT '[]',
+			ADD COLUMN IF NOT EXISTS map_embed_url TEXT NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS channels JSONB NOT NULL DEFAULT '[]'
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Customers and work orders
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS customers (
+			id SERIAL PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			phone VARCHAR(50),
+			email VARCHAR(255),
+			address TEXT,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS customers_phone_idx ON customers (phone);
+		CREATE INDEX IF NOT EXISTS customers_email_idx ON customers (lower(email));
+
+		CREATE TABLE IF NOT EXISTS work_orders (
+			id SERIAL PRIMARY KEY,
+			customer_id INTEGER NOT NULL REFERENCES customers(id),
+			title VARCHAR(255) NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			address TEXT NOT NULL DEFAULT '',
+			status VARCHAR(20) NOT NULL DEFAULT 'new',
+			source_message_id INTEGER,
+			created_by INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS work_orders_customer_idx ON work_orders (customer_id);
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Contact form messages (lead inbox)
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS contact_messages (
+			id SERIAL PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			email VARCHAR(255) NOT NULL DEFAULT '',
+			phone VARCHAR(50) NOT NULL DEFAULT '',
+			subject VARCHAR(255) NOT NULL DEFAULT '',
+			message TEXT NOT NULL,
+			ip VARCHAR(64) NOT NULL DEFAULT '',
+			user_agent TEXT NOT NULL DEFAULT '',
+			is_read BOOLEAN NOT NULL DEFAULT FALSE,
+			assigned_to INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			work_order_id INTEGER REFERENCES work_orders(id) ON DELETE SET NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS contact_messages_ip_idx ON contact_messages (ip, created_at);
+
+		CREATE TABLE IF NOT EXISTS contact_message_notes (
+			id SERIAL PRIMARY KEY,
+			message_id INTEGER NOT NULL REFERENCES contact_messages(id) ON DELETE CASCADE,
+			author_id INTEGER NOT NULL,
+			author_username VARCHAR(255) NOT NULL,
+			note TEXT NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// KVKK: consent texts, consent ledger and data subject requests
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS consent_texts (
+			id SERIAL PRIMARY KEY,
+			purpose VARCHAR(50) NOT NULL,
+			version INTEGER NOT NULL,
+			title VARCHAR(255) NOT NULL,
+			body TEXT NOT NULL,
+			required BOOLEAN NOT NULL DEFAULT FALSE,
+			created_by INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (purpose, version)
+		);
+
+		CREATE TABLE IF NOT EXISTS consents (
+			id BIGSERIAL PRIMARY KEY,
+			email_hash VARCHAR(64) NOT NULL DEFAULT '',
+			phone_hash VARCHAR(64) NOT NULL DEFAULT '',
+			purpose VARCHAR(50) NOT NULL,
+			version INTEGER NOT NULL,
+			granted BOOLEAN NOT NULL,
+			source VARCHAR(50) NOT NULL,
+			source_id INTEGER,
+			ip VARCHAR(64) NOT NULL DEFAULT '',
+			user_agent TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS consents_email_idx ON consents (email_hash) WHERE email_hash <> '';
+		CREATE INDEX IF NOT EXISTS consents_phone_idx ON consents (phone_hash) WHERE phone_hash <> '';
+
+		DROP TRIGGER IF EXISTS consents_no_update ON consents;
+		CREATE TRIGGER consents_no_update BEFORE UPDATE OR DELETE ON consents
+			FOR EACH ROW EXECUTE FUNCTION append_only_table();
+
+		-- Consent evidence keeps only the network part of the IP (/24 for IPv4,
+		-- /48 for IPv6) and a capped user agent; shorten rows written before that
+		SELECT set_config('kozan.redaction', 'on', true);
+		DO $$
+		DECLARE
+			r RECORD;
+			truncated TEXT;
+		BEGIN
+			FOR r IN SELECT id, ip FROM consents WHERE ip <> '' AND ip !~ '(\.0|::)$' LOOP
+				BEGIN
+					truncated := host(network(set_masklen(r.ip::inet, CASE WHEN family(r.ip::inet) = 4 THEN 24 ELSE 48 END)));
+				EXCEPTION WHEN invalid_text_representation THEN
+					truncated := '';
+				END;
+				UPDATE consents SET ip = truncated WHERE id = r.id;
+			END LOOP;
+		END;
+		$$;
+		UPDATE consents SET user_agent = left(user_agent, 255) WHERE length(user_agent) > 255;
+
+		CREATE TABLE IF NOT EXISTS data_subject_requests (
+			id SERIAL PRIMARY KEY,
+			kind VARCHAR(20) NOT NULL,
+			email_hash VARCHAR(64) NOT NULL DEFAULT '',
+			phone_hash VARCHAR(64) NOT NULL DEFAULT '',
+			reason TEXT NOT NULL DEFAULT '',
+			result JSONB NOT NULL DEFAULT '{}',
+			requested_by INTEGER NOT NULL,
+			requested_by_username VARCHAR(255) NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS contact_messages_created_idx ON contact_messages (created_at) WHERE work_order_id IS NULL;
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Notifications: templates, customer preferences and delivery log
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS notification_templates (
+			key VARCHAR(50) NOT NULL,
+			channel VARCHAR(20) NOT NULL,
+			subject VARCHAR(255) NOT NULL DEFAULT '',
+			body TEXT NOT NULL,
+			whatsapp_template VARCHAR(255) NOT NULL DEFAULT '',
+			whatsapp_params TEXT[] NOT NULL DEFAULT '{}',
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (key, channel)
+		);
+
+		ALTER TABLE customers
+			ADD COLUMN IF NOT EXISTS notify_channels TEXT[] NOT NULL DEFAULT '{sms,email}',
+			ADD COLUMN IF NOT EXISTS quiet_start VARCHAR(5) NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS quiet_end VARCHAR(5) NOT NULL DEFAULT '';
+
+		CREATE TABLE IF NOT EXISTS notifications (
+			id SERIAL PRIMARY KEY,
+			customer_id INTEGER REFERENCES customers(id) ON DELETE SET NULL,
+			work_order_id INTEGER REFERENCES work_orders(id) ON DELETE SET NULL,
+			channel VARCHAR(20) NOT NULL DEFAULT '',
+			recipient VARCHAR(255) NOT NULL DEFAULT '',
+			template VARCHAR(50) NOT NULL,
+			subject VARCHAR(255) NOT NULL DEFAULT '',
+			body TEXT NOT NULL DEFAULT '',
+			whatsapp_template VARCHAR(255) NOT NULL DEFAULT '',
+			whatsapp_params TEXT[] NOT NULL DEFAULT '{}',
+			status VARCHAR(20) NOT NULL DEFAULT 'queued',
+			error TEXT NOT NULL DEFAULT '',
+			provider_id VARCHAR(255) NOT NULL DEFAULT '',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			send_after TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			sent_at TIMESTAMP WITH TIME ZONE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS notifications_queue_idx ON notifications (send_after) WHERE status = 'queued';
+		CREATE INDEX IF NOT EXISTS notifications_customer_idx ON notifications (customer_id);
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Background job queue
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS jobs (
+			id BIGSERIAL PRIMARY KEY,
+			kind VARCHAR(100) NOT NULL,
+			payload JSONB NOT NULL DEFAULT '{}',
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			max_attempts INTEGER NOT NULL DEFAULT 5,
+			run_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			unique_key VARCHAR(255),
+			last_error TEXT NOT NULL DEFAULT '',
+			locked_by VARCHAR(255) NOT NULL DEFAULT '',
+			locked_at TIMESTAMP WITH TIME ZONE,
+			finished_at TIMESTAMP WITH TIME ZONE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS jobs_pending_idx ON jobs (run_at, id) WHERE status = 'pending';
+		CREATE UNIQUE INDEX IF NOT EXISTS jobs_unique_key_idx ON jobs (unique_key) WHERE status IN ('pending', 'running');
+
+		CREATE TABLE IF NOT EXISTS job_schedules (
+			name VARCHAR(100) PRIMARY KEY,
+			spec VARCHAR(100) NOT NULL,
+			kind VARCHAR(100) NOT NULL,
+			next_run_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			last_run_at TIMESTAMP WITH TIME ZONE
+		);
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Outbound webhooks and their delivery log
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS webhooks (
+			id SERIAL PRIMARY KEY,
+			url TEXT NOT NULL,
+			description VARCHAR(255) NOT NULL DEFAULT '',
+			events TEXT[] NOT NULL DEFAULT '{}',
+			secret VARCHAR(100) NOT NULL,
+			active BOOLEAN NOT NULL DEFAULT TRUE,
+			failure_count INTEGER NOT NULL DEFAULT 0,
+			disabled_at TIMESTAMP WITH TIME ZONE,
+			disabled_reason TEXT NOT NULL DEFAULT '',
+			created_by INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id BIGSERIAL PRIMARY KEY,
+			webhook_id INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+			event_id VARCHAR(50) NOT NULL,
+			event VARCHAR(100) NOT NULL,
+			payload JSONB NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			response_status INTEGER NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT '',
+			duration_ms BIGINT NOT NULL DEFAULT 0,
+			redelivery_of BIGINT REFERENCES webhook_deliveries(id) ON DELETE SET NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			delivered_at TIMESTAMP WITH TIME ZONE
+		);
+		CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_idx ON webhook_deliveries (webhook_id, id DESC);
+		CREATE INDEX IF NOT EXISTS webhook_deliveries_created_idx ON webhook_deliveries (created_at);
+		-- Response bodies are no longer stored
+		ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS response_body;
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Admin panel live events (SSE); the trigger wakes every backend instance
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS admin_events (
+			id BIGSERIAL PRIMARY KEY,
+			type VARCHAR(100) NOT NULL,
+			entity_type VARCHAR(50) NOT NULL,
+			entity_id INTEGER NOT NULL DEFAULT 0,
+			actor_id INTEGER NOT NULL DEFAULT 0,
+			data JSONB NOT NULL DEFAULT '{}',
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS admin_events_created_idx ON admin_events (created_at);
+
+		-- Event ids are allocated before commit, so resuming after an id can skip
+		-- events committed late. Resume uses the writing transaction id and the
+		-- oldest transaction still running when the event was written instead.
+		ALTER TABLE admin_events
+			ADD COLUMN IF NOT EXISTS txid BIGINT NOT NULL DEFAULT txid_current(),
+			ADD COLUMN IF NOT EXISTS snapshot_xmin BIGINT NOT NULL DEFAULT txid_snapshot_xmin(txid_current_snapshot());
+		CREATE INDEX IF NOT EXISTS admin_events_txid_idx ON admin_events (txid);
+
+		CREATE OR REPLACE FUNCTION notify_admin_event() RETURNS trigger AS $$
+		BEGIN
+			PERFORM pg_notify('admin_events', NEW.id::text);
+			RETURN NEW;
+		END;
+		$$ LANGUAGE plpgsql;
+
+		DROP TRIGGER IF EXISTS admin_events_notify ON admin_events;
+		CREATE TRIGGER admin_events_notify AFTER INSERT ON admin_events
+			FOR EACH ROW EXECUTE FUNCTION notify_admin_event();
+
+		-- SSE tickets are single-use; used ticket IDs are kept until they expire
+		CREATE TABLE IF NOT EXISTS used_event_tickets (
+			jti VARCHAR(64) PRIMARY KEY,
+			expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+		);
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Work order scheduling, technician travel, invoices and customer tracking links
+	_, err = DB.Exec(`
+		ALTER TABLE users
+			ADD COLUMN IF NOT EXISTS full_name VARCHAR(255) NOT NULL DEFAULT '';
+
+		ALTER TABLE work_orders
+			ADD COLUMN IF NOT EXISTS scheduled_start TIMESTAMP WITH TIME ZONE,
+			ADD COLUMN IF NOT EXISTS scheduled_end TIMESTAMP WITH TIME ZONE,
+			ADD COLUMN IF NOT EXISTS technician_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION,
+			ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION,
+			ADD COLUMN IF NOT EXISTS travel_started_at TIMESTAMP WITH TIME ZONE,
+			ADD COLUMN IF NOT EXISTS eta_at TIMESTAMP WITH TIME ZONE,
+			ADD COLUMN IF NOT EXISTS completed_at TIMESTAMP WITH TIME ZONE,
+			ADD COLUMN IF NOT EXISTS invoice_file TEXT NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS tracking_token VARCHAR(64),
+			ADD COLUMN IF NOT EXISTS tracking_expires_at TIMESTAMP WITH TIME ZONE;
+		CREATE UNIQUE INDEX IF NOT EXISTS work_orders_tracking_token_key ON work_orders (tracking_token);
+		CREATE INDEX IF NOT EXISTS work_orders_technician_idx ON work_orders (technician_id, scheduled_start);
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Technician field data: checkpoints, photos, parts used, checklist and signature
+	_, err = DB.Exec(`
+		ALTER TABLE work_orders
+			ADD COLUMN IF NOT EXISTS arrived_at TIMESTAMP WITH TIME ZONE,
+			ADD COLUMN IF NOT EXISTS technician_notes TEXT NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS signature_file TEXT NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS signer_name VARCHAR(255) NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS signed_at TIMESTAMP WITH TIME ZONE;
+
+		CREATE TABLE IF NOT EXISTS work_order_checkpoints (
+			id BIGSERIAL PRIMARY KEY,
+			work_order_id INTEGER NOT NULL REFERENCES work_orders(id) ON DELETE CASCADE,
+			user_id INTEGER NOT NULL DEFAULT 0,
+			kind VARCHAR(20) NOT NULL,
+			latitude DOUBLE PRECISION,
+			longitude DOUBLE PRECISION,
+			accuracy_m REAL,
+			recorded_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS work_order_checkpoints_wo_idx ON work_order_checkpoints (work_order_id, recorded_at);
+
+		CREATE TABLE IF NOT EXISTS work_order_photos (
+			id SERIAL PRIMARY KEY,
+			work_order_id INTEGER NOT NULL REFERENCES work_orders(id) ON DELETE CASCADE,
+			kind VARCHAR(10) NOT NULL,
+			file TEXT NOT NULL,
+			content_type VARCHAR(50) NOT NULL,
+			caption VARCHAR(255) NOT NULL DEFAULT '',
+			uploaded_by INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS work_order_photos_wo_idx ON work_order_photos (work_order_id);
+
+		CREATE TABLE IF NOT EXISTS work_order_parts (
+			id SERIAL PRIMARY KEY,
+			work_order_id INTEGER NOT NULL REFERENCES work_orders(id) ON DELETE CASCADE,
+			name VARCHAR(255) NOT NULL,
+			quantity NUMERIC(10,2) NOT NULL,
+			unit VARCHAR(20) NOT NULL DEFAULT 'adet',
+			note TEXT NOT NULL DEFAULT '',
+			created_by INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS work_order_parts_wo_idx ON work_order_parts (work_order_id);
+
+		CREATE TABLE IF NOT EXISTS work_order_checklist (
+			id SERIAL PRIMARY KEY,
+			work_order_id INTEGER NOT NULL REFERENCES work_orders(id) ON DELETE CASCADE,
+			position INTEGER NOT NULL DEFAULT 0,
+			label VARCHAR(255) NOT NULL,
+			done BOOLEAN NOT NULL DEFAULT FALSE,
+			note TEXT NOT NULL DEFAULT '',
+			done_by INTEGER,
+			done_at TIMESTAMP WITH TIME ZONE
+		);
+		CREATE INDEX IF NOT EXISTS work_order_checklist_wo_idx ON work_order_checklist (work_order_id, position);
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Offline sync for technician devices: applied mutations and per-technician change log
+	_, err = DB.Exec(`
+		ALTER TABLE work_orders ADD COLUMN IF NOT EXISTS technician_notes_at TIMESTAMP WITH TIME ZONE;
+		ALTER TABLE work_order_checklist ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE;
+		ALTER TABLE work_order_parts ADD COLUMN IF NOT EXISTS client_id VARCHAR(64);
+		ALTER TABLE work_order_photos ADD COLUMN IF NOT EXISTS client_id VARCHAR(64);
+		CREATE UNIQUE INDEX IF NOT EXISTS work_order_parts_client_idx ON work_order_parts (work_order_id, client_id);
+		CREATE UNIQUE INDEX IF NOT EXISTS work_order_photos_client_idx ON work_order_photos (work_order_id, client_id);
+
+		CREATE TABLE IF NOT EXISTS sync_mutations (
+			user_id INTEGER NOT NULL,
+			mutation_id VARCHAR(64) NOT NULL,
+			device_id VARCHAR(64) NOT NULL DEFAULT '',
+			type VARCHAR(40) NOT NULL,
+			work_order_id INTEGER NOT NULL DEFAULT 0,
+			client_time TIMESTAMP WITH TIME ZONE,
+			status VARCHAR(20) NOT NULL,
+			result JSONB NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, mutation_id)
+		);
+		CREATE INDEX IF NOT EXISTS sync_mutations_created_idx ON sync_mutations (created_at);
+
+		CREATE TABLE IF NOT EXISTS sync_changes (
+			id BIGSERIAL PRIMARY KEY,
+			technician_id INTEGER NOT NULL,
+			work_order_id INTEGER NOT NULL,
+			txid BIGINT NOT NULL DEFAULT txid_current(),
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS sync_changes_technician_idx ON sync_changes (technician_id, txid);
+
+		CREATE OR REPLACE FUNCTION log_work_order_sync_change() RETURNS trigger AS $$
+		BEGIN
+			IF TG_OP <> 'INSERT' AND OLD.technician_id IS NOT NULL THEN
+				INSERT INTO sync_changes (technician_id, work_order_id) VALUES (OLD.technician_id, OLD.id);
+			END IF;
+			IF TG_OP <> 'DELETE' AND NEW.technician_id IS NOT NULL
+				AND (TG_OP = 'INSERT' OR NEW.technician_id IS DISTINCT FROM OLD.technician_id) THEN
+				INSERT INTO sync_changes (technician_id, work_order_id) VALUES (NEW.technician_id, NEW.id);
+			END IF;
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql;
+
+		DROP TRIGGER IF EXISTS work_orders_sync_change ON work_orders;
+		CREATE TRIGGER work_orders_sync_change AFTER INSERT OR UPDATE OR DELETE ON work_orders
+			FOR EACH ROW EXECUTE FUNCTION log_work_order_sync_change();
+
+		CREATE OR REPLACE FUNCTION log_work_order_child_sync_change() RETURNS trigger AS $$
+		DECLARE
+			wo_id INTEGER;
+		BEGIN
+			IF TG_OP = 'DELETE' THEN
+				wo_id := OLD.work_order_id;
+			ELSE
+				wo_id := NEW.work_order_id;
+			END IF;
+			INSERT INTO sync_changes (technician_id, work_order_id)
+				SELECT technician_id, id FROM work_orders WHERE id = wo_id AND technician_id IS NOT NULL;
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql;
+
+		DROP TRIGGER IF EXISTS work_order_photos_sync_change ON work_order_photos;
+		CREATE TRIGGER work_order_photos_sync_change AFTER INSERT OR UPDATE OR DELETE ON work_order_photos
+			FOR EACH ROW EXECUTE FUNCTION log_work_order_child_sync_change();
+		DROP TRIGGER IF EXISTS work_order_parts_sync_change ON work_order_parts;
+		CREATE TRIGGER work_order_parts_sync_change AFTER INSERT OR UPDATE OR DELETE ON work_order_parts
+			FOR EACH ROW EXECUTE FUNCTION log_work_order_child_sync_change();
+		DROP TRIGGER IF EXISTS work_order_checklist_sync_change ON work_order_checklist;
+		CREATE TRIGGER work_order_checklist_sync_change AFTER INSERT OR UPDATE OR DELETE ON work_order_checklist
+			FOR EACH ROW EXECUTE FUNCTION log_work_order_child_sync_change();
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Daily technician route plans
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS route_plans (
+			id SERIAL PRIMARY KEY,
+			technician_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			plan_date DATE NOT NULL,
+			depot_lat DOUBLE PRECISION NOT NULL,
+			depot_lng DOUBLE PRECISION NOT NULL,
+			shift_start TIMESTAMP WITH TIME ZONE NOT NULL,
+			start_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			return_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			total_km DOUBLE PRECISION NOT NULL DEFAULT 0,
+			travel_minutes INTEGER NOT NULL DEFAULT 0,
+			late_minutes INTEGER NOT NULL DEFAULT 0,
+			manual BOOLEAN NOT NULL DEFAULT FALSE,
+			unlocated INTEGER[] NOT NULL DEFAULT '{}',
+			created_by INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (technician_id, plan_date)
+		);
+
+		CREATE TABLE IF NOT EXISTS route_plan_stops (
+			plan_id INTEGER NOT NULL REFERENCES route_plans(id) ON DELETE CASCADE,
+			position INTEGER NOT NULL,
+			work_order_id INTEGER NOT NULL REFERENCES work_orders(id) ON DELETE CASCADE,
+			window_start TIMESTAMP WITH TIME ZONE,
+			window_end TIMESTAMP WITH TIME ZONE,
+			arrival_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			departure_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			travel_km DOUBLE PRECISION NOT NULL DEFAULT 0,
+			travel_minutes INTEGER NOT NULL DEFAULT 0,
+			wait_minutes INTEGER NOT NULL DEFAULT 0,
+			late_minutes INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (plan_id, position)
+		);
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Service zones and call-out fees
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS service_zones (
+			id SERIAL PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			kind VARCHAR(20) NOT NULL,
+			center_lat DOUBLE PRECISION,
+			center_lng DOUBLE PRECISION,
+			radius_km DOUBLE PRECISION NOT NULL DEFAULT 0,
+			polygon JSONB NOT NULL DEFAULT '[]',
+			districts JSONB NOT NULL DEFAULT '[]',
+			neighbourhoods JSONB NOT NULL DEFAULT '[]',
+			call_out_fee NUMERIC(10,2) NOT NULL DEFAULT 0,
+			days JSONB NOT NULL DEFAULT '[1,2,3,4,5,6,7]',
+			priority INTEGER NOT NULL DEFAULT 0,
+			active BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+
+		ALTER TABLE work_orders
+			ADD COLUMN IF NOT EXISTS service_zone_id INTEGER REFERENCES service_zones(id) ON DELETE SET NULL,
+			ADD COLUMN IF NOT EXISTS call_out_fee NUMERIC(10,2) NOT NULL DEFAULT 0;
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Quotes; the call-out fee is taken from the address's service zone
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS quotes (
+			id SERIAL PRIMARY KEY,
+			customer_id INTEGER NOT NULL REFERENCES customers(id),
+			title VARCHAR(255) NOT NULL,
+			address TEXT NOT NULL DEFAULT '',
+			latitude DOUBLE PRECISION,
+			longitude DOUBLE PRECISION,
+			items JSONB NOT NULL DEFAULT '[]',
+			subtotal NUMERIC(12,2) NOT NULL DEFAULT 0,
+			service_zone_id INTEGER REFERENCES service_zones(id) ON DELETE SET NULL,
+			call_out_fee NUMERIC(10,2) NOT NULL DEFAULT 0,
+			total NUMERIC(12,2) NOT NULL DEFAULT 0,
+			status VARCHAR(20) NOT NULL DEFAULT 'draft',
+			valid_until TIMESTAMP WITH TIME ZONE,
+			notes TEXT NOT NULL DEFAULT '',
+			work_order_id INTEGER REFERENCES work_orders(id) ON DELETE SET NULL,
+			created_by INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS quotes_customer_idx ON quotes (customer_id);
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Spare parts inventory; stock_movements is the append-only ledger and
+	// stock_levels is kept in step with it inside the same transaction
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS parts (
+			id SERIAL PRIMARY KEY,
+			sku VARCHAR(64) UNIQUE NOT NULL,
+			barcode VARCHAR(64) UNIQUE,
+			name VARCHAR(255) NOT NULL,
+			unit VARCHAR(20) NOT NULL DEFAULT 'adet',
+			cost_price NUMERIC(12,2) NOT NULL DEFAULT 0,
+			sale_price NUMERIC(12,2) NOT NULL DEFAULT 0,
+			min_stock NUMERIC(12,3) NOT NULL DEFAULT 0,
+			active BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS stock_locations (
+			id SERIAL PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			kind VARCHAR(20) NOT NULL,
+			technician_id INTEGER UNIQUE REFERENCES users(id) ON DELETE SET NULL,
+			active BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS stock_movements (
+			id BIGSERIAL PRIMARY KEY,
+			part_id INTEGER NOT NULL REFERENCES parts(id),
+			kind VARCHAR(20) NOT NULL,
+			quantity NUMERIC(12,3) NOT NULL CHECK (quantity > 0),
+			from_location_id INTEGER REFERENCES stock_locations(id),
+			to_location_id INTEGER REFERENCES stock_locations(id),
+			work_order_id INTEGER REFERENCES work_orders(id),
+			unit_cost NUMERIC(12,2) NOT NULL DEFAULT 0,
+			note TEXT NOT NULL DEFAULT '',
+			created_by INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS stock_movements_part_idx ON stock_movements (part_id, id);
+		CREATE INDEX IF NOT EXISTS stock_movements_wo_idx ON stock_movements (work_order_id) WHERE work_order_id IS NOT NULL;
+
+		DROP TRIGGER IF EXISTS stock_movements_no_update ON stock_movements;
+		CREATE TRIGGER stock_movements_no_update BEFORE UPDATE OR DELETE ON stock_movements
+			FOR EACH ROW EXECUTE FUNCTION append_only_table();
+
+		DROP TRIGGER IF EXISTS stock_movements_no_truncate ON stock_movements;
+		CREATE TRIGGER stock_movements_no_truncate BEFORE TRUNCATE ON stock_movements
+			FOR EACH STATEMENT EXECUTE FUNCTION append_only_table();
+
+		CREATE TABLE IF NOT EXISTS stock_levels (
+			part_id INTEGER NOT NULL REFERENCES parts(id),
+			location_id INTEGER NOT NULL REFERENCES stock_locations(id),
+			quantity NUMERIC(12,3) NOT NULL DEFAULT 0,
+			PRIMARY KEY (part_id, location_id)
+		);
+
+		ALTER TABLE work_order_parts
+			ADD COLUMN IF NOT EXISTS part_id INTEGER REFERENCES parts(id),
+			ADD COLUMN IF NOT EXISTS location_id INTEGER REFERENCES stock_locations(id),
+			ADD COLUMN IF NOT EXISTS unit_price NUMERIC(12,2) NOT NULL DEFAULT 0;
+	`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	fmt.Println("Successfully created tables")
 }
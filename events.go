@@ -0,0 +1,418 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"kozan/db"
+	"kozan/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/lib/pq"
+)
+
+// Yönetim paneli için canlı olaylar (SSE). Olaylar admin_events tablosuna yazılır,
+// tablo üzerindeki tetikleyici pg_notify ile tüm backend örneklerini uyarır.
+// Tablo aynı zamanda Last-Event-ID ile kaldığı yerden devam etmeyi sağlar.
+//
+// Olay ID'leri commit'ten önce alındığından geç commit edilen bir olayın ID'si
+// daha önce gönderilmiş olaylarınkinden küçük olabilir; bu yüzden kaldığı
+// yerden devam ID ile değil işlem numarasıyla yapılır. SSE imleci
+// "<snapshot_xmin>.<id>" biçimindedir: snapshot_xmin olay yazılırken açık olan
+// en eski işlemdir. NOTIFY commit sırasıyla iletildiği için henüz gönderilmemiş
+// her olay bu işlemden sonra başlamıştır (txid >= snapshot_xmin). Aynı anda
+// çalışan işlemlerin olayları tekrar gönderilebilir; panel olayları ID ile ayırt eder.
+const (
+	adminEventsChannel = "admin_events"
+	eventsTicketTTL    = time.Minute
+	eventsHeartbeat    = 25 * time.Second
+	eventsReplayLimit  = 1000
+	eventsRetention    = 24 * time.Hour
+	eventsBuffer       = 64
+)
+
+// Sadece panele giden olaylar; diğerleri webhook olaylarıyla aynı adı taşır
+const (
+	eventWorkOrderStatusChanged = "work_order.status_changed"
+	eventAboutUpdated           = "about.updated"
+	eventContactUpdated         = "contact.updated"
+	eventHeroUpdated            = "hero.updated"
+	eventFooterUpdated          = "footer.updated"
+)
+
+// eventPermissions olayı görebilmek için gereken yetki (entity_type'a göre)
+var eventPermissions = map[string]string{
+	"product":         permProductsRead,
+	"service":         permServicesRead,
+	"about":           permContentRead,
+	"contact":         permContentRead,
+	"hero":            permContentRead,
+	"footer":          permContentRead,
+	"contact_message": permLeadsRead,
+	"work_order":      permWorkOrdersRead,
+	"part":            permInventoryRead,
+}
+
+// publishAdminEvent olayı kaydeder; bağlı tüm panellere NOTIFY ile iletilir.
+// Hata isteği bozmaz, sadece loglanır.
+func publishAdminEvent(c *gin.Context, eventType, entityType string, entityID int, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err == nil {
+		var actorID int
+		if c != nil {
+			actorID = currentUserID(c)
+		}
+		_, err = db.DB.Exec(
+			"INSERT INTO admin_events (type, entity_type, entity_id, actor_id, data) VALUES ($1, $2, $3, $4, $5)",
+			eventType, entityType, entityID, actorID, string(payload),
+		)
+	}
+	if err != nil {
+		log.Printf("events: %s could not be published: %v", eventType, err)
+	}
+}
+
+const adminEventColumns = "id, type, entity_type, entity_id, actor_id, data, created_at, snapshot_xmin"
+
+func scanAdminEvent(scanner interface{ Scan(...interface{}) error }) (models.AdminEvent, error) {
+	var e models.AdminEvent
+	var data []byte
+	err := scanner.Scan(&e.ID, &e.Type, &e.EntityType, &e.EntityID, &e.ActorID, &data, &e.CreatedAt, &e.SnapshotXmin)
+	e.Data = json.RawMessage(data)
+	return e, err
+}
+
+func queryAdminEvents(query string, args ...interface{}) ([]models.AdminEvent, error) {
+	rows, err := db.DB.Query("SELECT "+adminEventColumns+" FROM admin_events "+query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.AdminEvent
+	for rows.Next() {
+		e, err := scanAdminEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// eventHub bu örnekteki SSE bağlantılarına olayları dağıtır
+type eventHub struct {
+	mu          sync.Mutex
+	subscribers map[chan models.AdminEvent]struct{}
+	// last son dağıtılan olay; bağlantı koparsa buradan devam edilir
+	last models.AdminEvent
+}
+
+var eventStream = &eventHub{subscribers: make(map[chan models.AdminEvent]struct{})}
+
+func (h *eventHub) subscribe() chan models.AdminEvent {
+	ch := make(chan models.AdminEvent, eventsBuffer)
+	h.mu.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *eventHub) unsubscribe(ch chan models.AdminEvent) {
+	h.mu.Lock()
+	if _, ok := h.subscribers[ch]; ok {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+	h.mu.Unlock()
+}
+
+// broadcast yetişemeyen aboneyi düşürür; istemci Last-Event-ID ile yeniden bağlanıp eksikleri alır
+func (h *eventHub) broadcast(e models.AdminEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.last = e
+	for ch := range h.subscribers {
+		select {
+		case ch <- e:
+		default:
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// startEventHub admin_events kanalını dinler
+func startEventHub() {
+	// Açılıştan önce commit edilmiş olaylar dağıtılmaz; imleç şu anki anlık görüntüdür
+	if err := db.DB.QueryRow("SELECT txid_snapshot_xmin(txid_current_snapshot())").Scan(&eventStream.last.SnapshotXmin); err != nil {
+		log.Fatal(err)
+	}
+
+	listener := pq.NewListener(os.Getenv("DATABASE_URL"), 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("events: listener: %v", err)
+		}
+	})
+	if err := listener.Listen(adminEventsChannel); err != nil {
+		log.Fatal(err)
+	}
+
+	go func() {
+		ping := time.NewTicker(90 * time.Second)
+		defer ping.Stop()
+		for {
+			select {
+			case n := <-listener.Notify:
+				eventStream.handleNotification(n)
+			case <-ping.C:
+				go listener.Ping()
+			}
+		}
+	}()
+}
+
+// handleNotification NOTIFY ile gelen olayı yükler. n nil ise bağlantı yeniden
+// kurulmuştur; aradaki olaylar son dağıtılan olayın imlecinden itibaren okunur.
+func (h *eventHub) handleNotification(n *pq.Notification) {
+	var list []models.AdminEvent
+	var err error
+	if n == nil {
+		h.mu.Lock()
+		cursor := eventCursor(h.last)
+		h.mu.Unlock()
+		where, args := eventReplayCondition(cursor)
+		list, err = queryAdminEvents(where+" ORDER BY id LIMIT $"+strconv.Itoa(len(args)+1), append(args, eventsReplayLimit)...)
+	} else {
+		id, perr := strconv.ParseInt(n.Extra, 10, 64)
+		if perr != nil {
+			return
+		}
+		list, err = queryAdminEvents("WHERE id = $1", id)
+	}
+	if err != nil {
+		log.Printf("events: could not load events: %v", err)
+		return
+	}
+	for _, e := range list {
+		h.broadcast(e)
+	}
+}
+
+func cleanupAdminEvents(ctx context.Context) error {
+	if _, err := db.DB.ExecContext(ctx, "DELETE FROM admin_events WHERE created_at < $1", time.Now().Add(-eventsRetention)); err != nil {
+		return err
+	}
+	_, err := db.DB.ExecContext(ctx, "DELETE FROM used_event_tickets WHERE expires_at < NOW()")
+	return err
+}
+
+// createEventsTicketHandler EventSource başlık gönderemediği için akışa
+// ?ticket= ile bağlanmayı sağlayan kısa ömürlü, tek kullanımlık token üretir.
+// Bilet URL'de taşındığı için loglara düşebilir; ilk kullanımda jti kaydedilir
+// ve aynı bilet ikinci kez kabul edilmez.
+func createEventsTicketHandler(c *gin.Context) {
+	var perms []string
+	for _, p := range allPermissions {
+		if hasPermission(c, p) {
+			perms = append(perms, p)
+		}
+	}
+
+	jti, err := randomHex(16)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ticket, err := signPurposeToken("events", jwt.MapClaims{
+		"jti":         jti,
+		"user_id":     currentUserID(c),
+		"username":    c.GetString("username"),
+		"role_id":     c.GetInt("role_id"),
+		"permissions": perms,
+	}, eventsTicketTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ticket": ticket, "expires_in": int(eventsTicketTTL.Seconds())})
+}
+
+// eventsAuthMiddleware ?ticket= varsa onu, yoksa normal oturum doğrulamasını kullanır
+func eventsAuthMiddleware() gin.HandlerFunc {
+	auth := authMiddleware()
+	return func(c *gin.Context) {
+		ticket := c.Query("ticket")
+		if ticket == "" {
+			auth(c)
+			return
+		}
+
+		claims, err := parsePurposeToken("events", ticket)
+		if err == nil {
+			err = useEventsTicket(claims)
+		}
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid ticket"})
+			c.Abort()
+			return
+		}
+		userID, _ := claims["user_id"].(float64)
+		roleID, _ := claims["role_id"].(float64)
+		perms := make(map[string]bool)
+		list, _ := claims["permissions"].([]interface{})
+		for _, p := range list {
+			if s, ok := p.(string); ok {
+				perms[s] = true
+			}
+		}
+		c.Set("username", claims["username"])
+		c.Set("user_id", int(userID))
+		c.Set("role_id", int(roleID))
+		c.Set("permissions", perms)
+		c.Next()
+	}
+}
+
+// useEventsTicket bileti kullanılmış olarak işaretler; daha önce kullanılmışsa hata döner
+func useEventsTicket(claims jwt.MapClaims) error {
+	jti, _ := claims["jti"].(string)
+	exp, _ := claims["exp"].(float64)
+	if jti == "" {
+		return fmt.Errorf("bilet kimliği yok")
+	}
+	res, err := db.DB.Exec(
+		"INSERT INTO used_event_tickets (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING",
+		jti, time.Unix(int64(exp), 0),
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("bilet daha önce kullanılmış")
+	}
+	return nil
+}
+
+func canSeeEvent(c *gin.Context, e models.AdminEvent) bool {
+	perm, ok := eventPermissions[e.EntityType]
+	return ok && hasPermission(c, perm)
+}
+
+// eventCursor olayın SSE imlecidir
+func eventCursor(e models.AdminEvent) string {
+	return strconv.FormatInt(e.SnapshotXmin, 10) + "." + strconv.FormatInt(e.ID, 10)
+}
+
+// eventReplayCondition imleçten sonra gönderilmemiş olabilecek olayları seçen
+// koşulu döndürür. Sadece sayı içeren eski imleçlerde ID'den sonrası okunur;
+// imleç yoksa veya geçersizse koşul boş döner ve tekrar gönderim yapılmaz.
+func eventReplayCondition(cursor string) (string, []interface{}) {
+	xminPart, idPart, found := strings.Cut(cursor, ".")
+	if !found {
+		lastID, err := strconv.ParseInt(cursor, 10, 64)
+		if err != nil || lastID <= 0 {
+			return "", nil
+		}
+		return "WHERE id > $1", []interface{}{lastID}
+	}
+	xmin, err1 := strconv.ParseInt(xminPart, 10, 64)
+	lastID, err2 := strconv.ParseInt(idPart, 10, 64)
+	if err1 != nil || err2 != nil || xmin <= 0 {
+		return "", nil
+	}
+	return "WHERE txid >= $1 AND id <> $2", []interface{}{xmin, lastID}
+}
+
+func writeSSE(w io.Writer, e models.AdminEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", eventCursor(e), e.Type, data)
+	return err
+}
+
+// streamEventsHandler olay akışı. Last-Event-ID başlığı (veya ?last_event_id=)
+// verilirse kaçırılan olaylar önce gönderilir; kaçırılan olay sayısı sınırı
+// aşarsa "reset" olayı gönderilir ve istemcinin verileri yeniden yüklemesi beklenir.
+func streamEventsHandler(c *gin.Context) {
+	cursor := c.GetHeader("Last-Event-ID")
+	if cursor == "" {
+		cursor = c.Query("last_event_id")
+	}
+
+	// Tekrar oynatma sırasında gelen olaylar kaçmasın diye önce abone olunur
+	sub := eventStream.subscribe()
+	defer eventStream.unsubscribe(sub)
+
+	var replay []models.AdminEvent
+	if where, args := eventReplayCondition(cursor); where != "" {
+		var err error
+		replay, err = queryAdminEvents(where+" ORDER BY id LIMIT $"+strconv.Itoa(len(args)+1), append(args, eventsReplayLimit+1)...)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	h := c.Writer.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	fmt.Fprintf(w, "retry: 5000\n\n")
+	sent := make(map[int64]bool)
+	if len(replay) > eventsReplayLimit {
+		fmt.Fprintf(w, "event: reset\ndata: {}\n\n")
+	} else {
+		for _, e := range replay {
+			sent[e.ID] = true
+			if canSeeEvent(c, e) {
+				writeSSE(w, e)
+			}
+		}
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-sub:
+			if !ok {
+				// Yetişemeyen istemci; yeniden bağlanınca eksikler gönderilir
+				return
+			}
+			if sent[e.ID] || !canSeeEvent(c, e) {
+				continue
+			}
+			if err := writeSSE(w, e); err != nil {
+				return
+			}
+			w.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprintf(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			w.Flush()
+		}
+	}
+}
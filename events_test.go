@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"kozan/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// withTestKeys testin süresince geçici bir imzalama anahtarı kullanır
+func withTestKeys(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	if _, err := generateKey(dir); err != nil {
+		t.Fatal(err)
+	}
+	saved := jwtKeys
+	t.Cleanup(func() { jwtKeys = saved })
+	jwtKeys = &keyRing{dir: dir}
+	if err := jwtKeys.load(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateEventsTicket(t *testing.T) {
+	withTestKeys(t)
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/admin/events/ticket", nil)
+	c.Set("user_id", 5)
+	c.Set("username", "ofis")
+	c.Set("role_id", roleEditor)
+	c.Set("permissions", map[string]bool{permLeadsRead: true, permProductsRead: true})
+	createEventsTicketHandler(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	var resp struct {
+		Ticket    string `json:"ticket"`
+		ExpiresIn int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.ExpiresIn != int(eventsTicketTTL.Seconds()) {
+		t.Errorf("expires_in = %d, want %d", resp.ExpiresIn, int(eventsTicketTTL.Seconds()))
+	}
+
+	claims, err := parsePurposeToken("events", resp.Ticket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jti, _ := claims["jti"].(string); len(jti) != 32 {
+		t.Errorf("jti = %q, want 32 hex characters", jti)
+	}
+	if claims["user_id"] != float64(5) || claims["username"] != "ofis" || claims["role_id"] != float64(roleEditor) {
+		t.Errorf("claims = %v", claims)
+	}
+	// Bilet sadece kullanıcının sahip olduğu yetkileri taşır
+	var perms []string
+	for _, p := range claims["permissions"].([]interface{}) {
+		perms = append(perms, p.(string))
+	}
+	var want []string
+	for _, p := range allPermissions {
+		if p == permLeadsRead || p == permProductsRead {
+			want = append(want, p)
+		}
+	}
+	if !reflect.DeepEqual(perms, want) {
+		t.Errorf("permissions = %v, want %v", perms, want)
+	}
+}
+
+func TestEventsAuthRejectsInvalidTickets(t *testing.T) {
+	withTestKeys(t)
+	gin.SetMode(gin.TestMode)
+
+	sign := func(purpose string, ttl time.Duration) string {
+		token, err := signPurposeToken(purpose, jwt.MapClaims{"jti": "abc", "user_id": 1}, ttl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	// Geçerli bir bilet tek kullanımlık kaydı için veritabanına iner; buradaki
+	// biletler ondan önce reddedilir
+	tests := []struct {
+		name   string
+		ticket string
+	}{
+		{"bozuk bilet", "abc.def.ghi"},
+		{"süresi dolmuş", sign("events", -time.Minute)},
+		{"başka amaçlı token", sign("preview", time.Minute)},
+	}
+
+	r := gin.New()
+	r.GET("/api/admin/events", eventsAuthMiddleware(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/events?ticket="+tt.ticket, nil))
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestEventReplayCondition(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+		where  string
+		args   []interface{}
+	}{
+		{"imleç yok", "", "", nil},
+		{"işlem imleci", "81234.42", "WHERE txid >= $1 AND id <> $2", []interface{}{int64(81234), int64(42)}},
+		{"eski sayısal imleç", "42", "WHERE id > $1", []interface{}{int64(42)}},
+		{"sıfır", "0", "", nil},
+		{"geçersiz sayı", "abc", "", nil},
+		{"geçersiz işlem numarası", "x.42", "", nil},
+		{"geçersiz olay ID", "81234.x", "", nil},
+		{"işlem numarası sıfır", "0.42", "", nil},
+	}
+
+	for _, tt := range tests {
+		where, args := eventReplayCondition(tt.cursor)
+		if where != tt.where || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("%s: eventReplayCondition(%q) = %q %v, want %q %v", tt.name, tt.cursor, where, args, tt.where, tt.args)
+		}
+	}
+}
+
+func TestEventCursorRoundTrip(t *testing.T) {
+	e := models.AdminEvent{ID: 42, SnapshotXmin: 81234}
+	cursor := eventCursor(e)
+	if cursor != "81234.42" {
+		t.Fatalf("eventCursor = %q, want %q", cursor, "81234.42")
+	}
+	// Yeniden bağlanan istemci olayın yazıldığı andaki en eski açık işlemden
+	// itibaren okur; daha küçük ID ile geç commit edilen olaylar da bu kapsamdadır
+	_, args := eventReplayCondition(cursor)
+	if len(args) != 2 || args[0] != e.SnapshotXmin || args[1] != e.ID {
+		t.Errorf("eventReplayCondition(%q) args = %v", cursor, args)
+	}
+}
+
+func TestCanSeeEvent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("permissions", map[string]bool{permProductsRead: true})
+
+	tests := []struct {
+		entityType string
+		want       bool
+	}{
+		{"product", true},
+		{"contact_message", false},
+		{"bilinmeyen", false},
+	}
+
+	for _, tt := range tests {
+		if got := canSeeEvent(c, models.AdminEvent{EntityType: tt.entityType}); got != tt.want {
+			t.Errorf("canSeeEvent(%s) = %v, want %v", tt.entityType, got, tt.want)
+		}
+	}
+}
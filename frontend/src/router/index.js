@@ -2,6 +2,8 @@ import { createRouter, createWebHistory } from 'vue-router'
 import HomeView from '../views/HomeView.vue'
 import Admin from '../views/Admin.vue'
 import Login from '../views/Login.vue'
+import ContentDetailView from '../views/ContentDetailView.vue'
+import TrackingView from '../views/TrackingView.vue'
 
 const isAdminSubdomain = window.location.hostname.startsWith('admin.')
 
@@ -20,6 +22,23 @@ const router = createRouter({
         }
       }
     },
+    {
+      path: '/urunler/:slug',
+      name: 'product',
+      component: ContentDetailView,
+      props: { entity: 'products' }
+    },
+    {
+      path: '/hizmetler/:slug',
+      name: 'service',
+      component: ContentDetailView,
+      props: { entity: 'services' }
+    },
+    {
+      path: '/takip/:token',
+      name: 'tracking',
+      component: TrackingView
+    },
     {
       path: '/admin',
       name: 'admin',
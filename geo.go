@@ -0,0 +1,47 @@
+package main
+
+import (
+	"math"
+	"os"
+	"strconv"
+	"time"
+)
+
+const earthRadiusKm = 6371.0
+
+// haversineKm iki koordinat arasındaki kuş uçuşu mesafe (km)
+func haversineKm(lat1, lng1, lat2, lng2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLng := (lng2 - lng1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+func envFloat(key string, def float64) float64 {
+	if v, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
+
+// roadFactor kuş uçuşu mesafeyi yol mesafesine çevirir (ROUTE_ROAD_FACTOR, varsayılan 1.3)
+func roadFactor() float64 {
+	return envFloat("ROUTE_ROAD_FACTOR", 1.3)
+}
+
+// averageSpeedKmh şehir içi ortalama hız (ROUTE_AVG_SPEED_KMH, varsayılan 30)
+func averageSpeedKmh() float64 {
+	return envFloat("ROUTE_AVG_SPEED_KMH", 30)
+}
+
+// travelTime iki nokta arasındaki tahmini yol süresi
+func travelTime(lat1, lng1, lat2, lng2 float64) time.Duration {
+	km := haversineKm(lat1, lng1, lat2, lng2) * roadFactor()
+	return time.Duration(km / averageSpeedKmh() * float64(time.Hour))
+}
+
+func validCoordinates(lat, lng float64) bool {
+	return lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180 && !(lat == 0 && lng == 0)
+}
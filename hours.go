@@ -0,0 +1,587 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"kozan/db"
+	"kozan/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Çalışma saatleri: haftanın her günü için sıfır veya daha fazla aralık ile
+// bayram, resmi tatil ya da yaz nöbeti gibi tarih aralığına özel istisnalar.
+// Tüm hesaplamalar işletmenin saat diliminde yapılır. İletişim kaydındaki
+// eski metin alanları (weekdayHours vb.) bu programdan türetilir.
+const businessTimezone = "Europe/Istanbul"
+
+var businessLocation = loadBusinessLocation()
+
+func loadBusinessLocation() *time.Location {
+	loc, err := time.LoadLocation(businessTimezone)
+	if err != nil {
+		// tzdata olmayan imajlar için; Türkiye 2016'dan beri sabit UTC+3
+		return time.FixedZone("+03", 3*60*60)
+	}
+	return loc
+}
+
+var weekdayNames = []string{"", "Pazartesi", "Salı", "Çarşamba", "Perşembe", "Cuma", "Cumartesi", "Pazar"}
+var weekdayShortNames = []string{"", "Pzt", "Sal", "Çar", "Per", "Cum", "Cmt", "Paz"}
+
+const closedLabel = "Kapalı"
+
+// isoWeekday Pazartesi = 1 ... Pazar = 7
+func isoWeekday(t time.Time) int {
+	if wd := int(t.Weekday()); wd != 0 {
+		return wd
+	}
+	return 7
+}
+
+// parseClock "09:30" → 570 dakika. Kapanış için "24:00" kabul edilir.
+func parseClock(value string, allowEndOfDay bool) (int, error) {
+	h, m, ok := strings.Cut(strings.TrimSpace(value), ":")
+	hour, err1 := strconv.Atoi(h)
+	minute, err2 := strconv.Atoi(m)
+	if !ok || err1 != nil || err2 != nil || len(m) != 2 || minute < 0 || minute > 59 || hour < 0 {
+		return 0, fmt.Errorf("geçersiz saat: %q", value)
+	}
+	if hour > 23 && !(allowEndOfDay && hour == 24 && minute == 0) {
+		return 0, fmt.Errorf("geçersiz saat: %q", value)
+	}
+	return hour*60 + minute, nil
+}
+
+// validateIntervals aralıkları doğrular, sıralar ve "9:00" gibi değerleri "09:00" yapar
+func validateIntervals(intervals []models.OpeningInterval) ([]models.OpeningInterval, error) {
+	type span struct{ start, end int }
+	spans := make([]span, 0, len(intervals))
+	for _, iv := range intervals {
+		start, err := parseClock(iv.Opens, false)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseClock(iv.Closes, true)
+		if err != nil {
+			return nil, err
+		}
+		if start == end {
+			return nil, fmt.Errorf("açılış ve kapanış aynı olamaz: %s", iv.Opens)
+		}
+		if end < start {
+			// Gece yarısını geçen aralık
+			end += 24 * 60
+		}
+		spans = append(spans, span{start, end})
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	result := make([]models.OpeningInterval, 0, len(spans))
+	for i, s := range spans {
+		if i > 0 && s.start < spans[i-1].end {
+			return nil, fmt.Errorf("aralıklar çakışıyor")
+		}
+		result = append(result, models.OpeningInterval{Opens: formatClock(s.start), Closes: formatClock(s.end)})
+	}
+	return result, nil
+}
+
+func formatClock(minutes int) string {
+	if minutes > 24*60 {
+		minutes -= 24 * 60
+	}
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// loadWeeklyHours haftalık programı döndürür. Program hiç tanımlanmamışsa
+// harita boştur; tanımlıysa 7 gün de vardır (kapalı günlerde aralık yoktur).
+func loadWeeklyHours() (map[int][]models.OpeningInterval, error) {
+	rows, err := db.DB.Query("SELECT weekday, intervals FROM opening_hours")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	weekly := make(map[int][]models.OpeningInterval)
+	for rows.Next() {
+		var weekday int
+		var data []byte
+		if err := rows.Scan(&weekday, &data); err != nil {
+			return nil, err
+		}
+		intervals := []models.OpeningInterval{}
+		if err := json.Unmarshal(data, &intervals); err != nil {
+			return nil, fmt.Errorf("opening_hours %d: %w", weekday, err)
+		}
+		weekly[weekday] = intervals
+	}
+	return weekly, rows.Err()
+}
+
+func weeklyList(weekly map[int][]models.OpeningInterval) []models.WeeklyHours {
+	list := make([]models.WeeklyHours, 0, 7)
+	for day := 1; day <= 7; day++ {
+		intervals := weekly[day]
+		if intervals == nil {
+			intervals = []models.OpeningInterval{}
+		}
+		list = append(list, models.WeeklyHours{Weekday: day, Name: weekdayNames[day], Intervals: intervals})
+	}
+	return list
+}
+
+const exceptionColumns = "id, label, start_date, end_date, closed, intervals"
+
+func scanException(scanner interface{ Scan(...interface{}) error }) (models.OpeningException, error) {
+	var e models.OpeningException
+	var start, end time.Time
+	var data []byte
+	if err := scanner.Scan(&e.ID, &e.Label, &start, &end, &e.Closed, &data); err != nil {
+		return e, err
+	}
+	e.StartDate = start.Format("2006-01-02")
+	e.EndDate = end.Format("2006-01-02")
+	e.Intervals = []models.OpeningInterval{}
+	if err := json.Unmarshal(data, &e.Intervals); err != nil {
+		return e, fmt.Errorf("opening_hours_exceptions %d: %w", e.ID, err)
+	}
+	return e, nil
+}
+
+// loadExceptions from tarihinde veya sonrasında biten istisnaları getirir
+func loadExceptions(from string) ([]models.OpeningException, error) {
+	rows, err := db.DB.Query("SELECT "+exceptionColumns+" FROM opening_hours_exceptions WHERE end_date >= $1 ORDER BY start_date, id", from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exceptions := []models.OpeningException{}
+	for rows.Next() {
+		e, err := scanException(rows)
+		if err != nil {
+			return nil, err
+		}
+		exceptions = append(exceptions, e)
+	}
+	return exceptions, rows.Err()
+}
+
+// hoursOn verilen günün aralıklarını döndürür. Günü kapsayan istisnalardan en
+// kısa olanı geçerlidir (ör. yaz nöbeti içindeki bayram günü).
+func hoursOn(day time.Time, weekly map[int][]models.OpeningInterval, exceptions []models.OpeningException) ([]models.OpeningInterval, *models.OpeningException) {
+	date := day.Format("2006-01-02")
+	var match *models.OpeningException
+	for i := range exceptions {
+		e := &exceptions[i]
+		if e.StartDate > date || e.EndDate < date {
+			continue
+		}
+		if match == nil || exceptionDays(e) < exceptionDays(match) {
+			match = e
+		}
+	}
+	if match != nil {
+		if match.Closed {
+			return []models.OpeningInterval{}, match
+		}
+		return match.Intervals, match
+	}
+	return weekly[isoWeekday(day)], nil
+}
+
+func exceptionDays(e *models.OpeningException) int {
+	start, _ := time.Parse("2006-01-02", e.StartDate)
+	end, _ := time.Parse("2006-01-02", e.EndDate)
+	return int(end.Sub(start).Hours() / 24)
+}
+
+// computeOpeningStatus dünden başlayarak iki haftalık açık zaman dilimlerini
+// çıkarır, bitişik olanları birleştirir ve now'a göre durumu hesaplar
+func computeOpeningStatus(now time.Time, weekly map[int][]models.OpeningInterval, exceptions []models.OpeningException) models.OpeningStatus {
+	now = now.In(businessLocation)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, businessLocation)
+
+	type span struct{ start, end time.Time }
+	var spans []span
+	for d := -1; d <= 14; d++ {
+		day := today.AddDate(0, 0, d)
+		intervals, _ := hoursOn(day, weekly, exceptions)
+		for _, iv := range intervals {
+			start, _ := parseClock(iv.Opens, false)
+			end, _ := parseClock(iv.Closes, true)
+			if end <= start {
+				end += 24 * 60
+			}
+			spans = append(spans, span{
+				start: time.Date(day.Year(), day.Month(), day.Day(), 0, start, 0, 0, businessLocation),
+				end:   time.Date(day.Year(), day.Month(), day.Day(), 0, end, 0, 0, businessLocation),
+			})
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start.Before(spans[j].start) })
+
+	// Gece yarısında devam eden aralıklar (ör. 00:00-24:00 nöbet) tek aralık sayılır
+	var merged []span
+	for _, s := range spans {
+		if n := len(merged); n > 0 && !s.start.After(merged[n-1].end) {
+			if s.end.After(merged[n-1].end) {
+				merged[n-1].end = s.end
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+
+	todayIntervals, exception := hoursOn(today, weekly, exceptions)
+	status := models.OpeningStatus{
+		Now:      now,
+		Timezone: businessTimezone,
+		Today:    todayIntervals,
+	}
+	if status.Today == nil {
+		status.Today = []models.OpeningInterval{}
+	}
+	if exception != nil {
+		status.Exception = exception.Label
+	}
+
+	for _, s := range merged {
+		if !s.start.After(now) && now.Before(s.end) {
+			status.Open = true
+			closesAt := s.end
+			status.ClosesAt = &closesAt
+			continue
+		}
+		if s.start.After(now) {
+			nextOpening := s.start
+			status.NextOpening = &nextOpening
+			break
+		}
+	}
+	return status
+}
+
+// formatIntervals "09:00 - 12:00, 13:00 - 18:00" veya "Kapalı"
+func formatIntervals(intervals []models.OpeningInterval) string {
+	if len(intervals) == 0 {
+		return closedLabel
+	}
+	parts := make([]string, len(intervals))
+	for i, iv := range intervals {
+		parts[i] = iv.Opens + " - " + iv.Closes
+	}
+	return strings.Join(parts, ", ")
+}
+
+// legacyHours birden fazla günü tek metne çevirir. Günlerin saatleri farklıysa
+// ardışık aynı günler gruplanır: "Pzt-Per 09:00 - 18:00; Cum 09:00 - 17:00"
+func legacyHours(weekly map[int][]models.OpeningInterval, days ...int) string {
+	type group struct {
+		first, last int
+		hours       string
+	}
+	var groups []group
+	for _, day := range days {
+		hours := formatIntervals(weekly[day])
+		if n := len(groups); n > 0 && groups[n-1].hours == hours {
+			groups[n-1].last = day
+			continue
+		}
+		groups = append(groups, group{day, day, hours})
+	}
+	if len(groups) == 1 {
+		return groups[0].hours
+	}
+
+	parts := make([]string, len(groups))
+	for i, g := range groups {
+		name := weekdayShortNames[g.first]
+		if g.last != g.first {
+			name += "-" + weekdayShortNames[g.last]
+		}
+		parts[i] = name + " " + g.hours
+	}
+	return strings.Join(parts, "; ")
+}
+
+// applyDerivedHours program tanımlıysa iletişim kaydındaki metin alanlarını ondan üretir
+func applyDerivedHours(contact *models.Contact) {
+	weekly, err := loadWeeklyHours()
+	if err != nil || len(weekly) == 0 {
+		return
+	}
+	contact.WeekdayHours = legacyHours(weekly, 1, 2, 3, 4, 5)
+	contact.SaturdayHours = legacyHours(weekly, 6)
+	contact.SundayHours = legacyHours(weekly, 7)
+}
+
+// parseLegacyHours "09:00 - 12:00, 13:00 - 18:00" metnini aralıklara çevirir;
+// "Kapalı" veya okunamayan metin kapalı gün sayılır
+func parseLegacyHours(value string) []models.OpeningInterval {
+	intervals := []models.OpeningInterval{}
+	for _, part := range strings.Split(value, ",") {
+		if opens, closes, ok := parseHoursRange(part); ok {
+			intervals = append(intervals, models.OpeningInterval{Opens: opens, Closes: closes})
+		}
+	}
+	if valid, err := validateIntervals(intervals); err == nil {
+		return valid
+	}
+	return []models.OpeningInterval{}
+}
+
+// seedOpeningHours program hiç tanımlanmamışsa iletişim kaydındaki metinlerden üretir
+func seedOpeningHours() error {
+	var count int
+	if err := db.DB.QueryRow("SELECT COUNT(*) FROM opening_hours").Scan(&count); err != nil || count > 0 {
+		return err
+	}
+
+	var weekday, saturday, sunday string
+	err := db.DB.QueryRow(
+		"SELECT COALESCE(weekday_hours, ''), COALESCE(saturday_hours, ''), COALESCE(sunday_hours, '') FROM contact ORDER BY id LIMIT 1",
+	).Scan(&weekday, &saturday, &sunday)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	legacy := map[int]string{1: weekday, 2: weekday, 3: weekday, 4: weekday, 5: weekday, 6: saturday, 7: sunday}
+	for day, value := range legacy {
+		data, _ := json.Marshal(parseLegacyHours(value))
+		if _, err := db.DB.Exec("INSERT INTO opening_hours (weekday, intervals) VALUES ($1, $2) ON CONFLICT (weekday) DO NOTHING", day, string(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func openingHoursView() (gin.H, error) {
+	weekly, err := loadWeeklyHours()
+	if err != nil {
+		return nil, err
+	}
+	exceptions, err := loadExceptions(time.Now().In(businessLocation).Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	return gin.H{
+		"timezone":   businessTimezone,
+		"configured": len(weekly) > 0,
+		"weekly":     weeklyList(weekly),
+		"exceptions": exceptions,
+	}, nil
+}
+
+// Çalışma saati işlemleri
+func getOpeningHoursHandler(c *gin.Context) {
+	view, err := openingHoursView()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, view)
+}
+
+func getOpeningStatusHandler(c *gin.Context) {
+	weekly, err := loadWeeklyHours()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	now := time.Now().In(businessLocation)
+	exceptions, err := loadExceptions(now.AddDate(0, 0, -1).Format("2006-01-02"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.JSON(http.StatusOK, computeOpeningStatus(now, weekly, exceptions))
+}
+
+// updateWeeklyHoursHandler gönderilen günleri günceller; program ilk kez
+// tanımlanıyorsa gönderilmeyen günler kapalı kabul edilir
+func updateWeeklyHoursHandler(c *gin.Context) {
+	var input []models.WeeklyHours
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range input {
+		if input[i].Weekday < 1 || input[i].Weekday > 7 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Gün 1 (Pazartesi) ile 7 (Pazar) arasında olmalı"})
+			return
+		}
+		intervals, err := validateIntervals(input[i].Intervals)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": weekdayNames[input[i].Weekday] + ": " + err.Error()})
+			return
+		}
+		input[i].Intervals = intervals
+	}
+
+	before, err := loadWeeklyHours()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	for _, day := range input {
+		data, _ := json.Marshal(day.Intervals)
+		_, err = tx.Exec(
+			`INSERT INTO opening_hours (weekday, intervals) VALUES ($1, $2)
+			ON CONFLICT (weekday) DO UPDATE SET intervals = EXCLUDED.intervals, updated_at = NOW()`,
+			day.Weekday, string(data),
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	_, err = tx.Exec("INSERT INTO opening_hours (weekday) SELECT generate_series(1, 7) ON CONFLICT (weekday) DO NOTHING")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	after, _ := loadWeeklyHours()
+	recordAudit(c, "opening_hours", 0, auditUpdate, gin.H{"weekly": weeklyList(before)}, gin.H{"weekly": weeklyList(after)})
+	c.JSON(http.StatusOK, weeklyList(after))
+}
+
+// bindException istisnayı okur ve doğrular
+func bindException(c *gin.Context) (models.OpeningException, bool) {
+	var e models.OpeningException
+	if err := c.ShouldBindJSON(&e); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return e, false
+	}
+	if e.Label == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Açıklama zorunludur (ör. Kurban Bayramı)"})
+		return e, false
+	}
+	start, err1 := time.Parse("2006-01-02", e.StartDate)
+	end, err2 := time.Parse("2006-01-02", e.EndDate)
+	if e.EndDate == "" {
+		end, err2 = start, nil
+		e.EndDate = e.StartDate
+	}
+	if err1 != nil || err2 != nil || end.Before(start) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz tarih aralığı (YYYY-AA-GG)"})
+		return e, false
+	}
+
+	if e.Closed {
+		e.Intervals = []models.OpeningInterval{}
+	} else {
+		intervals, err := validateIntervals(e.Intervals)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return e, false
+		}
+		if len(intervals) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Açık günler için en az bir saat aralığı gerekli"})
+			return e, false
+		}
+		e.Intervals = intervals
+	}
+	return e, true
+}
+
+func createOpeningExceptionHandler(c *gin.Context) {
+	e, ok := bindException(c)
+	if !ok {
+		return
+	}
+
+	data, _ := json.Marshal(e.Intervals)
+	err := db.DB.QueryRow(
+		"INSERT INTO opening_hours_exceptions (label, start_date, end_date, closed, intervals) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		e.Label, e.StartDate, e.EndDate, e.Closed, string(data),
+	).Scan(&e.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, "opening_exception", e.ID, auditCreate, nil, e)
+	c.JSON(http.StatusCreated, e)
+}
+
+func updateOpeningExceptionHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	e, ok := bindException(c)
+	if !ok {
+		return
+	}
+
+	before, err := scanException(db.DB.QueryRow("SELECT "+exceptionColumns+" FROM opening_hours_exceptions WHERE id = $1", id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "İstisna bulunamadı"})
+		return
+	}
+
+	e.ID = id
+	data, _ := json.Marshal(e.Intervals)
+	_, err = db.DB.Exec(
+		"UPDATE opening_hours_exceptions SET label = $1, start_date = $2, end_date = $3, closed = $4, intervals = $5 WHERE id = $6",
+		e.Label, e.StartDate, e.EndDate, e.Closed, string(data), id,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, "opening_exception", id, auditUpdate, before, e)
+	c.JSON(http.StatusOK, e)
+}
+
+func deleteOpeningExceptionHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	before, err := scanException(db.DB.QueryRow("SELECT "+exceptionColumns+" FROM opening_hours_exceptions WHERE id = $1", id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "İstisna bulunamadı"})
+		return
+	}
+
+	if _, err := db.DB.Exec("DELETE FROM opening_hours_exceptions WHERE id = $1", id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, "opening_exception", id, auditDelete, before, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "İstisna silindi"})
+}
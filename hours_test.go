@@ -0,0 +1,177 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"kozan/models"
+)
+
+func iv(opens, closes string) models.OpeningInterval {
+	return models.OpeningInterval{Opens: opens, Closes: closes}
+}
+
+// everyDay haftanın her gününe aynı aralıkları atar
+func everyDay(intervals ...models.OpeningInterval) map[int][]models.OpeningInterval {
+	weekly := make(map[int][]models.OpeningInterval)
+	for day := 1; day <= 7; day++ {
+		weekly[day] = intervals
+	}
+	return weekly
+}
+
+func businessTime(day, hour, minute int) time.Time {
+	return time.Date(2026, 3, day, hour, minute, 0, 0, businessLocation)
+}
+
+func TestValidateIntervals(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []models.OpeningInterval
+		want    []models.OpeningInterval
+		wantErr bool
+	}{
+		{"tek aralık", []models.OpeningInterval{iv("09:00", "18:00")}, []models.OpeningInterval{iv("09:00", "18:00")}, false},
+		{"saat biçimi düzeltilir", []models.OpeningInterval{iv("9:00", "18:00")}, []models.OpeningInterval{iv("09:00", "18:00")}, false},
+		{"aralıklar sıralanır", []models.OpeningInterval{iv("13:00", "18:00"), iv("09:00", "12:00")},
+			[]models.OpeningInterval{iv("09:00", "12:00"), iv("13:00", "18:00")}, false},
+		{"bitişik aralıklar", []models.OpeningInterval{iv("09:00", "12:00"), iv("12:00", "18:00")},
+			[]models.OpeningInterval{iv("09:00", "12:00"), iv("12:00", "18:00")}, false},
+		{"gece yarısını geçen", []models.OpeningInterval{iv("22:00", "02:00")}, []models.OpeningInterval{iv("22:00", "02:00")}, false},
+		{"gündüz ve gece", []models.OpeningInterval{iv("20:00", "03:00"), iv("09:00", "12:00")},
+			[]models.OpeningInterval{iv("09:00", "12:00"), iv("20:00", "03:00")}, false},
+		{"tam gün", []models.OpeningInterval{iv("00:00", "24:00")}, []models.OpeningInterval{iv("00:00", "24:00")}, false},
+		{"boş gün", []models.OpeningInterval{}, []models.OpeningInterval{}, false},
+		{"çakışan aralıklar", []models.OpeningInterval{iv("09:00", "13:00"), iv("12:00", "18:00")}, nil, true},
+		{"gece aralığıyla çakışan", []models.OpeningInterval{iv("20:00", "02:00"), iv("23:00", "23:30")}, nil, true},
+		{"açılış ve kapanış aynı", []models.OpeningInterval{iv("09:00", "09:00")}, nil, true},
+		{"açılış 24:00 olamaz", []models.OpeningInterval{iv("24:00", "02:00")}, nil, true},
+		{"geçersiz saat", []models.OpeningInterval{iv("25:00", "26:00")}, nil, true},
+		{"geçersiz dakika", []models.OpeningInterval{iv("09:5", "18:00")}, nil, true},
+		{"24:30 kapanış", []models.OpeningInterval{iv("09:00", "24:30")}, nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := validateIntervals(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: validateIntervals(%v) error = %v, wantErr %v", tt.name, tt.input, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: validateIntervals(%v) = %v, want %v", tt.name, tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestHoursOn(t *testing.T) {
+	weekly := everyDay(iv("09:00", "18:00"))
+	exceptions := []models.OpeningException{
+		{ID: 1, Label: "Yaz nöbeti", StartDate: "2026-07-01", EndDate: "2026-08-31", Intervals: []models.OpeningInterval{iv("10:00", "14:00")}},
+		{ID: 2, Label: "Kurban Bayramı", StartDate: "2026-07-14", EndDate: "2026-07-16", Closed: true},
+		{ID: 3, Label: "Arife", StartDate: "2026-07-13", EndDate: "2026-07-13", Intervals: []models.OpeningInterval{iv("09:00", "12:00")}},
+	}
+
+	tests := []struct {
+		date      string
+		want      []models.OpeningInterval
+		exception string
+	}{
+		{"2026-06-30", []models.OpeningInterval{iv("09:00", "18:00")}, ""},
+		{"2026-07-01", []models.OpeningInterval{iv("10:00", "14:00")}, "Yaz nöbeti"},
+		{"2026-07-13", []models.OpeningInterval{iv("09:00", "12:00")}, "Arife"},
+		{"2026-07-15", []models.OpeningInterval{}, "Kurban Bayramı"},
+		{"2026-07-17", []models.OpeningInterval{iv("10:00", "14:00")}, "Yaz nöbeti"},
+		{"2026-09-01", []models.OpeningInterval{iv("09:00", "18:00")}, ""},
+	}
+
+	for _, tt := range tests {
+		day, _ := time.ParseInLocation("2006-01-02", tt.date, businessLocation)
+		got, exception := hoursOn(day, weekly, exceptions)
+		label := ""
+		if exception != nil {
+			label = exception.Label
+		}
+		if !reflect.DeepEqual(got, tt.want) || label != tt.exception {
+			t.Errorf("hoursOn(%s) = %v, %q, want %v, %q", tt.date, got, label, tt.want, tt.exception)
+		}
+	}
+}
+
+func TestComputeOpeningStatus(t *testing.T) {
+	// 10 Mart 2026 Salı. Açıkken de kapanıştan sonraki ilk açılış döner.
+	office := map[int][]models.OpeningInterval{
+		1: {iv("09:00", "18:00")}, 2: {iv("09:00", "18:00")}, 3: {iv("09:00", "18:00")},
+		4: {iv("09:00", "18:00")}, 5: {iv("09:00", "18:00"), iv("22:00", "02:00")},
+		6: {iv("10:00", "14:00")}, 7: {},
+	}
+	allDay := everyDay(iv("00:00", "24:00"))
+	closedThursday := []models.OpeningException{{ID: 1, Label: "Bakım", StartDate: "2026-03-12", EndDate: "2026-03-12", Closed: true}}
+
+	tests := []struct {
+		name        string
+		now         time.Time
+		weekly      map[int][]models.OpeningInterval
+		exceptions  []models.OpeningException
+		open        bool
+		closesAt    *time.Time
+		nextOpening *time.Time
+		exception   string
+	}{
+		{"mesai içinde", businessTime(10, 10, 0), office, nil, true, ptrTime(businessTime(10, 18, 0)), ptrTime(businessTime(11, 9, 0)), ""},
+		{"açılış anında açık", businessTime(10, 9, 0), office, nil, true, ptrTime(businessTime(10, 18, 0)), ptrTime(businessTime(11, 9, 0)), ""},
+		{"kapanış anında kapalı", businessTime(10, 18, 0), office, nil, false, nil, ptrTime(businessTime(11, 9, 0)), ""},
+		{"hafta sonuna devreden", businessTime(14, 15, 0), office, nil, false, nil, ptrTime(businessTime(16, 9, 0)), ""},
+		{"gece aralığında gece yarısından sonra", businessTime(14, 1, 0), office, nil, true, ptrTime(businessTime(14, 2, 0)), ptrTime(businessTime(14, 10, 0)), ""},
+		{"gece aralığından önce", businessTime(13, 20, 0), office, nil, false, nil, ptrTime(businessTime(13, 22, 0)), ""},
+		{"kesintisiz açık günler birleşir", businessTime(10, 12, 0), allDay, closedThursday, true, ptrTime(businessTime(12, 0, 0)), ptrTime(businessTime(13, 0, 0)), ""},
+		{"istisna günü", businessTime(12, 12, 0), allDay, closedThursday, false, nil, ptrTime(businessTime(13, 0, 0)), "Bakım"},
+		{"program yok", businessTime(10, 12, 0), map[int][]models.OpeningInterval{}, nil, false, nil, nil, ""},
+	}
+
+	for _, tt := range tests {
+		got := computeOpeningStatus(tt.now, tt.weekly, tt.exceptions)
+		if got.Open != tt.open || !equalTimePtr(got.ClosesAt, tt.closesAt) || !equalTimePtr(got.NextOpening, tt.nextOpening) || got.Exception != tt.exception {
+			t.Errorf("%s: open=%v closes=%v next=%v exception=%q, want open=%v closes=%v next=%v exception=%q",
+				tt.name, got.Open, got.ClosesAt, got.NextOpening, got.Exception, tt.open, tt.closesAt, tt.nextOpening, tt.exception)
+		}
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
+
+func equalTimePtr(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func TestLegacyHours(t *testing.T) {
+	weekly := map[int][]models.OpeningInterval{
+		1: {iv("09:00", "18:00")}, 2: {iv("09:00", "18:00")}, 3: {iv("09:00", "18:00")},
+		4: {iv("09:00", "18:00")}, 5: {iv("09:00", "17:00")},
+		6: {iv("09:00", "12:00"), iv("13:00", "16:00")}, 7: {},
+	}
+
+	tests := []struct {
+		days []int
+		want string
+	}{
+		{[]int{1, 2, 3, 4}, "09:00 - 18:00"},
+		{[]int{1, 2, 3, 4, 5}, "Pzt-Per 09:00 - 18:00; Cum 09:00 - 17:00"},
+		{[]int{6}, "09:00 - 12:00, 13:00 - 16:00"},
+		{[]int{7}, "Kapalı"},
+		{[]int{5, 6, 7}, "Cum 09:00 - 17:00; Cmt 09:00 - 12:00, 13:00 - 16:00; Paz Kapalı"},
+		// Aynı saatler ardışık değilse ayrı gruplanır
+		{[]int{1, 7, 2}, "Pzt 09:00 - 18:00; Paz Kapalı; Sal 09:00 - 18:00"},
+	}
+
+	for _, tt := range tests {
+		if got := legacyHours(weekly, tt.days...); got != tt.want {
+			t.Errorf("legacyHours(%v) = %q, want %q", tt.days, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,459 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"kozan/db"
+	"kozan/models"
+	"kozan/richtext"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// Kaynak dil Türkçe'dir; içerik tablolarındaki değerler Türkçe metindir.
+// Diğer diller translations tablosunda alan bazında saklanır.
+const defaultLocale = "tr"
+
+var supportedLocales = []models.Locale{
+	{Code: "tr", Name: "Türkçe", Dir: "ltr"},
+	{Code: "en", Name: "English", Dir: "ltr"},
+	{Code: "ar", Name: "العربية", Dir: "rtl"},
+}
+
+// Çevrilebilen alanlar (JSON alan adlarıyla)
+var translatableFields = map[string][]string{
+	"product":  {"name", "description", "seoTitle", "seoDescription"},
+	"service":  {"title", "description", "seoTitle", "seoDescription"},
+	"about":    {"title", "content"},
+	"contact":  {"title", "address", "weekdayHours", "saturdayHours", "sundayHours"},
+	"hero":     {"subheading", "heading", "buttonText"},
+	"footer":   {"copyright"},
+	"campaign": {"title", "description"},
+}
+
+func findLocale(code string) (models.Locale, bool) {
+	for _, l := range supportedLocales {
+		if l.Code == code {
+			return l, true
+		}
+	}
+	return models.Locale{}, false
+}
+
+func isTranslatable(entityType, field string) bool {
+	for _, f := range translatableFields[entityType] {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// negotiateLocale ?lang= parametresine, yoksa Accept-Language başlığına bakar
+func negotiateLocale(c *gin.Context) models.Locale {
+	if lang := strings.ToLower(c.Query("lang")); lang != "" {
+		if l, ok := findLocale(lang); ok {
+			return l
+		}
+	}
+
+	type candidate struct {
+		code string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(c.GetHeader("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		// "en-US" → "en"
+		primary, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		candidates = append(candidates, candidate{primary, q})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, cand := range candidates {
+		if l, ok := findLocale(cand.code); ok && cand.q > 0 {
+			return l
+		}
+	}
+	l, _ := findLocale(defaultLocale)
+	return l
+}
+
+// localize kayıtların çevrilebilir alanlarını istenen dile çevirir.
+// Çevirisi olmayan alanlar Türkçe kalır.
+func localize(c *gin.Context, entityType string, items []map[string]interface{}) {
+	locale := negotiateLocale(c)
+	c.Header("Content-Language", locale.Code)
+	c.Header("X-Content-Direction", locale.Dir)
+	c.Header("Vary", "Accept-Language")
+
+	for _, item := range items {
+		item["locale"] = locale.Code
+		item["dir"] = locale.Dir
+	}
+	if locale.Code == defaultLocale || len(items) == 0 {
+		return
+	}
+
+	ids := make([]int64, 0, len(items))
+	for _, item := range items {
+		if id, ok := item["id"].(float64); ok {
+			ids = append(ids, int64(id))
+		}
+	}
+
+	translated, err := itemTranslations(c, entityType, locale.Code, ids)
+	if err != nil {
+		log.Printf("localize %s failed: %v", entityType, err)
+		return
+	}
+
+	for _, item := range items {
+		id, _ := item["id"].(float64)
+		for field, value := range translated[int(id)] {
+			if value != "" {
+				item[field] = value
+			}
+		}
+	}
+}
+
+// itemTranslations yayındaki kayıtlar için yayın anında alınan çevirileri,
+// önizlenen kayıt ve yayın akışı dışındaki içerikler için güncel çevirileri döndürür
+func itemTranslations(c *gin.Context, entityType, locale string, ids []int64) (map[int]map[string]string, error) {
+	if !isPublishable(entityType) {
+		return liveTranslations(entityType, locale, ids)
+	}
+
+	rows, err := db.DB.Query(
+		"SELECT entity_id, translations->$2 FROM published_content WHERE entity_type = $1 AND translations ? $2 AND entity_id = ANY($3)",
+		entityType, locale, pq.Array(ids),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	translated := make(map[int]map[string]string)
+	for rows.Next() {
+		var id int
+		var data []byte
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		var fields map[string]string
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, err
+		}
+		translated[id] = fields
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if v, ok := c.Get(previewKey); ok {
+		if ref := v.(entityRef); ref.entityType == entityType {
+			live, err := liveTranslations(entityType, locale, []int64{int64(ref.id)})
+			if err != nil {
+				return nil, err
+			}
+			translated[ref.id] = live[ref.id]
+		}
+	}
+	return translated, nil
+}
+
+// liveTranslations translations tablosundaki güncel çevirileri döndürür
+func liveTranslations(entityType, locale string, ids []int64) (map[int]map[string]string, error) {
+	rows, err := db.DB.Query(
+		"SELECT entity_id, field, value FROM translations WHERE entity_type = $1 AND locale = $2 AND entity_id = ANY($3)",
+		entityType, locale, pq.Array(ids),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	translated := make(map[int]map[string]string)
+	for rows.Next() {
+		var id int
+		var field, value string
+		if err := rows.Scan(&id, &field, &value); err != nil {
+			return nil, err
+		}
+		if translated[id] == nil {
+			translated[id] = make(map[string]string)
+		}
+		translated[id][field] = value
+	}
+	return translated, rows.Err()
+}
+
+// contentRefs verilen tiplerdeki tüm kayıtların ID'lerini döndürür
+func contentRefs(entityTypes []string) ([]entityRef, error) {
+	queries := map[string]string{
+		"product":  "SELECT id FROM products ORDER BY id",
+		"service":  "SELECT id FROM services ORDER BY id",
+		"about":    "SELECT id FROM about ORDER BY id LIMIT 1",
+		"contact":  "SELECT id FROM contact ORDER BY id LIMIT 1",
+		"hero":     "SELECT id FROM hero ORDER BY id LIMIT 1",
+		"footer":   "SELECT id FROM footer ORDER BY id LIMIT 1",
+		"campaign": "SELECT id FROM campaigns ORDER BY id",
+	}
+
+	var refs []entityRef
+	for _, entityType := range entityTypes {
+		rows, err := db.DB.Query(queries[entityType])
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			refs = append(refs, entityRef{entityType, id})
+		}
+		rows.Close()
+	}
+	return refs, nil
+}
+
+// Dil işlemleri
+func getLocalesHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"default": defaultLocale,
+		"locales": supportedLocales,
+	})
+}
+
+func getTranslationsHandler(c *gin.Context) {
+	entityType, _, id, ok := revisionParams(c, false)
+	if !ok {
+		return
+	}
+	if _, ok := translatableFields[entityType]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bu içerik tipi çevrilemez"})
+		return
+	}
+
+	rows, err := db.DB.Query("SELECT locale, field, value FROM translations WHERE entity_type = $1 AND entity_id = $2", entityType, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	result := make(map[string]map[string]string)
+	for _, l := range supportedLocales {
+		if l.Code != defaultLocale {
+			result[l.Code] = make(map[string]string)
+		}
+	}
+	for rows.Next() {
+		var locale, field, value string
+		if err := rows.Scan(&locale, &field, &value); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if result[locale] != nil {
+			result[locale][field] = value
+		}
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// updateTranslationsHandler bir dil için alanları günceller; boş değer çeviriyi siler
+func updateTranslationsHandler(c *gin.Context) {
+	entityType, entity, id, ok := revisionParams(c, true)
+	if !ok {
+		return
+	}
+
+	locale := c.Param("locale")
+	if _, ok := findLocale(locale); !ok || locale == defaultLocale {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz dil: " + locale})
+		return
+	}
+	record, err := entity.load(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kayıt bulunamadı"})
+		return
+	}
+
+	var fields map[string]string
+	if err := c.ShouldBindJSON(&fields); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for field := range fields {
+		if !isTranslatable(entityType, field) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Çevrilemeyen alan: " + field})
+			return
+		}
+	}
+	// Zengin metin alanlarının çevirileri de kaynak kaydın biçimine göre temizlenir
+	source := toJSONMap(record)
+	for field, value := range fields {
+		if format, ok := richTextFormat(entityType, field, source); ok {
+			normalized, err := richtext.Normalize(value, format)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": field + ": " + err.Error()})
+				return
+			}
+			fields[field] = normalized
+		}
+	}
+
+	before := make(map[string]string)
+	rows, err := db.DB.Query("SELECT field, value FROM translations WHERE entity_type = $1 AND entity_id = $2 AND locale = $3", entityType, id, locale)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for rows.Next() {
+		var field, value string
+		rows.Scan(&field, &value)
+		before[field] = value
+	}
+	rows.Close()
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	after := make(map[string]string)
+	for k, v := range before {
+		after[k] = v
+	}
+	for field, value := range fields {
+		if value == "" {
+			_, err = tx.Exec("DELETE FROM translations WHERE entity_type = $1 AND entity_id = $2 AND locale = $3 AND field = $4", entityType, id, locale, field)
+			delete(after, field)
+		} else {
+			_, err = tx.Exec(
+				`INSERT INTO translations (entity_type, entity_id, locale, field, value) VALUES ($1, $2, $3, $4, $5)
+				ON CONFLICT (entity_type, entity_id, locale, field) DO UPDATE SET value = EXCLUDED.value, updated_at = NOW()`,
+				entityType, id, locale, field, value,
+			)
+			after[field] = value
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, entityType+"_translation", id, auditUpdate,
+		gin.H{"locale": locale, "fields": before}, gin.H{"locale": locale, "fields": after})
+	c.JSON(http.StatusOK, after)
+}
+
+// missingTranslationsHandler Türkçe değeri dolu olup çevirisi eksik alanları listeler
+func missingTranslationsHandler(c *gin.Context) {
+	locales := []string{}
+	if l := c.Query("locale"); l != "" {
+		if _, ok := findLocale(l); !ok || l == defaultLocale {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz dil: " + l})
+			return
+		}
+		locales = append(locales, l)
+	} else {
+		for _, l := range supportedLocales {
+			if l.Code != defaultLocale {
+				locales = append(locales, l.Code)
+			}
+		}
+	}
+
+	entityTypes := make([]string, 0, len(translatableFields))
+	if t := c.Query("entity_type"); t != "" {
+		if _, ok := translatableFields[t]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Bu içerik tipi çevrilemez"})
+			return
+		}
+		entityTypes = append(entityTypes, t)
+	} else {
+		for t := range translatableFields {
+			entityTypes = append(entityTypes, t)
+		}
+		sort.Strings(entityTypes)
+	}
+
+	refs, err := contentRefs(entityTypes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Mevcut çeviriler: tip:id:dil:alan
+	existing := make(map[string]bool)
+	rows, err := db.DB.Query("SELECT entity_type, entity_id, locale, field FROM translations WHERE value <> ''")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for rows.Next() {
+		var entityType, locale, field string
+		var id int
+		rows.Scan(&entityType, &id, &locale, &field)
+		existing[fmt.Sprintf("%s:%d:%s:%s", entityType, id, locale, field)] = true
+	}
+	rows.Close()
+
+	missing := []models.MissingTranslation{}
+	for _, ref := range refs {
+		record, err := revisionEntities[ref.entityType].load(ref.id)
+		if err != nil {
+			continue
+		}
+		source := toJSONMap(record)
+		for _, locale := range locales {
+			var fields []string
+			for _, field := range translatableFields[ref.entityType] {
+				if value, _ := source[field].(string); value == "" {
+					continue
+				}
+				if !existing[fmt.Sprintf("%s:%d:%s:%s", ref.entityType, ref.id, locale, field)] {
+					fields = append(fields, field)
+				}
+			}
+			if len(fields) > 0 {
+				missing = append(missing, models.MissingTranslation{
+					EntityType:    ref.entityType,
+					EntityID:      ref.id,
+					Locale:        locale,
+					MissingFields: fields,
+				})
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, missing)
+}
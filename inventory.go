@@ -0,0 +1,840 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kozan/db"
+	"kozan/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Yedek parça stoku. Stok sadece stock_movements defterine eklenen satırlarla
+// değişir; defter değiştirilemez, hatalar düzeltme (adjustment) hareketiyle
+// giderilir. stock_levels, hareketlerle aynı işlemde güncellenen özet tablodur.
+const (
+	movementPurchase   = "purchase"
+	movementTransfer   = "transfer"
+	movementUse        = "use"
+	movementReturn     = "return"
+	movementAdjustment = "adjustment"
+
+	locationWarehouse = "warehouse"
+	locationVan       = "van"
+)
+
+var movementKinds = map[string]bool{
+	movementPurchase: true, movementTransfer: true, movementUse: true, movementReturn: true, movementAdjustment: true,
+}
+
+var locationKinds = map[string]bool{locationWarehouse: true, locationVan: true}
+
+var (
+	errInvalidStock      = errors.New("geçersiz stok hareketi")
+	errInsufficientStock = errors.New("yetersiz stok")
+)
+
+// lowStockAlert toplam stok asgari seviyenin altına indiğinde yayınlanır
+type lowStockAlert struct {
+	PartID   int     `json:"part_id"`
+	SKU      string  `json:"sku"`
+	Name     string  `json:"name"`
+	Quantity float64 `json:"quantity"`
+	MinStock float64 `json:"min_stock"`
+}
+
+func invalidStock(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", errInvalidStock, fmt.Sprintf(format, args...))
+}
+
+// checkLocation yerin var ve aktif olduğunu doğrular
+func checkLocation(tx *sql.Tx, id *int) error {
+	if id == nil {
+		return nil
+	}
+	var active bool
+	err := tx.QueryRow("SELECT active FROM stock_locations WHERE id = $1", *id).Scan(&active)
+	if err == sql.ErrNoRows || (err == nil && !active) {
+		return invalidStock("stok yeri #%d bulunamadı", *id)
+	}
+	return err
+}
+
+// recordStockMovement hareketi doğrular, stok seviyelerini günceller ve deftere
+// yazar. Kullanım (use) stoku eksiye düşürebilir: parça fiilen takılmıştır ve
+// eksi stok eksik bir transferi gösterir. Diğer çıkışlar stoktan fazla olamaz.
+func recordStockMovement(tx *sql.Tx, m *models.StockMovement) (*lowStockAlert, error) {
+	if err := validateStockMovement(m); err != nil {
+		return nil, err
+	}
+	from, to := m.FromLocationID != nil, m.ToLocationID != nil
+	if err := checkLocation(tx, m.FromLocationID); err != nil {
+		return nil, err
+	}
+	if err := checkLocation(tx, m.ToLocationID); err != nil {
+		return nil, err
+	}
+	if m.WorkOrderID != nil {
+		var exists bool
+		if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM work_orders WHERE id = $1)", *m.WorkOrderID).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, invalidStock("iş emri bulunamadı")
+		}
+	}
+
+	// Ortalama maliyet hesabı için parça satırı kilitlenir
+	var part models.Part
+	err := tx.QueryRow("SELECT id, sku, name, cost_price, min_stock, active FROM parts WHERE id = $1 FOR UPDATE", m.PartID).
+		Scan(&part.ID, &part.SKU, &part.Name, &part.CostPrice, &part.MinStock, &part.Active)
+	if err == sql.ErrNoRows {
+		return nil, invalidStock("parça bulunamadı")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !part.Active && (m.Kind == movementPurchase || m.Kind == movementUse) {
+		return nil, invalidStock("parça kullanım dışı")
+	}
+
+	var total float64
+	if err := tx.QueryRow("SELECT COALESCE(SUM(quantity), 0) FROM stock_levels WHERE part_id = $1", m.PartID).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	if m.Kind == movementPurchase {
+		if m.UnitCost < 0 {
+			return nil, invalidStock("birim maliyet negatif olamaz")
+		}
+		if m.UnitCost == 0 {
+			m.UnitCost = part.CostPrice
+		}
+		cost := averageCost(total, part.CostPrice, m.Quantity, m.UnitCost)
+		if _, err := tx.Exec("UPDATE parts SET cost_price = $1, updated_at = NOW() WHERE id = $2", cost, m.PartID); err != nil {
+			return nil, err
+		}
+	} else {
+		m.UnitCost = part.CostPrice
+	}
+
+	if from {
+		// Satır kilitlenir; satır yoksa eşzamanlı eklemeler ON CONFLICT ile birleşir
+		var available float64
+		err := tx.QueryRow(
+			"SELECT quantity FROM stock_levels WHERE part_id = $1 AND location_id = $2 FOR UPDATE", m.PartID, *m.FromLocationID,
+		).Scan(&available)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if !canDebitStock(available, m.Quantity, m.Kind) {
+			return nil, fmt.Errorf("%w: %s", errInsufficientStock, part.Name)
+		}
+		_, err = tx.Exec(
+			`INSERT INTO stock_levels (part_id, location_id, quantity) VALUES ($1, $2, $3)
+			ON CONFLICT (part_id, location_id) DO UPDATE SET quantity = stock_levels.quantity + EXCLUDED.quantity`,
+			m.PartID, *m.FromLocationID, -m.Quantity,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if to {
+		_, err := tx.Exec(
+			`INSERT INTO stock_levels (part_id, location_id, quantity) VALUES ($1, $2, $3)
+			ON CONFLICT (part_id, location_id) DO UPDATE SET quantity = stock_levels.quantity + EXCLUDED.quantity`,
+			m.PartID, *m.ToLocationID, m.Quantity,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.QueryRow(
+		`INSERT INTO stock_movements (part_id, kind, quantity, from_location_id, to_location_id, work_order_id, unit_cost, note, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, created_at`,
+		m.PartID, m.Kind, m.Quantity, m.FromLocationID, m.ToLocationID, m.WorkOrderID, m.UnitCost, m.Note, m.CreatedBy,
+	).Scan(&m.ID, &m.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	after := total
+	if to {
+		after += m.Quantity
+	}
+	if from {
+		after -= m.Quantity
+	}
+	if crossedMinStock(part.MinStock, total, after) {
+		return &lowStockAlert{PartID: part.ID, SKU: part.SKU, Name: part.Name, Quantity: after, MinStock: part.MinStock}, nil
+	}
+	return nil, nil
+}
+
+// validateStockMovement türü, miktarı ve yönü denetler
+func validateStockMovement(m *models.StockMovement) error {
+	if !movementKinds[m.Kind] {
+		return invalidStock("hareket türü purchase, transfer, use, return veya adjustment olmalı")
+	}
+	if m.Quantity <= 0 {
+		return invalidStock("miktar pozitif olmalı")
+	}
+	m.Note = strings.TrimSpace(m.Note)
+
+	from, to := m.FromLocationID != nil, m.ToLocationID != nil
+	switch m.Kind {
+	case movementPurchase, movementReturn:
+		if !to || from {
+			return invalidStock("sadece hedef yer (to_location_id) verilmeli")
+		}
+	case movementTransfer:
+		if !to || !from || *m.FromLocationID == *m.ToLocationID {
+			return invalidStock("transfer için farklı iki yer verilmeli")
+		}
+	case movementUse:
+		if !from || to || m.WorkOrderID == nil {
+			return invalidStock("kullanım için kaynak yer ve iş emri verilmeli")
+		}
+	case movementAdjustment:
+		if from == to {
+			return invalidStock("düzeltmede artış için to_location_id, azalış için from_location_id verilmeli")
+		}
+		if m.Note == "" {
+			return invalidStock("düzeltme için açıklama zorunludur")
+		}
+	}
+	if m.Kind != movementUse && m.Kind != movementReturn {
+		m.WorkOrderID = nil
+	}
+	return nil
+}
+
+// canDebitStock yerdeki miktardan çıkış yapılabilir mi; sadece kullanım eksiye düşürebilir
+func canDebitStock(available, quantity float64, kind string) bool {
+	return kind == movementUse || available >= quantity
+}
+
+// averageCost alımdan sonraki ağırlıklı ortalama maliyet. Eldeki stok yoksa
+// veya eksideyse alımın birim maliyeti geçerlidir.
+func averageCost(stock, cost, quantity, unitCost float64) float64 {
+	if stock <= 0 {
+		return unitCost
+	}
+	return (stock*cost + quantity*unitCost) / (stock + quantity)
+}
+
+// crossedMinStock toplam stok bu hareketle asgari seviyenin altına mı indi.
+// Zaten altındaysa tekrar uyarılmaz.
+func crossedMinStock(minStock, before, after float64) bool {
+	return minStock > 0 && after < minStock && before >= minStock
+}
+
+// publishLowStock uyarıyı panele ve webhook'lara iletir; işlem tamamlandıktan sonra çağrılır
+func publishLowStock(c *gin.Context, alert *lowStockAlert) {
+	if alert == nil {
+		return
+	}
+	publishAdminEvent(c, eventPartLowStock, "part", alert.PartID, alert)
+	emitEvent(eventPartLowStock, alert)
+}
+
+// respondStockError doğrulama hatalarını 400, yetersiz stoku 409 olarak döndürür
+func respondStockError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errInvalidStock):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, errInsufficientStock):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// technicianVan teknisyenin aracındaki stok yeri
+func technicianVan(q queryRower, technicianID int) (int, error) {
+	var id int
+	err := q.QueryRow("SELECT id FROM stock_locations WHERE kind = 'van' AND technician_id = $1 AND active", technicianID).Scan(&id)
+	return id, err
+}
+
+// Katalog
+
+const partColumns = "id, sku, COALESCE(barcode, ''), name, unit, cost_price, sale_price, min_stock, active, created_at, updated_at"
+
+func scanPart(scanner interface{ Scan(...interface{}) error }) (models.Part, error) {
+	var p models.Part
+	err := scanner.Scan(&p.ID, &p.SKU, &p.Barcode, &p.Name, &p.Unit, &p.CostPrice, &p.SalePrice, &p.MinStock, &p.Active, &p.CreatedAt, &p.UpdatedAt)
+	return p, err
+}
+
+func getPart(id int) (models.Part, error) {
+	return scanPart(db.DB.QueryRow("SELECT "+partColumns+" FROM parts WHERE id = $1", id))
+}
+
+func validatePart(p *models.Part) error {
+	p.SKU, p.Barcode, p.Name, p.Unit = strings.TrimSpace(p.SKU), strings.TrimSpace(p.Barcode), strings.TrimSpace(p.Name), strings.TrimSpace(p.Unit)
+	if p.SKU == "" || p.Name == "" {
+		return fmt.Errorf("Stok kodu (SKU) ve ad zorunludur")
+	}
+	if p.Unit == "" {
+		p.Unit = "adet"
+	}
+	if p.CostPrice < 0 || p.SalePrice < 0 || p.MinStock < 0 {
+		return fmt.Errorf("Fiyat ve asgari stok negatif olamaz")
+	}
+	return nil
+}
+
+// getPartsHandler kataloğu listeler; q SKU, barkod veya adda arar, barcode tam eşleşir
+func getPartsHandler(c *gin.Context) {
+	query := "SELECT " + partColumns + " FROM parts WHERE 1=1"
+	var args []interface{}
+	if v := c.Query("barcode"); v != "" {
+		args = append(args, v)
+		query += fmt.Sprintf(" AND barcode = $%d", len(args))
+	}
+	if v := strings.TrimSpace(c.Query("q")); v != "" {
+		args = append(args, "%"+v+"%")
+		query += fmt.Sprintf(" AND (sku ILIKE $%d OR barcode ILIKE $%d OR name ILIKE $%d)", len(args), len(args), len(args))
+	}
+	if c.Query("active") == "true" {
+		query += " AND active"
+	}
+	query += " ORDER BY name, id"
+
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	parts := []models.Part{}
+	for rows.Next() {
+		p, err := scanPart(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		parts = append(parts, p)
+	}
+	c.JSON(http.StatusOK, parts)
+}
+
+func getPartHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	p, err := getPart(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Parça bulunamadı"})
+		return
+	}
+	levels, err := queryStockLevels("WHERE l.part_id = $1", id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"part": p, "stock": levels})
+}
+
+// createPartHandler parçayı ekler. Maliyet ilk alımdan sonra ağırlıklı ortalamayla güncellenir.
+func createPartHandler(c *gin.Context) {
+	p := models.Part{Active: true}
+	if err := c.ShouldBindJSON(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validatePart(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := db.DB.QueryRow(
+		`INSERT INTO parts (sku, barcode, name, unit, cost_price, sale_price, min_stock, active)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8) RETURNING id, created_at, updated_at`,
+		p.SKU, p.Barcode, p.Name, p.Unit, p.CostPrice, p.SalePrice, p.MinStock, p.Active,
+	).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	if isUniqueViolation(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Bu stok kodu veya barkod zaten kayıtlı"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, "part", p.ID, auditCreate, nil, p)
+	c.JSON(http.StatusCreated, p)
+}
+
+// updatePartHandler kataloğu günceller. Maliyet defterden hesaplandığı için
+// sadece stok yokken elle değiştirilebilir; aksi halde düzeltme hareketi gerekir.
+func updatePartHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	before, err := getPart(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Parça bulunamadı"})
+		return
+	}
+	p := before
+	if err := c.ShouldBindJSON(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p.ID = id
+	if err := validatePart(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if p.CostPrice != before.CostPrice {
+		var onHand float64
+		if err := db.DB.QueryRow("SELECT COALESCE(SUM(quantity), 0) FROM stock_levels WHERE part_id = $1", id).Scan(&onHand); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if onHand != 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Stokta parça varken maliyet elle değiştirilemez; maliyet alımlarla güncellenir"})
+			return
+		}
+	}
+
+	err = db.DB.QueryRow(
+		`UPDATE parts SET sku = $1, barcode = NULLIF($2, ''), name = $3, unit = $4, cost_price = $5, sale_price = $6,
+			min_stock = $7, active = $8, updated_at = NOW()
+		WHERE id = $9 RETURNING updated_at`,
+		p.SKU, p.Barcode, p.Name, p.Unit, p.CostPrice, p.SalePrice, p.MinStock, p.Active, id,
+	).Scan(&p.UpdatedAt)
+	if isUniqueViolation(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Bu stok kodu veya barkod zaten kayıtlı"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, "part", id, auditUpdate, before, p)
+	c.JSON(http.StatusOK, p)
+}
+
+// Stok yerleri
+
+func getStockLocationsHandler(c *gin.Context) {
+	rows, err := db.DB.Query("SELECT id, name, kind, technician_id, active, created_at FROM stock_locations ORDER BY kind DESC, name, id")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	locations := []models.StockLocation{}
+	for rows.Next() {
+		var l models.StockLocation
+		var technicianID sql.NullInt64
+		if err := rows.Scan(&l.ID, &l.Name, &l.Kind, &technicianID, &l.Active, &l.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if technicianID.Valid {
+			v := int(technicianID.Int64)
+			l.TechnicianID = &v
+		}
+		locations = append(locations, l)
+	}
+	c.JSON(http.StatusOK, locations)
+}
+
+func validateStockLocation(l *models.StockLocation) error {
+	l.Name = strings.TrimSpace(l.Name)
+	if l.Name == "" {
+		return fmt.Errorf("Ad zorunludur")
+	}
+	if !locationKinds[l.Kind] {
+		return fmt.Errorf("Tür warehouse veya van olmalı")
+	}
+	if l.Kind == locationWarehouse {
+		l.TechnicianID = nil
+		return nil
+	}
+	if l.TechnicianID == nil {
+		return fmt.Errorf("Araç için teknisyen zorunludur")
+	}
+	var exists bool
+	if err := db.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)", *l.TechnicianID).Scan(&exists); err != nil || !exists {
+		return fmt.Errorf("Teknisyen bulunamadı")
+	}
+	return nil
+}
+
+// createStockLocationHandler depo veya araç ekler; her teknisyenin bir aracı olabilir
+func createStockLocationHandler(c *gin.Context) {
+	l := models.StockLocation{Active: true}
+	if err := c.ShouldBindJSON(&l); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateStockLocation(&l); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := db.DB.QueryRow(
+		"INSERT INTO stock_locations (name, kind, technician_id, active) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+		l.Name, l.Kind, l.TechnicianID, l.Active,
+	).Scan(&l.ID, &l.CreatedAt)
+	if isUniqueViolation(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Bu teknisyenin zaten bir aracı var"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, "stock_location", l.ID, auditCreate, nil, l)
+	c.JSON(http.StatusCreated, l)
+}
+
+// updateStockLocationHandler adı, teknisyeni ve aktifliği değiştirir. Stoğu olan yer kapatılamaz.
+func updateStockLocationHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	var before models.StockLocation
+	var technicianID sql.NullInt64
+	err = db.DB.QueryRow("SELECT id, name, kind, technician_id, active, created_at FROM stock_locations WHERE id = $1", id).
+		Scan(&before.ID, &before.Name, &before.Kind, &technicianID, &before.Active, &before.CreatedAt)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stok yeri bulunamadı"})
+		return
+	}
+	if technicianID.Valid {
+		v := int(technicianID.Int64)
+		before.TechnicianID = &v
+	}
+	l := before
+	if err := c.ShouldBindJSON(&l); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	l.ID, l.Kind = id, before.Kind
+	if err := validateStockLocation(&l); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if before.Active && !l.Active {
+		var hasStock bool
+		if err := db.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM stock_levels WHERE location_id = $1 AND quantity <> 0)", id).Scan(&hasStock); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if hasStock {
+			c.JSON(http.StatusConflict, gin.H{"error": "Stoğu olan yer kapatılamaz; önce parçaları transfer edin"})
+			return
+		}
+	}
+
+	_, err = db.DB.Exec("UPDATE stock_locations SET name = $1, technician_id = $2, active = $3 WHERE id = $4", l.Name, l.TechnicianID, l.Active, id)
+	if isUniqueViolation(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Bu teknisyenin zaten bir aracı var"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, "stock_location", id, auditUpdate, before, l)
+	c.JSON(http.StatusOK, l)
+}
+
+// Hareketler
+
+const stockMovementColumns = "id, part_id, kind, quantity, from_location_id, to_location_id, work_order_id, unit_cost, note, created_by, created_at"
+
+func scanStockMovement(scanner interface{ Scan(...interface{}) error }) (models.StockMovement, error) {
+	var m models.StockMovement
+	var from, to, workOrderID sql.NullInt64
+	err := scanner.Scan(&m.ID, &m.PartID, &m.Kind, &m.Quantity, &from, &to, &workOrderID, &m.UnitCost, &m.Note, &m.CreatedBy, &m.CreatedAt)
+	for _, f := range []struct {
+		src sql.NullInt64
+		dst **int
+	}{{from, &m.FromLocationID}, {to, &m.ToLocationID}, {workOrderID, &m.WorkOrderID}} {
+		if f.src.Valid {
+			v := int(f.src.Int64)
+			*f.dst = &v
+		}
+	}
+	return m, err
+}
+
+func getStockMovementsHandler(c *gin.Context) {
+	query := "SELECT " + stockMovementColumns + " FROM stock_movements WHERE 1=1"
+	var args []interface{}
+	for _, f := range []struct{ param, column string }{
+		{"part_id", "part_id"}, {"kind", "kind"}, {"work_order_id", "work_order_id"},
+	} {
+		if v := c.Query(f.param); v != "" {
+			args = append(args, v)
+			query += fmt.Sprintf(" AND %s = $%d", f.column, len(args))
+		}
+	}
+	if v := c.Query("location_id"); v != "" {
+		args = append(args, v)
+		query += fmt.Sprintf(" AND (from_location_id = $%d OR to_location_id = $%d)", len(args), len(args))
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	offset, _ := strconv.Atoi(c.Query("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	args = append(args, limit, offset)
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	movements := []models.StockMovement{}
+	for rows.Next() {
+		m, err := scanStockMovement(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		movements = append(movements, m)
+	}
+	c.JSON(http.StatusOK, movements)
+}
+
+// createStockMovementHandler deftere hareket ekler. Miktar her zaman pozitiftir;
+// yön from_location_id / to_location_id ile belirlenir.
+func createStockMovementHandler(c *gin.Context) {
+	var m models.StockMovement
+	if err := c.ShouldBindJSON(&m); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	m.CreatedBy = currentUserID(c)
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	alert, err := recordStockMovement(tx, &m)
+	if err != nil {
+		respondStockError(c, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	publishLowStock(c, alert)
+	c.JSON(http.StatusCreated, m)
+}
+
+// Raporlar
+
+func queryStockLevels(where string, args ...interface{}) ([]models.StockLevel, error) {
+	rows, err := db.DB.Query(
+		`SELECT l.part_id, p.sku, p.name, p.unit, l.location_id, s.name, l.quantity,
+			l.quantity * p.cost_price, l.quantity * p.sale_price
+		FROM stock_levels l JOIN parts p ON p.id = l.part_id JOIN stock_locations s ON s.id = l.location_id `+where+`
+		ORDER BY s.kind DESC, s.name, p.name`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	levels := []models.StockLevel{}
+	for rows.Next() {
+		var l models.StockLevel
+		if err := rows.Scan(&l.PartID, &l.SKU, &l.Name, &l.Unit, &l.LocationID, &l.LocationName, &l.Quantity, &l.CostValue, &l.SaleValue); err != nil {
+			return nil, err
+		}
+		levels = append(levels, l)
+	}
+	return levels, rows.Err()
+}
+
+// getStockHandler yer bazında güncel stok; sıfır satırlar gösterilmez
+func getStockHandler(c *gin.Context) {
+	where := "WHERE l.quantity <> 0"
+	var args []interface{}
+	for _, f := range []struct{ param, column string }{{"location_id", "l.location_id"}, {"part_id", "l.part_id"}} {
+		if v := c.Query(f.param); v != "" {
+			args = append(args, v)
+			where += fmt.Sprintf(" AND %s = $%d", f.column, len(args))
+		}
+	}
+	levels, err := queryStockLevels(where, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, levels)
+}
+
+// getLowStockHandler toplam stoğu asgari seviyenin altındaki parçalar ve yer dağılımı
+func getLowStockHandler(c *gin.Context) {
+	rows, err := db.DB.Query(
+		`SELECT p.id, p.sku, p.name, COALESCE(SUM(l.quantity), 0) AS total, p.min_stock
+		FROM parts p LEFT JOIN stock_levels l ON l.part_id = p.id
+		WHERE p.active AND p.min_stock > 0
+		GROUP BY p.id HAVING COALESCE(SUM(l.quantity), 0) < p.min_stock
+		ORDER BY COALESCE(SUM(l.quantity), 0) / p.min_stock, p.name`,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	type lowStockItem struct {
+		lowStockAlert
+		Shortage float64             `json:"shortage"`
+		Stock    []models.StockLevel `json:"stock"`
+	}
+	items := []lowStockItem{}
+	for rows.Next() {
+		var item lowStockItem
+		if err := rows.Scan(&item.PartID, &item.SKU, &item.Name, &item.Quantity, &item.MinStock); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		item.Shortage = item.MinStock - item.Quantity
+		items = append(items, item)
+	}
+	rows.Close()
+	for i := range items {
+		levels, err := queryStockLevels("WHERE l.part_id = $1 AND l.quantity <> 0", items[i].PartID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		items[i].Stock = levels
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// stockValueLine bir yerdeki bir parçanın miktarı ve fiyatları
+type stockValueLine struct {
+	LocationID           int
+	Name, Kind           string
+	Quantity             float64
+	CostPrice, SalePrice float64
+}
+
+type locationValue struct {
+	LocationID int     `json:"location_id"`
+	Name       string  `json:"name"`
+	Kind       string  `json:"kind"`
+	CostValue  float64 `json:"cost_value"`
+	SaleValue  float64 `json:"sale_value"`
+}
+
+// valueStock satırları yer bazında toplar; satırlar yere göre sıralı gelir.
+// Eksi stok (eksik transfer) değere katılmaz, tutarlar kuruşa yuvarlanır.
+func valueStock(lines []stockValueLine) ([]locationValue, float64, float64) {
+	locations := []locationValue{}
+	var totalCost, totalSale float64
+	for _, l := range lines {
+		if n := len(locations); n == 0 || locations[n-1].LocationID != l.LocationID {
+			locations = append(locations, locationValue{LocationID: l.LocationID, Name: l.Name, Kind: l.Kind})
+		}
+		if l.Quantity <= 0 {
+			continue
+		}
+		v := &locations[len(locations)-1]
+		v.CostValue += l.Quantity * l.CostPrice
+		v.SaleValue += l.Quantity * l.SalePrice
+	}
+	for i := range locations {
+		locations[i].CostValue = roundMoney(locations[i].CostValue)
+		locations[i].SaleValue = roundMoney(locations[i].SaleValue)
+		totalCost += locations[i].CostValue
+		totalSale += locations[i].SaleValue
+	}
+	return locations, roundMoney(totalCost), roundMoney(totalSale)
+}
+
+// getStockValuationHandler stok değeri (ortalama maliyet ve satış fiyatıyla), yer bazında ve toplam
+func getStockValuationHandler(c *gin.Context) {
+	rows, err := db.DB.Query(
+		`SELECT s.id, s.name, s.kind, COALESCE(l.quantity, 0), COALESCE(p.cost_price, 0), COALESCE(p.sale_price, 0)
+		FROM stock_locations s
+		LEFT JOIN stock_levels l ON l.location_id = s.id
+		LEFT JOIN parts p ON p.id = l.part_id
+		ORDER BY s.kind DESC, s.name, s.id`,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	var lines []stockValueLine
+	for rows.Next() {
+		var l stockValueLine
+		if err := rows.Scan(&l.LocationID, &l.Name, &l.Kind, &l.Quantity, &l.CostPrice, &l.SalePrice); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		lines = append(lines, l)
+	}
+	locations, totalCost, totalSale := valueStock(lines)
+
+	c.JSON(http.StatusOK, gin.H{
+		"currency": priceCurrency, "locations": locations,
+		"total_cost_value": totalCost, "total_sale_value": totalSale,
+	})
+}
+
+// getTechnicianStockHandler teknisyenin aracındaki stok
+func getTechnicianStockHandler(c *gin.Context) {
+	van, err := technicianVan(db.DB, currentUserID(c))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Araç stoku tanımlı değil"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	levels, err := queryStockLevels("WHERE l.location_id = $1 AND l.quantity <> 0", van)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"location_id": van, "stock": levels})
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"kozan/models"
+)
+
+func TestValidateStockMovement(t *testing.T) {
+	warehouse, van, workOrder := 1, 2, 42
+	tests := []struct {
+		name string
+		m    models.StockMovement
+		ok   bool
+	}{
+		{"alım", models.StockMovement{Kind: movementPurchase, Quantity: 5, ToLocationID: &warehouse}, true},
+		{"transfer", models.StockMovement{Kind: movementTransfer, Quantity: 1, FromLocationID: &warehouse, ToLocationID: &van}, true},
+		{"kullanım", models.StockMovement{Kind: movementUse, Quantity: 1, FromLocationID: &van, WorkOrderID: &workOrder}, true},
+		{"sayım azalışı", models.StockMovement{Kind: movementAdjustment, Quantity: 1, FromLocationID: &van, Note: "sayım"}, true},
+		{"bilinmeyen tür", models.StockMovement{Kind: "sale", Quantity: 1, ToLocationID: &warehouse}, false},
+		{"sıfır miktar", models.StockMovement{Kind: movementPurchase, Quantity: 0, ToLocationID: &warehouse}, false},
+		{"eksi miktar", models.StockMovement{Kind: movementPurchase, Quantity: -2, ToLocationID: &warehouse}, false},
+		{"alımda kaynak yer", models.StockMovement{Kind: movementPurchase, Quantity: 1, FromLocationID: &van, ToLocationID: &warehouse}, false},
+		{"aynı yere transfer", models.StockMovement{Kind: movementTransfer, Quantity: 1, FromLocationID: &van, ToLocationID: &van}, false},
+		{"iş emirsiz kullanım", models.StockMovement{Kind: movementUse, Quantity: 1, FromLocationID: &van}, false},
+		{"açıklamasız düzeltme", models.StockMovement{Kind: movementAdjustment, Quantity: 1, ToLocationID: &van, Note: " "}, false},
+		{"iki yönlü düzeltme", models.StockMovement{Kind: movementAdjustment, Quantity: 1, FromLocationID: &warehouse, ToLocationID: &van, Note: "sayım"}, false},
+	}
+	for _, tt := range tests {
+		m := tt.m
+		err := validateStockMovement(&m)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: validateStockMovement error = %v, want ok %v", tt.name, err, tt.ok)
+		}
+		if err != nil && !errors.Is(err, errInvalidStock) {
+			t.Errorf("%s: error %v is not errInvalidStock", tt.name, err)
+		}
+	}
+
+	// İş emri sadece kullanım ve iadede tutulur
+	m := models.StockMovement{Kind: movementTransfer, Quantity: 1, FromLocationID: &warehouse, ToLocationID: &van, WorkOrderID: &workOrder}
+	if err := validateStockMovement(&m); err != nil || m.WorkOrderID != nil {
+		t.Errorf("transfer: work_order_id = %v, err = %v; want nil", m.WorkOrderID, err)
+	}
+}
+
+func TestCanDebitStock(t *testing.T) {
+	tests := []struct {
+		name      string
+		available float64
+		quantity  float64
+		kind      string
+		want      bool
+	}{
+		{"yeterli stok", 5, 3, movementTransfer, true},
+		{"stokun tamamı", 3, 3, movementTransfer, true},
+		{"yetersiz transfer", 2, 3, movementTransfer, false},
+		{"yetersiz sayım azalışı", 0, 1, movementAdjustment, false},
+		{"stok eksideyken", -1, 1, movementTransfer, false},
+		{"kullanım eksiye düşürür", 0, 2, movementUse, true},
+		{"eksideyken kullanım", -2, 1, movementUse, true},
+	}
+	for _, tt := range tests {
+		if got := canDebitStock(tt.available, tt.quantity, tt.kind); got != tt.want {
+			t.Errorf("%s: canDebitStock(%v, %v, %s) = %v, want %v", tt.name, tt.available, tt.quantity, tt.kind, got, tt.want)
+		}
+	}
+}
+
+func TestAverageCost(t *testing.T) {
+	tests := []struct {
+		name                            string
+		stock, cost, quantity, unitCost float64
+		want                            float64
+	}{
+		{"ilk alım", 0, 0, 10, 250, 250},
+		{"eşit miktar", 10, 200, 10, 300, 250},
+		{"ağırlıklı", 30, 100, 10, 200, 125},
+		{"eksi stokta alım", -4, 100, 10, 180, 180},
+	}
+	for _, tt := range tests {
+		if got := averageCost(tt.stock, tt.cost, tt.quantity, tt.unitCost); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: averageCost = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCrossedMinStock(t *testing.T) {
+	tests := []struct {
+		name                    string
+		minStock, before, after float64
+		want                    bool
+	}{
+		{"eşik yok", 0, 5, -1, false},
+		{"eşiğin üstünde kalır", 5, 10, 6, false},
+		{"eşiğe iner", 5, 6, 5, false},
+		{"eşiğin altına iner", 5, 5, 4, true},
+		{"zaten altında", 5, 4, 3, false},
+		{"alımla yükselir", 5, 3, 8, false},
+	}
+	for _, tt := range tests {
+		if got := crossedMinStock(tt.minStock, tt.before, tt.after); got != tt.want {
+			t.Errorf("%s: crossedMinStock(%v, %v, %v) = %v, want %v", tt.name, tt.minStock, tt.before, tt.after, got, tt.want)
+		}
+	}
+}
+
+func TestValueStock(t *testing.T) {
+	lines := []stockValueLine{
+		{LocationID: 1, Name: "Depo", Kind: locationWarehouse, Quantity: 3, CostPrice: 1200, SalePrice: 1500},
+		{LocationID: 1, Name: "Depo", Kind: locationWarehouse, Quantity: 2.5, CostPrice: 80.333, SalePrice: 100},
+		{LocationID: 2, Name: "Araç 1", Kind: locationVan, Quantity: 1, CostPrice: 450, SalePrice: 600},
+		// Eksik transfer yüzünden eksiye düşmüş stok değere katılmaz
+		{LocationID: 2, Name: "Araç 1", Kind: locationVan, Quantity: -2, CostPrice: 1200, SalePrice: 1500},
+		// Stoku olmayan yer sıfır değerle listelenir
+		{LocationID: 3, Name: "Araç 2", Kind: locationVan},
+	}
+	locations, totalCost, totalSale := valueStock(lines)
+
+	want := []locationValue{
+		{LocationID: 1, Name: "Depo", Kind: locationWarehouse, CostValue: 3800.83, SaleValue: 4750},
+		{LocationID: 2, Name: "Araç 1", Kind: locationVan, CostValue: 450, SaleValue: 600},
+		{LocationID: 3, Name: "Araç 2", Kind: locationVan},
+	}
+	if !reflect.DeepEqual(locations, want) {
+		t.Errorf("locations = %+v, want %+v", locations, want)
+	}
+	if totalCost != 4250.83 || totalSale != 5350 {
+		t.Errorf("totals = %v, %v, want 4250.83, 5350", totalCost, totalSale)
+	}
+
+	if locations, totalCost, totalSale := valueStock(nil); len(locations) != 0 || totalCost != 0 || totalSale != 0 {
+		t.Errorf("valueStock(nil) = %v, %v, %v", locations, totalCost, totalSale)
+	}
+}
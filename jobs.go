@@ -0,0 +1,642 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"kozan/db"
+	"kozan/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Arka plan işleri PostgreSQL'deki jobs tablosunda tutulur. Worker'lar işi
+// SELECT ... FOR UPDATE SKIP LOCKED ile alır; böylece aynı işi iki worker
+// çalıştırmaz ve birden fazla süreç güvenle çalışabilir.
+const (
+	jobPending = "pending"
+	jobDead    = "dead"
+
+	defaultJobAttempts = 5
+)
+
+// İş türleri
+const (
+	jobNotificationSend  = "notification.send"
+	jobLeadNotify        = "lead.notify"
+	jobWorkOrderNotify   = "workorder.notify"
+	jobLeadsPurge        = "leads.purge"
+	jobJobsCleanup       = "jobs.cleanup"
+	jobWebhookDeliver    = "webhook.deliver"
+	jobEventsCleanup     = "events.cleanup"
+	jobSyncCleanup       = "sync.cleanup"
+	jobMaintenanceRemind = "maintenance.remind"
+	jobPublishSchedule   = "content.schedule"
+	jobWebhooksCleanup   = "webhooks.cleanup"
+)
+
+// registerJobs tüm iş türlerinin işleyicilerini kaydeder
+func registerJobs() {
+	registerJob(jobNotificationSend, sendNotificationJob)
+	registerJob(jobLeadNotify, leadNotifyJob)
+	registerJob(jobWorkOrderNotify, workOrderNotifyJob)
+	registerJob(jobLeadsPurge, func(ctx context.Context, job models.Job, _ struct{}) error {
+		return purgeStaleLeads(ctx, leadRetention())
+	})
+	registerJob(jobJobsCleanup, func(ctx context.Context, job models.Job, _ struct{}) error {
+		return cleanupJobs(ctx)
+	})
+	registerJob(jobWebhookDeliver, deliverWebhookJob)
+	registerJob(jobEventsCleanup, func(ctx context.Context, job models.Job, _ struct{}) error {
+		return cleanupAdminEvents(ctx)
+	})
+	registerJob(jobSyncCleanup, func(ctx context.Context, job models.Job, _ struct{}) error {
+		return cleanupSyncLog(ctx)
+	})
+	registerJob(jobMaintenanceRemind, func(ctx context.Context, job models.Job, _ struct{}) error {
+		return sendMaintenanceReminders(ctx, maintenanceInterval())
+	})
+	registerJob(jobPublishSchedule, publishScheduleJob)
+	registerJob(jobWebhooksCleanup, func(ctx context.Context, job models.Job, _ struct{}) error {
+		return cleanupWebhookDeliveries(ctx)
+	})
+}
+
+// recurringJobs cron ifadesiyle periyodik olarak kuyruğa eklenen işler
+var recurringJobs = []recurringJob{
+	{Name: "leads-retention", Spec: "@hourly", Kind: jobLeadsPurge},
+	{Name: "jobs-cleanup", Spec: "30 3 * * *", Kind: jobJobsCleanup},
+	{Name: "events-cleanup", Spec: "15 * * * *", Kind: jobEventsCleanup},
+	{Name: "sync-cleanup", Spec: "45 3 * * *", Kind: jobSyncCleanup},
+	{Name: "maintenance-reminders", Spec: "0 10 * * *", Kind: jobMaintenanceRemind},
+	{Name: "webhooks-cleanup", Spec: "50 3 * * *", Kind: jobWebhooksCleanup},
+}
+
+type recurringJob struct {
+	Name string
+	Spec string
+	Kind string
+}
+
+type jobHandler func(ctx context.Context, job models.Job) error
+
+var jobHandlers = map[string]jobHandler{}
+
+// registerJob payload'ı T tipine çözen bir işleyici kaydeder
+func registerJob[T any](kind string, fn func(ctx context.Context, job models.Job, payload T) error) {
+	jobHandlers[kind] = func(ctx context.Context, job models.Job) error {
+		var payload T
+		if len(job.Payload) > 0 {
+			if err := json.Unmarshal(job.Payload, &payload); err != nil {
+				return permanentJobError(fmt.Errorf("payload çözülemedi: %w", err))
+			}
+		}
+		return fn(ctx, job, payload)
+	}
+}
+
+// permanentError tekrar denenmeyecek hatadır; iş doğrudan dead olur
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+func permanentJobError(err error) error {
+	return permanentError{err}
+}
+
+type jobOptions struct {
+	RunAt       time.Time
+	MaxAttempts int
+	// UniqueKey aynı anahtarla bekleyen veya çalışan bir iş varsa yenisinin eklenmesini engeller
+	UniqueKey string
+}
+
+// enqueueJob işi kuyruğa ekler. UniqueKey çakışırsa mevcut işin ID'si döner.
+// Kayıtla aynı transaction içinde eklemek için q olarak *sql.Tx verilebilir.
+func enqueueJob(q queryRower, kind string, payload interface{}, opts jobOptions) (int64, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultJobAttempts
+	}
+	if opts.RunAt.IsZero() {
+		opts.RunAt = time.Now()
+	}
+
+	var id int64
+	err = q.QueryRow(
+		`INSERT INTO jobs (kind, payload, run_at, max_attempts, unique_key) VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		ON CONFLICT (unique_key) WHERE status IN ('pending', 'running') DO NOTHING RETURNING id`,
+		kind, string(data), opts.RunAt, opts.MaxAttempts, opts.UniqueKey,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		err = q.QueryRow("SELECT id FROM jobs WHERE unique_key = $1 AND status IN ('pending', 'running')", opts.UniqueKey).Scan(&id)
+	}
+	if err == nil && !opts.RunAt.After(time.Now()) {
+		wakeJobWorkers()
+	}
+	return id, err
+}
+
+// jobWake aynı süreçteki worker'ları yeni iş için uyandırır
+var jobWake = make(chan struct{}, 1)
+
+func wakeJobWorkers() {
+	select {
+	case jobWake <- struct{}{}:
+	default:
+	}
+}
+
+// jobBackoff üstel bekleme süresi: 30s, 1dk, 2dk ... en fazla 1 saat, %20 sapmayla
+func jobBackoff(attempt int) time.Duration {
+	d := 15 * time.Second << uint(attempt)
+	if attempt > 10 || d > time.Hour {
+		d = time.Hour
+	}
+	return d + time.Duration(rand.Int63n(int64(d/5)+1))
+}
+
+func jobEnvInt(name string, def int) int {
+	if n, err := strconv.Atoi(os.Getenv(name)); err == nil && n > 0 {
+		return n
+	}
+	return def
+}
+
+func jobEnvDuration(name string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(name)); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
+
+// jobWorkerInProcess JOB_WORKER=off ise işler sadece ayrı "worker" sürecinde çalışır
+func jobWorkerInProcess() bool {
+	return os.Getenv("JOB_WORKER") != "off"
+}
+
+// startJobWorker JOB_CONCURRENCY kadar worker başlatır. ctx iptal edildiğinde
+// yeni iş alınmaz; dönen WaitGroup çalışan işlerin bitmesini bekler.
+func startJobWorker(ctx context.Context) *sync.WaitGroup {
+	if err := syncRecurringJobs(); err != nil {
+		log.Fatal(err)
+	}
+	if err := enqueuePendingSchedules(); err != nil {
+		log.Fatal(err)
+	}
+
+	hostname, _ := os.Hostname()
+	workerID := fmt.Sprintf("%s:%d", hostname, os.Getpid())
+	concurrency := jobEnvInt("JOB_CONCURRENCY", 4)
+	poll := jobEnvDuration("JOB_POLL_INTERVAL", 2*time.Second)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				worked, err := runNextJob(workerID)
+				if err != nil {
+					log.Printf("jobs: %v", err)
+				}
+				if worked {
+					continue
+				}
+				select {
+				case <-ctx.Done():
+				case <-jobWake:
+				case <-time.After(poll):
+				}
+			}
+		}()
+	}
+
+	go func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+		for {
+			if err := enqueueDueRecurringJobs(); err != nil {
+				log.Printf("jobs: recurring: %v", err)
+			}
+			if err := reclaimStaleJobs(); err != nil {
+				log.Printf("jobs: reclaim: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	log.Printf("jobs: worker %s started (%d concurrent)", workerID, concurrency)
+	return &wg
+}
+
+// runWorker "worker" alt komutudur: HTTP sunucusu olmadan sadece işleri çalıştırır.
+// SIGINT/SIGTERM geldiğinde yeni iş almaz ve çalışan işlerin bitmesini bekler.
+func runWorker() {
+	initNotifiers()
+	db.InitDB()
+	registerJobs()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	wg := startJobWorker(ctx)
+	<-ctx.Done()
+	log.Println("jobs: shutting down, waiting for running jobs")
+	wg.Wait()
+}
+
+const jobColumns = "id, kind, payload, status, attempts, max_attempts, run_at, COALESCE(unique_key, ''), last_error, locked_by, locked_at, finished_at, created_at"
+
+func scanJob(scanner interface{ Scan(...interface{}) error }) (models.Job, error) {
+	var job models.Job
+	var payload []byte
+	var lockedAt, finishedAt sql.NullTime
+	err := scanner.Scan(&job.ID, &job.Kind, &payload, &job.Status, &job.Attempts, &job.MaxAttempts, &job.RunAt,
+		&job.UniqueKey, &job.LastError, &job.LockedBy, &lockedAt, &finishedAt, &job.CreatedAt)
+	job.Payload = json.RawMessage(payload)
+	if lockedAt.Valid {
+		job.LockedAt = &lockedAt.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return job, err
+}
+
+// runNextJob zamanı gelen bir işi alır ve çalıştırır; iş yoksa false döner
+func runNextJob(workerID string) (bool, error) {
+	job, err := scanJob(db.DB.QueryRow(
+		`UPDATE jobs SET status = 'running', attempts = attempts + 1, locked_by = $1, locked_at = NOW()
+		WHERE id = (
+			SELECT id FROM jobs WHERE status = 'pending' AND run_at <= NOW()
+			ORDER BY run_at, id LIMIT 1 FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+jobColumns,
+		workerID,
+	))
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	stopHeartbeat := heartbeatJob(job.ID, workerID)
+	err = executeJob(job)
+	stopHeartbeat()
+
+	// Sonuç sadece iş hâlâ bu worker'daysa yazılır
+	switch {
+	case err == nil:
+		_, err = db.DB.Exec("UPDATE jobs SET status = 'done', last_error = '', finished_at = NOW() WHERE id = $1 AND locked_by = $2", job.ID, workerID)
+	case errors.As(err, new(permanentError)) || job.Attempts >= job.MaxAttempts:
+		log.Printf("jobs: %s #%d dead after %d attempts: %v", job.Kind, job.ID, job.Attempts, err)
+		_, err = db.DB.Exec("UPDATE jobs SET status = 'dead', last_error = $1, finished_at = NOW() WHERE id = $2 AND locked_by = $3", err.Error(), job.ID, workerID)
+	default:
+		log.Printf("jobs: %s #%d attempt %d failed: %v", job.Kind, job.ID, job.Attempts, err)
+		_, err = db.DB.Exec(
+			"UPDATE jobs SET status = 'pending', last_error = $1, run_at = $2 WHERE id = $3 AND locked_by = $4",
+			err.Error(), time.Now().Add(jobBackoff(job.Attempts)), job.ID, workerID,
+		)
+	}
+	return true, err
+}
+
+// heartbeatJob iş çalıştığı sürece kilidi tazeler. Böylece JOB_TIMEOUT'u aşan
+// ama hâlâ çalışan bir iş reclaimStaleJobs tarafından başka bir worker'a
+// verilmez; sadece çöken worker'ların işleri geri alınır.
+func heartbeatJob(id int64, workerID string) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(jobEnvDuration("JOB_TIMEOUT", 5*time.Minute) / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if _, err := db.DB.Exec(
+					"UPDATE jobs SET locked_at = NOW() WHERE id = $1 AND locked_by = $2 AND status = 'running'", id, workerID,
+				); err != nil {
+					log.Printf("jobs: heartbeat #%d: %v", id, err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// executeJob işleyiciyi zaman aşımıyla çalıştırır; panic hata olarak döner
+func executeJob(job models.Job) (err error) {
+	handler, ok := jobHandlers[job.Kind]
+	if !ok {
+		return permanentJobError(fmt.Errorf("bilinmeyen iş türü: %s", job.Kind))
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), jobEnvDuration("JOB_TIMEOUT", 5*time.Minute))
+	defer cancel()
+	return handler(ctx, job)
+}
+
+// reclaimStaleJobs çöken worker'ların yarım bıraktığı işleri tekrar kuyruğa alır.
+// Çalışan işlerin kilidi heartbeatJob ile tazelendiği için sadece kilidi
+// güncellenmeyen işler geri alınır.
+func reclaimStaleJobs() error {
+	timeout := jobEnvDuration("JOB_TIMEOUT", 5*time.Minute)
+	_, err := db.DB.Exec(
+		`UPDATE jobs SET status = 'pending', last_error = 'worker yanıt vermedi', run_at = NOW()
+		WHERE status = 'running' AND locked_at < $1`,
+		time.Now().Add(-2*timeout),
+	)
+	return err
+}
+
+// syncRecurringJobs koddaki periyodik işleri job_schedules tablosuna yazar
+func syncRecurringJobs() error {
+	for _, r := range recurringJobs {
+		schedule, err := parseCron(r.Spec)
+		if err != nil {
+			return fmt.Errorf("%s: %w", r.Name, err)
+		}
+		_, err = db.DB.Exec(
+			`INSERT INTO job_schedules (name, spec, kind, next_run_at) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (name) DO UPDATE SET kind = EXCLUDED.kind, spec = EXCLUDED.spec,
+				next_run_at = CASE WHEN job_schedules.spec <> EXCLUDED.spec THEN EXCLUDED.next_run_at ELSE job_schedules.next_run_at END`,
+			r.Name, r.Spec, r.Kind, schedule.next(time.Now()),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enqueueDueRecurringJobs zamanı gelen periyodik işleri kuyruğa ekler. Önceki
+// çalıştırma hâlâ bekliyor veya çalışıyorsa yenisi eklenmez.
+func enqueueDueRecurringJobs() error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT name, spec, kind FROM job_schedules WHERE next_run_at <= NOW() FOR UPDATE SKIP LOCKED")
+	if err != nil {
+		return err
+	}
+	var due []recurringJob
+	for rows.Next() {
+		var r recurringJob
+		if err := rows.Scan(&r.Name, &r.Spec, &r.Kind); err != nil {
+			rows.Close()
+			return err
+		}
+		due = append(due, r)
+	}
+	rows.Close()
+
+	for _, r := range due {
+		schedule, err := parseCron(r.Spec)
+		if err != nil {
+			return fmt.Errorf("%s: %w", r.Name, err)
+		}
+		if _, err := enqueueJob(tx, r.Kind, struct{}{}, jobOptions{UniqueKey: "cron:" + r.Name}); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE job_schedules SET next_run_at = $1, last_run_at = NOW() WHERE name = $2", schedule.next(time.Now()), r.Name); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// cleanupJobs başarıyla biten eski işleri siler; dead işler incelenmek üzere kalır
+func cleanupJobs(ctx context.Context) error {
+	_, err := db.DB.ExecContext(ctx, "DELETE FROM jobs WHERE status = 'done' AND finished_at < NOW() - INTERVAL '7 days'")
+	return err
+}
+
+// İş kuyruğu işlemleri
+func getJobsHandler(c *gin.Context) {
+	query := "SELECT " + jobColumns + " FROM jobs WHERE 1=1"
+	var args []interface{}
+	for param, column := range map[string]string{"status": "status", "kind": "kind"} {
+		if v := c.Query(param); v != "" {
+			args = append(args, v)
+			query += fmt.Sprintf(" AND %s = $%d", column, len(args))
+		}
+	}
+	if c.Query("failed") == "true" {
+		query += " AND last_error <> ''"
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if offset < 0 {
+		offset = 0
+	}
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT %d OFFSET %d", limit, offset)
+
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	jobs := []models.Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		jobs = append(jobs, job)
+	}
+
+	c.JSON(http.StatusOK, jobs)
+}
+
+// getJobStatsHandler tür ve duruma göre iş sayıları ile periyodik işler
+func getJobStatsHandler(c *gin.Context) {
+	rows, err := db.DB.Query("SELECT kind, status, COUNT(*) FROM jobs GROUP BY kind, status ORDER BY kind, status")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	counts := []gin.H{}
+	for rows.Next() {
+		var kind, status string
+		var count int
+		if err := rows.Scan(&kind, &status, &count); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		counts = append(counts, gin.H{"kind": kind, "status": status, "count": count})
+	}
+
+	scheduleRows, err := db.DB.Query("SELECT name, spec, kind, next_run_at, last_run_at FROM job_schedules ORDER BY name")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer scheduleRows.Close()
+
+	schedules := []gin.H{}
+	for scheduleRows.Next() {
+		var name, spec, kind string
+		var nextRun time.Time
+		var lastRun sql.NullTime
+		if err := scheduleRows.Scan(&name, &spec, &kind, &nextRun, &lastRun); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		schedule := gin.H{"name": name, "spec": spec, "kind": kind, "next_run_at": nextRun, "last_run_at": nil}
+		if lastRun.Valid {
+			schedule["last_run_at"] = lastRun.Time
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"counts": counts, "schedules": schedules})
+}
+
+func getJobHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	job, err := scanJob(db.DB.QueryRow("SELECT "+jobColumns+" FROM jobs WHERE id = $1", id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "İş bulunamadı"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// resetJobTarget tekrar denenen işin hedef kaydını tekrar gönderilebilir duruma
+// getirir. İşleyiciler sonuçlanmış kayıtları atladığı için bu yapılmazsa
+// tekrar deneme hiçbir şey yapmadan biter.
+func resetJobTarget(tx *sql.Tx, job models.Job) error {
+	switch job.Kind {
+	case jobPublishSchedule:
+		var payload publishSchedulePayload
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return err
+		}
+		_, err := tx.Exec(
+			"UPDATE publish_schedule SET status = 'pending', error = NULL, executed_at = NULL WHERE id = $1 AND status = 'failed'",
+			payload.ScheduleID,
+		)
+		return err
+	case jobWebhookDeliver:
+		var payload webhookDeliveryPayload
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return err
+		}
+		_, err := tx.Exec(
+			"UPDATE webhook_deliveries SET status = 'pending', error = '' WHERE id = $1 AND status = 'failed'",
+			payload.DeliveryID,
+		)
+		return err
+	case jobNotificationSend:
+		var payload notificationJob
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return err
+		}
+		_, err := tx.Exec(
+			"UPDATE notifications SET status = $1, error = '', attempts = 0 WHERE id = $2 AND status = $3",
+			notificationQueued, payload.NotificationID, notificationFailed,
+		)
+		return err
+	}
+	return nil
+}
+
+// retryJobHandler dead veya yeniden denemeyi bekleyen işi hemen tekrar çalıştırır
+func retryJobHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	before, err := scanJob(db.DB.QueryRow("SELECT "+jobColumns+" FROM jobs WHERE id = $1", id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "İş bulunamadı"})
+		return
+	}
+	if before.Status != jobDead && !(before.Status == jobPending && before.LastError != "") {
+		c.JSON(http.StatusConflict, gin.H{"error": "Sadece başarısız işler tekrar denenebilir"})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	job, err := scanJob(tx.QueryRow(
+		`UPDATE jobs SET status = 'pending', run_at = NOW(), finished_at = NULL,
+			attempts = CASE WHEN status = 'dead' THEN 0 ELSE attempts END
+		WHERE id = $1 AND (status = 'dead' OR (status = 'pending' AND last_error <> ''))
+		RETURNING `+jobColumns,
+		id,
+	))
+	if err == sql.ErrNoRows {
+		// Bu arada bir worker işi aldı veya iş tamamlandı
+		c.JSON(http.StatusConflict, gin.H{"error": "Sadece başarısız işler tekrar denenebilir"})
+		return
+	}
+	if err != nil {
+		// Aynı unique_key ile bekleyen başka bir iş varsa
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err := resetJobTarget(tx, job); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	wakeJobWorkers()
+	recordAudit(c, "job", int(id), auditUpdate, before, job)
+	c.JSON(http.StatusOK, job)
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"kozan/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Schema.org yapısal verisi: yerel arama sonuçları için işletme (HVACBusiness),
+// ürün (Product/Offer) ve hizmet (Service) JSON-LD belgeleri. Veriler public
+// API ile aynı kaynaktan (yayındaki içerik, ?lang ile çeviri) gelir.
+const schemaContext = "https://schema.org"
+
+// Fiyatlar Türk lirasıdır
+const priceCurrency = "TRY"
+
+var hoursRangeRe = regexp.MustCompile(`^(\d{1,2})[:.](\d{2})\s*[-–]\s*(\d{1,2})[:.](\d{2})$`)
+
+// parseHoursRange "09:00 - 18:00" biçimindeki metni açılış/kapanış saatine çevirir.
+// "Kapalı" gibi aralık olmayan değerlerde ok false döner.
+func parseHoursRange(value string) (opens, closes string, ok bool) {
+	m := hoursRangeRe.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil {
+		return "", "", false
+	}
+	pad := func(h string) string {
+		if len(h) == 1 {
+			return "0" + h
+		}
+		return h
+	}
+	return pad(m[1]) + ":" + m[2], pad(m[3]) + ":" + m[4], true
+}
+
+var schemaDayNames = []string{"", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+
+// openingHoursSpecification haftalık programı, aynı saatlere sahip günleri
+// gruplayarak yapısal hale getirir. Program tanımlı değilse iletişim
+// kaydındaki metinler okunur.
+func openingHoursSpecification(contact models.Contact) []gin.H {
+	weekly, err := loadWeeklyHours()
+	if err != nil || len(weekly) == 0 {
+		return legacyOpeningHoursSpecification(contact)
+	}
+
+	specs := []gin.H{}
+	index := make(map[string]int)
+	for day := 1; day <= 7; day++ {
+		for _, iv := range weekly[day] {
+			key := iv.Opens + "-" + iv.Closes
+			if i, ok := index[key]; ok {
+				specs[i]["dayOfWeek"] = append(specs[i]["dayOfWeek"].([]string), schemaDayNames[day])
+				continue
+			}
+			index[key] = len(specs)
+			specs = append(specs, gin.H{
+				"@type":     "OpeningHoursSpecification",
+				"dayOfWeek": []string{schemaDayNames[day]},
+				"opens":     iv.Opens,
+				"closes":    iv.Closes,
+			})
+		}
+	}
+	return specs
+}
+
+// specialOpeningHoursSpecification bugünden sonraki istisnalar; kapalı günler
+// schema.org'a göre 00:00-00:00 olarak yazılır
+func specialOpeningHoursSpecification() []gin.H {
+	exceptions, err := loadExceptions(time.Now().In(businessLocation).Format("2006-01-02"))
+	if err != nil {
+		return nil
+	}
+
+	specs := []gin.H{}
+	for _, e := range exceptions {
+		intervals := e.Intervals
+		if e.Closed {
+			intervals = []models.OpeningInterval{{Opens: "00:00", Closes: "00:00"}}
+		}
+		for _, iv := range intervals {
+			specs = append(specs, gin.H{
+				"@type":        "OpeningHoursSpecification",
+				"name":         e.Label,
+				"validFrom":    e.StartDate,
+				"validThrough": e.EndDate,
+				"opens":        iv.Opens,
+				"closes":       iv.Closes,
+			})
+		}
+	}
+	return specs
+}
+
+func legacyOpeningHoursSpecification(contact models.Contact) []gin.H {
+	groups := []struct {
+		days  []string
+		hours string
+	}{
+		{[]string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday"}, contact.WeekdayHours},
+		{[]string{"Saturday"}, contact.SaturdayHours},
+		{[]string{"Sunday"}, contact.SundayHours},
+	}
+
+	specs := []gin.H{}
+	for _, g := range groups {
+		opens, closes, ok := parseHoursRange(g.hours)
+		if !ok {
+			continue
+		}
+		specs = append(specs, gin.H{
+			"@type":     "OpeningHoursSpecification",
+			"dayOfWeek": g.days,
+			"opens":     opens,
+			"closes":    closes,
+		})
+	}
+	return specs
+}
+
+// absoluteURL /uploads gibi göreli yolları yapılandırılmış site adresiyle tamamlar.
+// İsteğin Host başlığı kullanılmaz; istemci kanonik adreslere başka alan adı sokamaz.
+func absoluteURL(path string) string {
+	if path == "" || strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	return siteURL() + "/" + strings.TrimPrefix(path, "/")
+}
+
+func businessID() string {
+	return siteURL() + "/#business"
+}
+
+// businessJSONLD işletme belgesini iletişim ve hakkımızda içeriğinden üretir
+func businessJSONLD(c *gin.Context) gin.H {
+	business := gin.H{
+		"@type": "HVACBusiness",
+		"@id":   businessID(),
+		"url":   siteURL() + "/",
+	}
+
+	if about, err := publicItems(c, "about"); err == nil && len(about) > 0 {
+		localize(c, "about", about[:1])
+		renderRichText("about", about[:1])
+		business["name"] = about[0]["title"]
+		business["description"] = about[0]["contentExcerpt"]
+		if image, _ := about[0]["image"].(string); image != "" {
+			business["image"] = absoluteURL(image)
+		}
+	}
+
+	if contact, err := getContact(); err == nil {
+		if contact.Phone != "" {
+			business["telephone"] = contact.Phone
+		}
+		if contact.Email != "" {
+			business["email"] = contact.Email
+		}
+		if len(contact.Channels) > 0 {
+			sameAs := make([]string, len(contact.Channels))
+			for i, ch := range contact.Channels {
+				sameAs[i] = ch.URL
+			}
+			business["sameAs"] = sameAs
+		}
+		if contact.Address != "" {
+			business["address"] = gin.H{
+				"@type":          "PostalAddress",
+				"streetAddress":  contact.Address,
+				"addressCountry": "TR",
+			}
+		}
+		if contact.Latitude != 0 || contact.Longitude != 0 {
+			business["geo"] = gin.H{
+				"@type":     "GeoCoordinates",
+				"latitude":  contact.Latitude,
+				"longitude": contact.Longitude,
+			}
+		}
+		if specs := openingHoursSpecification(contact); len(specs) > 0 {
+			business["openingHoursSpecification"] = specs
+		}
+		if specs := specialOpeningHoursSpecification(); len(specs) > 0 {
+			business["specialOpeningHoursSpecification"] = specs
+		}
+	}
+
+	return business
+}
+
+func productJSONLD(c *gin.Context, item map[string]interface{}) gin.H {
+	url := siteURL() + sitePaths["product"] + stringValue(item["slug"])
+	product := gin.H{
+		"@type":       "Product",
+		"@id":         url + "#product",
+		"url":         url,
+		"name":        item["name"],
+		"description": item["descriptionExcerpt"],
+	}
+	if image := stringValue(item["image"]); image != "" {
+		product["image"] = absoluteURL(image)
+	}
+	// Katalog ürünlerinin stoğu tutulmadığı için availability verilmez
+	if price, ok := item["price"].(float64); ok && price > 0 {
+		product["offers"] = gin.H{
+			"@type":         "Offer",
+			"url":           url,
+			"price":         price,
+			"priceCurrency": priceCurrency,
+			"seller":        gin.H{"@id": businessID()},
+		}
+	}
+	return product
+}
+
+func serviceJSONLD(c *gin.Context, item map[string]interface{}) gin.H {
+	url := siteURL() + sitePaths["service"] + stringValue(item["slug"])
+	service := gin.H{
+		"@type":       "Service",
+		"@id":         url + "#service",
+		"url":         url,
+		"name":        item["title"],
+		"serviceType": item["title"],
+		"description": item["descriptionExcerpt"],
+		"provider":    gin.H{"@id": businessID()},
+	}
+	if image := stringValue(item["image"]); image != "" {
+		service["image"] = absoluteURL(image)
+	}
+	if contact, err := getContact(); err == nil && contact.Address != "" {
+		service["areaServed"] = contact.Address
+	}
+	return service
+}
+
+func stringValue(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+// jsonLDItems yayındaki kayıtları çevrilmiş ve özetlenmiş olarak getirir
+func jsonLDItems(c *gin.Context, entityType string) ([]map[string]interface{}, error) {
+	items, err := publicItems(c, entityType)
+	if err != nil {
+		return nil, err
+	}
+	localize(c, entityType, items)
+	renderRichText(entityType, items)
+	return items, nil
+}
+
+func serveJSONLD(c *gin.Context, doc gin.H) {
+	doc["@context"] = schemaContext
+	if locale := c.Writer.Header().Get("Content-Language"); locale != "" {
+		doc["inLanguage"] = locale
+	}
+	c.Header("Content-Type", "application/ld+json; charset=utf-8")
+	c.JSON(http.StatusOK, doc)
+}
+
+// JSON-LD işlemleri
+func getBusinessJSONLDHandler(c *gin.Context) {
+	graph := []gin.H{businessJSONLD(c)}
+
+	products, err := jsonLDItems(c, "product")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, item := range products {
+		graph = append(graph, productJSONLD(c, item))
+	}
+
+	services, err := jsonLDItems(c, "service")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, item := range services {
+		graph = append(graph, serviceJSONLD(c, item))
+	}
+
+	serveJSONLD(c, gin.H{"@graph": graph})
+}
+
+// getItemJSONLDHandler ürün veya hizmet detay sayfası için tek belge döndürür
+func getItemJSONLDHandler(entityType string, build func(*gin.Context, map[string]interface{}) gin.H) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		items, err := jsonLDItems(c, entityType)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for _, item := range items {
+			if item["slug"] == c.Param("slug") {
+				serveJSONLD(c, build(c, item))
+				return
+			}
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kayıt bulunamadı"})
+	}
+}
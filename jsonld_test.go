@@ -0,0 +1,18 @@
+package main
+
+import "testing"
+
+func TestAbsoluteURL(t *testing.T) {
+	t.Setenv("SITE_URL", "https://klimakozan.com/")
+	tests := []struct{ in, want string }{
+		{"", ""},
+		{"/uploads/a.jpg", "https://klimakozan.com/uploads/a.jpg"},
+		{"uploads/a.jpg", "https://klimakozan.com/uploads/a.jpg"},
+		{"https://cdn.example.com/a.jpg", "https://cdn.example.com/a.jpg"},
+	}
+	for _, tt := range tests {
+		if got := absoluteURL(tt.in); got != tt.want {
+			t.Errorf("absoluteURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
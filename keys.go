@@ -0,0 +1,442 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Bilinen varsayılan / zayıf secret değerleri
+var weakSecrets = []string{
+	"your-secret-key-here",
+	"secret",
+	"changeme",
+	"jwt_secret",
+	"jwt-secret",
+	"password",
+}
+
+const minSecretLength = 32
+
+// signingKey anahtar dizinindeki tek bir anahtarı temsil eder.
+// private nil ise anahtar sadece doğrulama için kullanılır.
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+	created time.Time
+}
+
+// keyRing imzalama ve doğrulama anahtarlarını tutar
+type keyRing struct {
+	mu           sync.RWMutex
+	dir          string
+	keys         map[string]*signingKey
+	active       *signingKey
+	legacySecret []byte
+	legacyUntil  time.Time
+}
+
+// kid'in başındaki üretim zamanı biçimi (generateKey)
+const kidTimeLayout = "20060102T150405Z"
+
+var jwtKeys *keyRing
+
+func keyDir() string {
+	dir := os.Getenv("JWT_KEY_DIR")
+	if dir == "" {
+		dir = "keys"
+	}
+	return dir
+}
+
+func keyRetention() time.Duration {
+	if v := os.Getenv("JWT_KEY_RETENTION"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	// Token ömrünün iki katı
+	return 48 * time.Hour
+}
+
+// checkSecret zayıf veya varsayılan secret ile başlamayı engeller
+func checkSecret(secret string) error {
+	for _, weak := range weakSecrets {
+		if strings.EqualFold(secret, weak) {
+			return fmt.Errorf("JWT_SECRET varsayılan bir değer, lütfen değiştirin")
+		}
+	}
+	if len(secret) < minSecretLength {
+		return fmt.Errorf("JWT_SECRET en az %d karakter olmalı", minSecretLength)
+	}
+	return nil
+}
+
+// legacyCutoff eski HS256 token'ların kabul edileceği son zamanı okur
+// (JWT_LEGACY_UNTIL, RFC3339 veya YYYY-AA-GG)
+func legacyCutoff() (time.Time, error) {
+	v := os.Getenv("JWT_LEGACY_UNTIL")
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", v, time.UTC)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("JWT_LEGACY_UNTIL geçersiz: %q", v)
+	}
+	return t, nil
+}
+
+// initKeyRing anahtar dizinini yükler. Birden fazla sunucu aynı anahtarı
+// kullanmalı; bu yüzden dizin boşsa anahtar üretilmez, sunucu başlamaz.
+func initKeyRing() {
+	ring := &keyRing{dir: keyDir()}
+
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		if err := checkSecret(secret); err != nil {
+			log.Fatal(err)
+		}
+		until, err := legacyCutoff()
+		if err != nil {
+			log.Fatal(err)
+		}
+		// Eski HS256 token'lar sadece JWT_LEGACY_UNTIL'e kadar doğrulanır,
+		// yeni token bu secret ile imzalanmaz
+		if until.After(time.Now()) {
+			ring.legacySecret = []byte(secret)
+			ring.legacyUntil = until
+		} else {
+			log.Println("JWT_SECRET is set but JWT_LEGACY_UNTIL is unset or past; legacy HS256 tokens are rejected")
+		}
+	}
+
+	if err := ring.load(); err != nil {
+		log.Fatal(err)
+	}
+
+	if ring.active == nil {
+		log.Fatalf("no JWT signing key in %s; run `rotate-keys` once and share the directory with every server", ring.dir)
+	}
+
+	jwtKeys = ring
+}
+
+// kidTime generateKey'in kid başına yazdığı üretim zamanını okur.
+// Dosya zamanı kopyalama veya yedekten dönüşte değiştiği için kullanılmaz.
+func kidTime(kid string) (time.Time, bool) {
+	if len(kid) < len(kidTimeLayout) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(kidTimeLayout, kid[:len(kidTimeLayout)])
+	return t, err == nil
+}
+
+// newer a anahtarının b'den sonra üretildiğini bildirir; aynı saniyede
+// üretilen anahtarlar kid sırasıyla ayrılır
+func newer(a, b *signingKey) bool {
+	if !a.created.Equal(b.created) {
+		return a.created.After(b.created)
+	}
+	return a.kid > b.kid
+}
+
+// load dizindeki tüm *.pem dosyalarını okur. kid zamanına göre en yeni özel
+// anahtar aktif olur; kid'inde zaman olmayan anahtarlar en eski sayılır.
+func (k *keyRing) load() error {
+	files, err := filepath.Glob(filepath.Join(k.dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*signingKey)
+	var active *signingKey
+	for _, file := range files {
+		key, err := readKeyFile(file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		keys[key.kid] = key
+		if key.private != nil && (active == nil || newer(key, active)) {
+			active = key
+		}
+	}
+
+	if kid := os.Getenv("JWT_ACTIVE_KID"); kid != "" {
+		key, ok := keys[kid]
+		if !ok || key.private == nil {
+			return fmt.Errorf("JWT_ACTIVE_KID %q için özel anahtar bulunamadı", kid)
+		}
+		active = key
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.active = active
+	k.mu.Unlock()
+	return nil
+}
+
+func readKeyFile(file string) (*signingKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("geçersiz PEM")
+	}
+
+	key := &signingKey{kid: strings.TrimSuffix(strings.TrimSuffix(filepath.Base(file), ".pem"), ".pub")}
+	key.created, _ = kidTime(key.kid)
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("desteklenmeyen anahtar tipi")
+		}
+		key.private = signer
+		key.public = signer.Public()
+	case "RSA PRIVATE KEY":
+		parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key.private = parsed
+		key.public = parsed.Public()
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key.public = parsed
+	default:
+		return nil, fmt.Errorf("desteklenmeyen PEM tipi: %s", block.Type)
+	}
+
+	switch pub := key.public.(type) {
+	case ed25519.PublicKey:
+		key.method = jwt.SigningMethodEdDSA
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("RSA anahtarı en az 2048 bit olmalı")
+		}
+		key.method = jwt.SigningMethodRS256
+	default:
+		return nil, fmt.Errorf("desteklenmeyen anahtar tipi")
+	}
+
+	return key, nil
+}
+
+// generateKey dizine yeni bir Ed25519 anahtarı yazar ve kid değerini döndürür
+func generateKey(dir string) (string, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", err
+	}
+
+	// Aynı saniyedeki iki rotasyon çakışmasın diye zamana rastgele ek konur
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	kid := time.Now().UTC().Format(kidTimeLayout) + "-" + hex.EncodeToString(suffix)
+	file := filepath.Join(dir, kid+".pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	// Mevcut anahtar dosyası asla üzerine yazılmaz
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(file)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return kid, nil
+}
+
+// rotateKeys yeni anahtar üretir ve yerine yenisi geleli retention süresinden
+// fazla olmuş eski anahtarları siler
+func rotateKeys() {
+	dir := keyDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		log.Fatal(err)
+	}
+
+	kid, err := generateKey(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("New signing key: %s\n", kid)
+
+	ring := &keyRing{dir: dir}
+	if err := ring.load(); err != nil {
+		log.Fatal(err)
+	}
+
+	keys := make([]*signingKey, 0, len(ring.keys))
+	for _, key := range ring.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return newer(keys[j], keys[i]) })
+
+	retention := keyRetention()
+	for i := 0; i < len(keys)-1; i++ {
+		replacedAt := keys[i+1].created
+		if time.Since(replacedAt) > retention {
+			for _, ext := range []string{".pem", ".pub.pem"} {
+				os.Remove(filepath.Join(dir, keys[i].kid+ext))
+			}
+			fmt.Printf("Retired key: %s\n", keys[i].kid)
+		}
+	}
+	fmt.Println("Send SIGHUP to running servers to reload keys")
+}
+
+// signToken claim'leri aktif anahtar ile imzalar
+func signToken(claims jwt.MapClaims) (string, error) {
+	jwtKeys.mu.RLock()
+	active := jwtKeys.active
+	jwtKeys.mu.RUnlock()
+	if active == nil || active.private == nil {
+		return "", fmt.Errorf("aktif imzalama anahtarı yüklü değil")
+	}
+
+	token := jwt.NewWithClaims(active.method, claims)
+	token.Header["kid"] = active.kid
+	return token.SignedString(active.private)
+}
+
+// parseToken token'ı kid başlığına göre ilgili anahtarla doğrular
+func parseToken(tokenString string) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok && jwtKeys.legacySecret != nil && time.Now().Before(jwtKeys.legacyUntil) {
+				return jwtKeys.legacySecret, nil
+			}
+			return nil, fmt.Errorf("missing kid")
+		}
+
+		jwtKeys.mu.RLock()
+		key, ok := jwtKeys.keys[kid]
+		jwtKeys.mu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("unknown kid: %s", kid)
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key.public, nil
+	}, jwt.WithValidMethods([]string{"EdDSA", "RS256", "HS256"}))
+}
+
+func encodeSegment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// jwksHandler doğrulama anahtarlarını JWKS formatında yayınlar
+func jwksHandler(c *gin.Context) {
+	jwtKeys.mu.RLock()
+	defer jwtKeys.mu.RUnlock()
+
+	keys := make([]gin.H, 0, len(jwtKeys.keys))
+	for _, key := range jwtKeys.keys {
+		switch pub := key.public.(type) {
+		case ed25519.PublicKey:
+			keys = append(keys, gin.H{
+				"kty": "OKP",
+				"crv": "Ed25519",
+				"x":   encodeSegment(pub),
+				"kid": key.kid,
+				"alg": "EdDSA",
+				"use": "sig",
+			})
+		case *rsa.PublicKey:
+			keys = append(keys, gin.H{
+				"kty": "RSA",
+				"n":   encodeSegment(pub.N.Bytes()),
+				"e":   encodeSegment(big.NewInt(int64(pub.E)).Bytes()),
+				"kid": key.kid,
+				"alg": "RS256",
+				"use": "sig",
+			})
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i]["kid"].(string) < keys[j]["kid"].(string) })
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
+
+// reloadKeysOnSignal SIGHUP geldiğinde anahtar dizinini yeniden okur
+func reloadKeysOnSignal() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	for range sig {
+		if err := jwtKeys.load(); err != nil {
+			log.Printf("JWT key reload failed: %v", err)
+			continue
+		}
+		log.Println("JWT keys reloaded")
+	}
+}
+
+// signPurposeToken oturum dışı amaçlar (state çerezi, önizleme linki vb.) için
+// kısa ömürlü token üretir. Bu token'lar authMiddleware tarafından kabul edilmez.
+func signPurposeToken(purpose string, claims jwt.MapClaims, ttl time.Duration) (string, error) {
+	claims["purpose"] = purpose
+	claims["exp"] = time.Now().Add(ttl).Unix()
+	return signToken(claims)
+}
+
+// parsePurposeToken token'ı doğrular ve amacının beklenen amaç olduğunu kontrol eder
+func parsePurposeToken(purpose, tokenString string) (jwt.MapClaims, error) {
+	token, err := parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid || claims["purpose"] != purpose {
+		return nil, fmt.Errorf("invalid token purpose")
+	}
+	return claims, nil
+}
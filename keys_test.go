@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestGenerateKeyUniqueKid(t *testing.T) {
+	dir := t.TempDir()
+	seen := map[string]bool{}
+	for i := 0; i < 5; i++ {
+		kid, err := generateKey(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[kid] {
+			t.Fatalf("duplicate kid %s", kid)
+		}
+		seen[kid] = true
+		if _, err := os.Stat(filepath.Join(dir, kid+".pem")); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSignTokenWithoutActiveKey(t *testing.T) {
+	saved := jwtKeys
+	jwtKeys = &keyRing{keys: map[string]*signingKey{}}
+	defer func() { jwtKeys = saved }()
+
+	if _, err := signToken(jwt.MapClaims{"sub": "1"}); err == nil {
+		t.Fatal("expected error without an active key")
+	}
+}
+
+func TestLoadActiveKeyByKidTime(t *testing.T) {
+	dir := t.TempDir()
+	newest := "20300101T000000Z-00000000"
+	oldest := "20200101T000000Z-00000000"
+	for _, kid := range []string{newest, oldest} {
+		generated, err := generateKey(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(filepath.Join(dir, generated+".pem"), filepath.Join(dir, kid+".pem")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Yedekten dönen eski anahtarın dosya zamanı daha yeni olabilir
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, oldest+".pem"), future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	ring := &keyRing{dir: dir}
+	if err := ring.load(); err != nil {
+		t.Fatal(err)
+	}
+	if ring.active == nil || ring.active.kid != newest {
+		t.Fatalf("active = %v, want %s", ring.active, newest)
+	}
+}
+
+func TestLegacyTokenCutoff(t *testing.T) {
+	saved := jwtKeys
+	defer func() { jwtKeys = saved }()
+
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "1"}).SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		until time.Time
+		ok    bool
+	}{
+		{"süre içinde", time.Now().Add(time.Hour), true},
+		{"süre dolmuş", time.Now().Add(-time.Hour), false},
+		{"süre verilmemiş", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		jwtKeys = &keyRing{keys: map[string]*signingKey{}, legacySecret: secret, legacyUntil: tt.until}
+		if _, err := parseToken(legacy); (err == nil) != tt.ok {
+			t.Errorf("%s: parseToken error = %v, want ok = %v", tt.name, err, tt.ok)
+		}
+	}
+}
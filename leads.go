@@ -0,0 +1,525 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"kozan/db"
+	"kozan/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// İletişim formu ve gelen talepler. Spam koruması katmanlıdır: gizli alan
+// (honeypot), form token'ı ile doldurma süresi, IP başına limit ve
+// isteğe bağlı CAPTCHA.
+const (
+	formTokenTTL       = 2 * time.Hour
+	formMinFillTime    = 3 * time.Second
+	contactRateLimit   = 5
+	contactRateWindow  = 10 * time.Minute
+	maxContactMessage  = 5000
+	minContactMessage  = 10
+	contactFormPurpose = "contact_form"
+)
+
+// getContactFormTokenHandler form açılırken alınır; gönderimde süre kontrolü için kullanılır
+func getContactFormTokenHandler(c *gin.Context) {
+	token, err := signPurposeToken(contactFormPurpose, jwt.MapClaims{"iat": time.Now().Unix()}, formTokenTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Token oluşturulamadı"})
+		return
+	}
+	consents, err := activeConsentTexts(formConsentPurposes[contactFormPurpose])
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"form_token": token, "captcha": captcha != nil, "consents": consents})
+}
+
+type contactMessageInput struct {
+	Name         string         `json:"name"`
+	Email        string         `json:"email"`
+	Phone        string         `json:"phone"`
+	Subject      string         `json:"subject"`
+	Message      string         `json:"message"`
+	FormToken    string         `json:"form_token"`
+	CaptchaToken string         `json:"captcha_token"`
+	Consents     []consentInput `json:"consents"`
+	// Website gizli alandır; sadece botlar doldurur
+	Website string `json:"website"`
+}
+
+// validate alanları kontrol eder ve telefonu E.164'e çevirir
+func (in *contactMessageInput) validate() error {
+	in.Name = strings.TrimSpace(in.Name)
+	in.Email = strings.TrimSpace(in.Email)
+	in.Subject = strings.TrimSpace(in.Subject)
+	in.Message = strings.TrimSpace(in.Message)
+
+	if in.Name == "" || utf8.RuneCountInString(in.Name) > 100 {
+		return fmt.Errorf("Ad soyad zorunludur (en fazla 100 karakter)")
+	}
+	if in.Email == "" && in.Phone == "" {
+		return fmt.Errorf("E-posta veya telefon numarasından biri zorunludur")
+	}
+	if in.Email != "" {
+		addr, err := mail.ParseAddress(in.Email)
+		if err != nil || addr.Address != in.Email {
+			return fmt.Errorf("Geçersiz e-posta adresi")
+		}
+	}
+	if in.Phone != "" {
+		phone, err := normalizePhone(in.Phone)
+		if err != nil {
+			return fmt.Errorf("Geçersiz telefon numarası")
+		}
+		in.Phone = phone
+	}
+	if utf8.RuneCountInString(in.Subject) > 200 {
+		return fmt.Errorf("Konu en fazla 200 karakter olabilir")
+	}
+	if n := utf8.RuneCountInString(in.Message); n < minContactMessage || n > maxContactMessage {
+		return fmt.Errorf("Mesaj %d ile %d karakter arasında olmalı", minContactMessage, maxContactMessage)
+	}
+	return nil
+}
+
+// createContactMessageHandler public iletişim formu
+func createContactMessageHandler(c *gin.Context) {
+	var input contactMessageInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims, err := parsePurposeToken(contactFormPurpose, input.FormToken)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Formun süresi doldu, lütfen sayfayı yenileyin"})
+		return
+	}
+
+	// Botlara başarılı yanıt dönülür ama mesaj kaydedilmez
+	issuedAt, _ := claims["iat"].(float64)
+	tooFast := time.Since(time.Unix(int64(issuedAt), 0)) < formMinFillTime
+	if input.Website != "" || tooFast {
+		log.Printf("contact form: dropped spam from %s (honeypot=%t, too_fast=%t)", c.ClientIP(), input.Website != "", tooFast)
+		c.JSON(http.StatusCreated, gin.H{"message": "Mesajınız alındı"})
+		return
+	}
+
+	if err := input.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// ClientIP, X-Forwarded-For başlığını sadece TRUSTED_PROXIES'ten gelen
+	// isteklerde dikkate alır; sahte başlıkla limit aşılamaz
+	ip := c.ClientIP()
+
+	if err := checkFormConsents(contactFormPurpose, input.Consents); err == errConsentOutdated {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if captcha != nil {
+		if err := captcha.Verify(c.Request.Context(), input.CaptchaToken, ip); err != nil {
+			log.Printf("contact form: captcha failed for %s: %v", ip, err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Doğrulama başarısız, lütfen tekrar deneyin"})
+			return
+		}
+	}
+
+	msg := models.ContactMessage{
+		Name:      input.Name,
+		Email:     input.Email,
+		Phone:     input.Phone,
+		Subject:   input.Subject,
+		Message:   input.Message,
+		IP:        ip,
+		UserAgent: c.Request.UserAgent(),
+	}
+	tx, err := db.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	// Aynı IP'den eşzamanlı gönderimler sayım ve kayıt boyunca sıraya girer
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext('contact_rate:' || $1))", ip); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var recent int
+	err = tx.QueryRow(
+		"SELECT COUNT(*) FROM contact_messages WHERE ip = $1 AND created_at > $2",
+		ip, time.Now().Add(-contactRateWindow),
+	).Scan(&recent)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if recent >= contactRateLimit {
+		c.Header("Retry-After", strconv.Itoa(int(contactRateWindow.Seconds())))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Çok fazla mesaj gönderdiniz, lütfen daha sonra tekrar deneyin"})
+		return
+	}
+
+	err = tx.QueryRow(
+		`INSERT INTO contact_messages (name, email, phone, subject, message, ip, user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`,
+		msg.Name, msg.Email, msg.Phone, msg.Subject, msg.Message, msg.IP, msg.UserAgent,
+	).Scan(&msg.ID, &msg.CreatedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := recordConsents(tx, msg.Email, msg.Phone, contactFormPurpose, msg.ID, ip, msg.UserAgent, input.Consents); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := enqueueJob(tx, jobLeadNotify, leadNotifyPayload{MessageID: msg.ID}, jobOptions{}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// IP ve tarayıcı bilgisi dış sistemlere gönderilmez
+	lead := gin.H{
+		"id": msg.ID, "name": msg.Name, "email": msg.Email, "phone": msg.Phone,
+		"subject": msg.Subject, "message": msg.Message, "created_at": msg.CreatedAt,
+	}
+	emitEvent(eventLeadCreated, lead)
+	publishAdminEvent(c, eventLeadCreated, "contact_message", msg.ID, lead)
+	c.JSON(http.StatusCreated, gin.H{"message": "Mesajınız alındı"})
+}
+
+type leadNotifyPayload struct {
+	MessageID int `json:"message_id"`
+}
+
+// leadNotifyJob yeni talebi iletişim kaydındaki e-posta adresine bildirir
+func leadNotifyJob(ctx context.Context, job models.Job, payload leadNotifyPayload) error {
+	msg, err := getContactMessage(payload.MessageID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	contact, err := getContact()
+	if err != nil || contact.Email == "" {
+		return nil
+	}
+
+	vars := map[string]string{
+		"name":       msg.Name,
+		"email":      msg.Email,
+		"phone":      msg.Phone,
+		"subject":    msg.Subject,
+		"message":    msg.Message,
+		"message_id": strconv.Itoa(msg.ID),
+	}
+	return notifyAddress(channelEmail, contact.Email, templateNewLead, vars)
+}
+
+const contactMessageColumns = "id, name, email, phone, subject, message, ip, user_agent, is_read, assigned_to, work_order_id, created_at"
+
+func scanContactMessage(scanner interface{ Scan(...interface{}) error }) (models.ContactMessage, error) {
+	var msg models.ContactMessage
+	var assignedTo, workOrderID sql.NullInt64
+	err := scanner.Scan(&msg.ID, &msg.Name, &msg.Email, &msg.Phone, &msg.Subject, &msg.Message, &msg.IP, &msg.UserAgent,
+		&msg.Read, &assignedTo, &workOrderID, &msg.CreatedAt)
+	if assignedTo.Valid {
+		v := int(assignedTo.Int64)
+		msg.AssignedTo = &v
+	}
+	if workOrderID.Valid {
+		v := int(workOrderID.Int64)
+		msg.WorkOrderID = &v
+	}
+	return msg, err
+}
+
+func getContactMessage(id int) (models.ContactMessage, error) {
+	return scanContactMessage(db.DB.QueryRow("SELECT "+contactMessageColumns+" FROM contact_messages WHERE id = $1", id))
+}
+
+func getLeadNotes(messageID int) ([]models.LeadNote, error) {
+	rows, err := db.DB.Query(
+		"SELECT id, author_id, author_username, note, created_at FROM contact_message_notes WHERE message_id = $1 ORDER BY id",
+		messageID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []models.LeadNote{}
+	for rows.Next() {
+		var n models.LeadNote
+		if err := rows.Scan(&n.ID, &n.AuthorID, &n.AuthorUsername, &n.Note, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		notes = append(notes, n)
+	}
+	return notes, rows.Err()
+}
+
+// Gelen talep işlemleri
+func getContactMessagesHandler(c *gin.Context) {
+	query := "SELECT " + contactMessageColumns + " FROM contact_messages WHERE 1=1"
+	var args []interface{}
+	if v := c.Query("read"); v != "" {
+		args = append(args, v == "true")
+		query += fmt.Sprintf(" AND is_read = $%d", len(args))
+	}
+	if v := c.Query("assigned_to"); v != "" {
+		args = append(args, v)
+		query += fmt.Sprintf(" AND assigned_to = $%d", len(args))
+	}
+	if c.Query("converted") == "false" {
+		query += " AND work_order_id IS NULL"
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT %d OFFSET %d", limit, offset)
+
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	messages := []models.ContactMessage{}
+	for rows.Next() {
+		msg, err := scanContactMessage(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		messages = append(messages, msg)
+	}
+
+	var unread int
+	db.DB.QueryRow("SELECT COUNT(*) FROM contact_messages WHERE NOT is_read").Scan(&unread)
+	c.Header("X-Unread-Count", strconv.Itoa(unread))
+	c.JSON(http.StatusOK, messages)
+}
+
+func getContactMessageHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	msg, err := getContactMessage(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Mesaj bulunamadı"})
+		return
+	}
+	if msg.Notes, err = getLeadNotes(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, msg)
+}
+
+// updateContactMessageHandler okundu durumunu ve atamayı değiştirir.
+// assigned_to 0 gönderilirse atama kaldırılır.
+func updateContactMessageHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var input struct {
+		Read       *bool `json:"read"`
+		AssignedTo *int  `json:"assigned_to"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before, err := getContactMessage(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Mesaj bulunamadı"})
+		return
+	}
+
+	msg := before
+	if input.Read != nil {
+		msg.Read = *input.Read
+	}
+	if input.AssignedTo != nil {
+		if *input.AssignedTo == 0 {
+			msg.AssignedTo = nil
+		} else {
+			var exists bool
+			db.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)", *input.AssignedTo).Scan(&exists)
+			if !exists {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Kullanıcı bulunamadı"})
+				return
+			}
+			msg.AssignedTo = input.AssignedTo
+		}
+	}
+
+	if _, err := db.DB.Exec("UPDATE contact_messages SET is_read = $1, assigned_to = $2 WHERE id = $3", msg.Read, msg.AssignedTo, id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, "contact_message", id, auditUpdate, before, msg)
+	c.JSON(http.StatusOK, msg)
+}
+
+func addLeadNoteHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var input struct {
+		Note string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || strings.TrimSpace(input.Note) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Not boş olamaz"})
+		return
+	}
+	if _, err := getContactMessage(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Mesaj bulunamadı"})
+		return
+	}
+
+	note := models.LeadNote{
+		AuthorID:       currentUserID(c),
+		AuthorUsername: c.GetString("username"),
+		Note:           strings.TrimSpace(input.Note),
+	}
+	err = db.DB.QueryRow(
+		"INSERT INTO contact_message_notes (message_id, author_id, author_username, note) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+		id, note.AuthorID, note.AuthorUsername, note.Note,
+	).Scan(&note.ID, &note.CreatedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, "contact_message_note", note.ID, auditCreate, nil, note)
+	c.JSON(http.StatusCreated, note)
+}
+
+// convertLeadHandler talepten müşteri ve iş emri oluşturur
+func convertLeadHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var input struct {
+		Title              string `json:"title"`
+		Address            string `json:"address"`
+		OutsideServiceArea bool   `json:"outside_service_area"`
+	}
+	// Gövde isteğe bağlıdır; gönderildiyse geçerli olmalı
+	if err := c.ShouldBindJSON(&input); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	// Aynı talebin iki kez dönüştürülmemesi için satır kilitlenir
+	msg, err := scanContactMessage(tx.QueryRow("SELECT "+contactMessageColumns+" FROM contact_messages WHERE id = $1 FOR UPDATE", id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Mesaj bulunamadı"})
+		return
+	}
+	if msg.WorkOrderID != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Bu mesaj zaten iş emrine dönüştürülmüş", "work_order_id": *msg.WorkOrderID})
+		return
+	}
+
+	customerID, err := findOrCreateCustomer(tx, models.Customer{Name: msg.Name, Phone: msg.Phone, Email: msg.Email, Address: input.Address})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	title := input.Title
+	if title == "" {
+		title = msg.Subject
+	}
+	if title == "" {
+		title = "İletişim formu talebi #" + strconv.Itoa(msg.ID)
+	}
+	wo := models.WorkOrder{
+		CustomerID:      customerID,
+		Title:           title,
+		Description:     msg.Message,
+		Address:         input.Address,
+		SourceMessageID: &msg.ID,
+		CreatedBy:       currentUserID(c),
+	}
+	if err := applyServiceZone(&wo); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := checkServiceArea(wo, input.OutsideServiceArea); err != nil {
+		respondZoneError(c, err)
+		return
+	}
+	if err := insertWorkOrder(tx, &wo); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := tx.Exec("UPDATE contact_messages SET work_order_id = $1, is_read = TRUE WHERE id = $2", wo.ID, id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, "work_order", wo.ID, auditCreate, nil, wo)
+	emitEvent(eventWorkOrderCreated, wo)
+	c.JSON(http.StatusCreated, wo)
+}
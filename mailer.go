@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// sendMail düz metin e-postayı SMTP_* ayarlarıyla gönderir. Doğrudan
+// çağrılmaz; e-posta kanalı (smtpNotifier) üzerinden kullanılır.
+func sendMail(to, subject, body string) error {
+	host := os.Getenv("SMTP_HOST")
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		from = os.Getenv("SMTP_USER")
+	}
+
+	var auth smtp.Auth
+	if user := os.Getenv("SMTP_USER"); user != "" {
+		auth = smtp.PlainAuth("", user, os.Getenv("SMTP_PASSWORD"), host)
+	}
+
+	headers := []string{
+		"From: " + from,
+		"To: " + to,
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Transfer-Encoding: 8bit",
+	}
+	msg := strings.Join(headers, "\r\n") + "\r\n\r\n" + strings.ReplaceAll(body, "\n", "\r\n")
+
+	if err := smtp.SendMail(host+":"+port, auth, from, []string{to}, []byte(msg)); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	return nil
+}
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -26,12 +30,69 @@ func main() {
 		log.Fatal("Error loading .env file")
 	}
 
+	// Subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "rotate-keys":
+			rotateKeys()
+			return
+		case "worker":
+			runWorker()
+			return
+		default:
+			log.Fatalf("Unknown command: %s", os.Args[1])
+		}
+	}
+
+	// Load JWT signing keys
+	initKeyRing()
+	go reloadKeysOnSignal()
+
+	// Optional OIDC single sign-on
+	initOIDC()
+
+	// Optional CAPTCHA for public forms
+	initCaptcha()
+	initSubjectHashKey()
+
+	// Notification channels (email, SMS, WhatsApp)
+	initNotifiers()
+
 	// Initialize database
 	db.InitDB()
 
+	// Slugs for records created before the SEO fields
+	if err := backfillSlugs(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Opening hours from the legacy contact fields
+	if err := seedOpeningHours(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Default notification templates
+	if err := seedNotificationTemplates(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Background jobs (JOB_WORKER=off when a separate worker process runs them)
+	registerJobs()
+	if jobWorkerInProcess() {
+		startJobWorker(context.Background())
+	}
+
+	// Live admin events across instances (LISTEN/NOTIFY)
+	startEventHub()
+
 	// Initialize Gin
 	r := gin.Default()
 
+	// X-Forwarded-For sadece güvenilen proxy'lerden kabul edilir
+	if err := r.SetTrustedProxies(trustedProxies()); err != nil {
+		log.Fatal(err)
+	}
+
 	// Static file server
 	r.Static("/uploads", "./uploads")
 	if err := os.MkdirAll("uploads", 0755); err != nil {
@@ -42,8 +103,8 @@ func main() {
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173", "http://admin.localhost:5173"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Accept-Language", "Authorization", "X-API-Key", "X-Preview-Token", "Last-Event-ID", "X-Device-ID"},
+		ExposeHeaders:    []string{"Content-Length", "Content-Language", "X-Content-Direction"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -55,53 +116,237 @@ func main() {
 
 	// Public routes
 	r.POST("/api/auth/login", loginHandler)
+	r.GET("/.well-known/jwks.json", jwksHandler)
+	r.GET("/sitemap.xml", sitemapHandler)
+	r.GET("/robots.txt", robotsHandler)
+	if oidc != nil {
+		r.GET("/api/auth/oidc/login", oidcLoginHandler)
+		r.GET("/api/auth/oidc/callback", oidcCallbackHandler)
+	}
+
+	// Admin live events; EventSource cannot send headers so ?ticket= is also accepted
+	r.GET("/api/admin/events", eventsAuthMiddleware(), streamEventsHandler)
 
 	// Admin routes (protected)
 	admin := r.Group("/api/admin")
 	admin.Use(authMiddleware())
 	{
+		if oidc != nil {
+			admin.POST("/profile/oidc-link", oidcLinkHandler)
+		}
+
 		// Products
-		admin.GET("/products", getProductsHandler)
-		admin.POST("/products", createProductHandler)
-		admin.PUT("/products/:id", updateProductHandler)
-		admin.DELETE("/products/:id", deleteProductHandler)
+		admin.GET("/products", requirePermission(permProductsRead), getProductsHandler)
+		admin.POST("/products", requirePermission(permProductsWrite), createProductHandler)
+		admin.PUT("/products/:id", requirePermission(permProductsWrite), updateProductHandler)
+		admin.DELETE("/products/:id", requirePermission(permProductsWrite), deleteProductHandler)
 
 		// Services
-		admin.GET("/services", getServicesHandler)
-		admin.POST("/services", createServiceHandler)
-		admin.PUT("/services/:id", updateServiceHandler)
-		admin.DELETE("/services/:id", deleteServiceHandler)
+		admin.GET("/services", requirePermission(permServicesRead), getServicesHandler)
+		admin.POST("/services", requirePermission(permServicesWrite), createServiceHandler)
+		admin.PUT("/services/:id", requirePermission(permServicesWrite), updateServiceHandler)
+		admin.DELETE("/services/:id", requirePermission(permServicesWrite), deleteServiceHandler)
 
 		// About
-		admin.GET("/about", getAboutHandler)
-		admin.PUT("/about", updateAboutHandler)
+		admin.GET("/about", requirePermission(permContentRead), getAboutHandler)
+		admin.PUT("/about", requirePermission(permContentWrite), updateAboutHandler)
 
 		// Contact
-		admin.GET("/contact", getContactHandler)
-		admin.PUT("/contact", updateContactHandler)
+		admin.GET("/contact", requirePermission(permContentRead), getContactHandler)
+		admin.PUT("/contact", requirePermission(permContentWrite), updateContactHandler)
 
 		// Hero
-		admin.GET("/hero", getHeroHandler)
-		admin.PUT("/hero", updateHeroHandler)
+		admin.GET("/hero", requirePermission(permContentRead), getHeroHandler)
+		admin.PUT("/hero", requirePermission(permContentWrite), updateHeroHandler)
 
 		// Footer
-		admin.GET("/footer", getFooterHandler)
-		admin.PUT("/footer", updateFooterHandler)
+		admin.GET("/footer", requirePermission(permContentRead), getFooterHandler)
+		admin.PUT("/footer", requirePermission(permContentWrite), updateFooterHandler)
+
+		// Opening hours
+		admin.GET("/opening-hours", requirePermission(permContentRead), getOpeningHoursHandler)
+		admin.PUT("/opening-hours", requirePermission(permContentWrite), updateWeeklyHoursHandler)
+		admin.POST("/opening-hours/exceptions", requirePermission(permContentWrite), createOpeningExceptionHandler)
+		admin.PUT("/opening-hours/exceptions/:id", requirePermission(permContentWrite), updateOpeningExceptionHandler)
+		admin.DELETE("/opening-hours/exceptions/:id", requirePermission(permContentWrite), deleteOpeningExceptionHandler)
+
+		// Lead inbox
+		admin.GET("/messages", requirePermission(permLeadsRead), getContactMessagesHandler)
+		admin.GET("/messages/:id", requirePermission(permLeadsRead), getContactMessageHandler)
+		admin.PATCH("/messages/:id", requirePermission(permLeadsWrite), updateContactMessageHandler)
+		admin.POST("/messages/:id/notes", requirePermission(permLeadsWrite), addLeadNoteHandler)
+		admin.POST("/messages/:id/convert", requirePermission(permLeadsWrite), requirePermission(permWorkOrdersWrite), convertLeadHandler)
+
+		// Work orders
+		admin.GET("/work-orders", requirePermission(permWorkOrdersRead), getWorkOrdersHandler)
+		admin.GET("/work-orders/:id", requirePermission(permWorkOrdersRead), getWorkOrderHandler)
+		admin.POST("/work-orders", requirePermission(permWorkOrdersWrite), createWorkOrderHandler)
+		admin.PUT("/work-orders/:id", requirePermission(permWorkOrdersWrite), updateWorkOrderHandler)
+		admin.POST("/work-orders/:id/notify", requirePermission(permWorkOrdersWrite), requirePermission(permNotifyWrite), notifyWorkOrderHandler)
+		admin.POST("/work-orders/:id/start-travel", requirePermission(permWorkOrdersWrite), startTravelHandler)
+		admin.POST("/work-orders/:id/location", requirePermission(permWorkOrdersWrite), updateTravelLocationHandler)
+		admin.POST("/work-orders/:id/tracking-link", requirePermission(permWorkOrdersWrite), rotateTrackingLinkHandler)
+		admin.GET("/work-orders/:id/invoice", requirePermission(permWorkOrdersRead), getInvoiceHandler)
+		admin.PUT("/work-orders/:id/invoice", requirePermission(permWorkOrdersWrite), uploadInvoiceHandler)
+		admin.GET("/work-orders/:id/field-data", requirePermission(permWorkOrdersRead), getWorkOrderFieldDataHandler)
+		admin.GET("/work-orders/:id/photos/:photoId", requirePermission(permWorkOrdersRead), getWorkOrderPhotoHandler)
+		admin.GET("/work-orders/:id/report", requirePermission(permWorkOrdersRead), getWorkOrderReportHandler)
+
+		// Quotes
+		admin.GET("/quotes", requirePermission(permWorkOrdersRead), getQuotesHandler)
+		admin.GET("/quotes/:id", requirePermission(permWorkOrdersRead), getQuoteHandler)
+		admin.POST("/quotes", requirePermission(permWorkOrdersWrite), createQuoteHandler)
+		admin.PUT("/quotes/:id", requirePermission(permWorkOrdersWrite), updateQuoteHandler)
+		admin.DELETE("/quotes/:id", requirePermission(permWorkOrdersWrite), deleteQuoteHandler)
+		admin.POST("/quotes/:id/work-order", requirePermission(permWorkOrdersWrite), convertQuoteHandler)
+
+		// Service zones
+		admin.GET("/service-zones", requirePermission(permServiceArea), getServiceZonesHandler)
+		admin.POST("/service-zones", requirePermission(permServiceArea), createServiceZoneHandler)
+		admin.PUT("/service-zones/:id", requirePermission(permServiceArea), updateServiceZoneHandler)
+		admin.DELETE("/service-zones/:id", requirePermission(permServiceArea), deleteServiceZoneHandler)
+
+		// Route planning
+		admin.GET("/routes", requirePermission(permWorkOrdersRead), getRoutePlansHandler)
+		admin.GET("/routes/:id", requirePermission(permWorkOrdersRead), getRoutePlanHandler)
+		admin.POST("/routes/optimize", requirePermission(permWorkOrdersWrite), optimizeRoutesHandler)
+		admin.PUT("/routes/:id/order", requirePermission(permWorkOrdersWrite), reorderRoutePlanHandler)
+
+		// Spare parts inventory
+		admin.GET("/parts", requirePermission(permInventoryRead), getPartsHandler)
+		admin.GET("/parts/:id", requirePermission(permInventoryRead), getPartHandler)
+		admin.POST("/parts", requirePermission(permInventoryWrite), createPartHandler)
+		admin.PUT("/parts/:id", requirePermission(permInventoryWrite), updatePartHandler)
+		admin.GET("/stock-locations", requirePermission(permInventoryRead), getStockLocationsHandler)
+		admin.POST("/stock-locations", requirePermission(permInventoryWrite), createStockLocationHandler)
+		admin.PUT("/stock-locations/:id", requirePermission(permInventoryWrite), updateStockLocationHandler)
+		admin.GET("/stock-movements", requirePermission(permInventoryRead), getStockMovementsHandler)
+		admin.POST("/stock-movements", requirePermission(permInventoryWrite), createStockMovementHandler)
+		admin.GET("/stock", requirePermission(permInventoryRead), getStockHandler)
+		admin.GET("/stock/low", requirePermission(permInventoryRead), getLowStockHandler)
+		admin.GET("/stock/valuation", requirePermission(permInventoryRead), getStockValuationHandler)
+
+		// Notifications
+		admin.GET("/notifications", requirePermission(permNotifyRead), getNotificationsHandler)
+		admin.GET("/notification-templates", requirePermission(permNotifyRead), getNotificationTemplatesHandler)
+		admin.PUT("/notification-templates/:key/:channel", requirePermission(permNotifyWrite), updateNotificationTemplateHandler)
+		admin.GET("/customers/:id/notification-preferences", requirePermission(permWorkOrdersRead), getNotificationPreferencesHandler)
+		admin.PUT("/customers/:id/notification-preferences", requirePermission(permWorkOrdersWrite), updateNotificationPreferencesHandler)
+
+		// Background jobs
+		admin.GET("/jobs", requirePermission(permJobsManage), getJobsHandler)
+		admin.GET("/jobs/stats", requirePermission(permJobsManage), getJobStatsHandler)
+		admin.GET("/jobs/:id", requirePermission(permJobsManage), getJobHandler)
+		admin.POST("/jobs/:id/retry", requirePermission(permJobsManage), retryJobHandler)
+
+		admin.POST("/events/ticket", createEventsTicketHandler)
+
+		// Outbound webhooks
+		admin.GET("/webhooks", requirePermission(permWebhooksManage), getWebhooksHandler)
+		admin.POST("/webhooks", requirePermission(permWebhooksManage), createWebhookHandler)
+		admin.PUT("/webhooks/:id", requirePermission(permWebhooksManage), updateWebhookHandler)
+		admin.DELETE("/webhooks/:id", requirePermission(permWebhooksManage), deleteWebhookHandler)
+		admin.POST("/webhooks/:id/test", requirePermission(permWebhooksManage), testWebhookHandler)
+		admin.GET("/webhooks/:id/deliveries", requirePermission(permWebhooksManage), getWebhookDeliveriesHandler)
+		admin.POST("/webhooks/:id/deliveries/:deliveryId/redeliver", requirePermission(permWebhooksManage), redeliverWebhookHandler)
+
+		// KVKK
+		admin.GET("/consent-texts", requirePermission(permPrivacyManage), getConsentTextsHandler)
+		admin.POST("/consent-texts", requirePermission(permPrivacyManage), createConsentTextHandler)
+		admin.GET("/consents", requirePermission(permPrivacyManage), getConsentsHandler)
+		admin.GET("/data-subjects/export", requirePermission(permPrivacyManage), exportDataSubjectHandler)
+		admin.POST("/data-subjects/anonymize", requirePermission(permPrivacyManage), anonymizeDataSubjectHandler)
+		admin.GET("/data-subjects/requests", requirePermission(permPrivacyManage), getDataSubjectRequestsHandler)
 
 		// Users routes
-		admin.GET("/users", getUsersHandler)
-		admin.POST("/users", createUserHandler)
-		admin.PUT("/users/:id", updateUserHandler)
-		admin.DELETE("/users/:id", deleteUserHandler)
+		admin.GET("/users", requirePermission(permUsersRead), getUsersHandler)
+		admin.POST("/users", requirePermission(permUsersWrite), createUserHandler)
+		admin.PUT("/users/:id", requirePermission(permUsersWrite), updateUserHandler)
+		admin.DELETE("/users/:id", requirePermission(permUsersWrite), deleteUserHandler)
+
+		// API keys
+		admin.GET("/api-keys", getAPIKeysHandler)
+		admin.POST("/api-keys", createAPIKeyHandler)
+		admin.DELETE("/api-keys/:id", revokeAPIKeyHandler)
+
+		// Revisions
+		admin.GET("/revisions/:entity/:id", getRevisionsHandler)
+		admin.GET("/revisions/:entity/:id/diff", diffRevisionsHandler)
+		admin.POST("/revisions/:entity/:id/:revision/restore", restoreRevisionHandler)
+
+		// Campaigns
+		admin.GET("/campaigns", requirePermission(permContentRead), getCampaignsHandler)
+		admin.POST("/campaigns", requirePermission(permContentWrite), createCampaignHandler)
+		admin.PUT("/campaigns/:id", requirePermission(permContentWrite), updateCampaignHandler)
+		admin.DELETE("/campaigns/:id", requirePermission(permContentWrite), deleteCampaignHandler)
+
+		// Publishing
+		admin.GET("/publish", requirePermission(permContentRead), publishStatusHandler)
+		admin.POST("/publish/:entity/:id", requirePermission(permContentPublish), publishHandler)
+		admin.DELETE("/publish/:entity/:id", requirePermission(permContentPublish), unpublishHandler)
+		admin.POST("/preview-token/:entity/:id", createPreviewTokenHandler)
+		admin.DELETE("/preview-token/:entity/:id", revokePreviewTokensHandler)
+		admin.PUT("/schedule/:entity/:id", requirePermission(permContentPublish), setScheduleHandler)
+		admin.GET("/schedule", requirePermission(permContentRead), scheduleTimelineHandler)
+
+		// Translations
+		admin.GET("/translations/missing", requirePermission(permContentRead), missingTranslationsHandler)
+		admin.GET("/translations/:entity/:id", getTranslationsHandler)
+		admin.PUT("/translations/:entity/:id/:locale", updateTranslationsHandler)
+
+		// Audit log
+		admin.GET("/audit", requirePermission(permAuditRead), getAuditLogHandler)
+	}
+
+	// Technician mobile API
+	tech := r.Group("/api/tech")
+	tech.Use(authMiddleware(), requirePermission(permTechnicianJobs))
+	{
+		tech.GET("/jobs", getTechnicianJobsHandler)
+		tech.GET("/jobs/:id", getTechnicianJobHandler)
+		tech.POST("/jobs/:id/start-travel", jobTransitionHandler(startTravel))
+		tech.POST("/jobs/:id/location", jobTransitionHandler(updateJobLocation))
+		tech.POST("/jobs/:id/arrive", jobTransitionHandler(arriveAtJob))
+		tech.POST("/jobs/:id/finish", jobTransitionHandler(finishJob))
+		tech.POST("/jobs/:id/photos", uploadJobPhotoHandler)
+		tech.GET("/jobs/:id/photos/:photoId", getJobPhotoHandler)
+		tech.DELETE("/jobs/:id/photos/:photoId", deleteJobPhotoHandler)
+		tech.POST("/jobs/:id/parts", addJobPartHandler)
+		tech.DELETE("/jobs/:id/parts/:partId", deleteJobPartHandler)
+		tech.POST("/jobs/:id/checklist", addChecklistItemHandler)
+		tech.PATCH("/jobs/:id/checklist/:itemId", updateChecklistItemHandler)
+		tech.PUT("/jobs/:id/signature", saveSignatureHandler)
+		tech.GET("/jobs/:id/report", getJobReportHandler)
+		tech.GET("/route", getTechnicianRouteHandler)
+		tech.GET("/stock", getTechnicianStockHandler)
+		tech.GET("/sync", pullSyncHandler)
+		tech.POST("/sync", syncHandler)
 	}
 
 	// Public API routes
 	api := r.Group("/api")
 	{
-		api.GET("/products", getProductsHandler)
-		api.GET("/services", getServicesHandler)
-		api.GET("/about", getAboutHandler)
-		api.GET("/contact", getContactHandler)
+		api.GET("/products", getPublicProductsHandler)
+		api.GET("/products/:slug", getPublicProductHandler)
+		api.GET("/services", getPublicServicesHandler)
+		api.GET("/services/:slug", getPublicServiceHandler)
+		api.GET("/about", getPublicAboutHandler)
+		api.GET("/contact", getPublicContactHandler)
+		api.GET("/contact/form-token", getContactFormTokenHandler)
+		api.POST("/contact/messages", createContactMessageHandler)
+		api.GET("/consents/:purpose", getPublicConsentTextHandler)
+		api.GET("/hero", getPublicHeroHandler)
+		api.GET("/footer", getPublicFooterHandler)
+		api.GET("/campaigns", getPublicCampaignsHandler)
+		api.GET("/locales", getLocalesHandler)
+		api.GET("/opening-hours", getOpeningHoursHandler)
+		api.GET("/opening-hours/status", getOpeningStatusHandler)
+		api.POST("/service-area/check", checkServiceAreaHandler)
+		api.GET("/track/:token", getTrackingHandler)
+		api.GET("/track/:token/invoice", getTrackingInvoiceHandler)
+		api.GET("/jsonld", getBusinessJSONLDHandler)
+		api.GET("/jsonld/products/:slug", getItemJSONLDHandler("product", productJSONLD))
+		api.GET("/jsonld/services/:slug", getItemJSONLDHandler("service", serviceJSONLD))
 	}
 
 	// Start server
@@ -142,14 +387,12 @@ func loginHandler(c *gin.Context) {
 	}
 
 	// JWT token oluştur
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	tokenString, err := signToken(jwt.MapClaims{
 		"username": user.Username,
 		"user_id":  user.ID,
 		"role_id":  user.RoleID,
 		"exp":      time.Now().Add(time.Hour * 24).Unix(),
 	})
-
-	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Token oluşturulamadı"})
 		return
@@ -168,7 +411,7 @@ func loginHandler(c *gin.Context) {
 func authMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
+		if authHeader == "" && c.GetHeader("X-API-Key") == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "No token provided"})
 			c.Abort()
 			return
@@ -179,13 +422,22 @@ func authMiddleware() gin.HandlerFunc {
 		if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
 			tokenString = authHeader[7:]
 		}
+		if key := c.GetHeader("X-API-Key"); key != "" {
+			tokenString = key
+		}
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		// API anahtarı
+		if isAPIKey(tokenString) {
+			if err := authenticateAPIKey(c, tokenString); err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+				c.Abort()
+				return
 			}
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
+			c.Next()
+			return
+		}
+
+		token, err := parseToken(tokenString)
 
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
@@ -193,8 +445,13 @@ func authMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid && claims["purpose"] == nil {
+			userID, _ := claims["user_id"].(float64)
+			roleID, _ := claims["role_id"].(float64)
 			c.Set("username", claims["username"])
+			c.Set("user_id", int(userID))
+			c.Set("role_id", int(roleID))
+			c.Set("permissions", permissionSet(int(roleID)))
 			c.Next()
 		} else {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
@@ -205,23 +462,37 @@ func authMiddleware() gin.HandlerFunc {
 }
 
 // Ürün işlemleri
-func getProductsHandler(c *gin.Context) {
-	rows, err := db.DB.Query("SELECT id, name, description, price, image FROM products")
+func getProduct(id int) (models.Product, error) {
+	var p models.Product
+	err := db.DB.QueryRow("SELECT id, name, description, description_format, price, image, "+seoColumns+" FROM products WHERE id = $1", id).
+		Scan(&p.ID, &p.Name, &p.Description, &p.DescriptionFormat, &p.Price, &p.Image, &p.Slug, &p.SEOTitle, &p.SEODescription, &p.OGImage)
+	return p, err
+}
+
+func listProducts() ([]models.Product, error) {
+	rows, err := db.DB.Query("SELECT id, name, description, description_format, price, image, " + seoColumns + " FROM products ORDER BY id")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		return nil, err
 	}
 	defer rows.Close()
 
 	var products []models.Product
 	for rows.Next() {
 		var p models.Product
-		if err := rows.Scan(&p.ID, &p.Name, &p.Description, &p.Price, &p.Image); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
+		if err := rows.Scan(&p.ID, &p.Name, &p.Description, &p.DescriptionFormat, &p.Price, &p.Image, &p.Slug, &p.SEOTitle, &p.SEODescription, &p.OGImage); err != nil {
+			return nil, err
 		}
 		products = append(products, p)
 	}
+	return products, nil
+}
+
+func getProductsHandler(c *gin.Context) {
+	products, err := listProducts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
 	c.JSON(http.StatusOK, products)
 }
@@ -232,17 +503,31 @@ func createProductHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := normalizeRichText(&product.Description, &product.DescriptionFormat); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	err := db.DB.QueryRow(
-		"INSERT INTO products (name, description, price, image) VALUES ($1, $2, $3, $4) RETURNING id",
-		product.Name, product.Description, product.Price, product.Image,
-	).Scan(&product.ID)
-
+	err := saveContentChange(c, "product", auditCreate, nil, 0, func(tx *sql.Tx) (int, interface{}, error) {
+		err := writeWithSlug(tx, "product", 0, &product.Slug, product.Name, func() error {
+			return tx.QueryRow(
+				"INSERT INTO products (name, description, description_format, price, image, slug, seo_title, seo_description, og_image) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id",
+				product.Name, product.Description, product.DescriptionFormat, product.Price, product.Image,
+				product.Slug, product.SEOTitle, product.SEODescription, product.OGImage,
+			).Scan(&product.ID)
+		})
+		if err == nil {
+			err = updateSlugRedirects(tx, "product", product.ID, "", product.Slug)
+		}
+		return product.ID, product, err
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	emitEvent(eventProductCreated, product)
+	publishAdminEvent(c, eventProductCreated, "product", product.ID, product)
 	c.JSON(http.StatusCreated, product)
 }
 
@@ -258,21 +543,48 @@ func updateProductHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := normalizeRichText(&product.Description, &product.DescriptionFormat); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	_, err = db.DB.Exec(
-		"UPDATE products SET name = $1, description = $2, price = $3, image = $4 WHERE id = $5",
-		product.Name, product.Description, product.Price, product.Image, id,
-	)
+	before, err := getProduct(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
 
+	product.ID = id
+	err = saveContentChange(c, "product", auditUpdate, before, 0, func(tx *sql.Tx) (int, interface{}, error) {
+		err := updateProduct(tx, before, &product)
+		return id, product, err
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	product.ID = id
+	// Webhook olayları yayında gönderilir; panel taslak değişikliğini hemen görür
+	publishAdminEvent(c, eventProductUpdated, "product", product.ID, product)
 	c.JSON(http.StatusOK, product)
 }
 
+// updateProduct ürünü ve slug yönlendirmelerini işlem içinde günceller
+func updateProduct(tx *sql.Tx, before models.Product, product *models.Product) error {
+	err := writeWithSlug(tx, "product", product.ID, &product.Slug, product.Name, func() error {
+		_, err := tx.Exec(
+			"UPDATE products SET name = $1, description = $2, description_format = $3, price = $4, image = $5, slug = $6, seo_title = $7, seo_description = $8, og_image = $9 WHERE id = $10",
+			product.Name, product.Description, product.DescriptionFormat, product.Price, product.Image,
+			product.Slug, product.SEOTitle, product.SEODescription, product.OGImage, product.ID,
+		)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return updateSlugRedirects(tx, "product", product.ID, before.Slug, product.Slug)
+}
+
 func deleteProductHandler(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -280,6 +592,12 @@ func deleteProductHandler(c *gin.Context) {
 		return
 	}
 
+	before, err := getProduct(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
 	result, err := db.DB.Exec("DELETE FROM products WHERE id = $1", id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -297,27 +615,47 @@ func deleteProductHandler(c *gin.Context) {
 		return
 	}
 
+	unpublishEntity(db.DB, "product", id)
+	cancelSchedules("product", id)
+	deleteSlugRedirects("product", id)
+	recordAudit(c, "product", id, auditDelete, before, nil)
+	emitEvent(eventProductDeleted, before)
+	publishAdminEvent(c, eventProductDeleted, "product", id, gin.H{"id": id})
 	c.JSON(http.StatusOK, gin.H{"message": "Product deleted"})
 }
 
 // Hizmet işlemleri
-func getServicesHandler(c *gin.Context) {
-	rows, err := db.DB.Query("SELECT id, title, description, image FROM services")
+func getService(id int) (models.Service, error) {
+	var s models.Service
+	err := db.DB.QueryRow("SELECT id, title, description, description_format, image, "+seoColumns+" FROM services WHERE id = $1", id).
+		Scan(&s.ID, &s.Title, &s.Description, &s.DescriptionFormat, &s.Image, &s.Slug, &s.SEOTitle, &s.SEODescription, &s.OGImage)
+	return s, err
+}
+
+func listServices() ([]models.Service, error) {
+	rows, err := db.DB.Query("SELECT id, title, description, description_format, image, " + seoColumns + " FROM services ORDER BY id")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		return nil, err
 	}
 	defer rows.Close()
 
 	var services []models.Service
 	for rows.Next() {
 		var s models.Service
-		if err := rows.Scan(&s.ID, &s.Title, &s.Description, &s.Image); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
+		if err := rows.Scan(&s.ID, &s.Title, &s.Description, &s.DescriptionFormat, &s.Image, &s.Slug, &s.SEOTitle, &s.SEODescription, &s.OGImage); err != nil {
+			return nil, err
 		}
 		services = append(services, s)
 	}
+	return services, nil
+}
+
+func getServicesHandler(c *gin.Context) {
+	services, err := listServices()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
 	c.JSON(http.StatusOK, services)
 }
@@ -328,17 +666,31 @@ func createServiceHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := normalizeRichText(&service.Description, &service.DescriptionFormat); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	err := db.DB.QueryRow(
-		"INSERT INTO services (title, description, image) VALUES ($1, $2, $3) RETURNING id",
-		service.Title, service.Description, service.Image,
-	).Scan(&service.ID)
-
+	err := saveContentChange(c, "service", auditCreate, nil, 0, func(tx *sql.Tx) (int, interface{}, error) {
+		err := writeWithSlug(tx, "service", 0, &service.Slug, service.Title, func() error {
+			return tx.QueryRow(
+				"INSERT INTO services (title, description, description_format, image, slug, seo_title, seo_description, og_image) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id",
+				service.Title, service.Description, service.DescriptionFormat, service.Image,
+				service.Slug, service.SEOTitle, service.SEODescription, service.OGImage,
+			).Scan(&service.ID)
+		})
+		if err == nil {
+			err = updateSlugRedirects(tx, "service", service.ID, "", service.Slug)
+		}
+		return service.ID, service, err
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	emitEvent(eventServiceCreated, service)
+	publishAdminEvent(c, eventServiceCreated, "service", service.ID, service)
 	c.JSON(http.StatusCreated, service)
 }
 
@@ -354,21 +706,53 @@ func updateServiceHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := normalizeRichText(&service.Description, &service.DescriptionFormat); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	_, err = db.DB.Exec(
-		"UPDATE services SET title = $1, description = $2, image = $3 WHERE id = $4",
-		service.Title, service.Description, service.Image, id,
-	)
+	before, err := getService(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return
+	}
 
+	service.ID = id
+	err = saveContentChange(c, "service", auditUpdate, before, 0, func(tx *sql.Tx) (int, interface{}, error) {
+		err := updateService(tx, before, &service)
+		return id, service, err
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	service.ID = id
+	serviceUpdated(c, service)
 	c.JSON(http.StatusOK, service)
 }
 
+// serviceUpdated kaydedilen hizmet için olayları yayınlar
+func serviceUpdated(c *gin.Context, service models.Service) {
+	emitEvent(eventServiceUpdated, service)
+	publishAdminEvent(c, eventServiceUpdated, "service", service.ID, service)
+}
+
+// updateService hizmeti ve slug yönlendirmelerini işlem içinde günceller
+func updateService(tx *sql.Tx, before models.Service, service *models.Service) error {
+	err := writeWithSlug(tx, "service", service.ID, &service.Slug, service.Title, func() error {
+		_, err := tx.Exec(
+			"UPDATE services SET title = $1, description = $2, description_format = $3, image = $4, slug = $5, seo_title = $6, seo_description = $7, og_image = $8 WHERE id = $9",
+			service.Title, service.Description, service.DescriptionFormat, service.Image,
+			service.Slug, service.SEOTitle, service.SEODescription, service.OGImage, service.ID,
+		)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return updateSlugRedirects(tx, "service", service.ID, before.Slug, service.Slug)
+}
+
 func deleteServiceHandler(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -376,6 +760,12 @@ func deleteServiceHandler(c *gin.Context) {
 		return
 	}
 
+	before, err := getService(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return
+	}
+
 	result, err := db.DB.Exec("DELETE FROM services WHERE id = $1", id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -393,15 +783,25 @@ func deleteServiceHandler(c *gin.Context) {
 		return
 	}
 
+	unpublishEntity(db.DB, "service", id)
+	cancelSchedules("service", id)
+	deleteSlugRedirects("service", id)
+	recordAudit(c, "service", id, auditDelete, before, nil)
+	emitEvent(eventServiceDeleted, before)
+	publishAdminEvent(c, eventServiceDeleted, "service", id, gin.H{"id": id})
 	c.JSON(http.StatusOK, gin.H{"message": "Service deleted"})
 }
 
 // Hakkımızda işlemleri
-func getAboutHandler(c *gin.Context) {
+func getAbout() (models.About, error) {
 	var about models.About
-	err := db.DB.QueryRow("SELECT id, title, content, image FROM about ORDER BY id LIMIT 1").
-		Scan(&about.ID, &about.Title, &about.Content, &about.Image)
+	err := db.DB.QueryRow("SELECT id, title, content, content_format, image FROM about ORDER BY id LIMIT 1").
+		Scan(&about.ID, &about.Title, &about.Content, &about.ContentFormat, &about.Image)
+	return about, err
+}
 
+func getAboutHandler(c *gin.Context) {
+	about, err := getAbout()
 	if err != nil {
 		c.JSON(http.StatusOK, models.About{}) // Veri yoksa boş döndür
 		return
@@ -416,38 +816,66 @@ func updateAboutHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := normalizeRichText(&about.Content, &about.ContentFormat); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	var existingID int
-	err := db.DB.QueryRow("SELECT id FROM about ORDER BY id LIMIT 1").Scan(&existingID)
+	// Kayıt yoksa before boş kalır ve yeni kayıt eklenir
+	before, _ := getAbout()
+	existingID := before.ID
+	action, previous := singletonChange(existingID, before)
+	err := saveContentChange(c, "about", action, previous, 0, func(tx *sql.Tx) (int, interface{}, error) {
+		err := saveAbout(tx, &about, existingID)
+		return about.ID, about, err
+	})
 	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	publishAdminEvent(c, eventAboutUpdated, "about", about.ID, about)
+	c.JSON(http.StatusOK, about)
+}
+
+func saveAbout(tx *sql.Tx, about *models.About, existingID int) error {
+	var err error
+	if existingID == 0 {
 		// Kayıt yoksa yeni ekle
-		err = db.DB.QueryRow(
-			"INSERT INTO about (title, content, image) VALUES ($1, $2, $3) RETURNING id",
-			about.Title, about.Content, about.Image,
+		err = tx.QueryRow(
+			"INSERT INTO about (title, content, content_format, image) VALUES ($1, $2, $3, $4) RETURNING id",
+			about.Title, about.Content, about.ContentFormat, about.Image,
 		).Scan(&about.ID)
 	} else {
 		// Varolan kaydı güncelle
-		_, err = db.DB.Exec(
-			"UPDATE about SET title = $1, content = $2, image = $3 WHERE id = $4",
-			about.Title, about.Content, about.Image, existingID,
+		_, err = tx.Exec(
+			"UPDATE about SET title = $1, content = $2, content_format = $3, image = $4 WHERE id = $5",
+			about.Title, about.Content, about.ContentFormat, about.Image, existingID,
 		)
 		about.ID = existingID
 	}
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, about)
+	return err
 }
 
 // İletişim işlemleri
-func getContactHandler(c *gin.Context) {
+func getContact() (models.Contact, error) {
 	var contact models.Contact
-	err := db.DB.QueryRow("SELECT id, title, email, address, weekday_hours, saturday_hours, sunday_hours FROM contact ORDER BY id LIMIT 1").
-		Scan(&contact.ID, &contact.Title, &contact.Email, &contact.Address, &contact.WeekdayHours, &contact.SaturdayHours, &contact.SundayHours)
+	var phones, channels []byte
+	err := db.DB.QueryRow(`SELECT id, title, COALESCE(phone, ''), phones, COALESCE(email, ''), COALESCE(address, ''), COALESCE(latitude, 0), COALESCE(longitude, 0),
+		map_embed_url, channels, COALESCE(weekday_hours, ''), COALESCE(saturday_hours, ''), COALESCE(sunday_hours, '') FROM contact ORDER BY id LIMIT 1`).
+		Scan(&contact.ID, &contact.Title, &contact.Phone, &phones, &contact.Email, &contact.Address, &contact.Latitude, &contact.Longitude,
+			&contact.MapEmbedURL, &channels, &contact.WeekdayHours, &contact.SaturdayHours, &contact.SundayHours)
+	if err == nil {
+		json.Unmarshal(phones, &contact.Phones)
+		json.Unmarshal(channels, &contact.Channels)
+		fillContactPhones(&contact)
+		applyDerivedHours(&contact)
+	}
+	return contact, err
+}
 
+func getContactHandler(c *gin.Context) {
+	contact, err := getContact()
 	if err != nil {
 		c.JSON(http.StatusOK, models.Contact{}) // Veri yoksa boş döndür
 		return
@@ -462,47 +890,91 @@ func updateContactHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := validateContact(&contact); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	var existingID int
-	err := db.DB.QueryRow("SELECT id FROM contact ORDER BY id LIMIT 1").Scan(&existingID)
+	// Kayıt yoksa before boş kalır ve yeni kayıt eklenir
+	before, _ := getContact()
+	existingID := before.ID
+	action, previous := singletonChange(existingID, before)
+	err := saveContentChange(c, "contact", action, previous, 0, func(tx *sql.Tx) (int, interface{}, error) {
+		err := saveContact(tx, &contact, existingID)
+		return contact.ID, contact, err
+	})
 	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	publishAdminEvent(c, eventContactUpdated, "contact", contact.ID, contact)
+	c.JSON(http.StatusOK, contact)
+}
+
+func saveContact(tx *sql.Tx, contact *models.Contact, existingID int) error {
+	// Saatler tanımlıysa metin alanları programdan gelir
+	applyDerivedHours(contact)
+	fillContactPhones(contact)
+	if contact.Channels == nil {
+		contact.Channels = []models.ContactChannel{}
+	}
+	phones, _ := json.Marshal(contact.Phones)
+	channels, _ := json.Marshal(contact.Channels)
+
+	var err error
+	if existingID == 0 {
 		// Kayıt yoksa yeni ekle
-		err = db.DB.QueryRow(
-			"INSERT INTO contact (title, email, address, weekday_hours, saturday_hours, sunday_hours) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
-			contact.Title, contact.Email, contact.Address, contact.WeekdayHours, contact.SaturdayHours, contact.SundayHours,
+		err = tx.QueryRow(
+			`INSERT INTO contact (title, phone, phones, email, address, latitude, longitude, map_embed_url, channels, weekday_hours, saturday_hours, sunday_hours)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id`,
+			contact.Title, contact.Phone, string(phones), contact.Email, contact.Address, contact.Latitude, contact.Longitude,
+			contact.MapEmbedURL, string(channels), contact.WeekdayHours, contact.SaturdayHours, contact.SundayHours,
 		).Scan(&contact.ID)
 	} else {
 		// Varolan kaydı güncelle
-		_, err = db.DB.Exec(
-			"UPDATE contact SET title = $1, email = $2, address = $3, weekday_hours = $4, saturday_hours = $5, sunday_hours = $6 WHERE id = $7",
-			contact.Title, contact.Email, contact.Address, contact.WeekdayHours, contact.SaturdayHours, contact.SundayHours, existingID,
+		_, err = tx.Exec(
+			`UPDATE contact SET title = $1, phone = $2, phones = $3, email = $4, address = $5, latitude = $6, longitude = $7,
+			map_embed_url = $8, channels = $9, weekday_hours = $10, saturday_hours = $11, sunday_hours = $12 WHERE id = $13`,
+			contact.Title, contact.Phone, string(phones), contact.Email, contact.Address, contact.Latitude, contact.Longitude,
+			contact.MapEmbedURL, string(channels), contact.WeekdayHours, contact.SaturdayHours, contact.SundayHours, existingID,
 		)
 		contact.ID = existingID
 	}
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, contact)
+	return err
 }
 
-// Public handlers
+// Public handlers: önizleme token'ı yoksa sadece yayındaki veri döner,
+// içerik istenen dile çevrilir
 func getPublicProductsHandler(c *gin.Context) {
-	getProductsHandler(c)
+	servePublic(c, "product")
 }
 
 func getPublicServicesHandler(c *gin.Context) {
-	getServicesHandler(c)
+	servePublic(c, "service")
 }
 
 func getPublicAboutHandler(c *gin.Context) {
-	getAboutHandler(c)
+	servePublicSingle(c, "about", models.About{})
 }
 
 func getPublicContactHandler(c *gin.Context) {
-	getContactHandler(c)
+	contact, err := getContact()
+	if err != nil {
+		c.JSON(http.StatusOK, models.Contact{}) // Veri yoksa boş döndür
+		return
+	}
+	item := toJSONMap(contact)
+	localize(c, "contact", []map[string]interface{}{item})
+	c.JSON(http.StatusOK, item)
+}
+
+func getPublicHeroHandler(c *gin.Context) {
+	servePublicSingle(c, "hero", models.Hero{})
+}
+
+func getPublicFooterHandler(c *gin.Context) {
+	servePublicSingle(c, "footer", models.Footer{})
 }
 
 // Dosya yükleme işleyicisi
@@ -528,25 +1000,189 @@ func uploadHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"url": fileURL})
 }
 
+// Hero işlemleri
+func getHero() (models.Hero, error) {
+	var hero models.Hero
+	err := db.DB.QueryRow("SELECT id, subheading, heading, button_text, background_image FROM hero ORDER BY id LIMIT 1").
+		Scan(&hero.ID, &hero.Subheading, &hero.Heading, &hero.ButtonText, &hero.BackgroundImage)
+	return hero, err
+}
+
 func getHeroHandler(c *gin.Context) {
-	// Implementation of getHeroHandler
+	hero, err := getHero()
+	if err != nil {
+		c.JSON(http.StatusOK, models.Hero{}) // Veri yoksa boş döndür
+		return
+	}
+
+	c.JSON(http.StatusOK, hero)
 }
 
 func updateHeroHandler(c *gin.Context) {
-	// Implementation of updateHeroHandler
+	var hero models.Hero
+	if err := c.BindJSON(&hero); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateHero(&hero); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Kayıt yoksa before boş kalır ve yeni kayıt eklenir
+	before, _ := getHero()
+	existingID := before.ID
+	action, previous := singletonChange(existingID, before)
+	err := saveContentChange(c, "hero", action, previous, 0, func(tx *sql.Tx) (int, interface{}, error) {
+		err := saveHero(tx, &hero, existingID)
+		return hero.ID, hero, err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	publishAdminEvent(c, eventHeroUpdated, "hero", hero.ID, hero)
+	c.JSON(http.StatusOK, hero)
+}
+
+func saveHero(tx *sql.Tx, hero *models.Hero, existingID int) error {
+	var err error
+	if existingID == 0 {
+		// Kayıt yoksa yeni ekle
+		err = tx.QueryRow(
+			"INSERT INTO hero (subheading, heading, button_text, background_image) VALUES ($1, $2, $3, $4) RETURNING id",
+			hero.Subheading, hero.Heading, hero.ButtonText, hero.BackgroundImage,
+		).Scan(&hero.ID)
+	} else {
+		// Varolan kaydı güncelle
+		_, err = tx.Exec(
+			"UPDATE hero SET subheading = $1, heading = $2, button_text = $3, background_image = $4 WHERE id = $5",
+			hero.Subheading, hero.Heading, hero.ButtonText, hero.BackgroundImage, existingID,
+		)
+		hero.ID = existingID
+	}
+	return err
+}
+
+// validateHero başlığı zorunlu tutar ve arka plan görselinin yüklenen bir
+// dosya veya https adresi olmasını sağlar
+func validateHero(hero *models.Hero) error {
+	hero.Heading = strings.TrimSpace(hero.Heading)
+	if hero.Heading == "" {
+		return fmt.Errorf("Başlık zorunludur")
+	}
+	if hero.BackgroundImage != "" && !validImageURL(hero.BackgroundImage) {
+		return fmt.Errorf("Arka plan görseli /uploads altında veya https adresi olmalı")
+	}
+	return nil
+}
+
+// validImageURL yüklenen dosyalara ve https adreslerine izin verir
+func validImageURL(raw string) bool {
+	if strings.HasPrefix(raw, "/uploads/") {
+		return true
+	}
+	u, err := url.Parse(raw)
+	return err == nil && u.Scheme == "https" && u.Host != ""
+}
+
+// Footer işlemleri
+func getFooter() (models.Footer, error) {
+	var footer models.Footer
+	var socialLinks, links []byte
+	err := db.DB.QueryRow("SELECT id, copyright, social_links, links FROM footer ORDER BY id LIMIT 1").
+		Scan(&footer.ID, &footer.Copyright, &socialLinks, &links)
+	if err != nil {
+		return footer, err
+	}
+	json.Unmarshal(socialLinks, &footer.SocialLinks)
+	json.Unmarshal(links, &footer.Links)
+	return footer, nil
 }
 
 func getFooterHandler(c *gin.Context) {
-	// Implementation of getFooterHandler
+	footer, err := getFooter()
+	if err != nil {
+		c.JSON(http.StatusOK, models.Footer{}) // Veri yoksa boş döndür
+		return
+	}
+
+	c.JSON(http.StatusOK, footer)
 }
 
 func updateFooterHandler(c *gin.Context) {
-	// Implementation of updateFooterHandler
+	var footer models.Footer
+	if err := c.BindJSON(&footer); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateFooter(&footer); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Kayıt yoksa before boş kalır ve yeni kayıt eklenir
+	before, _ := getFooter()
+	existingID := before.ID
+	action, previous := singletonChange(existingID, before)
+	err := saveContentChange(c, "footer", action, previous, 0, func(tx *sql.Tx) (int, interface{}, error) {
+		err := saveFooter(tx, &footer, existingID)
+		return footer.ID, footer, err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	publishAdminEvent(c, eventFooterUpdated, "footer", footer.ID, footer)
+	c.JSON(http.StatusOK, footer)
+}
+
+// validateFooter link adreslerini kontrol eder: sosyal medya linkleri https,
+// diğer linkler http(s) veya site içi göreli adres olmalı
+func validateFooter(footer *models.Footer) error {
+	for name, link := range footer.SocialLinks {
+		u, err := url.Parse(link)
+		if link != "" && (err != nil || u.Scheme != "https" || u.Host == "") {
+			return fmt.Errorf("%s adresi https ile başlamalı", name)
+		}
+	}
+	for name, link := range footer.Links {
+		u, err := url.Parse(link)
+		if err != nil || (u.Scheme == "" && !strings.HasPrefix(link, "/")) ||
+			(u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("%s linki http(s) veya / ile başlayan bir adres olmalı", name)
+		}
+	}
+	return nil
+}
+
+func saveFooter(tx *sql.Tx, footer *models.Footer, existingID int) error {
+	socialLinks, _ := json.Marshal(footer.SocialLinks)
+	links, _ := json.Marshal(footer.Links)
+
+	var err error
+	if existingID == 0 {
+		// Kayıt yoksa yeni ekle
+		err = tx.QueryRow(
+			"INSERT INTO footer (copyright, social_links, links) VALUES ($1, $2, $3) RETURNING id",
+			footer.Copyright, socialLinks, links,
+		).Scan(&footer.ID)
+	} else {
+		// Varolan kaydı güncelle
+		_, err = tx.Exec(
+			"UPDATE footer SET copyright = $1, social_links = $2, links = $3 WHERE id = $4",
+			footer.Copyright, socialLinks, links, existingID,
+		)
+		footer.ID = existingID
+	}
+	return err
 }
 
 // Users endpoints
 func getUsersHandler(c *gin.Context) {
-	rows, err := db.DB.Query("SELECT id, username, email, role_id FROM users")
+	rows, err := db.DB.Query("SELECT id, username, email, full_name, role_id FROM users")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -556,7 +1192,7 @@ func getUsersHandler(c *gin.Context) {
 	var users []models.User
 	for rows.Next() {
 		var u models.User
-		if err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.RoleID); err != nil {
+		if err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.FullName, &u.RoleID); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
@@ -593,8 +1229,8 @@ func createUserHandler(c *gin.Context) {
 
 	// Kullanıcıyı veritabanına ekle
 	err = db.DB.QueryRow(
-		"INSERT INTO users (username, email, password, role_id) VALUES ($1, $2, $3, $4) RETURNING id",
-		user.Username, user.Email, string(hashedPassword), user.RoleID,
+		"INSERT INTO users (username, email, full_name, password, role_id) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		user.Username, user.Email, strings.TrimSpace(user.FullName), string(hashedPassword), user.RoleID,
 	).Scan(&user.ID)
 
 	if err != nil {
@@ -614,6 +1250,7 @@ func createUserHandler(c *gin.Context) {
 
 	// Şifreyi response'dan temizle
 	user.Password = ""
+	recordAudit(c, "user", user.ID, auditCreate, nil, user)
 	c.JSON(http.StatusCreated, user)
 }
 
@@ -628,6 +1265,7 @@ func updateUserHandler(c *gin.Context) {
 	var updateData struct {
 		Username string `json:"username"`
 		Email    string `json:"email"`
+		FullName string `json:"full_name"`
 		RoleID   int    `json:"role_id"`
 	}
 
@@ -638,8 +1276,8 @@ func updateUserHandler(c *gin.Context) {
 
 	// Mevcut kullanıcıyı kontrol et
 	var user models.User
-	err = db.DB.QueryRow("SELECT id, username, email, role_id FROM users WHERE id = $1", userID).
-		Scan(&user.ID, &user.Username, &user.Email, &user.RoleID)
+	err = db.DB.QueryRow("SELECT id, username, email, full_name, role_id FROM users WHERE id = $1", userID).
+		Scan(&user.ID, &user.Username, &user.Email, &user.FullName, &user.RoleID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Kullanıcı bulunamadı"})
 		return
@@ -647,8 +1285,8 @@ func updateUserHandler(c *gin.Context) {
 
 	// Kullanıcıyı güncelle
 	_, err = db.DB.Exec(
-		"UPDATE users SET username = $1, email = $2, role_id = $3 WHERE id = $4",
-		updateData.Username, updateData.Email, updateData.RoleID, userID,
+		"UPDATE users SET username = $1, email = $2, full_name = $3, role_id = $4 WHERE id = $5",
+		updateData.Username, updateData.Email, strings.TrimSpace(updateData.FullName), updateData.RoleID, userID,
 	)
 
 	if err != nil {
@@ -667,20 +1305,35 @@ func updateUserHandler(c *gin.Context) {
 	}
 
 	// Güncellenmiş kullanıcı bilgilerini getir
-	err = db.DB.QueryRow("SELECT id, username, email, role_id FROM users WHERE id = $1", userID).
-		Scan(&user.ID, &user.Username, &user.Email, &user.RoleID)
+	before := user
+	err = db.DB.QueryRow("SELECT id, username, email, full_name, role_id FROM users WHERE id = $1", userID).
+		Scan(&user.ID, &user.Username, &user.Email, &user.FullName, &user.RoleID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Güncellenmiş kullanıcı bilgileri alınamadı"})
 		return
 	}
 
+	recordAudit(c, "user", userID, auditUpdate, before, user)
 	c.JSON(http.StatusOK, user)
 }
 
 func deleteUserHandler(c *gin.Context) {
 	id := c.Param("id")
+	userID, err := strconv.Atoi(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz kullanıcı ID"})
+		return
+	}
+
+	var before models.User
+	err = db.DB.QueryRow("SELECT id, username, email, full_name, role_id FROM users WHERE id = $1", userID).
+		Scan(&before.ID, &before.Username, &before.Email, &before.FullName, &before.RoleID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kullanıcı bulunamadı"})
+		return
+	}
 
-	result, err := db.DB.Exec("DELETE FROM users WHERE id = $1", id)
+	result, err := db.DB.Exec("DELETE FROM users WHERE id = $1", userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -697,5 +1350,6 @@ func deleteUserHandler(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, "user", userID, auditDelete, before, nil)
 	c.JSON(http.StatusOK, gin.H{"message": "Kullanıcı silindi"})
 }
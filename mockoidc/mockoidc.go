@@ -0,0 +1,164 @@
+// Package mockoidc yerel geliştirme ve testler için basit bir OIDC sağlayıcısıdır.
+// Üretim sunucusuna derlenmez; cmd/mock-oidc ile ayrı çalıştırılır.
+package mockoidc
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Provider her yetkilendirme isteğini Email kullanıcısı adına onaylar
+type Provider struct {
+	Issuer string
+	Email  string
+	Groups []string
+}
+
+type authRequest struct {
+	clientID    string
+	redirectURI string
+	nonce       string
+	challenge   string
+	email       string
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Handler discovery, JWKS, authorize ve token uç noktalarını döndürür.
+// İmzalama anahtarı her çağrıda yeniden üretilir.
+func (p *Provider) Handler() (http.Handler, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	codes := make(map[string]authRequest)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"issuer":                                p.Issuer,
+			"authorization_endpoint":                p.Issuer + "/authorize",
+			"token_endpoint":                        p.Issuer + "/token",
+			"jwks_uri":                              p.Issuer + "/jwks",
+			"response_types_supported":              []string{"code"},
+			"subject_types_supported":               []string{"public"},
+			"id_token_signing_alg_values_supported": []string{"EdDSA"},
+			"code_challenge_methods_supported":      []string{"S256"},
+		})
+	})
+
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "OKP", "crv": "Ed25519", "kid": "mock", "alg": "EdDSA", "use": "sig",
+				"x": base64.RawURLEncoding.EncodeToString(public),
+			}},
+		})
+	})
+
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+			http.Error(w, "unsupported request", http.StatusBadRequest)
+			return
+		}
+		code, err := randomHex(16)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		req := authRequest{
+			clientID:    q.Get("client_id"),
+			redirectURI: q.Get("redirect_uri"),
+			nonce:       q.Get("nonce"),
+			challenge:   q.Get("code_challenge"),
+			email:       p.Email,
+		}
+		// Farklı kullanıcıları denemek için ?login_hint=e-posta
+		if hint := q.Get("login_hint"); hint != "" {
+			req.email = hint
+		}
+		mu.Lock()
+		codes[code] = req
+		mu.Unlock()
+
+		redirect := req.redirectURI + "?" + url.Values{"code": {code}, "state": {q.Get("state")}}.Encode()
+		http.Redirect(w, r, redirect, http.StatusFound)
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		code := r.FormValue("code")
+		mu.Lock()
+		req, ok := codes[code]
+		delete(codes, code)
+		mu.Unlock()
+
+		if !ok || r.FormValue("grant_type") != "authorization_code" ||
+			r.FormValue("client_id") != req.clientID || r.FormValue("redirect_uri") != req.redirectURI {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		if pkceChallenge(r.FormValue("code_verifier")) != req.challenge {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+			return
+		}
+
+		username, _, _ := strings.Cut(req.email, "@")
+		token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{
+			"iss":                p.Issuer,
+			"sub":                "mock-" + username,
+			"aud":                req.clientID,
+			"exp":                time.Now().Add(5 * time.Minute).Unix(),
+			"iat":                time.Now().Unix(),
+			"nonce":              req.nonce,
+			"email":              req.email,
+			"email_verified":     true,
+			"preferred_username": username,
+			"groups":             p.Groups,
+		})
+		token.Header["kid"] = "mock"
+		idToken, err := token.SignedString(private)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"access_token": code,
+			"token_type":   "Bearer",
+			"expires_in":   300,
+			"id_token":     idToken,
+		})
+	})
+
+	return mux, nil
+}
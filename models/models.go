@@ -1,37 +1,73 @@
 package models
 
-type Product struct {
-	ID          int     `json:"id"`
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
-	Image       string  `json:"image"`
+import (
+	"encoding/json"
+	"time"
+)
+
+// SEO ürün ve hizmetlerin arama motoru alanlarıdır
+type SEO struct {
+	Slug           string `json:"slug"`
+	SEOTitle       string `json:"seoTitle"`
+	SEODescription string `json:"seoDescription"`
+	OGImage        string `json:"ogImage"`
 }
 
-type Service struct {
+type Product struct {
 	ID          int    `json:"id"`
-	Title       string `json:"title"`
+	Name        string `json:"name"`
 	Description string `json:"description"`
-	Image       string `json:"image"`
+	// DescriptionFormat text, markdown veya html
+	DescriptionFormat string  `json:"descriptionFormat"`
+	Price             float64 `json:"price"`
+	Image             string  `json:"image"`
+	SEO
+}
+
+type Service struct {
+	ID                int    `json:"id"`
+	Title             string `json:"title"`
+	Description       string `json:"description"`
+	DescriptionFormat string `json:"descriptionFormat"`
+	Image             string `json:"image"`
+	SEO
 }
 
 type About struct {
-	ID      int    `json:"id"`
-	Title   string `json:"title"`
-	Content string `json:"content"`
-	Image   string `json:"image"`
+	ID            int    `json:"id"`
+	Title         string `json:"title"`
+	Content       string `json:"content"`
+	ContentFormat string `json:"contentFormat"`
+	Image         string `json:"image"`
 }
 
 type Contact struct {
-	ID            int     `json:"id"`
-	Title         string  `json:"title"`
-	Email         string  `json:"email"`
-	Address       string  `json:"address"`
-	Latitude      float64 `json:"latitude"`
-	Longitude     float64 `json:"longitude"`
-	WeekdayHours  string  `json:"weekdayHours"`
-	SaturdayHours string  `json:"saturdayHours"`
-	SundayHours   string  `json:"sundayHours"`
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	// Phone birincil numaradır (phones listesinin ilki)
+	Phone         string           `json:"phone"`
+	Phones        []ContactPhone   `json:"phones"`
+	Email         string           `json:"email"`
+	Address       string           `json:"address"`
+	Latitude      float64          `json:"latitude"`
+	Longitude     float64          `json:"longitude"`
+	MapEmbedURL   string           `json:"mapEmbedUrl"`
+	Channels      []ContactChannel `json:"channels"`
+	WeekdayHours  string           `json:"weekdayHours"`
+	SaturdayHours string           `json:"saturdayHours"`
+	SundayHours   string           `json:"sundayHours"`
+}
+
+type ContactPhone struct {
+	Type   string `json:"type"`   // landline, mobile, whatsapp
+	Number string `json:"number"` // E.164, ör. +905385153191
+	Label  string `json:"label,omitempty"`
+	Href   string `json:"href"` // tel: veya https://wa.me/ linki
+}
+
+type ContactChannel struct {
+	Type string `json:"type"` // instagram, facebook, x, youtube, linkedin, tiktok, telegram, google
+	URL  string `json:"url"`
 }
 
 type Hero struct {
@@ -53,7 +89,510 @@ type User struct {
 	ID        int    `json:"id"`
 	Username  string `json:"username"`
 	Email     string `json:"email"`
+	FullName  string `json:"full_name"`
 	Password  string `json:"password,omitempty"`
 	RoleID    int    `json:"role_id"`
 	CreatedAt string `json:"created_at,omitempty"`
 }
+
+type APIKey struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	AllowedIPs []string   `json:"allowed_ips"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type AuditEntry struct {
+	ID            int             `json:"id"`
+	ActorID       int             `json:"actor_id"`
+	ActorUsername string          `json:"actor_username"`
+	APIKeyID      *int            `json:"api_key_id,omitempty"`
+	IP            string          `json:"ip"`
+	UserAgent     string          `json:"user_agent"`
+	EntityType    string          `json:"entity_type"`
+	EntityID      int             `json:"entity_id"`
+	Action        string          `json:"action"`
+	Before        json.RawMessage `json:"before"`
+	After         json.RawMessage `json:"after"`
+	Diff          json.RawMessage `json:"diff"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+type Revision struct {
+	ID             int             `json:"id"`
+	EntityType     string          `json:"entity_type"`
+	EntityID       int             `json:"entity_id"`
+	Revision       int             `json:"revision"`
+	Data           json.RawMessage `json:"data"`
+	AuthorID       int             `json:"author_id"`
+	AuthorUsername string          `json:"author_username"`
+	RestoredFrom   *int            `json:"restored_from,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+type PublishStatus struct {
+	EntityType  string     `json:"entity_type"`
+	EntityID    int        `json:"entity_id"`
+	Status      string     `json:"status"` // draft, published, changed
+	PublishedBy int        `json:"published_by,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+}
+
+type Campaign struct {
+	ID                int      `json:"id"`
+	Title             string   `json:"title"`
+	Description       string   `json:"description"`
+	DescriptionFormat string   `json:"descriptionFormat"`
+	Image             string   `json:"image"`
+	Link              string   `json:"link"`
+	ProductID         *int     `json:"productId,omitempty"`
+	SpecialPrice      *float64 `json:"specialPrice,omitempty"`
+}
+
+type ScheduleEntry struct {
+	ID         int        `json:"id"`
+	EntityType string     `json:"entity_type"`
+	EntityID   int        `json:"entity_id"`
+	Label      string     `json:"label"`
+	Action     string     `json:"action"` // publish, unpublish
+	RunAt      time.Time  `json:"run_at"`
+	Status     string     `json:"status"` // pending, done, failed
+	Error      string     `json:"error,omitempty"`
+	ExecutedAt *time.Time `json:"executed_at,omitempty"`
+}
+
+type Locale struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+	Dir  string `json:"dir"` // ltr, rtl
+}
+
+type MissingTranslation struct {
+	EntityType    string   `json:"entity_type"`
+	EntityID      int      `json:"entity_id"`
+	Locale        string   `json:"locale"`
+	MissingFields []string `json:"missing_fields"`
+}
+
+type OpeningInterval struct {
+	Opens  string `json:"opens"`  // "09:00"
+	Closes string `json:"closes"` // "18:00"; açılıştan önceyse ertesi gün
+}
+
+type WeeklyHours struct {
+	Weekday   int               `json:"weekday"` // 1 = Pazartesi ... 7 = Pazar
+	Name      string            `json:"name"`
+	Intervals []OpeningInterval `json:"intervals"`
+}
+
+type OpeningException struct {
+	ID        int               `json:"id"`
+	Label     string            `json:"label"`
+	StartDate string            `json:"start_date"` // "2026-03-30"
+	EndDate   string            `json:"end_date"`
+	Closed    bool              `json:"closed"`
+	Intervals []OpeningInterval `json:"intervals"`
+}
+
+type OpeningStatus struct {
+	Open        bool              `json:"open"`
+	Now         time.Time         `json:"now"`
+	Timezone    string            `json:"timezone"`
+	ClosesAt    *time.Time        `json:"closes_at,omitempty"`
+	NextOpening *time.Time        `json:"next_opening,omitempty"`
+	Today       []OpeningInterval `json:"today"`
+	Exception   string            `json:"exception,omitempty"`
+}
+
+type ContactMessage struct {
+	ID          int        `json:"id"`
+	Name        string     `json:"name"`
+	Email       string     `json:"email"`
+	Phone       string     `json:"phone"`
+	Subject     string     `json:"subject"`
+	Message     string     `json:"message"`
+	IP          string     `json:"ip,omitempty"`
+	UserAgent   string     `json:"user_agent,omitempty"`
+	Read        bool       `json:"read"`
+	AssignedTo  *int       `json:"assigned_to"`
+	WorkOrderID *int       `json:"work_order_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Notes       []LeadNote `json:"notes,omitempty"`
+}
+
+type LeadNote struct {
+	ID             int       `json:"id"`
+	AuthorID       int       `json:"author_id"`
+	AuthorUsername string    `json:"author_username"`
+	Note           string    `json:"note"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type Customer struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Phone     string    `json:"phone"`
+	Email     string    `json:"email"`
+	Address   string    `json:"address"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WorkOrder struct {
+	ID              int        `json:"id"`
+	CustomerID      int        `json:"customer_id"`
+	Customer        *Customer  `json:"customer,omitempty"`
+	Title           string     `json:"title"`
+	Description     string     `json:"description"`
+	Address         string     `json:"address"`
+	Status          string     `json:"status"` // new, scheduled, on_the_way, in_progress, completed, cancelled
+	SourceMessageID *int       `json:"source_message_id,omitempty"`
+	ScheduledStart  *time.Time `json:"scheduled_start"`
+	ScheduledEnd    *time.Time `json:"scheduled_end"`
+	TechnicianID    *int       `json:"technician_id"`
+	Latitude        *float64   `json:"latitude"`
+	Longitude       *float64   `json:"longitude"`
+	TravelStartedAt *time.Time `json:"travel_started_at"`
+	ETA             *time.Time `json:"eta"`
+	ArrivedAt       *time.Time `json:"arrived_at"`
+	CompletedAt     *time.Time `json:"completed_at"`
+	TechnicianNotes string     `json:"technician_notes"`
+	SignerName      string     `json:"signer_name"`
+	SignedAt        *time.Time `json:"signed_at"`
+	HasInvoice      bool       `json:"has_invoice"`
+	ServiceZoneID   *int       `json:"service_zone_id"`
+	CallOutFee      float64    `json:"call_out_fee"`
+	CreatedBy       int        `json:"created_by"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+type ConsentText struct {
+	ID        int       `json:"id"`
+	Purpose   string    `json:"purpose"`
+	Version   int       `json:"version"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	Required  bool      `json:"required"`
+	CreatedBy int       `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ConsentRecord onay defterindeki bir satırdır; kişi e-posta/telefon hash'i ile tutulur
+type ConsentRecord struct {
+	ID        int64     `json:"id"`
+	Purpose   string    `json:"purpose"`
+	Version   int       `json:"version"`
+	Granted   bool      `json:"granted"`
+	Source    string    `json:"source"`
+	SourceID  *int      `json:"source_id,omitempty"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type DataSubjectRequest struct {
+	ID                  int             `json:"id"`
+	Kind                string          `json:"kind"` // export, anonymize
+	Reason              string          `json:"reason"`
+	Result              json.RawMessage `json:"result"`
+	RequestedBy         int             `json:"requested_by"`
+	RequestedByUsername string          `json:"requested_by_username"`
+	CreatedAt           time.Time       `json:"created_at"`
+}
+
+type NotificationTemplate struct {
+	Key              string    `json:"key"`
+	Channel          string    `json:"channel"`
+	Subject          string    `json:"subject"`
+	Body             string    `json:"body"`
+	WhatsAppTemplate string    `json:"whatsapp_template"`
+	WhatsAppParams   []string  `json:"whatsapp_params"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// Notification gönderim kaydıdır (teslim günlüğü)
+type Notification struct {
+	ID          int        `json:"id"`
+	CustomerID  *int       `json:"customer_id"`
+	WorkOrderID *int       `json:"work_order_id"`
+	Channel     string     `json:"channel"`
+	Recipient   string     `json:"recipient"`
+	Template    string     `json:"template"`
+	Subject     string     `json:"subject"`
+	Body        string     `json:"body"`
+	Status      string     `json:"status"` // queued, sent, failed, skipped
+	Error       string     `json:"error"`
+	ProviderID  string     `json:"provider_id"`
+	Attempts    int        `json:"attempts"`
+	SendAfter   time.Time  `json:"send_after"`
+	SentAt      *time.Time `json:"sent_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type NotificationPreferences struct {
+	Channels   []string `json:"channels"`
+	QuietStart string   `json:"quiet_start"`
+	QuietEnd   string   `json:"quiet_end"`
+}
+
+type Job struct {
+	ID          int64           `json:"id"`
+	Kind        string          `json:"kind"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"` // pending, running, done, dead
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"`
+	UniqueKey   string          `json:"unique_key,omitempty"`
+	LastError   string          `json:"last_error"`
+	LockedBy    string          `json:"locked_by"`
+	LockedAt    *time.Time      `json:"locked_at"`
+	FinishedAt  *time.Time      `json:"finished_at"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// Webhook giden olay aboneliği. Secret sadece oluşturulurken döndürülür.
+type Webhook struct {
+	ID             int        `json:"id"`
+	URL            string     `json:"url"`
+	Description    string     `json:"description"`
+	Events         []string   `json:"events"`
+	Secret         string     `json:"secret,omitempty"`
+	Active         bool       `json:"active"`
+	FailureCount   int        `json:"failure_count"`
+	DisabledAt     *time.Time `json:"disabled_at"`
+	DisabledReason string     `json:"disabled_reason"`
+	CreatedBy      int        `json:"created_by"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// WebhookDelivery tek bir webhook gönderiminin kaydı
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	WebhookID      int             `json:"webhook_id"`
+	EventID        string          `json:"event_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"` // pending, success, failed
+	Attempts       int             `json:"attempts"`
+	ResponseStatus int             `json:"response_status"`
+	Error          string          `json:"error"`
+	DurationMS     int64           `json:"duration_ms"`
+	RedeliveryOf   *int64          `json:"redelivery_of"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+}
+
+// AdminEvent yönetim paneline canlı gönderilen olay
+type AdminEvent struct {
+	ID         int64           `json:"id"`
+	Type       string          `json:"type"`
+	EntityType string          `json:"entity_type"`
+	EntityID   int             `json:"entity_id"`
+	ActorID    int             `json:"actor_id"`
+	Data       json.RawMessage `json:"data"`
+	CreatedAt  time.Time       `json:"created_at"`
+	// SnapshotXmin olay yazılırken açık olan en eski işlem; SSE imlecinde kullanılır
+	SnapshotXmin int64 `json:"-"`
+}
+
+// WorkOrderCheckpoint teknisyenin yola çıkış, varış ve bitiş kaydı
+type WorkOrderCheckpoint struct {
+	ID          int64     `json:"id"`
+	WorkOrderID int       `json:"work_order_id"`
+	UserID      int       `json:"user_id"`
+	Kind        string    `json:"kind"` // travel_started, arrived, finished
+	Latitude    *float64  `json:"latitude"`
+	Longitude   *float64  `json:"longitude"`
+	Accuracy    *float64  `json:"accuracy"`
+	RecordedAt  time.Time `json:"recorded_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type WorkOrderPhoto struct {
+	ID          int       `json:"id"`
+	ClientID    string    `json:"client_id,omitempty"`
+	WorkOrderID int       `json:"work_order_id"`
+	Kind        string    `json:"kind"` // before, after
+	ContentType string    `json:"content_type"`
+	Caption     string    `json:"caption"`
+	UploadedBy  int       `json:"uploaded_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// WorkOrderPart iş emrinde kullanılan parça
+type WorkOrderPart struct {
+	ID          int       `json:"id"`
+	ClientID    string    `json:"client_id,omitempty"`
+	WorkOrderID int       `json:"work_order_id"`
+	PartID      *int      `json:"part_id"`     // katalogdan seçildiyse
+	LocationID  *int      `json:"location_id"` // stoktan düşülen yer
+	Name        string    `json:"name"`
+	Quantity    float64   `json:"quantity"`
+	Unit        string    `json:"unit"`
+	UnitPrice   float64   `json:"unit_price"`
+	Note        string    `json:"note"`
+	CreatedBy   int       `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ChecklistItem struct {
+	ID          int        `json:"id"`
+	WorkOrderID int        `json:"work_order_id"`
+	Position    int        `json:"position"`
+	Label       string     `json:"label"`
+	Done        bool       `json:"done"`
+	Note        string     `json:"note"`
+	DoneBy      *int       `json:"done_by"`
+	DoneAt      *time.Time `json:"done_at"`
+}
+
+// RoutePlan teknisyenin bir günlük ziyaret sırası
+type RoutePlan struct {
+	ID            int         `json:"id"`
+	TechnicianID  int         `json:"technician_id"`
+	Date          string      `json:"date"` // YYYY-MM-DD
+	DepotLat      float64     `json:"depot_latitude"`
+	DepotLng      float64     `json:"depot_longitude"`
+	ShiftStart    time.Time   `json:"shift_start"` // en erken çıkış
+	StartAt       time.Time   `json:"start_at"`    // depodan çıkış
+	ReturnAt      time.Time   `json:"return_at"`
+	TotalKm       float64     `json:"total_km"`
+	TravelMinutes int         `json:"travel_minutes"`
+	LateMinutes   int         `json:"late_minutes"`
+	Manual        bool        `json:"manual"` // sıra elle değiştirildi
+	Stops         []RouteStop `json:"stops"`
+	Unlocated     []int       `json:"unlocated"` // koordinatı olmayan iş emirleri
+	CreatedBy     int         `json:"created_by"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+}
+
+// RouteStop rotadaki bir ziyaret
+type RouteStop struct {
+	Position      int        `json:"position"`
+	WorkOrderID   int        `json:"work_order_id"`
+	Title         string     `json:"title"`
+	Address       string     `json:"address"`
+	Latitude      float64    `json:"latitude"`
+	Longitude     float64    `json:"longitude"`
+	WindowStart   *time.Time `json:"window_start"`
+	WindowEnd     *time.Time `json:"window_end"`
+	ArrivalAt     time.Time  `json:"arrival_at"`
+	DepartureAt   time.Time  `json:"departure_at"`
+	TravelKm      float64    `json:"travel_km"`
+	TravelMinutes int        `json:"travel_minutes"`
+	WaitMinutes   int        `json:"wait_minutes"`
+	LateMinutes   int        `json:"late_minutes"`
+}
+
+// ServiceZone hizmet bölgesi. Kind: radius (merkez yoksa dükkân konumu),
+// polygon veya area (ilçe/mahalle listesi).
+type ServiceZone struct {
+	ID             int          `json:"id"`
+	Name           string       `json:"name"`
+	Kind           string       `json:"kind"`
+	CenterLat      *float64     `json:"center_latitude"`
+	CenterLng      *float64     `json:"center_longitude"`
+	RadiusKm       float64      `json:"radius_km"`
+	Polygon        [][2]float64 `json:"polygon"` // [enlem, boylam] noktaları
+	Districts      []string     `json:"districts"`
+	Neighbourhoods []string     `json:"neighbourhoods"`
+	CallOutFee     float64      `json:"call_out_fee"`
+	Days           []int        `json:"days"` // 1 = Pazartesi ... 7 = Pazar
+	Priority       int          `json:"priority"`
+	Active         bool         `json:"active"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+// Quote müşteriye verilen fiyat teklifi. Yol ücreti adresin hizmet bölgesinden
+// alınır; Total kalemlerin toplamı ile yol ücretidir.
+type Quote struct {
+	ID            int         `json:"id"`
+	CustomerID    int         `json:"customer_id"`
+	Customer      *Customer   `json:"customer,omitempty"`
+	Title         string      `json:"title"`
+	Address       string      `json:"address"`
+	Latitude      *float64    `json:"latitude"`
+	Longitude     *float64    `json:"longitude"`
+	Items         []QuoteItem `json:"items"`
+	Subtotal      float64     `json:"subtotal"`
+	ServiceZoneID *int        `json:"service_zone_id"`
+	CallOutFee    float64     `json:"call_out_fee"`
+	Total         float64     `json:"total"`
+	Status        string      `json:"status"` // draft, sent, accepted, rejected
+	ValidUntil    *time.Time  `json:"valid_until"`
+	Notes         string      `json:"notes"`
+	WorkOrderID   *int        `json:"work_order_id"` // kabul edilip iş emrine dönüştürüldüyse
+	CreatedBy     int         `json:"created_by"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+}
+
+// QuoteItem teklif kalemi (işçilik, parça, montaj...)
+type QuoteItem struct {
+	Description string  `json:"description"`
+	Quantity    float64 `json:"quantity"`
+	UnitPrice   float64 `json:"unit_price"`
+}
+
+// Part yedek parça kataloğu kaydı
+type Part struct {
+	ID        int       `json:"id"`
+	SKU       string    `json:"sku"`
+	Barcode   string    `json:"barcode"`
+	Name      string    `json:"name"`
+	Unit      string    `json:"unit"`
+	CostPrice float64   `json:"cost_price"` // ağırlıklı ortalama maliyet
+	SalePrice float64   `json:"sale_price"`
+	MinStock  float64   `json:"min_stock"` // toplam stok bunun altına düşünce uyarı verilir
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// StockLocation depo veya teknisyen aracı
+type StockLocation struct {
+	ID           int       `json:"id"`
+	Name         string    `json:"name"`
+	Kind         string    `json:"kind"` // warehouse, van
+	TechnicianID *int      `json:"technician_id"`
+	Active       bool      `json:"active"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// StockMovement stok defterindeki değiştirilemez bir satır
+type StockMovement struct {
+	ID             int64     `json:"id"`
+	PartID         int       `json:"part_id"`
+	Kind           string    `json:"kind"` // purchase, transfer, use, return, adjustment
+	Quantity       float64   `json:"quantity"`
+	FromLocationID *int      `json:"from_location_id"`
+	ToLocationID   *int      `json:"to_location_id"`
+	WorkOrderID    *int      `json:"work_order_id"`
+	UnitCost       float64   `json:"unit_cost"`
+	Note           string    `json:"note"`
+	CreatedBy      int       `json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// StockLevel bir parçanın bir yerdeki güncel miktarı
+type StockLevel struct {
+	PartID       int     `json:"part_id"`
+	SKU          string  `json:"sku"`
+	Name         string  `json:"name"`
+	Unit         string  `json:"unit"`
+	LocationID   int     `json:"location_id"`
+	LocationName string  `json:"location_name"`
+	Quantity     float64 `json:"quantity"`
+	CostValue    float64 `json:"cost_value"`
+	SaleValue    float64 `json:"sale_value"`
+}
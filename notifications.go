@@ -0,0 +1,725 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"kozan/db"
+	"kozan/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// Bildirimler önce notifications tablosuna "queued" olarak yazılır, sonra
+// iş kuyruğu üzerinden gönderilir. Müşterinin sessiz saatlerine denk gelen
+// bildirimler sessiz saatlerin bitimine ertelenir.
+const (
+	notificationQueued  = "queued"
+	notificationSending = "sending"
+	notificationSent    = "sent"
+	notificationFailed  = "failed"
+	notificationSkipped = "skipped"
+
+	notificationMaxAttempts = 3
+)
+
+// Şablon anahtarları
+const (
+	templateAppointmentConfirmation = "appointment_confirmation"
+	templateTechnicianOnTheWay      = "technician_on_the_way"
+	templateMaintenanceReminder     = "maintenance_reminder"
+	templateNewLead                 = "new_lead"
+)
+
+// defaultNotificationTemplates ilk açılışta eklenir; sonrasında panelden düzenlenir.
+// Değişkenler {{.customer_name}} biçimindedir, tanımsız değişkenler boş yazılır.
+var defaultNotificationTemplates = []models.NotificationTemplate{
+	{
+		Key: templateAppointmentConfirmation, Channel: channelEmail,
+		Subject: "Servis randevunuz oluşturuldu (#{{.work_order_id}})",
+		Body: "Sayın {{.customer_name}},\n\n\"{{.work_order_title}}\" talebiniz için servis kaydınız oluşturuldu." +
+			"{{if .appointment}} Randevu zamanı: {{.appointment}}.{{end}}\n\n" +
+			"{{if .tracking_url}}Servis durumunu buradan takip edebilirsiniz: {{.tracking_url}}\n\n{{end}}" +
+			"Değişiklik için {{.company_phone}} numarasından bize ulaşabilirsiniz.",
+	},
+	{
+		Key: templateAppointmentConfirmation, Channel: channelSMS,
+		Body: "Sayın {{.customer_name}}, #{{.work_order_id}} numaralı servis kaydınız oluşturuldu.{{if .appointment}} Randevu: {{.appointment}}.{{end}}{{if .tracking_url}} Takip: {{.tracking_url}}{{end}} Bilgi: {{.company_phone}}",
+	},
+	{
+		Key: templateAppointmentConfirmation, Channel: channelWhatsApp,
+		WhatsAppTemplate: "appointment_confirmation",
+		WhatsAppParams:   []string{"customer_name", "work_order_id", "appointment", "tracking_url"},
+		Body:             "Sayın {{.customer_name}}, #{{.work_order_id}} numaralı servis kaydınız oluşturuldu.{{if .appointment}} Randevu: {{.appointment}}.{{end}}{{if .tracking_url}} Takip: {{.tracking_url}}{{end}}",
+	},
+	{
+		Key: templateTechnicianOnTheWay, Channel: channelEmail,
+		Subject: "Teknisyenimiz yola çıktı",
+		Body: "Sayın {{.customer_name}},\n\nTeknisyenimiz{{if .technician_name}} {{.technician_name}}{{end}} #{{.work_order_id}} numaralı servis için yola çıktı.{{if .eta}} Tahmini varış: {{.eta}}.{{end}}" +
+			"{{if .tracking_url}}\n\nCanlı takip: {{.tracking_url}}{{end}}",
+	},
+	{
+		Key: templateTechnicianOnTheWay, Channel: channelSMS,
+		Body: "Teknisyenimiz{{if .technician_name}} {{.technician_name}}{{end}} yola çıktı.{{if .eta}} Tahmini varış: {{.eta}}.{{end}}{{if .tracking_url}} Takip: {{.tracking_url}}{{end}} Bilgi: {{.company_phone}}",
+	},
+	{
+		Key: templateTechnicianOnTheWay, Channel: channelWhatsApp,
+		WhatsAppTemplate: "technician_on_the_way",
+		WhatsAppParams:   []string{"technician_name", "eta", "tracking_url"},
+		Body:             "Teknisyenimiz{{if .technician_name}} {{.technician_name}}{{end}} yola çıktı.{{if .eta}} Tahmini varış: {{.eta}}.{{end}}{{if .tracking_url}} Takip: {{.tracking_url}}{{end}}",
+	},
+	{
+		Key: templateMaintenanceReminder, Channel: channelEmail,
+		Subject: "Periyodik bakım zamanınız geldi",
+		Body: "Sayın {{.customer_name}},\n\nCihazınızın periyodik bakım zamanı geldi. Verimli çalışma ve arızaların önlenmesi için " +
+			"randevu almak üzere {{.company_phone}} numarasını arayabilir veya {{.site_url}} adresinden bize yazabilirsiniz.",
+	},
+	{
+		Key: templateMaintenanceReminder, Channel: channelSMS,
+		Body: "Sayın {{.customer_name}}, cihazınızın periyodik bakım zamanı geldi. Randevu için: {{.company_phone}}",
+	},
+	{
+		Key: templateMaintenanceReminder, Channel: channelWhatsApp,
+		WhatsAppTemplate: "maintenance_reminder",
+		WhatsAppParams:   []string{"customer_name"},
+		Body:             "Sayın {{.customer_name}}, cihazınızın periyodik bakım zamanı geldi. Randevu için bu mesajı yanıtlayabilirsiniz.",
+	},
+	{
+		Key: templateNewLead, Channel: channelEmail,
+		Subject: "Yeni iletişim mesajı: {{.name}}{{if .subject}} - {{.subject}}{{end}}",
+		Body: "Ad Soyad: {{.name}}\nE-posta: {{.email}}\nTelefon: {{.phone}}\nKonu: {{.subject}}\n\n{{.message}}\n\n" +
+			"Yönetim panelinde görüntüleyin: {{.site_url}}/admin (talep #{{.message_id}})",
+	},
+}
+
+var templateKeyRe = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+// seedNotificationTemplates eksik şablonları ekler. WhatsApp şablon adı
+// girilmemiş mevcut kayıtlara varsayılan ad ve parametreler yazılır; işletme
+// başlattığı WhatsApp mesajları onaylı şablon olmadan gönderilemez.
+func seedNotificationTemplates() error {
+	for _, t := range defaultNotificationTemplates {
+		_, err := db.DB.Exec(
+			`INSERT INTO notification_templates (key, channel, subject, body, whatsapp_template, whatsapp_params)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (key, channel) DO UPDATE SET
+				whatsapp_template = EXCLUDED.whatsapp_template, whatsapp_params = EXCLUDED.whatsapp_params
+			WHERE notification_templates.whatsapp_template = '' AND EXCLUDED.whatsapp_template <> ''`,
+			t.Key, t.Channel, t.Subject, t.Body, t.WhatsAppTemplate, pq.Array(t.WhatsAppParams),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseNotificationTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=zero").Parse(text)
+}
+
+func renderTemplate(name, text string, vars map[string]string) (string, error) {
+	tmpl, err := parseNotificationTemplate(name, text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+func getNotificationTemplate(key, channel string) (models.NotificationTemplate, error) {
+	var t models.NotificationTemplate
+	err := db.DB.QueryRow(
+		"SELECT key, channel, subject, body, whatsapp_template, whatsapp_params, updated_at FROM notification_templates WHERE key = $1 AND channel = $2",
+		key, channel,
+	).Scan(&t.Key, &t.Channel, &t.Subject, &t.Body, &t.WhatsAppTemplate, pq.Array(&t.WhatsAppParams), &t.UpdatedAt)
+	return t, err
+}
+
+// renderNotification şablonu değişkenlerle işler
+func renderNotification(t models.NotificationTemplate, vars map[string]string) (outboundMessage, error) {
+	var msg outboundMessage
+	var err error
+	if msg.Subject, err = renderTemplate(t.Key+".subject", t.Subject, vars); err != nil {
+		return msg, err
+	}
+	if msg.Body, err = renderTemplate(t.Key+".body", t.Body, vars); err != nil {
+		return msg, err
+	}
+	msg.WhatsAppTemplate = t.WhatsAppTemplate
+	for _, name := range t.WhatsAppParams {
+		// WhatsApp boş şablon parametresini reddeder
+		value := vars[name]
+		if value == "" {
+			value = "-"
+		}
+		msg.WhatsAppParams = append(msg.WhatsAppParams, value)
+	}
+	return msg, nil
+}
+
+// baseNotificationVars tüm şablonlarda kullanılabilen değişkenler
+func baseNotificationVars() map[string]string {
+	vars := map[string]string{"site_url": siteURL()}
+	if contact, err := getContact(); err == nil {
+		vars["company_phone"] = contact.Phone
+		vars["company_email"] = contact.Email
+	}
+	return vars
+}
+
+// defaultQuietHours NOTIFY_QUIET_HOURS ("21:00-09:00") değeridir; boş değer sessiz saati kapatır
+func defaultQuietHours() (string, string) {
+	value, ok := os.LookupEnv("NOTIFY_QUIET_HOURS")
+	if !ok {
+		value = "21:00-09:00"
+	}
+	start, end, _ := strings.Cut(value, "-")
+	return strings.TrimSpace(start), strings.TrimSpace(end)
+}
+
+// nextSendTime sessiz saatler içindeyse sessiz saatlerin bitişini, değilse now'ı döndürür.
+// Gece yarısını geçen aralıklar (21:00-09:00) desteklenir.
+func nextSendTime(now time.Time, quietStart, quietEnd string) time.Time {
+	start, err1 := parseClock(quietStart, false)
+	end, err2 := parseClock(quietEnd, false)
+	if err1 != nil || err2 != nil || start == end {
+		return now
+	}
+
+	local := now.In(businessLocation)
+	minute := local.Hour()*60 + local.Minute()
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, businessLocation)
+	switch {
+	case start < end && minute >= start && minute < end:
+		return midnight.Add(time.Duration(end) * time.Minute)
+	case start > end && minute >= start:
+		return midnight.AddDate(0, 0, 1).Add(time.Duration(end) * time.Minute)
+	case start > end && minute < end:
+		return midnight.Add(time.Duration(end) * time.Minute)
+	}
+	return now
+}
+
+func getNotificationPreferences(customerID int) (models.NotificationPreferences, error) {
+	var prefs models.NotificationPreferences
+	err := db.DB.QueryRow(
+		"SELECT notify_channels, quiet_start, quiet_end FROM customers WHERE id = $1", customerID,
+	).Scan(pq.Array(&prefs.Channels), &prefs.QuietStart, &prefs.QuietEnd)
+	if prefs.Channels == nil {
+		prefs.Channels = []string{}
+	}
+	return prefs, err
+}
+
+// notificationRequest bir müşteriye şablonlu bildirim isteğidir
+type notificationRequest struct {
+	CustomerID  int
+	WorkOrderID *int
+	Template    string
+	Vars        map[string]string
+}
+
+// notifyCustomer müşterinin tercih sırasındaki ilk uygun kanaldan bildirimi kuyruğa ekler.
+// Uygun kanal yoksa bildirim "skipped" olarak kaydedilir.
+func notifyCustomer(req notificationRequest) (models.Notification, error) {
+	n := models.Notification{CustomerID: &req.CustomerID, WorkOrderID: req.WorkOrderID, Template: req.Template}
+
+	customer, err := getCustomer(req.CustomerID)
+	if err != nil {
+		return n, err
+	}
+	prefs, err := getNotificationPreferences(req.CustomerID)
+	if err != nil {
+		return n, err
+	}
+
+	vars := baseNotificationVars()
+	vars["customer_name"] = customer.Name
+	for k, v := range req.Vars {
+		vars[k] = v
+	}
+
+	quietStart, quietEnd := defaultQuietHours()
+	if prefs.QuietStart != "" && prefs.QuietEnd != "" {
+		quietStart, quietEnd = prefs.QuietStart, prefs.QuietEnd
+	}
+
+	for _, channel := range prefs.Channels {
+		recipient := customer.Phone
+		if channel == channelEmail {
+			recipient = customer.Email
+		}
+		if recipient == "" {
+			continue
+		}
+		t, err := getNotificationTemplate(req.Template, channel)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return n, err
+		}
+		msg, err := renderNotification(t, vars)
+		if err != nil {
+			return n, err
+		}
+		msg.To = recipient
+		n.Channel = channel
+		return n, enqueueNotification(&n, msg, nextSendTime(time.Now(), quietStart, quietEnd))
+	}
+
+	n.Status = notificationSkipped
+	n.Error = "Müşterinin tercih ettiği kanallarda iletişim bilgisi veya şablon yok"
+	return n, insertNotification(db.DB, &n, outboundMessage{}, time.Now())
+}
+
+// notifyAddress ekibe giden bildirimler içindir; tercih ve sessiz saat uygulanmaz
+func notifyAddress(channel, recipient, key string, vars map[string]string) error {
+	t, err := getNotificationTemplate(key, channel)
+	if err != nil {
+		return fmt.Errorf("şablon bulunamadı: %s/%s", key, channel)
+	}
+	all := baseNotificationVars()
+	for k, v := range vars {
+		all[k] = v
+	}
+	msg, err := renderNotification(t, all)
+	if err != nil {
+		return err
+	}
+	msg.To = recipient
+	n := models.Notification{Channel: channel, Template: key}
+	return enqueueNotification(&n, msg, time.Now())
+}
+
+// enqueueNotification kaydı ve gönderim işini aynı transaction içinde ekler
+func enqueueNotification(n *models.Notification, msg outboundMessage, sendAfter time.Time) error {
+	n.Status = notificationQueued
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertNotification(tx, n, msg, sendAfter); err != nil {
+		return err
+	}
+	_, err = enqueueJob(tx, jobNotificationSend, notificationJob{NotificationID: n.ID}, jobOptions{
+		RunAt:       sendAfter,
+		MaxAttempts: notificationMaxAttempts,
+		UniqueKey:   "notification:" + strconv.Itoa(n.ID),
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertNotification(q queryRower, n *models.Notification, msg outboundMessage, sendAfter time.Time) error {
+	n.Recipient, n.Subject, n.Body, n.SendAfter = msg.To, msg.Subject, msg.Body, sendAfter
+	return q.QueryRow(
+		`INSERT INTO notifications (customer_id, work_order_id, channel, recipient, template, subject, body,
+			whatsapp_template, whatsapp_params, status, error, send_after)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id, created_at`,
+		n.CustomerID, n.WorkOrderID, n.Channel, n.Recipient, n.Template, n.Subject, n.Body,
+		msg.WhatsAppTemplate, pq.Array(msg.WhatsAppParams), n.Status, n.Error, sendAfter,
+	).Scan(&n.ID, &n.CreatedAt)
+}
+
+type notificationJob struct {
+	NotificationID int `json:"notification_id"`
+}
+
+// sendNotificationJob bildirimi kanalından gönderir. Hata dönerse iş kuyruğu
+// tekrar dener; son denemede bildirim "failed" olarak işaretlenir. Bildirim
+// gönderimden önce "sending" durumuna alınır: gönderim başarılı olup sonuç
+// yazılamazsa iş tekrar denense de mesaj ikinci kez gönderilmez.
+func sendNotificationJob(ctx context.Context, job models.Job, payload notificationJob) error {
+	var channel, status string
+	var msg outboundMessage
+	err := db.DB.QueryRow(
+		"SELECT channel, recipient, subject, body, whatsapp_template, whatsapp_params, status FROM notifications WHERE id = $1",
+		payload.NotificationID,
+	).Scan(&channel, &msg.To, &msg.Subject, &msg.Body, &msg.WhatsAppTemplate, pq.Array(&msg.WhatsAppParams), &status)
+	if err == sql.ErrNoRows || (err == nil && status != notificationQueued) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	notifier, ok := notifiers[channel]
+	if !ok {
+		sendErr := permanentJobError(fmt.Errorf("kanal yapılandırılmamış: %s", channel))
+		db.DB.Exec("UPDATE notifications SET status = $1, error = $2, attempts = $3 WHERE id = $4",
+			notificationFailed, sendErr.Error(), job.Attempts, payload.NotificationID)
+		return sendErr
+	}
+
+	res, err := db.DB.Exec("UPDATE notifications SET status = $1, attempts = $2 WHERE id = $3 AND status = $4",
+		notificationSending, job.Attempts, payload.NotificationID, notificationQueued)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+
+	providerID, sendErr := notifier.Send(ctx, msg)
+	if sendErr != nil {
+		status = notificationQueued
+		if job.Attempts >= job.MaxAttempts {
+			status = notificationFailed
+		}
+		if _, err := db.DB.Exec("UPDATE notifications SET status = $1, error = $2 WHERE id = $3",
+			status, sendErr.Error(), payload.NotificationID); err != nil {
+			log.Printf("notifications: #%d could not be returned to the queue: %v", payload.NotificationID, err)
+		}
+		return sendErr
+	}
+
+	_, err = db.DB.Exec(
+		"UPDATE notifications SET status = $1, error = '', provider_id = $2, sent_at = NOW() WHERE id = $3",
+		notificationSent, providerID, payload.NotificationID,
+	)
+	if err != nil {
+		// Mesaj gitti; tekrar denemek ikinci bir gönderim olur. Bildirim "sending" kalır.
+		log.Printf("notifications: #%d sent (provider id %q) but status update failed: %v", payload.NotificationID, providerID, err)
+		return permanentJobError(fmt.Errorf("gönderildi, durum yazılamadı: %w", err))
+	}
+	return nil
+}
+
+// notifyWorkOrder iş emrinin müşterisine iş emri değişkenleriyle bildirim gönderir
+func notifyWorkOrder(wo models.WorkOrder, key string, vars map[string]string) (models.Notification, error) {
+	all := workOrderVars(wo)
+	for k, v := range vars {
+		all[k] = v
+	}
+	return notifyCustomer(notificationRequest{CustomerID: wo.CustomerID, WorkOrderID: &wo.ID, Template: key, Vars: all})
+}
+
+type workOrderNotifyPayload struct {
+	WorkOrderID int    `json:"work_order_id"`
+	Template    string `json:"template"`
+}
+
+func workOrderNotifyJob(ctx context.Context, job models.Job, payload workOrderNotifyPayload) error {
+	wo, err := getWorkOrder(payload.WorkOrderID)
+	if err == sql.ErrNoRows {
+		return permanentJobError(fmt.Errorf("iş emri bulunamadı: %d", payload.WorkOrderID))
+	}
+	if err != nil {
+		return err
+	}
+	_, err = notifyWorkOrder(wo, payload.Template, nil)
+	return err
+}
+
+// maintenanceInterval son tamamlanan servisten sonra bakım hatırlatmasına kadar
+// geçen süredir (MAINTENANCE_REMINDER_DAYS, varsayılan 365, 0 = kapalı)
+func maintenanceInterval() time.Duration {
+	days := 365
+	if v := os.Getenv("MAINTENANCE_REMINDER_DAYS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			days = n
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// maintenanceBatchSize tek çalıştırmada hatırlatma gönderilen en fazla müşteri sayısı
+const maintenanceBatchSize = 500
+
+// sendMaintenanceReminders son tamamlanan servisinin üzerinden interval geçmiş,
+// açık iş emri olmayan ve o servisten sonra hatırlatma almamış müşterilere
+// maintenance_reminder bildirimini kuyruğa ekler. Kanal bulunamayan müşteriler
+// için de "skipped" kaydı yazıldığından aynı müşteri tekrar seçilmez.
+func sendMaintenanceReminders(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return nil
+	}
+	ids, err := selectIDs(db.DB,
+		`SELECT c.id FROM customers c
+		JOIN LATERAL (
+			SELECT MAX(COALESCE(completed_at, updated_at)) AS last_service
+			FROM work_orders WHERE customer_id = c.id AND status = 'completed'
+		) w ON TRUE
+		WHERE w.last_service < $1
+			AND NOT EXISTS (SELECT 1 FROM work_orders WHERE customer_id = c.id AND status NOT IN ('completed', 'cancelled'))
+			AND NOT EXISTS (SELECT 1 FROM notifications n WHERE n.customer_id = c.id AND n.template = $2 AND n.created_at > w.last_service)
+		ORDER BY c.id LIMIT $3`,
+		time.Now().Add(-interval), templateMaintenanceReminder, maintenanceBatchSize,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := notifyCustomer(notificationRequest{CustomerID: id, Template: templateMaintenanceReminder}); err != nil {
+			return fmt.Errorf("müşteri #%d: %w", id, err)
+		}
+	}
+	if len(ids) > 0 {
+		log.Printf("notifications: %d maintenance reminders queued", len(ids))
+	}
+	return nil
+}
+
+// Bildirim şablonu işlemleri
+func getNotificationTemplatesHandler(c *gin.Context) {
+	rows, err := db.DB.Query("SELECT key, channel, subject, body, whatsapp_template, whatsapp_params, updated_at FROM notification_templates ORDER BY key, channel")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	templates := []models.NotificationTemplate{}
+	for rows.Next() {
+		var t models.NotificationTemplate
+		if err := rows.Scan(&t.Key, &t.Channel, &t.Subject, &t.Body, &t.WhatsAppTemplate, pq.Array(&t.WhatsAppParams), &t.UpdatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		templates = append(templates, t)
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+// updateNotificationTemplateHandler şablonu oluşturur veya günceller
+func updateNotificationTemplateHandler(c *gin.Context) {
+	var t models.NotificationTemplate
+	if err := c.ShouldBindJSON(&t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	t.Key = c.Param("key")
+	t.Channel = c.Param("channel")
+	if !templateKeyRe.MatchString(t.Key) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz şablon anahtarı"})
+		return
+	}
+	if !notificationChannels[t.Channel] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz kanal: " + t.Channel})
+		return
+	}
+	if strings.TrimSpace(t.Body) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Metin zorunludur"})
+		return
+	}
+	if t.Channel == channelEmail && strings.TrimSpace(t.Subject) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "E-posta şablonunda konu zorunludur"})
+		return
+	}
+	for _, text := range []string{t.Subject, t.Body} {
+		if _, err := parseNotificationTemplate(t.Key, text); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Şablon hatası: " + err.Error()})
+			return
+		}
+	}
+	if t.WhatsAppParams == nil {
+		t.WhatsAppParams = []string{}
+	}
+
+	before, _ := getNotificationTemplate(t.Key, t.Channel)
+	err := db.DB.QueryRow(
+		`INSERT INTO notification_templates (key, channel, subject, body, whatsapp_template, whatsapp_params)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (key, channel) DO UPDATE SET subject = EXCLUDED.subject, body = EXCLUDED.body,
+			whatsapp_template = EXCLUDED.whatsapp_template, whatsapp_params = EXCLUDED.whatsapp_params, updated_at = NOW()
+		RETURNING updated_at`,
+		t.Key, t.Channel, t.Subject, t.Body, t.WhatsAppTemplate, pq.Array(t.WhatsAppParams),
+	).Scan(&t.UpdatedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, "notification_template", 0, auditUpdate, before, t)
+	c.JSON(http.StatusOK, t)
+}
+
+const notificationColumns = "id, customer_id, work_order_id, channel, recipient, template, subject, body, status, error, provider_id, attempts, send_after, sent_at, created_at"
+
+func scanNotification(scanner interface{ Scan(...interface{}) error }) (models.Notification, error) {
+	var n models.Notification
+	var customerID, workOrderID sql.NullInt64
+	var sentAt sql.NullTime
+	err := scanner.Scan(&n.ID, &customerID, &workOrderID, &n.Channel, &n.Recipient, &n.Template, &n.Subject, &n.Body,
+		&n.Status, &n.Error, &n.ProviderID, &n.Attempts, &n.SendAfter, &sentAt, &n.CreatedAt)
+	if customerID.Valid {
+		v := int(customerID.Int64)
+		n.CustomerID = &v
+	}
+	if workOrderID.Valid {
+		v := int(workOrderID.Int64)
+		n.WorkOrderID = &v
+	}
+	if sentAt.Valid {
+		n.SentAt = &sentAt.Time
+	}
+	return n, err
+}
+
+// getNotificationsHandler teslim günlüğü
+func getNotificationsHandler(c *gin.Context) {
+	query := "SELECT " + notificationColumns + " FROM notifications WHERE 1=1"
+	var args []interface{}
+	for param, column := range map[string]string{"status": "status", "channel": "channel", "customer_id": "customer_id", "work_order_id": "work_order_id"} {
+		if v := c.Query(param); v != "" {
+			args = append(args, v)
+			query += fmt.Sprintf(" AND %s = $%d", column, len(args))
+		}
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if offset < 0 {
+		offset = 0
+	}
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT %d OFFSET %d", limit, offset)
+
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		notifications = append(notifications, n)
+	}
+
+	c.JSON(http.StatusOK, notifications)
+}
+
+func getNotificationPreferencesHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	prefs, err := getNotificationPreferences(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Müşteri bulunamadı"})
+		return
+	}
+	c.JSON(http.StatusOK, prefs)
+}
+
+// updateNotificationPreferencesHandler kanal sırası ve sessiz saatleri kaydeder.
+// Boş kanal listesi müşteriye bildirim gönderilmeyeceği anlamına gelir.
+func updateNotificationPreferencesHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var prefs models.NotificationPreferences
+	if err := c.ShouldBindJSON(&prefs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	seen := make(map[string]bool)
+	for _, ch := range prefs.Channels {
+		if !notificationChannels[ch] || seen[ch] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz kanal: " + ch})
+			return
+		}
+		seen[ch] = true
+	}
+	if prefs.Channels == nil {
+		prefs.Channels = []string{}
+	}
+	if (prefs.QuietStart == "") != (prefs.QuietEnd == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Sessiz saat başlangıcı ve bitişi birlikte girilmeli"})
+		return
+	}
+	for _, v := range []string{prefs.QuietStart, prefs.QuietEnd} {
+		if v == "" {
+			continue
+		}
+		if _, err := parseClock(v, false); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	before, err := getNotificationPreferences(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Müşteri bulunamadı"})
+		return
+	}
+	_, err = db.DB.Exec(
+		"UPDATE customers SET notify_channels = $1, quiet_start = $2, quiet_end = $3, updated_at = NOW() WHERE id = $4",
+		pq.Array(prefs.Channels), prefs.QuietStart, prefs.QuietEnd, id,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, "customer_notification_preferences", id, auditUpdate, before, prefs)
+	c.JSON(http.StatusOK, prefs)
+}
+
+// notifyWorkOrderHandler iş emrinin müşterisine elle bildirim gönderir
+func notifyWorkOrderHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var input struct {
+		Template string            `json:"template"`
+		Vars     map[string]string `json:"vars"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || input.Template == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Şablon zorunludur"})
+		return
+	}
+
+	wo, err := getWorkOrder(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "İş emri bulunamadı"})
+		return
+	}
+	n, err := notifyWorkOrder(wo, input.Template, input.Vars)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, n)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextSendTime(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 3, day, hour, minute, 0, 0, businessLocation)
+	}
+	tests := []struct {
+		name       string
+		now        time.Time
+		start, end string
+		want       time.Time
+	}{
+		{"gündüz sessiz saat dışında", at(10, 14, 0), "21:00", "09:00", at(10, 14, 0)},
+		{"gece yarısından önce", at(10, 22, 30), "21:00", "09:00", at(11, 9, 0)},
+		{"gece yarısından sonra", at(10, 3, 15), "21:00", "09:00", at(10, 9, 0)},
+		{"bitiş anı", at(10, 9, 0), "21:00", "09:00", at(10, 9, 0)},
+		{"başlangıç anı", at(10, 21, 0), "21:00", "09:00", at(11, 9, 0)},
+		{"aynı gün içinde aralık", at(10, 13, 30), "13:00", "14:00", at(10, 14, 0)},
+		{"aynı gün aralık dışında", at(10, 12, 59), "13:00", "14:00", at(10, 12, 59)},
+		{"kapalı", at(10, 23, 0), "", "", at(10, 23, 0)},
+		{"eşit uçlar", at(10, 23, 0), "09:00", "09:00", at(10, 23, 0)},
+		{"geçersiz saat", at(10, 23, 0), "25:00", "09:00", at(10, 23, 0)},
+	}
+	for _, tt := range tests {
+		if got := nextSendTime(tt.now, tt.start, tt.end); !got.Equal(tt.want) {
+			t.Errorf("%s: nextSendTime(%v, %q, %q) = %v, want %v", tt.name, tt.now, tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+func TestMaskRecipient(t *testing.T) {
+	tests := []struct {
+		to   string
+		want string
+	}{
+		{"ali@ornek.com", "a***@ornek.com"},
+		{"@ornek.com", "***@ornek.com"},
+		{"+905385153191", "***91"},
+		{"12", "***"},
+		{"", "***"},
+	}
+
+	for _, tt := range tests {
+		if got := maskRecipient(tt.to); got != tt.want {
+			t.Errorf("maskRecipient(%q) = %q, want %q", tt.to, got, tt.want)
+		}
+	}
+}
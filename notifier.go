@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Bildirim kanalları
+const (
+	channelEmail    = "email"
+	channelSMS      = "sms"
+	channelWhatsApp = "whatsapp"
+)
+
+var notificationChannels = map[string]bool{channelEmail: true, channelSMS: true, channelWhatsApp: true}
+
+// outboundMessage bir kanala gönderilecek, şablonu işlenmiş mesajdır
+type outboundMessage struct {
+	To      string
+	Subject string
+	Body    string
+	// WhatsApp'ta işletme tarafından başlatılan mesajlar onaylı şablon ister
+	WhatsAppTemplate string
+	WhatsAppParams   []string
+}
+
+// Notifier tek bir kanaldan mesaj gönderir ve sağlayıcının mesaj ID'sini döndürür
+type Notifier interface {
+	Send(ctx context.Context, msg outboundMessage) (string, error)
+}
+
+// notifiers kanal → gönderici. Sağlayıcı ayarı olmayan kanallar logNotifier kullanır.
+var notifiers = map[string]Notifier{}
+
+func initNotifiers() {
+	notifiers[channelEmail] = &logNotifier{channel: channelEmail}
+	if os.Getenv("SMTP_HOST") != "" {
+		notifiers[channelEmail] = smtpNotifier{}
+	}
+
+	notifiers[channelSMS] = &logNotifier{channel: channelSMS}
+	switch provider := strings.ToLower(os.Getenv("SMS_PROVIDER")); provider {
+	case "", "log":
+	case "twilio":
+		sid, token, from := os.Getenv("TWILIO_ACCOUNT_SID"), os.Getenv("TWILIO_AUTH_TOKEN"), os.Getenv("SMS_FROM")
+		if sid == "" || token == "" || from == "" {
+			log.Fatal("TWILIO_ACCOUNT_SID, TWILIO_AUTH_TOKEN ve SMS_FROM zorunludur")
+		}
+		notifiers[channelSMS] = &twilioNotifier{accountSID: sid, authToken: token, from: from, client: providerClient()}
+	default:
+		log.Fatalf("Bilinmeyen SMS_PROVIDER: %s", provider)
+	}
+
+	notifiers[channelWhatsApp] = &logNotifier{channel: channelWhatsApp}
+	if token, phoneID := os.Getenv("WHATSAPP_TOKEN"), os.Getenv("WHATSAPP_PHONE_NUMBER_ID"); token != "" && phoneID != "" {
+		notifiers[channelWhatsApp] = &whatsAppNotifier{token: token, phoneNumberID: phoneID, client: providerClient()}
+	}
+}
+
+func providerClient() *http.Client {
+	return &http.Client{Timeout: 15 * time.Second}
+}
+
+// logNotifier geliştirme ve test için sahte kanaldır: mesajı gönderilmiş sayar ve loglar.
+// Loglar kişisel veri taşımasın diye alıcı maskelenir, konu ve metin yazılmaz.
+type logNotifier struct {
+	channel string
+	sent    int64
+}
+
+func (n *logNotifier) Send(ctx context.Context, msg outboundMessage) (string, error) {
+	id := atomic.AddInt64(&n.sent, 1)
+	log.Printf("notify (%s, log only) #%d to=%s template=%q body=%d bytes",
+		n.channel, id, maskRecipient(msg.To), msg.WhatsAppTemplate, len(msg.Body))
+	return fmt.Sprintf("log-%s-%d", n.channel, id), nil
+}
+
+// maskRecipient e-postada ilk harf ve alan adını, telefonda son iki haneyi bırakır
+func maskRecipient(to string) string {
+	if local, domain, ok := strings.Cut(to, "@"); ok {
+		if local == "" {
+			return "***@" + domain
+		}
+		return local[:1] + "***@" + domain
+	}
+	if len(to) <= 2 {
+		return "***"
+	}
+	return "***" + to[len(to)-2:]
+}
+
+// smtpNotifier e-postayı SMTP_* ayarlarıyla gönderir
+type smtpNotifier struct{}
+
+func (smtpNotifier) Send(ctx context.Context, msg outboundMessage) (string, error) {
+	return "", sendMail(msg.To, msg.Subject, msg.Body)
+}
+
+// twilioNotifier Twilio Messages API ile SMS gönderir
+type twilioNotifier struct {
+	accountSID string
+	authToken  string
+	from       string
+	client     *http.Client
+}
+
+func (n *twilioNotifier) Send(ctx context.Context, msg outboundMessage) (string, error) {
+	form := url.Values{"To": {msg.To}, "From": {n.from}, "Body": {msg.Body}}
+	endpoint := "https://api.twilio.com/2010-04-01/Accounts/" + n.accountSID + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(n.accountSID, n.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var result struct {
+		SID     string `json:"sid"`
+		Message string `json:"message"`
+	}
+	if err := doProviderRequest(n.client, req, &result); err != nil {
+		if result.Message != "" {
+			return "", fmt.Errorf("twilio: %s", result.Message)
+		}
+		return "", fmt.Errorf("twilio: %w", err)
+	}
+	return result.SID, nil
+}
+
+// whatsAppNotifier WhatsApp Business Cloud API ile mesaj gönderir
+type whatsAppNotifier struct {
+	token         string
+	phoneNumberID string
+	client        *http.Client
+}
+
+func (n *whatsAppNotifier) Send(ctx context.Context, msg outboundMessage) (string, error) {
+	payload := map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"to":                strings.TrimPrefix(msg.To, "+"),
+	}
+	if msg.WhatsAppTemplate != "" {
+		params := make([]map[string]string, len(msg.WhatsAppParams))
+		for i, p := range msg.WhatsAppParams {
+			params[i] = map[string]string{"type": "text", "text": p}
+		}
+		payload["type"] = "template"
+		payload["template"] = map[string]interface{}{
+			"name":       msg.WhatsAppTemplate,
+			"language":   map[string]string{"code": "tr"},
+			"components": []interface{}{map[string]interface{}{"type": "body", "parameters": params}},
+		}
+	} else {
+		payload["type"] = "text"
+		payload["text"] = map[string]string{"body": msg.Body}
+	}
+
+	body, _ := json.Marshal(payload)
+	endpoint := "https://graph.facebook.com/v20.0/" + n.phoneNumberID + "/messages"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+n.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	var result struct {
+		Messages []struct {
+			ID string `json:"id"`
+		} `json:"messages"`
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := doProviderRequest(n.client, req, &result); err != nil {
+		if result.Error.Message != "" {
+			return "", fmt.Errorf("whatsapp: %s", result.Error.Message)
+		}
+		return "", fmt.Errorf("whatsapp: %w", err)
+	}
+	if len(result.Messages) == 0 {
+		return "", nil
+	}
+	return result.Messages[0].ID, nil
+}
+
+// doProviderRequest isteği gönderir ve JSON yanıtı out'a okur; 2xx dışı yanıtlar hatadır
+func doProviderRequest(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	json.Unmarshal(data, out)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,566 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"kozan/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// oidcConfig OIDC_* ortam değişkenlerinden okunur
+type oidcConfig struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	scopes       string
+	groupsClaim  string
+	roleMap      map[string]int
+	postLoginURL string
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcProvider discovery dokümanını ve IdP anahtarlarını önbellekte tutar
+type oidcProvider struct {
+	config oidcConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]interface{}
+	keysAt    time.Time
+}
+
+var oidc *oidcProvider
+
+// initOIDC OIDC_ISSUER tanımlıysa SSO'yu etkinleştirir
+func initOIDC() {
+	issuer := os.Getenv("OIDC_ISSUER")
+	if issuer == "" {
+		return
+	}
+
+	config := oidcConfig{
+		issuer:       strings.TrimSuffix(issuer, "/"),
+		clientID:     os.Getenv("OIDC_CLIENT_ID"),
+		clientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		redirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		scopes:       os.Getenv("OIDC_SCOPES"),
+		groupsClaim:  os.Getenv("OIDC_GROUPS_CLAIM"),
+		roleMap:      make(map[string]int),
+		postLoginURL: os.Getenv("OIDC_POST_LOGIN_URL"),
+	}
+	if config.clientID == "" || config.redirectURL == "" {
+		log.Fatal("OIDC_CLIENT_ID ve OIDC_REDIRECT_URL zorunludur")
+	}
+	if config.scopes == "" {
+		config.scopes = "openid email profile"
+	}
+	if config.groupsClaim == "" {
+		config.groupsClaim = "groups"
+	}
+	if config.postLoginURL == "" {
+		config.postLoginURL = "http://admin.localhost:5173/"
+	}
+
+	// OIDC_ROLE_MAP örneği: "ofis-yonetici=1,ofis=2"
+	for _, pair := range strings.Split(os.Getenv("OIDC_ROLE_MAP"), ",") {
+		group, role, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+		roleID, err := strconv.Atoi(role)
+		if err != nil {
+			log.Fatalf("OIDC_ROLE_MAP geçersiz rol: %s", pair)
+		}
+		config.roleMap[group] = roleID
+	}
+	if len(config.roleMap) == 0 {
+		log.Fatal("OIDC_ROLE_MAP en az bir grup içermeli")
+	}
+
+	oidc = &oidcProvider{config: config, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (p *oidcProvider) getDiscovery() (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var d oidcDiscovery
+	if err := p.getJSON(p.config.issuer+"/.well-known/openid-configuration", &d); err != nil {
+		return nil, err
+	}
+	if strings.TrimSuffix(d.Issuer, "/") != p.config.issuer {
+		return nil, fmt.Errorf("issuer mismatch: %s", d.Issuer)
+	}
+	p.discovery = &d
+	return p.discovery, nil
+}
+
+func (p *oidcProvider) getJSON(url string, v interface{}) error {
+	resp, err := p.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// key kid'e karşılık gelen IdP anahtarını döndürür, bilinmiyorsa JWKS'i yeniler
+func (p *oidcProvider) key(kid string) (interface{}, error) {
+	d, err := p.getDiscovery()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	// IdP'yi bilinmeyen kid'lerle boğmamak için
+	if time.Since(p.keysAt) < 10*time.Second {
+		return nil, fmt.Errorf("unknown kid: %s", kid)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.getJSON(d.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]interface{})
+	for _, jwk := range set.Keys {
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	p.keys = keys
+	p.keysAt = time.Now()
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown kid: %s", kid)
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve: %s", k.Crv)
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if k.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("unsupported curve: %s", k.Crv)
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type: %s", k.Kty)
+}
+
+// verifyIDToken imzayı, issuer, audience, süre ve nonce değerlerini doğrular
+func (p *oidcProvider) verifyIDToken(idToken, nonce string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(idToken, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "ES256", "EdDSA"}),
+		jwt.WithIssuer(p.config.issuer),
+		jwt.WithAudience(p.config.clientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	if claims["nonce"] != nonce {
+		return nil, fmt.Errorf("nonce mismatch")
+	}
+	return claims, nil
+}
+
+// mapRole kullanıcının gruplarından en yetkili rolü seçer (küçük numara = daha yetkili)
+func (p *oidcProvider) mapRole(claims jwt.MapClaims) (int, bool) {
+	groups, _ := claims[p.config.groupsClaim].([]interface{})
+	roleID := 0
+	for _, g := range groups {
+		name, _ := g.(string)
+		if role, ok := p.config.roleMap[name]; ok && (roleID == 0 || role < roleID) {
+			roleID = role
+		}
+	}
+	return roleID, roleID != 0
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+const oidcStateCookie = "oidc_state"
+
+// authorizationURL state, nonce ve PKCE verifier'ı imzalı bir çerezde saklar ve
+// kimlik sağlayıcının yetkilendirme adresini döndürür. Sunucuda durum tutulmaz.
+func authorizationURL(c *gin.Context, extra jwt.MapClaims) (string, error) {
+	d, err := oidc.getDiscovery()
+	if err != nil {
+		return "", err
+	}
+
+	state, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+	verifier, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+
+	claims := jwt.MapClaims{
+		"state":    state,
+		"nonce":    nonce,
+		"verifier": verifier,
+	}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	cookie, err := signPurposeToken("oidc_state", claims, 10*time.Minute)
+	if err != nil {
+		return "", err
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, cookie, 600, "/api/auth/oidc", "", c.Request.TLS != nil, true)
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {oidc.config.clientID},
+		"redirect_uri":          {oidc.config.redirectURL},
+		"scope":                 {oidc.config.scopes},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {pkceChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	return d.AuthorizationEndpoint + "?" + query.Encode(), nil
+}
+
+// SSO işlemleri
+func oidcLoginHandler(c *gin.Context) {
+	target, err := authorizationURL(c, nil)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Kimlik sağlayıcıya ulaşılamadı"})
+		return
+	}
+	c.Redirect(http.StatusFound, target)
+}
+
+// oidcLinkHandler oturum açmış kullanıcının mevcut hesabını SSO kimliğine bağlamak
+// için akışı başlatır. Hesaplar e-posta eşleşmesiyle asla otomatik bağlanmaz.
+// Tarayıcı yönlendirmesi Authorization başlığı taşıyamadığı için adres JSON ile döner.
+func oidcLinkHandler(c *gin.Context) {
+	if _, viaKey := c.Get("api_key_id"); viaKey {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Hesap bağlama API anahtarıyla yapılamaz"})
+		return
+	}
+	target, err := authorizationURL(c, jwt.MapClaims{"link_user_id": currentUserID(c)})
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Kimlik sağlayıcıya ulaşılamadı"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"url": target})
+}
+
+func oidcCallbackHandler(c *gin.Context) {
+	if e := c.Query("error"); e != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Giriş reddedildi: " + e})
+		return
+	}
+
+	cookie, err := c.Cookie(oidcStateCookie)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Oturum süresi doldu, tekrar deneyin"})
+		return
+	}
+	c.SetCookie(oidcStateCookie, "", -1, "/api/auth/oidc", "", c.Request.TLS != nil, true)
+
+	stateClaims, err := parsePurposeToken("oidc_state", cookie)
+	if err != nil || stateClaims["state"] != c.Query("state") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz state"})
+		return
+	}
+
+	d, err := oidc.getDiscovery()
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Kimlik sağlayıcıya ulaşılamadı"})
+		return
+	}
+
+	// Authorization code'u token ile değiştir
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {c.Query("code")},
+		"redirect_uri":  {oidc.config.redirectURL},
+		"client_id":     {oidc.config.clientID},
+		"code_verifier": {stateClaims["verifier"].(string)},
+	}
+	if oidc.config.clientSecret != "" {
+		form.Set("client_secret", oidc.config.clientSecret)
+	}
+	resp, err := oidc.client.PostForm(d.TokenEndpoint, form)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Kimlik sağlayıcıya ulaşılamadı"})
+		return
+	}
+	defer resp.Body.Close()
+
+	var tokenResp struct {
+		IDToken string `json:"id_token"`
+	}
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&tokenResp) != nil || tokenResp.IDToken == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token alınamadı"})
+		return
+	}
+
+	claims, err := oidc.verifyIDToken(tokenResp.IDToken, stateClaims["nonce"].(string))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Geçersiz ID token"})
+		return
+	}
+
+	roleID, ok := oidc.mapRole(claims)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Bu hesabın yönetim paneline erişimi yok"})
+		return
+	}
+
+	var userID int
+	var username string
+	if linkUserID, ok := stateClaims["link_user_id"].(float64); ok {
+		userID, username, roleID, err = linkOIDCUser(claims, int(linkUserID))
+	} else {
+		userID, username, roleID, err = provisionOIDCUser(claims, roleID)
+	}
+	switch {
+	case errors.Is(err, errOIDCAccountConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, errOIDCClaims):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Kullanıcı oluşturulamadı: " + err.Error()})
+		return
+	}
+
+	tokenString, err := signToken(jwt.MapClaims{
+		"username": username,
+		"user_id":  userID,
+		"role_id":  roleID,
+		"exp":      time.Now().Add(time.Hour * 24).Unix(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Token oluşturulamadı"})
+		return
+	}
+
+	// Token fragment'ta taşınır, sunucu loglarına düşmez
+	c.Redirect(http.StatusFound, oidc.config.postLoginURL+"#token="+url.QueryEscape(tokenString))
+}
+
+var (
+	errOIDCClaims          = errors.New("geçersiz kimlik bilgisi")
+	errOIDCAccountConflict = errors.New("hesap çakışması")
+)
+
+// oidcIdentity sub ve doğrulanmış e-posta claim'lerini döndürür. email_verified
+// claim'i açıkça true olmalıdır.
+func oidcIdentity(claims jwt.MapClaims) (string, string, error) {
+	subject, _ := claims["sub"].(string)
+	email, _ := claims["email"].(string)
+	if subject == "" || email == "" {
+		return "", "", fmt.Errorf("%w: sub ve email claim'leri zorunludur", errOIDCClaims)
+	}
+	if verified, _ := claims["email_verified"].(bool); !verified {
+		return "", "", fmt.Errorf("%w: e-posta doğrulanmamış", errOIDCClaims)
+	}
+	return subject, email, nil
+}
+
+// isUniqueViolation hatanın bir unique kısıtı ihlali (23505) olup olmadığını bildirir
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// provisionOIDCUser kullanıcıyı sadece issuer + subject ile bulur, yoksa oluşturur.
+// SSO ile oluşturulan hesapların rolü her girişte IdP gruplarına göre güncellenir.
+// E-postası yerel bir hesaba ait kimlik reddedilir; hesap sahibi oturum açıp
+// bağlamalıdır. Token'a yazılacak güncel rol döner.
+func provisionOIDCUser(claims jwt.MapClaims, roleID int) (int, string, int, error) {
+	subject, email, err := oidcIdentity(claims)
+	if err != nil {
+		return 0, "", 0, err
+	}
+
+	var userID int
+	var username string
+	var linked bool
+	err = db.DB.QueryRow(
+		"SELECT id, username, oidc_provisioned FROM users WHERE oidc_issuer = $1 AND oidc_subject = $2",
+		oidc.config.issuer, subject,
+	).Scan(&userID, &username, &linked)
+
+	if err == nil {
+		// Elle bağlanmış yerel hesapların rolü panelden yönetilir
+		if !linked {
+			err = db.DB.QueryRow("SELECT role_id FROM users WHERE id = $1", userID).Scan(&roleID)
+			return userID, username, roleID, err
+		}
+		_, err = db.DB.Exec("UPDATE users SET role_id = $1, email = $2 WHERE id = $3", roleID, email, userID)
+		if isUniqueViolation(err) {
+			return 0, "", 0, fmt.Errorf("%w: e-posta başka bir hesapta kayıtlı", errOIDCAccountConflict)
+		}
+		return userID, username, roleID, err
+	}
+	if err != sql.ErrNoRows {
+		return 0, "", 0, err
+	}
+
+	var taken bool
+	if err := db.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM users WHERE email = $1)", email).Scan(&taken); err != nil {
+		return 0, "", 0, err
+	}
+	if taken {
+		return 0, "", 0, fmt.Errorf("%w: bu e-posta yerel bir hesaba ait; hesabınızla giriş yapıp SSO'yu bağlayın", errOIDCAccountConflict)
+	}
+
+	// Yeni kullanıcı: şifre ile giriş yapamaması için rastgele bir şifre hash'lenir
+	secret, err := randomHex(32)
+	if err != nil {
+		return 0, "", 0, err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return 0, "", 0, err
+	}
+
+	base, _ := claims["preferred_username"].(string)
+	if base == "" {
+		base, _, _ = strings.Cut(email, "@")
+	}
+	username = base
+	for i := 2; ; i++ {
+		err = db.DB.QueryRow(
+			`INSERT INTO users (username, email, password, role_id, oidc_issuer, oidc_subject, oidc_provisioned)
+			VALUES ($1, $2, $3, $4, $5, $6, TRUE) ON CONFLICT (username) DO NOTHING RETURNING id`,
+			username, email, string(hashedPassword), roleID, oidc.config.issuer, subject,
+		).Scan(&userID)
+		if err != sql.ErrNoRows || i > 20 {
+			break
+		}
+		username = fmt.Sprintf("%s%d", base, i)
+	}
+	// Kontrol ile ekleme arasında aynı e-postayla hesap oluşturulmuş olabilir
+	if isUniqueViolation(err) {
+		return 0, "", 0, fmt.Errorf("%w: e-posta başka bir hesapta kayıtlı", errOIDCAccountConflict)
+	}
+	return userID, username, roleID, err
+}
+
+// linkOIDCUser SSO kimliğini oturum açmış kullanıcının hesabına bağlar. Rol değişmez.
+func linkOIDCUser(claims jwt.MapClaims, userID int) (int, string, int, error) {
+	subject, _, err := oidcIdentity(claims)
+	if err != nil {
+		return 0, "", 0, err
+	}
+
+	var username string
+	var roleID int
+	err = db.DB.QueryRow(
+		`UPDATE users SET oidc_issuer = $1, oidc_subject = $2, oidc_provisioned = FALSE
+		WHERE id = $3 AND (oidc_subject IS NULL OR (oidc_issuer = $1 AND oidc_subject = $2))
+		RETURNING username, role_id`,
+		oidc.config.issuer, subject, userID,
+	).Scan(&username, &roleID)
+	if isUniqueViolation(err) {
+		return 0, "", 0, fmt.Errorf("%w: bu SSO kimliği başka bir hesaba bağlı", errOIDCAccountConflict)
+	}
+	if err == sql.ErrNoRows {
+		return 0, "", 0, fmt.Errorf("%w: hesap zaten başka bir SSO kimliğine bağlı", errOIDCAccountConflict)
+	}
+	return userID, username, roleID, err
+}
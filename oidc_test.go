@@ -0,0 +1,158 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"kozan/mockoidc"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oidcTestServer sahte sağlayıcıyı ve giriş/callback uç noktalarını ayağa kaldırır.
+// Sağlayıcının verdiği grup eşlenmediği için başarılı bir akış, veritabanına
+// inmeden rol kontrolünde 403 ile biter.
+func oidcTestServer(t *testing.T) (*httptest.Server, *http.Client) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	dir := t.TempDir()
+	if _, err := generateKey(dir); err != nil {
+		t.Fatal(err)
+	}
+	savedKeys, savedOIDC := jwtKeys, oidc
+	t.Cleanup(func() { jwtKeys, oidc = savedKeys, savedOIDC })
+	jwtKeys = &keyRing{dir: dir}
+	if err := jwtKeys.load(); err != nil {
+		t.Fatal(err)
+	}
+
+	idp := httptest.NewUnstartedServer(nil)
+	idp.Start()
+	t.Cleanup(idp.Close)
+	handler, err := (&mockoidc.Provider{Issuer: idp.URL, Email: "ofis@klimakozan.com", Groups: []string{"misafir"}}).Handler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp.Config.Handler = handler
+
+	r := gin.New()
+	r.GET("/api/auth/oidc/login", oidcLoginHandler)
+	r.GET("/api/auth/oidc/callback", oidcCallbackHandler)
+	app := httptest.NewServer(r)
+	t.Cleanup(app.Close)
+
+	oidc = &oidcProvider{
+		config: oidcConfig{
+			issuer:      idp.URL,
+			clientID:    "kozan",
+			redirectURL: app.URL + "/api/auth/oidc/callback",
+			scopes:      "openid email profile",
+			groupsClaim: "groups",
+			roleMap:     map[string]int{"ofis": roleEditor},
+		},
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+
+	// Yönlendirmeler elle takip edilir; çerezler istemci tarafında tutulur
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	return app, client
+}
+
+// oidcAuthorize girişi başlatır ve sağlayıcının callback adresini döndürür
+func oidcAuthorize(t *testing.T, app *httptest.Server, client *http.Client) (string, *http.Cookie) {
+	t.Helper()
+	resp, err := client.Get(app.URL + "/api/auth/oidc/login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("login status = %d", resp.StatusCode)
+	}
+	var state *http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == oidcStateCookie {
+			state = c
+		}
+	}
+	if state == nil {
+		t.Fatal("state çerezi yok")
+	}
+
+	resp, err = client.Get(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	callback := resp.Header.Get("Location")
+	if resp.StatusCode != http.StatusFound || !strings.HasPrefix(callback, app.URL+"/api/auth/oidc/callback?") {
+		t.Fatalf("authorize = %d %q", resp.StatusCode, callback)
+	}
+	return callback, state
+}
+
+func oidcCallback(t *testing.T, client *http.Client, callback string, state *http.Cookie) int {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, callback, nil)
+	if state != nil {
+		req.AddCookie(state)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestOIDCLoginFlow(t *testing.T) {
+	app, client := oidcTestServer(t)
+
+	t.Run("kod değişimi ve ID token doğrulanır", func(t *testing.T) {
+		callback, state := oidcAuthorize(t, app, client)
+		if status := oidcCallback(t, client, callback, state); status != http.StatusForbidden {
+			t.Errorf("callback status = %d, want %d", status, http.StatusForbidden)
+		}
+		// Kod tek kullanımlıktır
+		if status := oidcCallback(t, client, callback, state); status != http.StatusUnauthorized {
+			t.Errorf("tekrar callback status = %d, want %d", status, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("state çerezi olmadan", func(t *testing.T) {
+		callback, _ := oidcAuthorize(t, app, client)
+		if status := oidcCallback(t, client, callback, nil); status != http.StatusBadRequest {
+			t.Errorf("callback status = %d, want %d", status, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("başka akışın state değeri", func(t *testing.T) {
+		callback, _ := oidcAuthorize(t, app, client)
+		_, other := oidcAuthorize(t, app, client)
+		if status := oidcCallback(t, client, callback, other); status != http.StatusBadRequest {
+			t.Errorf("callback status = %d, want %d", status, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("PKCE verifier eşleşmezse", func(t *testing.T) {
+		callback, _ := oidcAuthorize(t, app, client)
+		_, other := oidcAuthorize(t, app, client)
+		u, _ := url.Parse(callback)
+		q := u.Query()
+		// Diğer akışın çerezi ve state değeriyle bu akışın kodu kullanılır
+		claims, err := parsePurposeToken("oidc_state", other.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		q.Set("state", claims["state"].(string))
+		u.RawQuery = q.Encode()
+		if status := oidcCallback(t, client, u.String(), other); status != http.StatusUnauthorized {
+			t.Errorf("callback status = %d, want %d", status, http.StatusUnauthorized)
+		}
+	})
+}
@@ -0,0 +1,262 @@
+// Package pdf harici bağımlılık olmadan basit, metin tabanlı PDF belgeleri
+// üretir (A4, Helvetica). Türkçe karakterler Windows-1254 düzenine göre
+// kodlanır; bu sayede standart fontla doğru görüntülenir.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	pageWidth  = 595
+	pageHeight = 842
+	margin     = 50
+	fontSize   = 10
+	leading    = 14
+	// Helvetica 10pt için satır başına yaklaşık karakter
+	wrapWidth = 92
+)
+
+// Document satır satır yazılan çok sayfalı bir belgedir
+type Document struct {
+	pages [][]line
+}
+
+type line struct {
+	text  string
+	size  int
+	bold  bool
+	image *picture
+}
+
+// picture sayfaya çizilecek, Flate ile sıkıştırılmış RGB görüntü
+type picture struct {
+	pixelsW, pixelsH int
+	width, height    int
+	data             []byte
+}
+
+func New() *Document {
+	return &Document{}
+}
+
+// Heading kalın başlık satırı ekler
+func (d *Document) Heading(text string) {
+	d.add(line{text: text, size: 14, bold: true})
+	d.add(line{})
+}
+
+// Section kalın ara başlık ekler
+func (d *Document) Section(text string) {
+	d.add(line{})
+	d.add(line{text: text, size: 11, bold: true})
+}
+
+// Text metni satırlara bölerek ekler
+func (d *Document) Text(text string) {
+	for _, para := range strings.Split(text, "\n") {
+		for _, l := range wrap(para, wrapWidth) {
+			d.add(line{text: l, size: fontSize})
+		}
+	}
+}
+
+// Image görüntüyü verilen genişlikte (pt), oranını koruyarak ekler.
+// Saydam pikseller beyaz zemin üzerine basılır (ör. imza).
+func (d *Document) Image(img image.Image, width int) {
+	b := img.Bounds()
+	if b.Dx() == 0 || b.Dy() == 0 {
+		return
+	}
+	if max := pageWidth - 2*margin; width > max {
+		width = max
+	}
+	height := width * b.Dy() / b.Dx()
+	if max := pageHeight - 2*margin - leading; height > max {
+		width, height = width*max/height, max
+	}
+
+	var raw bytes.Buffer
+	zw := zlib.NewWriter(&raw)
+	row := make([]byte, 0, b.Dx()*3)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row = row[:0]
+		for x := b.Min.X; x < b.Max.X; x++ {
+			// RGBA() önceden çarpılmış değer döndürür; beyazla harmanlamak için (1-a) eklenir
+			r, g, bl, a := img.At(x, y).RGBA()
+			row = append(row, byte((r+0xffff-a)>>8), byte((g+0xffff-a)>>8), byte((bl+0xffff-a)>>8))
+		}
+		zw.Write(row)
+	}
+	zw.Close()
+
+	// Görüntü bölünmesin diye gereken satırlar aynı sayfaya ayrılır
+	lines := (height + leading - 1) / leading
+	perPage := (pageHeight - 2*margin) / leading
+	if n := len(d.pages); n == 0 || len(d.pages[n-1])+lines > perPage {
+		d.pages = append(d.pages, nil)
+	}
+	d.add(line{image: &picture{pixelsW: b.Dx(), pixelsH: b.Dy(), width: width, height: height, data: raw.Bytes()}})
+	for i := 1; i < lines; i++ {
+		d.add(line{})
+	}
+}
+
+func (d *Document) add(l line) {
+	perPage := (pageHeight - 2*margin) / leading
+	if len(d.pages) == 0 || len(d.pages[len(d.pages)-1]) >= perPage {
+		d.pages = append(d.pages, nil)
+	}
+	d.pages[len(d.pages)-1] = append(d.pages[len(d.pages)-1], l)
+}
+
+func wrap(text string, width int) []string {
+	if utf8.RuneCountInString(text) <= width {
+		return []string{text}
+	}
+	var lines []string
+	var current []rune
+	for _, word := range strings.Fields(text) {
+		w := []rune(word)
+		// Tek başına sığmayan kelimeler bölünür
+		for len(w) > width {
+			if len(current) > 0 {
+				lines = append(lines, string(current))
+				current = nil
+			}
+			lines = append(lines, string(w[:width]))
+			w = w[width:]
+		}
+		if len(current) > 0 && len(current)+1+len(w) > width {
+			lines = append(lines, string(current))
+			current = nil
+		}
+		if len(current) > 0 {
+			current = append(current, ' ')
+		}
+		current = append(current, w...)
+	}
+	if len(current) > 0 {
+		lines = append(lines, string(current))
+	}
+	return lines
+}
+
+// Windows-1254'te WinAnsi'den farklı olan Türkçe karakterler ve
+// WinAnsi'nin 128-159 aralığındaki noktalama işaretleri
+var specialCodes = map[rune]byte{
+	'Ğ': 208, 'İ': 221, 'Ş': 222,
+	'ğ': 240, 'ı': 253, 'ş': 254,
+	'€': 128, '…': 133, '‘': 145, '’': 146, '“': 147, '”': 148,
+	'•': 149, '–': 150, '—': 151,
+}
+
+// encode metni PDF string'i olarak kodlar; karşılığı olmayan karakterler "?" olur
+func encode(text string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, r := range text {
+		var c byte
+		switch {
+		case specialCodes[r] != 0:
+			c = specialCodes[r]
+		case r == 'Ð' || r == 'Ý' || r == 'Þ' || r == 'ð' || r == 'ý' || r == 'þ':
+			c = '?'
+		case r >= 32 && r < 127, r >= 160 && r <= 255:
+			c = byte(r)
+		default:
+			c = '?'
+		}
+		if c == '(' || c == ')' || c == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// Bytes belgeyi PDF olarak döndürür
+func (d *Document) Bytes() []byte {
+	if len(d.pages) == 0 {
+		d.pages = [][]line{nil}
+	}
+
+	var objects []string
+	addObject := func(body string) int {
+		objects = append(objects, body)
+		return len(objects)
+	}
+
+	encoding := "<< /Type /Encoding /BaseEncoding /WinAnsiEncoding " +
+		"/Differences [208 /Gbreve 221 /Idotaccent 222 /Scedilla 240 /gbreve 253 /dotlessi 254 /scedilla] >>"
+	catalogID := addObject("")
+	pagesID := addObject("")
+	encodingID := addObject(encoding)
+	regularID := addObject(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding %d 0 R >>", encodingID))
+	boldID := addObject(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding %d 0 R >>", encodingID))
+
+	var kids []string
+	for i, page := range d.pages {
+		var content strings.Builder
+		var xobjects []string
+		content.WriteString("BT\n")
+		y := pageHeight - margin
+		for _, l := range page {
+			if l.image != nil {
+				p := l.image
+				imageID := addObject(fmt.Sprintf(
+					"<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream",
+					p.pixelsW, p.pixelsH, len(p.data), p.data,
+				))
+				name := fmt.Sprintf("Im%d", len(xobjects)+1)
+				xobjects = append(xobjects, fmt.Sprintf("/%s %d 0 R", name, imageID))
+				fmt.Fprintf(&content, "ET\nq %d 0 0 %d %d %d cm /%s Do Q\nBT\n", p.width, p.height, margin, y+fontSize-p.height, name)
+			}
+			if l.text != "" {
+				font := "F1"
+				if l.bold {
+					font = "F2"
+				}
+				fmt.Fprintf(&content, "/%s %d Tf 1 0 0 1 %d %d Tm %s Tj\n", font, l.size, margin, y, encode(l.text))
+			}
+			y -= leading
+		}
+		fmt.Fprintf(&content, "/F1 8 Tf 1 0 0 1 %d %d Tm %s Tj\n", pageWidth-margin-40, margin/2, encode(fmt.Sprintf("%d / %d", i+1, len(d.pages))))
+		content.WriteString("ET")
+
+		stream := content.String()
+		contentID := addObject(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream))
+		resources := fmt.Sprintf("/Font << /F1 %d 0 R /F2 %d 0 R >>", regularID, boldID)
+		if len(xobjects) > 0 {
+			resources += " /XObject << " + strings.Join(xobjects, " ") + " >>"
+		}
+		pageID := addObject(fmt.Sprintf(
+			"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %d %d] /Resources << %s >> /Contents %d 0 R >>",
+			pagesID, pageWidth, pageHeight, resources, contentID,
+		))
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageID))
+	}
+	objects[catalogID-1] = fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesID)
+	objects[pagesID-1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids))
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, body := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, body)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, catalogID, xref)
+	return buf.Bytes()
+}
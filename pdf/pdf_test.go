@@ -0,0 +1,40 @@
+package pdf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  []string
+	}{
+		{"kısa metin", 10, []string{"kısa metin"}},
+		{"bir iki üç dört", 7, []string{"bir iki", "üç dört"}},
+		{"abcdefghij kl", 4, []string{"abcd", "efgh", "ij", "kl"}},
+		{"  çok   boşluk  ", 5, []string{"çok", "boşlu", "k"}},
+	}
+	for _, tt := range tests {
+		if got := wrap(tt.text, tt.width); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("wrap(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"abc", "(abc)"},
+		{"(a\\b)", `(\(a\\b\))`},
+		{"ĞİŞğış", "(\xd0\xdd\xde\xf0\xfd\xfe)"},
+		{"çöü", "(\xe7\xf6\xfc)"},
+		{"€ – …", "(\x80 \x96 \x85)"},
+		{"Ýþ✓", "(???)"},
+	}
+	for _, tt := range tests {
+		if got := encode(tt.in); got != tt.want {
+			t.Errorf("encode(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
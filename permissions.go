@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Roller (frontend ile aynı numaralar)
+const (
+	roleAdmin      = 1
+	roleEditor     = 2
+	roleTechnician = 3
+)
+
+// Yetkiler. API anahtarı scope'ları da bu değerlerden oluşur.
+const (
+	permProductsRead    = "products:read"
+	permProductsWrite   = "products:write"
+	permServicesRead    = "services:read"
+	permServicesWrite   = "services:write"
+	permContentRead     = "content:read"
+	permContentWrite    = "content:write"
+	permContentPublish  = "content:publish"
+	permUsersRead       = "users:read"
+	permUsersWrite      = "users:write"
+	permAuditRead       = "audit:read"
+	permLeadsRead       = "leads:read"
+	permLeadsWrite      = "leads:write"
+	permWorkOrdersRead  = "workorders:read"
+	permWorkOrdersWrite = "workorders:write"
+	permPrivacyManage   = "privacy:manage"
+	permNotifyRead      = "notifications:read"
+	permNotifyWrite     = "notifications:write"
+	permJobsManage      = "jobs:manage"
+	permWebhooksManage  = "webhooks:manage"
+	permTechnicianJobs  = "technician:jobs"
+	permServiceArea     = "service_area:manage"
+	permInventoryRead   = "inventory:read"
+	permInventoryWrite  = "inventory:write"
+)
+
+var allPermissions = []string{
+	permProductsRead, permProductsWrite,
+	permServicesRead, permServicesWrite,
+	permContentRead, permContentWrite,
+	permContentPublish,
+	permUsersRead, permUsersWrite,
+	permAuditRead,
+	permLeadsRead, permLeadsWrite,
+	permWorkOrdersRead, permWorkOrdersWrite,
+	permPrivacyManage,
+	permNotifyRead, permNotifyWrite,
+	permJobsManage,
+	permWebhooksManage,
+	permTechnicianJobs,
+	permServiceArea,
+	permInventoryRead, permInventoryWrite,
+}
+
+var rolePermissions = map[int][]string{
+	roleAdmin: allPermissions,
+	roleEditor: {
+		permProductsRead, permProductsWrite,
+		permServicesRead, permServicesWrite,
+		permContentRead, permContentWrite,
+		permLeadsRead, permLeadsWrite,
+		permWorkOrdersRead, permWorkOrdersWrite,
+		permNotifyRead, permNotifyWrite,
+		permInventoryRead, permInventoryWrite,
+	},
+	// Teknisyenler sadece kendilerine atanmış işleri mobil API'den görür
+	roleTechnician: {
+		permTechnicianJobs,
+	},
+}
+
+func isValidPermission(perm string) bool {
+	for _, p := range allPermissions {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// permissionSet rolün yetkilerini küme olarak döndürür
+func permissionSet(roleID int) map[string]bool {
+	set := make(map[string]bool)
+	for _, p := range rolePermissions[roleID] {
+		set[p] = true
+	}
+	return set
+}
+
+// requirePermission authMiddleware'den sonra çalışır ve yetkiyi kontrol eder
+func requirePermission(perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hasPermission(c, perm) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Bu işlem için yetkiniz yok"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func hasPermission(c *gin.Context, perm string) bool {
+	perms, ok := c.Get("permissions")
+	if !ok {
+		return false
+	}
+	return perms.(map[string]bool)[perm]
+}
+
+// currentUserID oturumdaki kullanıcının ID'sini döndürür
+func currentUserID(c *gin.Context) int {
+	return c.GetInt("user_id")
+}
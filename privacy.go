@@ -0,0 +1,909 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/png"
+	"log"
+	"net"
+	"net/http"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"kozan/db"
+	"kozan/models"
+	"kozan/pdf"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// KVKK: onay metinleri sürümlüdür ve verilen her onay değiştirilemez bir
+// deftere yazılır. Defterde e-posta ve telefon yerine hash'leri tutulur;
+// kişi anonimleştirildiğinde de onayın kanıtı kalır.
+const (
+	auditAnonymize = "anonymize"
+	auditPurge     = "purge"
+)
+
+// formConsentPurposes public formlarda sorulan onay amaçları
+var formConsentPurposes = map[string][]string{
+	contactFormPurpose: {"kvkk_notice", "marketing"},
+}
+
+var consentPurposeRe = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+var errConsentOutdated = errors.New("Onay metni güncellendi, lütfen sayfayı yenileyin")
+
+type consentInput struct {
+	Purpose string `json:"purpose"`
+	Version int    `json:"version"`
+	Granted bool   `json:"granted"`
+}
+
+// subjectHashKey SUBJECT_HASH_KEY değişkeninden okunan HMAC anahtarıdır;
+// anahtar olmadan defterdeki hash'ler tahminle geri çevrilemez
+var subjectHashKey []byte
+
+func initSubjectHashKey() {
+	key := os.Getenv("SUBJECT_HASH_KEY")
+	if len(key) < minSecretLength {
+		log.Fatalf("SUBJECT_HASH_KEY en az %d karakter olmalı", minSecretLength)
+	}
+	subjectHashKey = []byte(key)
+}
+
+func normalizeSubject(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
+// subjectHash e-posta veya telefonu defterde aranabilir ama okunamaz hale getirir
+func subjectHash(value string) string {
+	value = normalizeSubject(value)
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, subjectHashKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// legacySubjectHash anahtarsız SHA-256 ile yazılmış eski kayıtları bulmak için
+func legacySubjectHash(value string) string {
+	value = normalizeSubject(value)
+	if value == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// truncateIP onay kanıtı için IP'nin ağ kısmını tutar: IPv4'te /24, IPv6'da /48
+func truncateIP(value string) string {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return ""
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// maxConsentUserAgent defterde saklanan tarayıcı bilgisinin uzunluğu
+const maxConsentUserAgent = 255
+
+const consentTextColumns = "id, purpose, version, title, body, required, created_by, created_at"
+
+func scanConsentText(scanner interface{ Scan(...interface{}) error }) (models.ConsentText, error) {
+	var t models.ConsentText
+	err := scanner.Scan(&t.ID, &t.Purpose, &t.Version, &t.Title, &t.Body, &t.Required, &t.CreatedBy, &t.CreatedAt)
+	return t, err
+}
+
+// activeConsentTexts her amaç için en son sürümü döndürür
+func activeConsentTexts(purposes []string) ([]models.ConsentText, error) {
+	rows, err := db.DB.Query(
+		"SELECT DISTINCT ON (purpose) "+consentTextColumns+" FROM consent_texts WHERE purpose = ANY($1) ORDER BY purpose, version DESC",
+		pq.Array(purposes),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	texts := []models.ConsentText{}
+	for rows.Next() {
+		t, err := scanConsentText(rows)
+		if err != nil {
+			return nil, err
+		}
+		texts = append(texts, t)
+	}
+	return texts, rows.Err()
+}
+
+// checkFormConsents gönderilen onayları formun güncel metinleriyle karşılaştırır.
+// Eski sürüme verilen onaylar errConsentOutdated döndürür.
+func checkFormConsents(form string, given []consentInput) error {
+	texts, err := activeConsentTexts(formConsentPurposes[form])
+	if err != nil {
+		return err
+	}
+	active := make(map[string]models.ConsentText)
+	for _, t := range texts {
+		active[t.Purpose] = t
+	}
+
+	granted := make(map[string]bool)
+	for _, g := range given {
+		text, ok := active[g.Purpose]
+		if !ok {
+			return fmt.Errorf("Bilinmeyen onay: %s", g.Purpose)
+		}
+		if g.Version != text.Version {
+			return errConsentOutdated
+		}
+		granted[g.Purpose] = g.Granted
+	}
+	for _, t := range texts {
+		if t.Required && !granted[t.Purpose] {
+			return fmt.Errorf("%s onayı zorunludur", t.Title)
+		}
+	}
+	return nil
+}
+
+// recordConsents onayları deftere yazar; form kaydıyla aynı transaction içinde çağrılır.
+// IP kısaltılarak, tarayıcı bilgisi sınırlı uzunlukta saklanır; ikisi de
+// anonimleştirme ve saklama süresi dolduğunda silinir.
+func recordConsents(tx *sql.Tx, email, phone, source string, sourceID int, ip, userAgent string, consents []consentInput) error {
+	ip = truncateIP(ip)
+	if len(userAgent) > maxConsentUserAgent {
+		userAgent = strings.ToValidUTF8(userAgent[:maxConsentUserAgent], "")
+	}
+	for _, g := range consents {
+		_, err := tx.Exec(
+			`INSERT INTO consents (email_hash, phone_hash, purpose, version, granted, source, source_id, ip, user_agent)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			subjectHash(email), subjectHash(phone), g.Purpose, g.Version, g.Granted, source, sourceID, ip, userAgent,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getPublicConsentTextHandler bir amacın güncel onay metnini döndürür
+func getPublicConsentTextHandler(c *gin.Context) {
+	text, err := scanConsentText(db.DB.QueryRow(
+		"SELECT "+consentTextColumns+" FROM consent_texts WHERE purpose = $1 ORDER BY version DESC LIMIT 1",
+		c.Param("purpose"),
+	))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Onay metni bulunamadı"})
+		return
+	}
+	c.JSON(http.StatusOK, text)
+}
+
+// Onay metni işlemleri
+func getConsentTextsHandler(c *gin.Context) {
+	rows, err := db.DB.Query("SELECT " + consentTextColumns + " FROM consent_texts ORDER BY purpose, version DESC")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	texts := []models.ConsentText{}
+	for rows.Next() {
+		t, err := scanConsentText(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		texts = append(texts, t)
+	}
+
+	c.JSON(http.StatusOK, texts)
+}
+
+// createConsentTextHandler metnin yeni sürümünü yayınlar; eski sürümler değiştirilmez
+func createConsentTextHandler(c *gin.Context) {
+	var text models.ConsentText
+	if err := c.BindJSON(&text); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	text.Title = strings.TrimSpace(text.Title)
+	text.Body = strings.TrimSpace(text.Body)
+	if !consentPurposeRe.MatchString(text.Purpose) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz amaç: küçük harf, rakam ve alt çizgi kullanın"})
+		return
+	}
+	if text.Title == "" || text.Body == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Başlık ve metin zorunludur"})
+		return
+	}
+
+	text.CreatedBy = currentUserID(c)
+	err := db.DB.QueryRow(
+		`INSERT INTO consent_texts (purpose, version, title, body, required, created_by)
+		VALUES ($1, (SELECT COALESCE(MAX(version), 0) + 1 FROM consent_texts WHERE purpose = $1), $2, $3, $4, $5)
+		RETURNING id, version, created_at`,
+		text.Purpose, text.Title, text.Body, text.Required, text.CreatedBy,
+	).Scan(&text.ID, &text.Version, &text.CreatedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, "consent_text", text.ID, auditCreate, nil, text)
+	c.JSON(http.StatusCreated, text)
+}
+
+// dataSubject e-posta ve/veya telefonla tanımlanan kişidir
+type dataSubject struct {
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+}
+
+func (s *dataSubject) normalize() error {
+	s.Email = strings.TrimSpace(s.Email)
+	if s.Email == "" && strings.TrimSpace(s.Phone) == "" {
+		return fmt.Errorf("E-posta veya telefon numarası zorunludur")
+	}
+	if s.Email != "" {
+		if _, err := mail.ParseAddress(s.Email); err != nil {
+			return fmt.Errorf("Geçersiz e-posta adresi")
+		}
+	}
+	if s.Phone != "" {
+		phone, err := normalizePhone(s.Phone)
+		if err != nil {
+			return err
+		}
+		s.Phone = phone
+	}
+	return nil
+}
+
+func getConsentRecords(subject dataSubject) ([]models.ConsentRecord, error) {
+	rows, err := db.DB.Query(
+		`SELECT id, purpose, version, granted, source, source_id, ip, user_agent, created_at FROM consents
+		WHERE ($1 <> '' AND email_hash IN ($1, $3)) OR ($2 <> '' AND phone_hash IN ($2, $4))
+		ORDER BY id`,
+		subjectHash(subject.Email), subjectHash(subject.Phone), legacySubjectHash(subject.Email), legacySubjectHash(subject.Phone),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []models.ConsentRecord{}
+	for rows.Next() {
+		var r models.ConsentRecord
+		var sourceID sql.NullInt64
+		if err := rows.Scan(&r.ID, &r.Purpose, &r.Version, &r.Granted, &r.Source, &sourceID, &r.IP, &r.UserAgent, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		if sourceID.Valid {
+			v := int(sourceID.Int64)
+			r.SourceID = &v
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+func getConsentsHandler(c *gin.Context) {
+	subject := dataSubject{Email: c.Query("email"), Phone: c.Query("phone")}
+	if err := subject.normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	records, err := getConsentRecords(subject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, records)
+}
+
+// subjectWorkOrder iş emri, sahada toplanan veriler (fotoğraf, konum, kontrol
+// listesi, parça) ve müşteri imzası. Fotoğraf dosyaları yönetim panelindeki
+// fotoğraf adresinden indirilir.
+type subjectWorkOrder struct {
+	jobDetail
+	Signature string `json:"signature,omitempty"` // data:image/png;base64,...
+}
+
+// subjectData bir kişi hakkında tutulan tüm kayıtlardır
+type subjectData struct {
+	Subject         dataSubject             `json:"subject"`
+	GeneratedAt     time.Time               `json:"generated_at"`
+	Customers       []models.Customer       `json:"customers"`
+	WorkOrders      []subjectWorkOrder      `json:"work_orders"`
+	ContactMessages []models.ContactMessage `json:"contact_messages"`
+	Notifications   []models.Notification   `json:"notifications"`
+	Consents        []models.ConsentRecord  `json:"consents"`
+}
+
+func (d subjectData) counts() gin.H {
+	return gin.H{
+		"customers":        len(d.Customers),
+		"work_orders":      len(d.WorkOrders),
+		"contact_messages": len(d.ContactMessages),
+		"notifications":    len(d.Notifications),
+		"consents":         len(d.Consents),
+	}
+}
+
+const subjectMatch = "($1 <> '' AND phone = $1) OR ($2 <> '' AND lower(email) = lower($2))"
+
+func collectSubjectData(subject dataSubject) (subjectData, error) {
+	data := subjectData{
+		Subject:         subject,
+		GeneratedAt:     time.Now(),
+		Customers:       []models.Customer{},
+		WorkOrders:      []subjectWorkOrder{},
+		ContactMessages: []models.ContactMessage{},
+		Notifications:   []models.Notification{},
+	}
+
+	rows, err := db.DB.Query(
+		"SELECT id, name, COALESCE(phone, ''), COALESCE(email, ''), COALESCE(address, ''), created_at FROM customers WHERE "+subjectMatch+" ORDER BY id",
+		subject.Phone, subject.Email,
+	)
+	if err != nil {
+		return data, err
+	}
+	var customerIDs []int
+	for rows.Next() {
+		var customer models.Customer
+		if err := rows.Scan(&customer.ID, &customer.Name, &customer.Phone, &customer.Email, &customer.Address, &customer.CreatedAt); err != nil {
+			rows.Close()
+			return data, err
+		}
+		data.Customers = append(data.Customers, customer)
+		customerIDs = append(customerIDs, customer.ID)
+	}
+	rows.Close()
+
+	if len(customerIDs) > 0 {
+		rows, err = db.DB.Query("SELECT "+workOrderColumns+" FROM work_orders WHERE customer_id = ANY($1) ORDER BY id", pq.Array(customerIDs))
+		if err != nil {
+			return data, err
+		}
+		var workOrders []models.WorkOrder
+		for rows.Next() {
+			wo, err := scanWorkOrder(rows)
+			if err != nil {
+				rows.Close()
+				return data, err
+			}
+			workOrders = append(workOrders, wo)
+		}
+		rows.Close()
+		for _, wo := range workOrders {
+			detail, err := loadJobDetail(wo)
+			if err != nil {
+				return data, err
+			}
+			item := subjectWorkOrder{jobDetail: detail}
+			if img := signatureImage(wo.ID); img != nil {
+				item.Signature = "data:image/png;base64," + base64.StdEncoding.EncodeToString(img)
+			}
+			data.WorkOrders = append(data.WorkOrders, item)
+		}
+
+		rows, err = db.DB.Query("SELECT "+notificationColumns+" FROM notifications WHERE customer_id = ANY($1) ORDER BY id", pq.Array(customerIDs))
+		if err != nil {
+			return data, err
+		}
+		for rows.Next() {
+			n, err := scanNotification(rows)
+			if err != nil {
+				rows.Close()
+				return data, err
+			}
+			data.Notifications = append(data.Notifications, n)
+		}
+		rows.Close()
+	}
+
+	rows, err = db.DB.Query("SELECT "+contactMessageColumns+" FROM contact_messages WHERE "+subjectMatch+" ORDER BY id", subject.Phone, subject.Email)
+	if err != nil {
+		return data, err
+	}
+	for rows.Next() {
+		msg, err := scanContactMessage(rows)
+		if err != nil {
+			rows.Close()
+			return data, err
+		}
+		data.ContactMessages = append(data.ContactMessages, msg)
+	}
+	rows.Close()
+	for i := range data.ContactMessages {
+		if data.ContactMessages[i].Notes, err = getLeadNotes(data.ContactMessages[i].ID); err != nil {
+			return data, err
+		}
+	}
+
+	data.Consents, err = getConsentRecords(subject)
+	return data, err
+}
+
+// logDataSubjectRequest talebi kaydeder; kişinin kendisi değil hash'i saklanır
+func logDataSubjectRequest(c *gin.Context, kind string, subject dataSubject, reason string, result gin.H) (int, error) {
+	resultJSON, _ := json.Marshal(result)
+	var id int
+	err := db.DB.QueryRow(
+		`INSERT INTO data_subject_requests (kind, email_hash, phone_hash, reason, result, requested_by, requested_by_username)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		kind, subjectHash(subject.Email), subjectHash(subject.Phone), reason, string(resultJSON), currentUserID(c), c.GetString("username"),
+	).Scan(&id)
+	return id, err
+}
+
+// exportDataSubjectHandler kişi hakkında tutulan her şeyi JSON veya PDF olarak verir
+func exportDataSubjectHandler(c *gin.Context) {
+	subject := dataSubject{Email: c.Query("email"), Phone: c.Query("phone")}
+	if err := subject.normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "pdf" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz format: json veya pdf olmalı"})
+		return
+	}
+
+	data, err := collectSubjectData(subject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := logDataSubjectRequest(c, "export", subject, c.Query("reason"), data.counts()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := "kisisel-veri-" + data.GeneratedAt.Format("20060102-150405")
+	c.Header("Cache-Control", "no-store")
+	if format == "pdf" {
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.pdf"`)
+		c.Data(http.StatusOK, "application/pdf", subjectDataPDF(data))
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+	c.JSON(http.StatusOK, data)
+}
+
+func formatLocalTime(t time.Time) string {
+	return t.In(businessLocation).Format("02.01.2006 15:04")
+}
+
+func subjectDataPDF(data subjectData) []byte {
+	doc := pdf.New()
+	doc.Heading("Kişisel Veri Dökümü (KVKK md. 11)")
+	doc.Text("E-posta: " + data.Subject.Email)
+	doc.Text("Telefon: " + data.Subject.Phone)
+	doc.Text("Oluşturulma: " + formatLocalTime(data.GeneratedAt))
+
+	doc.Section(fmt.Sprintf("Müşteri Kayıtları (%d)", len(data.Customers)))
+	for _, customer := range data.Customers {
+		doc.Text(fmt.Sprintf("#%d %s | %s | %s | %s | kayıt: %s",
+			customer.ID, customer.Name, customer.Phone, customer.Email, customer.Address, formatLocalTime(customer.CreatedAt)))
+	}
+
+	doc.Section(fmt.Sprintf("İş Emirleri (%d)", len(data.WorkOrders)))
+	for _, wo := range data.WorkOrders {
+		doc.Text(fmt.Sprintf("#%d %s [%s] - %s", wo.ID, wo.Title, wo.Status, formatLocalTime(wo.CreatedAt)))
+		if wo.Address != "" {
+			doc.Text("Adres: " + wo.Address)
+		}
+		if wo.Description != "" {
+			doc.Text(wo.Description)
+		}
+		if wo.TechnicianNotes != "" {
+			doc.Text("Teknisyen notu: " + wo.TechnicianNotes)
+		}
+		for _, cp := range wo.Checkpoints {
+			if cp.Latitude != nil && cp.Longitude != nil {
+				doc.Text(fmt.Sprintf("Konum (%s, %s): %.6f, %.6f", cp.Kind, formatLocalTime(cp.RecordedAt), *cp.Latitude, *cp.Longitude))
+			}
+		}
+		for _, item := range wo.Checklist {
+			if item.Note != "" {
+				doc.Text(fmt.Sprintf("Kontrol notu (%s): %s", item.Label, item.Note))
+			}
+		}
+		for _, part := range wo.Parts {
+			if part.Note != "" {
+				doc.Text(fmt.Sprintf("Parça notu (%s): %s", part.Name, part.Note))
+			}
+		}
+		for _, photo := range wo.Photos {
+			doc.Text(fmt.Sprintf("Fotoğraf #%d (%s, %s) %s", photo.ID, photo.Kind, formatLocalTime(photo.CreatedAt), photo.Caption))
+		}
+		if wo.SignedAt != nil {
+			if data := signatureImage(wo.ID); data != nil {
+				if img, err := png.Decode(bytes.NewReader(data)); err == nil {
+					doc.Image(img, 180)
+				}
+			}
+			doc.Text(fmt.Sprintf("İmzalayan: %s, %s", wo.SignerName, formatLocalTime(*wo.SignedAt)))
+		}
+	}
+
+	doc.Section(fmt.Sprintf("İletişim Mesajları (%d)", len(data.ContactMessages)))
+	for _, msg := range data.ContactMessages {
+		doc.Text(fmt.Sprintf("#%d %s - %s | %s | %s | IP: %s",
+			msg.ID, formatLocalTime(msg.CreatedAt), msg.Name, msg.Email, msg.Phone, msg.IP))
+		if msg.Subject != "" {
+			doc.Text("Konu: " + msg.Subject)
+		}
+		doc.Text(msg.Message)
+		for _, note := range msg.Notes {
+			doc.Text(fmt.Sprintf("Not (%s, %s): %s", note.AuthorUsername, formatLocalTime(note.CreatedAt), note.Note))
+		}
+	}
+
+	doc.Section(fmt.Sprintf("Bildirimler (%d)", len(data.Notifications)))
+	for _, n := range data.Notifications {
+		doc.Text(fmt.Sprintf("#%d %s - %s → %s [%s]", n.ID, formatLocalTime(n.CreatedAt), n.Channel, n.Recipient, n.Status))
+		doc.Text(n.Body)
+	}
+
+	doc.Section(fmt.Sprintf("Onaylar (%d)", len(data.Consents)))
+	for _, r := range data.Consents {
+		state := "verildi"
+		if !r.Granted {
+			state = "verilmedi"
+		}
+		doc.Text(fmt.Sprintf("%s - %s v%d: %s (kaynak: %s, IP: %s)",
+			formatLocalTime(r.CreatedAt), r.Purpose, r.Version, state, r.Source, r.IP))
+	}
+
+	return doc.Bytes()
+}
+
+// queryer *sql.DB ve *sql.Tx için ortak arayüz
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// selectIDs sorgunun döndürdüğü ID'leri toplar
+func selectIDs(tx queryer, query string, args ...interface{}) ([]int, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// workOrderMediaFiles iş emirlerine ait imza ve fotoğraf dosyaları
+func workOrderMediaFiles(tx queryer, workOrderIDs []int) ([]string, error) {
+	rows, err := tx.Query(
+		`SELECT signature_file FROM work_orders WHERE id = ANY($1) AND signature_file <> ''
+		UNION ALL SELECT file FROM work_order_photos WHERE work_order_id = ANY($1)`,
+		pq.Array(workOrderIDs),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []string
+	for rows.Next() {
+		var file string
+		if err := rows.Scan(&file); err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, rows.Err()
+}
+
+// subjectPIIKeys denetim kaydı, yönetim olayı ve webhook gövdelerinden
+// anonimleştirmede çıkarılan alanlardır
+var subjectPIIKeys = []string{
+	"name", "email", "phone", "address", "subject", "message", "ip", "user_agent",
+	"note", "notes", "title", "description", "latitude", "longitude", "customer",
+	"recipient", "body", "whatsapp_params", "technician_notes", "signer_name", "caption",
+}
+
+// anonymizeDataSubjectHandler kişisel verileri siler. İş emirleri servis ve
+// fatura kaydı olarak kalır, sadece müşteri bağlantısı anonim kayda işaret eder.
+// Sahada toplanan fotoğraflar ve imza silinir, konum kayıtlarından koordinat,
+// notlardan metin çıkarılır. Aynı kişiye ait denetim kayıtları, yönetim
+// olayları, webhook teslimatları ve iş hataları da temizlenir. Onay defterinde
+// kişi hash'le tutulur ve onayın kanıtı olarak kalır; IP ve tarayıcı bilgisi silinir.
+func anonymizeDataSubjectHandler(c *gin.Context) {
+	var input struct {
+		dataSubject
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	subject := input.dataSubject
+	if err := subject.normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.TrimSpace(input.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Gerekçe zorunludur"})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	customerIDs, err := selectIDs(tx, "SELECT id FROM customers WHERE "+subjectMatch+" FOR UPDATE", subject.Phone, subject.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	messageIDs, err := selectIDs(tx, "SELECT id FROM contact_messages WHERE "+subjectMatch+" FOR UPDATE", subject.Phone, subject.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(customerIDs) == 0 && len(messageIDs) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Bu kişiye ait kayıt bulunamadı"})
+		return
+	}
+
+	workOrderIDs, err := selectIDs(tx,
+		"SELECT id FROM work_orders WHERE customer_id = ANY($1) OR source_message_id = ANY($2) FOR UPDATE",
+		pq.Array(customerIDs), pq.Array(messageIDs),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	notificationIDs, err := selectIDs(tx, "SELECT id FROM notifications WHERE customer_id = ANY($1)", pq.Array(customerIDs))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	noteIDs, err := selectIDs(tx, "SELECT id FROM contact_message_notes WHERE message_id = ANY($1)", pq.Array(messageIDs))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// Dosyalar kayıtlar silindikten sonra, işlem onaylanınca kaldırılır
+	mediaFiles, err := workOrderMediaFiles(tx, workOrderIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Denetim kaydı append-only tetikleyicisi sadece bu işlem içinde ve
+	// sadece UPDATE için gevşetilir; kayıtlar silinmez, kişisel alanlar çıkarılır
+	if _, err := tx.Exec("SELECT set_config('kozan.redaction', 'on', true)"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	customers, workOrders, messages := pq.Array(customerIDs), pq.Array(workOrderIDs), pq.Array(messageIDs)
+	piiKeys := pq.Array(subjectPIIKeys)
+	var redactedAudit, redactedDeliveries int64
+	statements := []struct {
+		query string
+		args  []interface{}
+		count *int64
+	}{
+		{"UPDATE customers SET name = 'Anonim Müşteri #' || id, phone = NULL, email = NULL, address = NULL, updated_at = NOW() WHERE id = ANY($1)",
+			[]interface{}{customers}, nil},
+		{`UPDATE work_orders SET title = '[anonimleştirildi]', description = '', address = '',
+			latitude = NULL, longitude = NULL, technician_notes = '', signer_name = '', signature_file = '',
+			updated_at = NOW() WHERE id = ANY($1)`,
+			[]interface{}{workOrders}, nil},
+		{"DELETE FROM work_order_photos WHERE work_order_id = ANY($1)", []interface{}{workOrders}, nil},
+		{"UPDATE work_order_checkpoints SET latitude = NULL, longitude = NULL, accuracy_m = NULL WHERE work_order_id = ANY($1)",
+			[]interface{}{workOrders}, nil},
+		{"UPDATE work_order_checklist SET note = '' WHERE work_order_id = ANY($1) AND note <> ''", []interface{}{workOrders}, nil},
+		{"UPDATE work_order_parts SET note = '' WHERE work_order_id = ANY($1) AND note <> ''", []interface{}{workOrders}, nil},
+		{"UPDATE stock_movements SET note = '' WHERE work_order_id = ANY($1) AND note <> ''", []interface{}{workOrders}, nil},
+		// Çevrimdışı senkronizasyon yanıtları fotoğraf, parça ve madde kayıtlarını içerir
+		{"UPDATE sync_mutations SET result = result - 'data' WHERE work_order_id = ANY($1)", []interface{}{workOrders}, nil},
+		{"UPDATE notifications SET recipient = '', subject = '', body = '', whatsapp_params = '{}' WHERE customer_id = ANY($1)",
+			[]interface{}{customers}, nil},
+		{"DELETE FROM contact_message_notes WHERE message_id = ANY($1)", []interface{}{messages}, nil},
+		{`UPDATE contact_messages SET name = 'Anonim', email = '', phone = '', subject = '',
+			message = '[anonimleştirildi]', ip = '', user_agent = '' WHERE id = ANY($1)`,
+			[]interface{}{messages}, nil},
+		{`UPDATE consents SET ip = '', user_agent = ''
+			WHERE ((email_hash <> '' AND email_hash IN ($1, $2)) OR (phone_hash <> '' AND phone_hash IN ($3, $4)))
+				AND (ip <> '' OR user_agent <> '')`,
+			[]interface{}{subjectHash(subject.Email), legacySubjectHash(subject.Email), subjectHash(subject.Phone), legacySubjectHash(subject.Phone)}, nil},
+		{`UPDATE audit_log SET before = before - $5::text[], after = after - $5::text[], diff = diff - $5::text[]
+			WHERE (entity_type IN ('customer', 'customer_notification_preferences') AND entity_id = ANY($1))
+				OR (entity_type = 'work_order' AND entity_id = ANY($2))
+				OR (entity_type IN ('work_order_photo', 'work_order_part', 'work_order_checklist')
+					AND (COALESCE(after, before)->>'work_order_id')::int = ANY($2))
+				OR (entity_type = 'contact_message' AND entity_id = ANY($3))
+				OR (entity_type = 'contact_message_note' AND entity_id = ANY($4))`,
+			[]interface{}{customers, workOrders, messages, pq.Array(noteIDs), piiKeys}, &redactedAudit},
+		{`UPDATE admin_events SET data = data - $3::text[]
+			WHERE (entity_type = 'work_order' AND entity_id = ANY($1))
+				OR (entity_type = 'contact_message' AND entity_id = ANY($2))`,
+			[]interface{}{workOrders, messages, piiKeys}, nil},
+		// Dış sistemlere gönderilmiş olay gövdelerinden sadece kimlik kalır
+		{`UPDATE webhook_deliveries SET payload = jsonb_set(payload, '{data}', jsonb_build_object('id', payload->'data'->'id'))
+			WHERE (event LIKE 'work_order.%' AND (payload->'data'->>'id')::int = ANY($1))
+				OR (event LIKE 'lead.%' AND (payload->'data'->>'id')::int = ANY($2))`,
+			[]interface{}{workOrders, messages}, &redactedDeliveries},
+		// İş gövdeleri sadece kimlik taşır; gönderim hataları alıcı adresini içerebilir
+		{`UPDATE jobs SET last_error = '' WHERE last_error <> '' AND (
+				(kind = $1 AND (payload->>'notification_id')::int = ANY($2))
+				OR (kind = $3 AND (payload->>'work_order_id')::int = ANY($4))
+				OR (kind = $5 AND (payload->>'message_id')::int = ANY($6)))`,
+			[]interface{}{jobNotificationSend, pq.Array(notificationIDs), jobWorkOrderNotify, workOrders, jobLeadNotify, messages}, nil},
+	}
+	for _, stmt := range statements {
+		res, err := tx.Exec(stmt.query, stmt.args...)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if stmt.count != nil {
+			*stmt.count, _ = res.RowsAffected()
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, file := range mediaFiles {
+		os.Remove(filepath.Join(serviceMediaDir(), file))
+	}
+
+	result := gin.H{
+		"customers":          len(customerIDs),
+		"work_orders":        len(workOrderIDs),
+		"contact_messages":   len(messageIDs),
+		"audit_entries":      redactedAudit,
+		"webhook_deliveries": redactedDeliveries,
+	}
+	id, err := logDataSubjectRequest(c, "anonymize", subject, input.Reason, result)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, "data_subject_request", id, auditAnonymize, nil, result)
+	c.JSON(http.StatusOK, result)
+}
+
+func getDataSubjectRequestsHandler(c *gin.Context) {
+	rows, err := db.DB.Query(
+		"SELECT id, kind, reason, result, requested_by, requested_by_username, created_at FROM data_subject_requests ORDER BY id DESC LIMIT 200",
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	requests := []models.DataSubjectRequest{}
+	for rows.Next() {
+		var r models.DataSubjectRequest
+		var result []byte
+		if err := rows.Scan(&r.ID, &r.Kind, &r.Reason, &result, &r.RequestedBy, &r.RequestedByUsername, &r.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		r.Result = json.RawMessage(result)
+		requests = append(requests, r)
+	}
+
+	c.JSON(http.StatusOK, requests)
+}
+
+// leadRetention dönüştürülmemiş taleplerin saklanma süresidir (LEAD_RETENTION_DAYS, 0 = kapalı)
+func leadRetention() time.Duration {
+	days := 365
+	if v := os.Getenv("LEAD_RETENTION_DAYS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			days = n
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// purgeStaleLeads iş emrine dönüşmemiş eski mesajları notlarıyla birlikte siler.
+// Mesajların denetim kayıtlarından, yönetim olaylarından ve lead.created webhook
+// gövdelerinden kişisel alanlar, onay defterinden IP ve tarayıcı bilgisi
+// aynı işlemde çıkarılır. İş kuyruğunda saatlik periyodik iş olarak çalışır.
+func purgeStaleLeads(ctx context.Context, retention time.Duration) error {
+	if retention == 0 {
+		return nil
+	}
+	cutoff := time.Now().Add(-retention)
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	messageIDs, err := selectIDs(tx, "SELECT id FROM contact_messages WHERE work_order_id IS NULL AND created_at < $1 FOR UPDATE", cutoff)
+	if err != nil || len(messageIDs) == 0 {
+		return err
+	}
+	messages := pq.Array(messageIDs)
+	noteIDs, err := selectIDs(tx, "SELECT id FROM contact_message_notes WHERE message_id = ANY($1)", messages)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "SELECT set_config('kozan.redaction', 'on', true)"); err != nil {
+		return err
+	}
+
+	piiKeys := pq.Array(subjectPIIKeys)
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{"DELETE FROM contact_messages WHERE id = ANY($1)", []interface{}{messages}},
+		{`UPDATE audit_log SET before = before - $3::text[], after = after - $3::text[], diff = diff - $3::text[]
+			WHERE (entity_type = 'contact_message' AND entity_id = ANY($1))
+				OR (entity_type = 'contact_message_note' AND entity_id = ANY($2))`,
+			[]interface{}{messages, pq.Array(noteIDs), piiKeys}},
+		{"UPDATE admin_events SET data = data - $2::text[] WHERE entity_type = 'contact_message' AND entity_id = ANY($1)",
+			[]interface{}{messages, piiKeys}},
+		{`UPDATE webhook_deliveries SET payload = jsonb_set(payload, '{data}', jsonb_build_object('id', payload->'data'->'id'))
+			WHERE event LIKE 'lead.%' AND (payload->'data'->>'id')::int = ANY($1)`,
+			[]interface{}{messages}},
+		{"UPDATE consents SET ip = '', user_agent = '' WHERE source = $2 AND source_id = ANY($1)",
+			[]interface{}{messages, contactFormPurpose}},
+		{"UPDATE jobs SET last_error = '' WHERE last_error <> '' AND kind = $1 AND (payload->>'message_id')::int = ANY($2)",
+			[]interface{}{jobLeadNotify, messages}},
+	}
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt.query, stmt.args...); err != nil {
+			return err
+		}
+	}
+	if err := insertAudit(tx, systemActor("retention"), "contact_message", 0, auditPurge, nil,
+		gin.H{"count": len(messageIDs), "created_before": cutoff}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,457 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"kozan/db"
+	"kozan/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Yayın akışı: ürün, hizmet, hakkımızda, hero, footer ve kampanya tabloları taslak
+// (çalışma kopyası) olarak kullanılır. Yayınlanan hal published_content
+// tablosunda JSON olarak saklanır ve public /api route'ları sadece onu okur.
+var publishableEntities = []string{"product", "service", "about", "hero", "footer", "campaign"}
+
+type entityRef struct {
+	entityType string
+	id         int
+}
+
+func isPublishable(entityType string) bool {
+	for _, e := range publishableEntities {
+		if e == entityType {
+			return true
+		}
+	}
+	return false
+}
+
+// translationSnapshot kaydın o anki çevirilerini {"en": {"name": "..."}}
+// biçiminde toplayan alt sorguyu verilen tip ve ID ifadeleriyle oluşturur
+func translationSnapshot(entityType, entityID string) string {
+	return `(SELECT COALESCE(jsonb_object_agg(locale, fields), '{}'::jsonb) FROM (
+		SELECT locale, jsonb_object_agg(field, value) AS fields FROM translations
+		WHERE entity_type = ` + entityType + ` AND entity_id = ` + entityID + ` AND value <> '' GROUP BY locale) t)`
+}
+
+// publishEntity kaydın güncel halini çevirileriyle birlikte yayına alır; yayın
+// sonrası yapılan çeviri değişiklikleri bir sonraki yayına kadar taslakta kalır
+func publishEntity(q execer, publishedBy int, entityType string, entityID int, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(
+		`INSERT INTO published_content (entity_type, entity_id, data, translations, published_by, published_at)
+		VALUES ($1, $2, $3, `+translationSnapshot("$1", "$2")+`, $4, NOW())
+		ON CONFLICT (entity_type, entity_id) DO UPDATE SET data = EXCLUDED.data, translations = EXCLUDED.translations,
+			published_by = EXCLUDED.published_by, published_at = NOW()`,
+		entityType, entityID, string(payload), publishedBy,
+	)
+	return err
+}
+
+// publishedData kaydın yayındaki halini döndürür; yayında değilse nil
+func publishedData(q queryRower, entityType string, entityID int) ([]byte, error) {
+	var data []byte
+	err := q.QueryRow("SELECT data FROM published_content WHERE entity_type = $1 AND entity_id = $2", entityType, entityID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return data, err
+}
+
+// publishEvents yayına alınan kaydın webhook olaylarını yayınla aynı işlemde
+// kuyruğa ekler. Taslak kaydetmek dış sistemlere olay göndermez; ürün fiyatı
+// yayındaki önceki fiyatla karşılaştırılır.
+func publishEvents(tx *sql.Tx, entityType string, previous []byte, data interface{}) error {
+	product, ok := data.(models.Product)
+	if entityType != "product" || !ok {
+		return nil
+	}
+	if err := queueEvent(tx, eventProductUpdated, product, 0); err != nil {
+		return err
+	}
+	if previous == nil {
+		return nil
+	}
+	var old models.Product
+	if err := json.Unmarshal(previous, &old); err != nil {
+		return err
+	}
+	if old.Price != product.Price {
+		return queueEvent(tx, eventProductPriceChanged, gin.H{"product": product, "previous_price": old.Price}, 0)
+	}
+	return nil
+}
+
+func unpublishEntity(q execer, entityType string, entityID int) error {
+	_, err := q.Exec("DELETE FROM published_content WHERE entity_type = $1 AND entity_id = $2", entityType, entityID)
+	return err
+}
+
+// publishedList yayındaki kayıtları ham JSON olarak döndürür
+func publishedList(entityType string) ([]json.RawMessage, error) {
+	rows, err := db.DB.Query("SELECT data FROM published_content WHERE entity_type = $1 ORDER BY entity_id", entityType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []json.RawMessage{}
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		list = append(list, json.RawMessage(data))
+	}
+	return list, rows.Err()
+}
+
+// previewKey önizlenen kaydı istek bağlamında tutar; localize taslak
+// çevirileri bu kayıt için okur
+const previewKey = "preview_ref"
+
+// previewTTL önizleme linklerinin varsayılan ve en uzun geçerlilik süresi
+const (
+	defaultPreviewTTL = 30 * time.Minute
+	maxPreviewTTL     = 24 * time.Hour
+)
+
+// previewVersion kaydın önizleme sürümünü döndürür; sürüm artırılınca kayıt
+// için verilmiş tüm önizleme linkleri geçersiz olur
+func previewVersion(ref entityRef) (int, error) {
+	var version int
+	err := db.DB.QueryRow(
+		"SELECT version FROM preview_versions WHERE entity_type = $1 AND entity_id = $2",
+		ref.entityType, ref.id,
+	).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return version, err
+}
+
+// previewRef istek bu içerik tipinden bir kayda ait geçerli bir önizleme
+// token'ı taşıyorsa o kaydı döndürür
+func previewRef(c *gin.Context, entityType string) (entityRef, bool) {
+	token := c.Query("preview")
+	if token == "" {
+		token = c.GetHeader("X-Preview-Token")
+	}
+	if token == "" {
+		return entityRef{}, false
+	}
+	claims, err := parsePurposeToken("preview", token)
+	if err != nil {
+		return entityRef{}, false
+	}
+	tokenType, _ := claims["entity_type"].(string)
+	id, _ := claims["entity_id"].(float64)
+	version, _ := claims["version"].(float64)
+	if tokenType != entityType {
+		return entityRef{}, false
+	}
+
+	ref := entityRef{entityType, int(id)}
+	current, err := previewVersion(ref)
+	if err != nil || current != int(version) {
+		return entityRef{}, false
+	}
+	// Önizleme yanıtları önbelleğe alınmamalı
+	c.Header("Cache-Control", "no-store")
+	c.Set(previewKey, ref)
+	return ref, true
+}
+
+// singleEntities tek kayıtlı içerikler; önizlemede yayındaki kaydın yerine geçer
+var singleEntities = map[string]bool{"about": true, "hero": true, "footer": true}
+
+// withDraft önizlenen kaydın taslağını yayındaki kayıtların arasına yerleştirir
+func withDraft(items []map[string]interface{}, ref entityRef) []map[string]interface{} {
+	draft, err := revisionEntities[ref.entityType].load(ref.id)
+	if err != nil {
+		// Kayıt silinmişse yayındaki hal gösterilir
+		return items
+	}
+	item := toJSONMap(draft)
+	if singleEntities[ref.entityType] {
+		return []map[string]interface{}{item}
+	}
+	for i, existing := range items {
+		if id, _ := existing["id"].(float64); int(id) == ref.id {
+			items[i] = item
+			return items
+		}
+	}
+	return append(items, item)
+}
+
+// publicItems yayındaki kayıtları döndürür; önizleme token'ı taşıyan isteklerde
+// token'ın ait olduğu kaydın taslağı gösterilir
+func publicItems(c *gin.Context, entityType string) ([]map[string]interface{}, error) {
+	list, err := publishedList(entityType)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []map[string]interface{}{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+	if ref, ok := previewRef(c, entityType); ok {
+		items = withDraft(items, ref)
+	}
+	if entityType == "product" {
+		if err := applyCampaignPrices(c, items); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// servePublic liste içerikleri (ürün, hizmet, kampanya) için
+func servePublic(c *gin.Context, entityType string) {
+	items, err := publicItems(c, entityType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	localize(c, entityType, items)
+	renderRichText(entityType, items)
+	applySEODefaults(entityType, items)
+	c.JSON(http.StatusOK, items)
+}
+
+// servePublicSingle tek kayıtlı içerikler (hakkımızda, hero, footer) için
+func servePublicSingle(c *gin.Context, entityType string, empty interface{}) {
+	items, err := publicItems(c, entityType)
+	if err != nil || len(items) == 0 {
+		c.JSON(http.StatusOK, empty) // Veri yoksa boş döndür
+		return
+	}
+	localize(c, entityType, items[:1])
+	renderRichText(entityType, items[:1])
+	c.JSON(http.StatusOK, items[0])
+}
+
+// Yayın işlemleri
+func publishHandler(c *gin.Context) {
+	entityType, entity, id, ok := revisionParams(c, false)
+	if !ok {
+		return
+	}
+	if !isPublishable(entityType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bu içerik tipi yayın akışında değil"})
+		return
+	}
+
+	data, err := entity.load(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kayıt bulunamadı"})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	published, err := publishedData(tx, entityType, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var before interface{}
+	if published != nil {
+		before = json.RawMessage(published)
+	}
+
+	err = publishEntity(tx, currentUserID(c), entityType, id, data)
+	if err == nil {
+		err = publishEvents(tx, entityType, published, data)
+	}
+	if err == nil {
+		err = insertAudit(tx, requestActor(c), entityType, id, auditPublish, before, data)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Yayınlandı", "data": data})
+}
+
+func unpublishHandler(c *gin.Context) {
+	entityType, _, id, ok := revisionParams(c, false)
+	if !ok {
+		return
+	}
+	if !isPublishable(entityType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bu içerik tipi yayın akışında değil"})
+		return
+	}
+
+	var published []byte
+	if err := db.DB.QueryRow("SELECT data FROM published_content WHERE entity_type = $1 AND entity_id = $2", entityType, id).Scan(&published); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kayıt yayında değil"})
+		return
+	}
+
+	if err := unpublishEntity(db.DB, entityType, id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, entityType, id, auditUnpublish, json.RawMessage(published), nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Yayından kaldırıldı"})
+}
+
+// publishStatusHandler her kaydın taslak / yayında / değişmiş durumunu listeler
+func publishStatusHandler(c *gin.Context) {
+	published := make(map[string]models.PublishStatus)
+	rows, err := db.DB.Query(
+		`SELECT entity_type, entity_id, data, published_by, published_at,
+			translations = ` + translationSnapshot("p.entity_type", "p.entity_id") + `
+		FROM published_content p`,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	publishedData := make(map[string][]byte)
+	// Yayından sonra çevirisi değişen kayıtlar
+	translationsChanged := make(map[string]bool)
+	for rows.Next() {
+		var st models.PublishStatus
+		var data []byte
+		var publishedAt time.Time
+		var translationsCurrent bool
+		if err := rows.Scan(&st.EntityType, &st.EntityID, &data, &st.PublishedBy, &publishedAt, &translationsCurrent); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		st.PublishedAt = &publishedAt
+		key := st.EntityType + ":" + strconv.Itoa(st.EntityID)
+		published[key] = st
+		publishedData[key] = data
+		translationsChanged[key] = !translationsCurrent
+	}
+
+	ids, err := contentRefs(publishableEntities)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	statuses := []models.PublishStatus{}
+	for _, item := range ids {
+		key := item.entityType + ":" + strconv.Itoa(item.id)
+		st, ok := published[key]
+		if !ok {
+			statuses = append(statuses, models.PublishStatus{EntityType: item.entityType, EntityID: item.id, Status: "draft"})
+			continue
+		}
+
+		st.Status = "published"
+		if translationsChanged[key] {
+			st.Status = "changed"
+		} else if draft, err := revisionEntities[item.entityType].load(item.id); err == nil {
+			var publishedMap map[string]interface{}
+			json.Unmarshal(publishedData[key], &publishedMap)
+			if !reflect.DeepEqual(toJSONMap(draft), publishedMap) {
+				st.Status = "changed"
+			}
+		}
+		statuses = append(statuses, st)
+	}
+
+	c.JSON(http.StatusOK, statuses)
+}
+
+// createPreviewTokenHandler bir kaydın taslağını canlı sayfada gösteren süreli
+// bir link üretir
+func createPreviewTokenHandler(c *gin.Context) {
+	entityType, _, id, ok := revisionParams(c, false)
+	if !ok {
+		return
+	}
+	if !isPublishable(entityType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bu içerik tipi yayın akışında değil"})
+		return
+	}
+
+	var input struct {
+		TTLMinutes int `json:"ttl_minutes"`
+	}
+	c.ShouldBindJSON(&input)
+	ttl := time.Duration(input.TTLMinutes) * time.Minute
+	if ttl <= 0 {
+		ttl = defaultPreviewTTL
+	}
+	if ttl > maxPreviewTTL {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Önizleme linki en fazla 24 saat geçerli olabilir"})
+		return
+	}
+
+	version, err := previewVersion(entityRef{entityType, id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	token, err := signPurposeToken("preview", jwt.MapClaims{
+		"created_by":  currentUserID(c),
+		"entity_type": entityType,
+		"entity_id":   id,
+		"version":     version,
+	}, ttl)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Token oluşturulamadı"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"token":      token,
+		"expires_at": time.Now().Add(ttl),
+	})
+}
+
+// revokePreviewTokensHandler kayıt için verilmiş tüm önizleme linklerini
+// önizleme sürümünü artırarak geçersiz kılar
+func revokePreviewTokensHandler(c *gin.Context) {
+	entityType, _, id, ok := revisionParams(c, true)
+	if !ok {
+		return
+	}
+
+	_, err := db.DB.Exec(
+		`INSERT INTO preview_versions (entity_type, entity_id, version) VALUES ($1, $2, 1)
+		ON CONFLICT (entity_type, entity_id) DO UPDATE SET version = preview_versions.version + 1`,
+		entityType, id,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Önizleme linkleri iptal edildi"})
+}
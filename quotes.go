@@ -0,0 +1,438 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"kozan/db"
+	"kozan/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Fiyat teklifleri. Yol ücreti iş emirlerinde olduğu gibi adresin hizmet
+// bölgesinden alınır; elle verilen ücret bölgeninkinin yerine geçer. Kabul
+// edilen teklif iş emrine dönüştürülür ve bölge ile yol ücreti aynen taşınır.
+var quoteStatuses = map[string]bool{"draft": true, "sent": true, "accepted": true, "rejected": true}
+
+// errQuoteConverted iş emrine dönüştürülmüş teklif değiştirilemez
+var errQuoteConverted = errors.New("Teklif iş emrine dönüştürülmüş, değiştirilemez")
+
+type quoteInput struct {
+	CustomerID int                `json:"customer_id"`
+	Customer   *models.Customer   `json:"customer"`
+	Title      string             `json:"title"`
+	Address    string             `json:"address"`
+	Latitude   *float64           `json:"latitude"`
+	Longitude  *float64           `json:"longitude"`
+	Items      []models.QuoteItem `json:"items"`
+	Status     string             `json:"status"`
+	ValidUntil *time.Time         `json:"valid_until"`
+	Notes      string             `json:"notes"`
+	// Verilmezse hizmet bölgesinden alınır
+	CallOutFee *float64 `json:"call_out_fee"`
+	// Hiçbir hizmet bölgesine düşmeyen adresi onaylar
+	OutsideServiceArea bool `json:"outside_service_area"`
+}
+
+// applyTo girdiyi teklife yazar ve doğrular; müşteri sadece oluştururken verilir
+func (in quoteInput) applyTo(q *models.Quote) error {
+	q.Title, q.Address, q.Latitude, q.Longitude = in.Title, strings.TrimSpace(in.Address), in.Latitude, in.Longitude
+	q.Items, q.Status, q.ValidUntil, q.Notes = in.Items, in.Status, in.ValidUntil, strings.TrimSpace(in.Notes)
+	if in.CallOutFee != nil && *in.CallOutFee < 0 {
+		return fmt.Errorf("Yol ücreti negatif olamaz")
+	}
+	if in.Status == "accepted" {
+		return fmt.Errorf("Teklif iş emrine dönüştürülerek kabul edilir")
+	}
+	return validateQuote(q)
+}
+
+// roundMoney tutarı kuruşa yuvarlar
+func roundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// quoteTotals kalemlerin toplamını ve yol ücretiyle genel toplamı hesaplar
+func quoteTotals(q *models.Quote) {
+	subtotal := 0.0
+	for _, item := range q.Items {
+		subtotal += roundMoney(item.Quantity * item.UnitPrice)
+	}
+	q.Subtotal = roundMoney(subtotal)
+	q.Total = roundMoney(q.Subtotal + q.CallOutFee)
+}
+
+// validateQuote başlığı, durumu ve kalemleri denetler
+func validateQuote(q *models.Quote) error {
+	q.Title = strings.TrimSpace(q.Title)
+	if q.Title == "" {
+		return fmt.Errorf("Başlık zorunludur")
+	}
+	if q.Status == "" {
+		q.Status = "draft"
+	}
+	if !quoteStatuses[q.Status] {
+		return fmt.Errorf("Geçersiz durum: %s", q.Status)
+	}
+	if (q.Latitude == nil) != (q.Longitude == nil) || (q.Latitude != nil && !validCoordinates(*q.Latitude, *q.Longitude)) {
+		return fmt.Errorf("Geçersiz koordinat")
+	}
+	if len(q.Items) == 0 {
+		return fmt.Errorf("En az bir kalem girilmeli")
+	}
+	for i := range q.Items {
+		item := &q.Items[i]
+		item.Description = strings.TrimSpace(item.Description)
+		if item.Description == "" || item.Quantity <= 0 || item.UnitPrice < 0 {
+			return fmt.Errorf("%d. kalem: açıklama, pozitif miktar ve negatif olmayan fiyat zorunludur", i+1)
+		}
+	}
+	return nil
+}
+
+// priceQuote teklifin bölgesini ve yol ücretini belirler, toplamları hesaplar
+func priceQuote(q *models.Quote, fee *float64, allowOutside bool) error {
+	zoneID, zoneFee, err := serviceZoneFee(serviceLocation{Address: q.Address, Latitude: q.Latitude, Longitude: q.Longitude})
+	if err != nil {
+		return err
+	}
+	q.ServiceZoneID, q.CallOutFee = zoneID, zoneFee
+	if fee != nil {
+		q.CallOutFee = *fee
+	}
+	if err := checkZoneCoverage(q.ServiceZoneID, q.Address, q.Latitude, fee != nil || allowOutside); err != nil {
+		return err
+	}
+	quoteTotals(q)
+	return nil
+}
+
+const quoteColumns = `id, customer_id, title, address, latitude, longitude, items, subtotal, service_zone_id,
+	call_out_fee, total, status, valid_until, notes, work_order_id, created_by, created_at, updated_at`
+
+func scanQuote(scanner interface{ Scan(...interface{}) error }) (models.Quote, error) {
+	var q models.Quote
+	var lat, lng sql.NullFloat64
+	var zoneID, workOrderID sql.NullInt64
+	var validUntil sql.NullTime
+	var items []byte
+	err := scanner.Scan(&q.ID, &q.CustomerID, &q.Title, &q.Address, &lat, &lng, &items, &q.Subtotal, &zoneID,
+		&q.CallOutFee, &q.Total, &q.Status, &validUntil, &q.Notes, &workOrderID, &q.CreatedBy, &q.CreatedAt, &q.UpdatedAt)
+	if err != nil {
+		return q, err
+	}
+	if lat.Valid && lng.Valid {
+		q.Latitude, q.Longitude = &lat.Float64, &lng.Float64
+	}
+	if zoneID.Valid {
+		v := int(zoneID.Int64)
+		q.ServiceZoneID = &v
+	}
+	if workOrderID.Valid {
+		v := int(workOrderID.Int64)
+		q.WorkOrderID = &v
+	}
+	if validUntil.Valid {
+		q.ValidUntil = &validUntil.Time
+	}
+	q.Items = []models.QuoteItem{}
+	json.Unmarshal(items, &q.Items)
+	return q, nil
+}
+
+func getQuote(q queryRower, id int) (models.Quote, error) {
+	return scanQuote(q.QueryRow("SELECT "+quoteColumns+" FROM quotes WHERE id = $1", id))
+}
+
+// respondQuoteError dönüştürülmüş teklifi 409, bölge hatalarını 400, diğerlerini 500 olarak döndürür
+func respondQuoteError(c *gin.Context, err error) {
+	if errors.Is(err, errQuoteConverted) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	respondZoneError(c, err)
+}
+
+func getQuotesHandler(c *gin.Context) {
+	query := "SELECT " + quoteColumns + " FROM quotes WHERE 1=1"
+	var args []interface{}
+	for _, param := range []string{"status", "customer_id"} {
+		if v := c.Query(param); v != "" {
+			args = append(args, v)
+			query += fmt.Sprintf(" AND %s = $%d", param, len(args))
+		}
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	offset, _ := strconv.Atoi(c.Query("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	args = append(args, limit, offset)
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	quotes := []models.Quote{}
+	for rows.Next() {
+		q, err := scanQuote(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		quotes = append(quotes, q)
+	}
+	c.JSON(http.StatusOK, quotes)
+}
+
+func getQuoteHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	q, err := getQuote(db.DB, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Teklif bulunamadı"})
+		return
+	}
+	if customer, err := getCustomer(q.CustomerID); err == nil {
+		q.Customer = &customer
+	}
+	c.JSON(http.StatusOK, q)
+}
+
+func createQuoteHandler(c *gin.Context) {
+	var input quoteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	q := models.Quote{CustomerID: input.CustomerID, CreatedBy: currentUserID(c)}
+	if err := input.applyTo(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Müşteri iş emirlerinde olduğu gibi customer_id ya da customer bilgileriyle verilir
+	customer := input.Customer
+	if q.CustomerID == 0 {
+		if customer == nil || strings.TrimSpace(customer.Name) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Müşteri bilgisi zorunludur"})
+			return
+		}
+		if customer.Phone != "" {
+			phone, err := normalizePhone(customer.Phone)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			customer.Phone = phone
+		}
+	} else if _, err := getCustomer(q.CustomerID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Müşteri bulunamadı"})
+		return
+	}
+	if err := priceQuote(&q, input.CallOutFee, input.OutsideServiceArea); err != nil {
+		respondQuoteError(c, err)
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	if q.CustomerID == 0 {
+		if q.CustomerID, err = findOrCreateCustomer(tx, *customer); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	items, _ := json.Marshal(q.Items)
+	err = tx.QueryRow(
+		`INSERT INTO quotes (customer_id, title, address, latitude, longitude, items, subtotal, service_zone_id,
+			call_out_fee, total, status, valid_until, notes, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id, created_at, updated_at`,
+		q.CustomerID, q.Title, q.Address, q.Latitude, q.Longitude, string(items), q.Subtotal, q.ServiceZoneID,
+		q.CallOutFee, q.Total, q.Status, q.ValidUntil, q.Notes, q.CreatedBy,
+	).Scan(&q.ID, &q.CreatedAt, &q.UpdatedAt)
+	if err == nil {
+		err = insertAudit(tx, requestActor(c), "quote", q.ID, auditCreate, nil, q)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, q)
+}
+
+// updateQuoteHandler teklifi tamamen değiştirir; müşteri değiştirilemez. Yol
+// ücreti verilmezse adresin bölgesinden yeniden hesaplanır.
+func updateQuoteHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	var input quoteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	before, err := getQuote(db.DB, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Teklif bulunamadı"})
+		return
+	}
+	if before.WorkOrderID != nil {
+		respondQuoteError(c, errQuoteConverted)
+		return
+	}
+
+	q := before
+	if err := input.applyTo(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := priceQuote(&q, input.CallOutFee, input.OutsideServiceArea); err != nil {
+		respondQuoteError(c, err)
+		return
+	}
+
+	items, _ := json.Marshal(q.Items)
+	err = db.DB.QueryRow(
+		`UPDATE quotes SET title = $1, address = $2, latitude = $3, longitude = $4, items = $5, subtotal = $6,
+			service_zone_id = $7, call_out_fee = $8, total = $9, status = $10, valid_until = $11, notes = $12, updated_at = NOW()
+		WHERE id = $13 AND work_order_id IS NULL RETURNING updated_at`,
+		q.Title, q.Address, q.Latitude, q.Longitude, string(items), q.Subtotal,
+		q.ServiceZoneID, q.CallOutFee, q.Total, q.Status, q.ValidUntil, q.Notes, id,
+	).Scan(&q.UpdatedAt)
+	if err == sql.ErrNoRows {
+		respondQuoteError(c, errQuoteConverted)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, "quote", id, auditUpdate, before, q)
+	c.JSON(http.StatusOK, q)
+}
+
+func deleteQuoteHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	before, err := getQuote(db.DB, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Teklif bulunamadı"})
+		return
+	}
+	if before.WorkOrderID != nil {
+		respondQuoteError(c, errQuoteConverted)
+		return
+	}
+	if _, err := db.DB.Exec("DELETE FROM quotes WHERE id = $1 AND work_order_id IS NULL", id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, "quote", id, auditDelete, before, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Teklif silindi"})
+}
+
+// convertQuoteHandler teklifi kabul eder ve iş emrine dönüştürür. İş emri
+// teklifin bölgesini ve yol ücretini taşır; randevu sonradan verilir.
+func convertQuoteHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	before, err := scanQuote(tx.QueryRow("SELECT "+quoteColumns+" FROM quotes WHERE id = $1 FOR UPDATE", id))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Teklif bulunamadı"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if before.WorkOrderID != nil {
+		respondQuoteError(c, errQuoteConverted)
+		return
+	}
+	if before.Status == "rejected" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Reddedilen teklif iş emrine dönüştürülemez"})
+		return
+	}
+
+	lines := make([]string, len(before.Items))
+	for i, item := range before.Items {
+		lines[i] = fmt.Sprintf("%s × %g", item.Description, item.Quantity)
+	}
+	wo := models.WorkOrder{
+		CustomerID: before.CustomerID, Title: before.Title, Description: strings.Join(lines, "\n"),
+		Address: before.Address, Latitude: before.Latitude, Longitude: before.Longitude,
+		ServiceZoneID: before.ServiceZoneID, CallOutFee: before.CallOutFee, CreatedBy: currentUserID(c),
+	}
+	if err := insertWorkOrder(tx, &wo); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	q := before
+	q.Status, q.WorkOrderID = "accepted", &wo.ID
+	err = tx.QueryRow(
+		"UPDATE quotes SET status = 'accepted', work_order_id = $1, updated_at = NOW() WHERE id = $2 RETURNING updated_at",
+		wo.ID, id,
+	).Scan(&q.UpdatedAt)
+	if err == nil {
+		err = insertAudit(tx, requestActor(c), "quote", id, auditUpdate, before, q)
+	}
+	if err == nil {
+		err = insertAudit(tx, requestActor(c), "work_order", wo.ID, auditCreate, nil, wo)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	emitEvent(eventWorkOrderCreated, wo)
+	c.JSON(http.StatusCreated, gin.H{"quote": q, "work_order": wo})
+}
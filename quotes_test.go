@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"kozan/models"
+)
+
+func TestQuoteTotals(t *testing.T) {
+	tests := []struct {
+		name     string
+		items    []models.QuoteItem
+		fee      float64
+		subtotal float64
+		total    float64
+	}{
+		{"yol ücretsiz", []models.QuoteItem{{Description: "Klima montajı", Quantity: 1, UnitPrice: 1500}}, 0, 1500, 1500},
+		{"uzak köy", []models.QuoteItem{{Description: "Klima montajı", Quantity: 1, UnitPrice: 1500}, {Description: "Bakır boru (m)", Quantity: 3, UnitPrice: 120}}, 250, 1860, 2110},
+		{"kuruş yuvarlama", []models.QuoteItem{{Description: "Gaz dolumu (kg)", Quantity: 1.333, UnitPrice: 450}}, 0, 599.85, 599.85},
+		{"kalem kalem yuvarlanır", []models.QuoteItem{{Description: "Vida", Quantity: 3, UnitPrice: 0.125}, {Description: "Dübel", Quantity: 3, UnitPrice: 0.125}}, 0.1, 0.76, 0.86},
+	}
+	for _, tt := range tests {
+		q := models.Quote{Items: tt.items, CallOutFee: tt.fee}
+		quoteTotals(&q)
+		if q.Subtotal != tt.subtotal || q.Total != tt.total {
+			t.Errorf("%s: quoteTotals = %v, %v, want %v, %v", tt.name, q.Subtotal, q.Total, tt.subtotal, tt.total)
+		}
+	}
+}
+
+func TestQuoteInputApplyTo(t *testing.T) {
+	item := []models.QuoteItem{{Description: "Klima montajı", Quantity: 1, UnitPrice: 1500}}
+	fee, negative := 300.0, -1.0
+	lat, lng := 37.45, 35.81
+	tests := []struct {
+		name  string
+		input quoteInput
+		ok    bool
+	}{
+		{"geçerli", quoteInput{Title: "Salon kliması", Items: item}, true},
+		{"elle yol ücreti", quoteInput{Title: "Salon kliması", Items: item, CallOutFee: &fee}, true},
+		{"koordinatlı", quoteInput{Title: "Salon kliması", Items: item, Latitude: &lat, Longitude: &lng}, true},
+		{"başlık yok", quoteInput{Title: " ", Items: item}, false},
+		{"kalem yok", quoteInput{Title: "Salon kliması"}, false},
+		{"sıfır miktar", quoteInput{Title: "Salon kliması", Items: []models.QuoteItem{{Description: "Montaj", Quantity: 0, UnitPrice: 100}}}, false},
+		{"negatif fiyat", quoteInput{Title: "Salon kliması", Items: []models.QuoteItem{{Description: "İndirim", Quantity: 1, UnitPrice: -50}}}, false},
+		{"negatif yol ücreti", quoteInput{Title: "Salon kliması", Items: item, CallOutFee: &negative}, false},
+		{"eksik koordinat", quoteInput{Title: "Salon kliması", Items: item, Latitude: &lat}, false},
+		{"geçersiz durum", quoteInput{Title: "Salon kliması", Items: item, Status: "pending"}, false},
+		{"doğrudan kabul", quoteInput{Title: "Salon kliması", Items: item, Status: "accepted"}, false},
+	}
+	for _, tt := range tests {
+		var q models.Quote
+		err := tt.input.applyTo(&q)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: applyTo error = %v, want ok %v", tt.name, err, tt.ok)
+		}
+		if err == nil && q.Status != "draft" && tt.input.Status == "" {
+			t.Errorf("%s: status = %q, want draft", tt.name, q.Status)
+		}
+	}
+}
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"kozan/db"
+	"kozan/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errInvalidRevision revizyon verisi güncel doğrulamadan geçmediğinde döner
+var errInvalidRevision = errors.New("revizyon geri yüklenemez")
+
+// invalidRevision doğrulama hatasını errInvalidRevision ile sarar
+func invalidRevision(err error) error {
+	return fmt.Errorf("%w: %v", errInvalidRevision, err)
+}
+
+// revisionEntity revizyon tutulan bir içerik tipini tanımlar
+type revisionEntity struct {
+	readPerm  string
+	writePerm string
+	// load kaydın güncel halini getirir
+	load func(id int) (interface{}, error)
+	// restore revizyon verisini güncel kurallarla doğrular, işlem içinde kayda
+	// uygular ve yeni hali döndürür
+	restore func(tx *sql.Tx, id int, before interface{}, data []byte) (interface{}, error)
+	// restored geri yükleme tamamlandıktan sonra olayları yayınlar
+	restored func(c *gin.Context, id int, before, after interface{})
+}
+
+// adminEventOnly sadece panele olay gönderen tekil içerikler için
+func adminEventOnly(eventType, entityType string) func(c *gin.Context, id int, before, after interface{}) {
+	return func(c *gin.Context, id int, _, after interface{}) {
+		publishAdminEvent(c, eventType, entityType, id, after)
+	}
+}
+
+var revisionEntities = map[string]revisionEntity{
+	"product": {
+		readPerm:  permProductsRead,
+		writePerm: permProductsWrite,
+		load:      func(id int) (interface{}, error) { return getProduct(id) },
+		restore: func(tx *sql.Tx, id int, before interface{}, data []byte) (interface{}, error) {
+			var product models.Product
+			if err := json.Unmarshal(data, &product); err != nil {
+				return nil, err
+			}
+			if err := normalizeRichText(&product.Description, &product.DescriptionFormat); err != nil {
+				return nil, invalidRevision(err)
+			}
+			product.ID = id
+			// Eski revizyondaki slug başka bir kayda geçmiş olabilir; updateProduct
+			// slug'ı yeniden hazırlar
+			err := updateProduct(tx, before.(models.Product), &product)
+			return product, err
+		},
+		restored: adminEventOnly(eventProductUpdated, "product"),
+	},
+	"service": {
+		readPerm:  permServicesRead,
+		writePerm: permServicesWrite,
+		load:      func(id int) (interface{}, error) { return getService(id) },
+		restore: func(tx *sql.Tx, id int, before interface{}, data []byte) (interface{}, error) {
+			var service models.Service
+			if err := json.Unmarshal(data, &service); err != nil {
+				return nil, err
+			}
+			if err := normalizeRichText(&service.Description, &service.DescriptionFormat); err != nil {
+				return nil, invalidRevision(err)
+			}
+			service.ID = id
+			// Eski revizyondaki slug başka bir kayda geçmiş olabilir; updateService
+			// slug'ı yeniden hazırlar
+			err := updateService(tx, before.(models.Service), &service)
+			return service, err
+		},
+		restored: func(c *gin.Context, _ int, _, after interface{}) {
+			serviceUpdated(c, after.(models.Service))
+		},
+	},
+	"about": {
+		readPerm:  permContentRead,
+		writePerm: permContentWrite,
+		load:      func(id int) (interface{}, error) { return getAbout() },
+		restore: func(tx *sql.Tx, id int, _ interface{}, data []byte) (interface{}, error) {
+			var about models.About
+			if err := json.Unmarshal(data, &about); err != nil {
+				return nil, err
+			}
+			if err := normalizeRichText(&about.Content, &about.ContentFormat); err != nil {
+				return nil, invalidRevision(err)
+			}
+			err := saveAbout(tx, &about, id)
+			return about, err
+		},
+		restored: adminEventOnly(eventAboutUpdated, "about"),
+	},
+	"contact": {
+		readPerm:  permContentRead,
+		writePerm: permContentWrite,
+		load:      func(id int) (interface{}, error) { return getContact() },
+		restore: func(tx *sql.Tx, id int, _ interface{}, data []byte) (interface{}, error) {
+			var contact models.Contact
+			if err := json.Unmarshal(data, &contact); err != nil {
+				return nil, err
+			}
+			if err := validateContact(&contact); err != nil {
+				return nil, invalidRevision(err)
+			}
+			err := saveContact(tx, &contact, id)
+			return contact, err
+		},
+		restored: adminEventOnly(eventContactUpdated, "contact"),
+	},
+	"hero": {
+		readPerm:  permContentRead,
+		writePerm: permContentWrite,
+		load:      func(id int) (interface{}, error) { return getHero() },
+		restore: func(tx *sql.Tx, id int, _ interface{}, data []byte) (interface{}, error) {
+			var hero models.Hero
+			if err := json.Unmarshal(data, &hero); err != nil {
+				return nil, err
+			}
+			if err := validateHero(&hero); err != nil {
+				return nil, invalidRevision(err)
+			}
+			err := saveHero(tx, &hero, id)
+			return hero, err
+		},
+		restored: adminEventOnly(eventHeroUpdated, "hero"),
+	},
+	"footer": {
+		readPerm:  permContentRead,
+		writePerm: permContentWrite,
+		load:      func(id int) (interface{}, error) { return getFooter() },
+		restore: func(tx *sql.Tx, id int, _ interface{}, data []byte) (interface{}, error) {
+			var footer models.Footer
+			if err := json.Unmarshal(data, &footer); err != nil {
+				return nil, err
+			}
+			if err := validateFooter(&footer); err != nil {
+				return nil, invalidRevision(err)
+			}
+			err := saveFooter(tx, &footer, id)
+			return footer, err
+		},
+		restored: adminEventOnly(eventFooterUpdated, "footer"),
+	},
+	"campaign": {
+		readPerm:  permContentRead,
+		writePerm: permContentWrite,
+		load:      func(id int) (interface{}, error) { return getCampaign(id) },
+		restore: func(tx *sql.Tx, id int, _ interface{}, data []byte) (interface{}, error) {
+			var campaign models.Campaign
+			if err := json.Unmarshal(data, &campaign); err != nil {
+				return nil, err
+			}
+			if err := validateCampaign(&campaign); err != nil {
+				return nil, invalidRevision(err)
+			}
+			campaign.ID = id
+			return campaign, updateCampaign(tx, campaign)
+		},
+	},
+}
+
+// singletonChange tekil içeriklerde (hakkımızda, iletişim, hero, footer) kayıt
+// yoksa oluşturma, varsa güncelleme olarak audit aksiyonunu seçer
+func singletonChange(existingID int, before interface{}) (string, interface{}) {
+	if existingID == 0 {
+		return auditCreate, nil
+	}
+	return auditUpdate, before
+}
+
+// saveContentChange içerik değişikliğini, audit kaydını ve yeni revizyonu tek
+// işlemde yazar; biri başarısız olursa hiçbiri kalıcı olmaz. mutate kaydı
+// işlemde yazar, kimliğini ve yeni halini döndürür. Olaylar işlem
+// tamamlandıktan sonra çağıran tarafından yayınlanır.
+func saveContentChange(c *gin.Context, entityType, action string, before interface{}, restoredFrom int,
+	mutate func(tx *sql.Tx) (int, interface{}, error)) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	id, after, err := mutate(tx)
+	if err != nil {
+		return err
+	}
+	if err := insertAudit(tx, requestActor(c), entityType, id, action, before, after); err != nil {
+		return err
+	}
+	if err := saveRevision(tx, c, entityType, id, after, restoredFrom); err != nil {
+		return fmt.Errorf("Revizyon kaydedilemedi: %w", err)
+	}
+	return tx.Commit()
+}
+
+// saveRevision kaydın yeni halini değiştirilemez bir revizyon olarak saklar.
+// restoredFrom sıfırdan farklıysa revizyon bir geri yüklemedir. Eşzamanlı
+// kayıtların aynı revizyon numarasını almaması için numara, kayıt başına bir
+// advisory lock altında değişiklikle aynı işlemde hesaplanır.
+func saveRevision(tx *sql.Tx, c *gin.Context, entityType string, entityID int, data interface{}, restoredFrom int) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	var restored interface{}
+	if restoredFrom != 0 {
+		restored = restoredFrom
+	}
+
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext('content_revisions:' || $1), $2)", entityType, entityID); err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		`INSERT INTO content_revisions (entity_type, entity_id, revision, data, author_id, author_username, restored_from)
+		SELECT $1, $2, COALESCE(MAX(revision), 0) + 1, $3, $4, $5, $6
+		FROM content_revisions WHERE entity_type = $1 AND entity_id = $2`,
+		entityType, entityID, string(payload), currentUserID(c), c.GetString("username"), restored,
+	)
+	return err
+}
+
+func getRevision(entityType string, entityID, revision int) (models.Revision, error) {
+	var r models.Revision
+	var data []byte
+	var restoredFrom sql.NullInt64
+	err := db.DB.QueryRow(
+		`SELECT id, entity_type, entity_id, revision, data, author_id, author_username, restored_from, created_at
+		FROM content_revisions WHERE entity_type = $1 AND entity_id = $2 AND revision = $3`,
+		entityType, entityID, revision,
+	).Scan(&r.ID, &r.EntityType, &r.EntityID, &r.Revision, &data, &r.AuthorID, &r.AuthorUsername, &restoredFrom, &r.CreatedAt)
+	r.Data = json.RawMessage(data)
+	if restoredFrom.Valid {
+		v := int(restoredFrom.Int64)
+		r.RestoredFrom = &v
+	}
+	return r, err
+}
+
+// revisionParams :entity ve :id parametrelerini okur ve yetkiyi kontrol eder
+func revisionParams(c *gin.Context, write bool) (string, revisionEntity, int, bool) {
+	entityType := c.Param("entity")
+	entity, ok := revisionEntities[entityType]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Bilinmeyen içerik tipi"})
+		return "", entity, 0, false
+	}
+
+	perm := entity.readPerm
+	if write {
+		perm = entity.writePerm
+	}
+	if !hasPermission(c, perm) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Bu işlem için yetkiniz yok"})
+		return "", entity, 0, false
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return "", entity, 0, false
+	}
+	return entityType, entity, id, true
+}
+
+// Revizyon işlemleri
+func getRevisionsHandler(c *gin.Context) {
+	entityType, _, id, ok := revisionParams(c, false)
+	if !ok {
+		return
+	}
+
+	rows, err := db.DB.Query(
+		`SELECT id, entity_type, entity_id, revision, data, author_id, author_username, restored_from, created_at
+		FROM content_revisions WHERE entity_type = $1 AND entity_id = $2 ORDER BY revision DESC`,
+		entityType, id,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	revisions := []models.Revision{}
+	for rows.Next() {
+		var r models.Revision
+		var data []byte
+		var restoredFrom sql.NullInt64
+		if err := rows.Scan(&r.ID, &r.EntityType, &r.EntityID, &r.Revision, &data, &r.AuthorID, &r.AuthorUsername, &restoredFrom, &r.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		r.Data = json.RawMessage(data)
+		if restoredFrom.Valid {
+			v := int(restoredFrom.Int64)
+			r.RestoredFrom = &v
+		}
+		revisions = append(revisions, r)
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+func diffRevisionsHandler(c *gin.Context) {
+	entityType, _, id, ok := revisionParams(c, false)
+	if !ok {
+		return
+	}
+
+	from, err1 := strconv.Atoi(c.Query("from"))
+	to, err2 := strconv.Atoi(c.Query("to"))
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from ve to revizyon numaraları zorunludur"})
+		return
+	}
+
+	fromRev, err := getRevision(entityType, id, from)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revizyon bulunamadı"})
+		return
+	}
+	toRev, err := getRevision(entityType, id, to)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revizyon bulunamadı"})
+		return
+	}
+
+	var fromData, toData map[string]interface{}
+	json.Unmarshal(fromRev.Data, &fromData)
+	json.Unmarshal(toRev.Data, &toData)
+
+	c.JSON(http.StatusOK, gin.H{
+		"from": from,
+		"to":   to,
+		"diff": jsonDiff(fromData, toData),
+	})
+}
+
+func restoreRevisionHandler(c *gin.Context) {
+	entityType, entity, id, ok := revisionParams(c, true)
+	if !ok {
+		return
+	}
+
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz revizyon"})
+		return
+	}
+
+	rev, err := getRevision(entityType, id, revision)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revizyon bulunamadı"})
+		return
+	}
+
+	before, err := entity.load(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kayıt bulunamadı, silinmiş olabilir"})
+		return
+	}
+
+	var restored interface{}
+	err = saveContentChange(c, entityType, auditUpdate, before, revision, func(tx *sql.Tx) (int, interface{}, error) {
+		var err error
+		restored, err = entity.restore(tx, id, before, rev.Data)
+		return id, restored, err
+	})
+	if errors.Is(err, errInvalidRevision) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if entity.restored != nil {
+		entity.restored(c, id, before, restored)
+	}
+	c.JSON(http.StatusOK, restored)
+}
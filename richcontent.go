@@ -0,0 +1,57 @@
+package main
+
+import "kozan/richtext"
+
+// Zengin metin alanları: içerik alanı ve biçimini tutan alan (JSON adlarıyla)
+type richTextField struct {
+	field       string
+	formatField string
+}
+
+var richTextFields = map[string][]richTextField{
+	"product":  {{"description", "descriptionFormat"}},
+	"service":  {{"description", "descriptionFormat"}},
+	"about":    {{"content", "contentFormat"}},
+	"campaign": {{"description", "descriptionFormat"}},
+}
+
+// Public API'de dönen düz metin özetin uzunluğu
+const excerptLength = 160
+
+// normalizeRichText yazma sırasında biçimi doğrular ve HTML'i temizler
+func normalizeRichText(value, format *string) error {
+	if *format == "" {
+		*format = richtext.FormatText
+	}
+	normalized, err := richtext.Normalize(*value, *format)
+	if err != nil {
+		return err
+	}
+	*value = normalized
+	return nil
+}
+
+// richTextFormat alan zengin metinse kaydın biçimini döndürür
+func richTextFormat(entityType, field string, record map[string]interface{}) (string, bool) {
+	for _, f := range richTextFields[entityType] {
+		if f.field == field {
+			format, _ := record[f.formatField].(string)
+			return format, true
+		}
+	}
+	return "", false
+}
+
+// renderRichText public yanıtlara <alan>Html ve <alan>Excerpt alanlarını ekler.
+// Biçimi olmayan eski yayın kayıtları düz metin kabul edilir.
+func renderRichText(entityType string, items []map[string]interface{}) {
+	for _, item := range items {
+		for _, f := range richTextFields[entityType] {
+			value, _ := item[f.field].(string)
+			format, _ := item[f.formatField].(string)
+			rendered := richtext.Render(value, format)
+			item[f.field+"Html"] = rendered
+			item[f.field+"Excerpt"] = richtext.Excerpt(richtext.PlainText(rendered), excerptLength)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package richtext
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RenderMarkdown admin panelinde kullanılan Markdown alt kümesini HTML'e çevirir:
+// başlıklar (##, ###, ####), paragraflar, sıralı/sırasız listeler, alıntılar,
+// kalın, italik, satır içi kod ve linkler. Kaynaktaki HTML kaçışlanır.
+func RenderMarkdown(src string) string {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	var b strings.Builder
+	var para []string
+	list := ""
+
+	flushPara := func() {
+		if len(para) > 0 {
+			b.WriteString("<p>" + strings.Join(para, "<br>") + "</p>")
+			para = nil
+		}
+	}
+	closeList := func() {
+		if list != "" {
+			b.WriteString("</" + list + ">")
+			list = ""
+		}
+	}
+	openList := func(tag string) {
+		if list != tag {
+			closeList()
+			b.WriteString("<" + tag + ">")
+			list = tag
+		}
+	}
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flushPara()
+			closeList()
+		case headingRe.MatchString(trimmed):
+			flushPara()
+			closeList()
+			m := headingRe.FindStringSubmatch(trimmed)
+			// Sayfa başlığı h1 olduğu için içerik başlıkları h2'den başlar
+			level := len(m[1]) + 1
+			if level > 4 {
+				level = 4
+			}
+			tag := "h" + strconv.Itoa(level)
+			b.WriteString("<" + tag + ">" + renderInline(m[2]) + "</" + tag + ">")
+		case unorderedRe.MatchString(trimmed):
+			flushPara()
+			openList("ul")
+			b.WriteString("<li>" + renderInline(unorderedRe.FindStringSubmatch(trimmed)[1]) + "</li>")
+		case orderedRe.MatchString(trimmed):
+			flushPara()
+			openList("ol")
+			b.WriteString("<li>" + renderInline(orderedRe.FindStringSubmatch(trimmed)[1]) + "</li>")
+		case strings.HasPrefix(trimmed, ">"):
+			flushPara()
+			closeList()
+			b.WriteString("<blockquote>" + renderInline(strings.TrimSpace(strings.TrimPrefix(trimmed, ">"))) + "</blockquote>")
+		default:
+			closeList()
+			para = append(para, renderInline(trimmed))
+		}
+	}
+	flushPara()
+	closeList()
+
+	// Üretilen HTML de allowlist'ten geçirilir
+	return Sanitize(b.String())
+}
+
+var (
+	headingRe   = regexp.MustCompile(`^(#{1,4})\s+(.+)$`)
+	unorderedRe = regexp.MustCompile(`^[-*+]\s+(.+)$`)
+	orderedRe   = regexp.MustCompile(`^\d+[.)]\s+(.+)$`)
+
+	codeRe   = regexp.MustCompile("`([^`]+)`")
+	linkRe   = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	boldRe   = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	italicRe = regexp.MustCompile(`\*([^*]+)\*|\b_([^_]+)_\b`)
+)
+
+// renderInline satır içi biçimlendirmeyi uygular. Metin önce kaçışlanır,
+// böylece kaynaktaki HTML etiketleri metin olarak görünür.
+func renderInline(text string) string {
+	text = html.EscapeString(text)
+
+	// Kod parçaları diğer kurallardan etkilenmesin diye yer tutucuyla saklanır
+	var codes []string
+	text = codeRe.ReplaceAllStringFunc(text, func(m string) string {
+		codes = append(codes, "<code>"+codeRe.FindStringSubmatch(m)[1]+"</code>")
+		return "\x00" + strconv.Itoa(len(codes)-1) + "\x00"
+	})
+
+	text = linkRe.ReplaceAllStringFunc(text, func(m string) string {
+		parts := linkRe.FindStringSubmatch(m)
+		href := html.UnescapeString(parts[2])
+		if !safeURL(href) {
+			return parts[1]
+		}
+		return `<a href="` + html.EscapeString(href) + `">` + parts[1] + "</a>"
+	})
+	text = boldRe.ReplaceAllString(text, "<strong>$1$2</strong>")
+	text = italicRe.ReplaceAllString(text, "<em>$1$2</em>")
+
+	for i, code := range codes {
+		text = strings.Replace(text, "\x00"+strconv.Itoa(i)+"\x00", code, 1)
+	}
+	return text
+}
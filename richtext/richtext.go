@@ -0,0 +1,88 @@
+// Package richtext admin panelinden gelen zengin metni (Markdown veya kısıtlı
+// HTML) güvenli HTML'e çevirir ve düz metin özet üretir.
+package richtext
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// Desteklenen içerik biçimleri
+const (
+	FormatText     = "text"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// ValidFormat biçimin desteklenip desteklenmediğini döndürür. Boş değer düz metindir.
+func ValidFormat(format string) bool {
+	switch format {
+	case "", FormatText, FormatMarkdown, FormatHTML:
+		return true
+	}
+	return false
+}
+
+// Normalize yazma sırasında çağrılır. HTML allowlist'e göre temizlenir,
+// Markdown ve düz metin kaynak olarak saklanır (okurken kaçışlanır).
+func Normalize(value, format string) (string, error) {
+	if !ValidFormat(format) {
+		return "", fmt.Errorf("desteklenmeyen biçim: %s", format)
+	}
+	value = strings.ToValidUTF8(strings.ReplaceAll(value, "\x00", ""), "")
+	if format == FormatHTML {
+		return Sanitize(value), nil
+	}
+	return value, nil
+}
+
+// Render saklanan değeri güvenli HTML olarak döndürür
+func Render(value, format string) string {
+	switch format {
+	case FormatHTML:
+		// Yazarken temizlenmiş olsa da eski kayıtlar için tekrar temizlenir
+		return Sanitize(value)
+	case FormatMarkdown:
+		return RenderMarkdown(value)
+	default:
+		return renderText(value)
+	}
+}
+
+// renderText düz metni paragraflara böler
+func renderText(value string) string {
+	var b strings.Builder
+	for _, para := range splitParagraphs(value) {
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(para), "\n", "<br>"))
+		b.WriteString("</p>")
+	}
+	return b.String()
+}
+
+func splitParagraphs(value string) []string {
+	value = strings.ReplaceAll(value, "\r\n", "\n")
+	var paras []string
+	for _, p := range strings.Split(value, "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			paras = append(paras, p)
+		}
+	}
+	return paras
+}
+
+// Excerpt metni kelime sınırında en fazla limit karakter olacak şekilde kısaltır
+func Excerpt(text string, limit int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	runes := []rune(text)
+	cut := string(runes[:limit])
+	if i := strings.LastIndex(cut, " "); i > limit/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
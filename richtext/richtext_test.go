@@ -0,0 +1,121 @@
+package richtext
+
+import "testing"
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"izinli etiketler", "<p>Merhaba <strong>dünya</strong></p>", "<p>Merhaba <strong>dünya</strong></p>"},
+		{"script içeriğiyle atılır", "<p>a</p><script>alert(1)</script><p>b</p>", "<p>a</p><p>b</p>"},
+		{"iç içe atılan etiket", "<svg><script>x</script><g>y</g></svg>z", "z"},
+		{"izinsiz etiketin metni kalır", "<div><span>metin</span></div>", "metin"},
+		{"olay öznitelikleri atılır", `<p onclick="x()" class="c">a</p>`, "<p>a</p>"},
+		{"güvenli link", `<a href="https://kozan.example/servis" title="Servis">git</a>`,
+			`<a href="https://kozan.example/servis" title="Servis" rel="nofollow noopener noreferrer">git</a>`},
+		{"javascript linki", `<a href="javascript:alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer">x</a>`},
+		{"büyük harfli javascript", `<a href="JaVaScRiPt:alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer">x</a>`},
+		{"varlıkla gizlenmiş javascript", `<a href="javascript&#58;alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer">x</a>`},
+		{"data adresi", `<a href="data:text/html,x">x</a>`, `<a rel="nofollow noopener noreferrer">x</a>`},
+		{"tel ve göreli adres", `<a href="tel:+903220000000">ara</a><a href="/iletisim">iletişim</a>`,
+			`<a href="tel:+903220000000" rel="nofollow noopener noreferrer">ara</a><a href="/iletisim" rel="nofollow noopener noreferrer">iletişim</a>`},
+		{"öznitelik kaçışlanır", `<a href="/a?x=1&y=&quot;2&quot;">x</a>`, `<a href="/a?x=1&amp;y=&#34;2&#34;" rel="nofollow noopener noreferrer">x</a>`},
+		{"metin kaçışlanır", "1 < 2 & 3 > 2", "1 &lt; 2 &amp; 3 &gt; 2"},
+		{"kapatılmamış etiketler kapatılır", "<ul><li>a<li>b", "<ul><li>a<li>b</li></li></ul>"},
+		{"açılmamış kapanış yok sayılır", "a</strong></p>", "a"},
+		{"aradaki etiketler de kapatılır", "<p><em>a</p>b", "<p><em>a</em></p>b"},
+		{"br boş etikettir", "a<br>b<br/>c</br>", "a<br>b<br>c"},
+		{"img atılır", `<img src="x" onerror="alert(1)">`, ""},
+		{"yorum atılır", "a<!-- <script>x</script> -->b", "ab"},
+	}
+	for _, tt := range tests {
+		if got := Sanitize(tt.in); got != tt.want {
+			t.Errorf("%s: Sanitize(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"paragraflar", "a\nb\n\nc", "<p>a<br>b</p><p>c</p>"},
+		{"başlık h2'den başlar", "# Başlık\n#### Alt", "<h2>Başlık</h2><h4>Alt</h4>"},
+		{"listeler", "- a\n- b\n1. c", "<ul><li>a</li><li>b</li></ul><ol><li>c</li></ol>"},
+		{"alıntı", "> söz", "<blockquote>söz</blockquote>"},
+		{"satır içi", "**kalın** *italik* `kod`", "<p><strong>kalın</strong> <em>italik</em> <code>kod</code></p>"},
+		{"kod içi biçimlenmez", "`**x**`", "<p><code>**x**</code></p>"},
+		{"link", "[servis](https://kozan.example)", `<p><a href="https://kozan.example" rel="nofollow noopener noreferrer">servis</a></p>`},
+		{"güvensiz link metne döner", "[x](javascript:void)", "<p>x</p>"},
+		{"kaynaktaki HTML kaçışlanır", "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+	}
+	for _, tt := range tests {
+		if got := RenderMarkdown(tt.in); got != tt.want {
+			t.Errorf("%s: RenderMarkdown(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		value, format, want string
+	}{
+		{"a <b>\n\nc", FormatText, "<p>a &lt;b&gt;</p><p>c</p>"},
+		{"a\nb", "", "<p>a<br>b</p>"},
+		{"<p>a</p><script>x</script>", FormatHTML, "<p>a</p>"},
+		{"**a**", FormatMarkdown, "<p><strong>a</strong></p>"},
+	}
+	for _, tt := range tests {
+		if got := Render(tt.value, tt.format); got != tt.want {
+			t.Errorf("Render(%q, %q) = %q, want %q", tt.value, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	if _, err := Normalize("x", "rtf"); err == nil {
+		t.Error("Normalize desteklenmeyen biçimi kabul etti")
+	}
+	if got, _ := Normalize("<p>a</p><script>x</script>", FormatHTML); got != "<p>a</p>" {
+		t.Errorf("Normalize HTML = %q", got)
+	}
+	if got, _ := Normalize("a\x00b\xff", FormatMarkdown); got != "ab" {
+		t.Errorf("Normalize Markdown = %q", got)
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"<p>a</p><p>b</p>", "a b"},
+		{"<ul><li>bir</li><li>iki</li></ul>", "bir iki"},
+		{"a<script>x</script>b", "ab"},
+		{"<strong>kal</strong>ın", "kalın"},
+		{"a &amp; b", "a & b"},
+	}
+	for _, tt := range tests {
+		if got := PlainText(tt.in); got != tt.want {
+			t.Errorf("PlainText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		in    string
+		limit int
+		want  string
+	}{
+		{"kısa metin", 20, "kısa metin"},
+		{"  boşluklar   birleşir  ", 30, "boşluklar birleşir"},
+		{"kombi bakımı ve onarımı yapılır", 20, "kombi bakımı ve…"},
+		{"kombi, bakım", 7, "kombi…"},
+		{"çokuzunkelimebölünür", 10, "çokuzunkel…"},
+	}
+	for _, tt := range tests {
+		if got := Excerpt(tt.in, tt.limit); got != tt.want {
+			t.Errorf("Excerpt(%q, %d) = %q, want %q", tt.in, tt.limit, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package richtext
+
+import (
+	"html"
+	"net/url"
+	"strings"
+
+	nethtml "golang.org/x/net/html"
+)
+
+// İzin verilen etiketler ve öznitelikleri
+var allowedTags = map[string][]string{
+	"p":          nil,
+	"br":         nil,
+	"strong":     nil,
+	"b":          nil,
+	"em":         nil,
+	"i":          nil,
+	"u":          nil,
+	"ul":         nil,
+	"ol":         nil,
+	"li":         nil,
+	"h2":         nil,
+	"h3":         nil,
+	"h4":         nil,
+	"blockquote": nil,
+	"code":       nil,
+	"pre":        nil,
+	"a":          {"href", "title"},
+}
+
+// İçeriğiyle birlikte tamamen atılan etiketler
+var droppedTags = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"iframe":   true,
+	"object":   true,
+	"embed":    true,
+	"noscript": true,
+	"template": true,
+	"svg":      true,
+	"math":     true,
+}
+
+var voidTags = map[string]bool{"br": true}
+
+// Düz metne çevirirken boşlukla ayrılan blok etiketleri
+var blockTags = map[string]bool{
+	"p": true, "br": true, "li": true, "ul": true, "ol": true,
+	"h2": true, "h3": true, "h4": true, "blockquote": true, "pre": true, "div": true,
+}
+
+// safeURL sadece http, https, mailto, tel ve göreli adreslere izin verir
+func safeURL(raw string) bool {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https", "mailto", "tel":
+		return true
+	}
+	return false
+}
+
+// Sanitize HTML'i allowlist'e göre temizler. İzin verilmeyen etiketler atılır
+// ama metinleri korunur; script gibi etiketler içerikleriyle birlikte atılır.
+func Sanitize(input string) string {
+	z := nethtml.NewTokenizer(strings.NewReader(input))
+	var b strings.Builder
+	var open []string
+	skipDepth := 0
+
+	for {
+		// Girdinin sonu (io.EOF) veya okunamayan girdi
+		tt := z.Next()
+		if tt == nethtml.ErrorToken {
+			break
+		}
+		token := z.Token()
+		name := token.Data
+
+		switch tt {
+		case nethtml.TextToken:
+			if skipDepth == 0 {
+				b.WriteString(html.EscapeString(token.Data))
+			}
+		case nethtml.StartTagToken, nethtml.SelfClosingTagToken:
+			if droppedTags[name] {
+				if tt == nethtml.StartTagToken {
+					skipDepth++
+				}
+				continue
+			}
+			attrs, ok := allowedTags[name]
+			if !ok || skipDepth > 0 {
+				continue
+			}
+			b.WriteString("<" + name)
+			for _, attr := range token.Attr {
+				if !contains(attrs, attr.Key) {
+					continue
+				}
+				if attr.Key == "href" && !safeURL(attr.Val) {
+					continue
+				}
+				b.WriteString(" " + attr.Key + `="` + html.EscapeString(attr.Val) + `"`)
+			}
+			if name == "a" {
+				b.WriteString(` rel="nofollow noopener noreferrer"`)
+			}
+			b.WriteString(">")
+			if !voidTags[name] {
+				open = append(open, name)
+			}
+		case nethtml.EndTagToken:
+			if droppedTags[name] {
+				if skipDepth > 0 {
+					skipDepth--
+				}
+				continue
+			}
+			if _, ok := allowedTags[name]; !ok || voidTags[name] || skipDepth > 0 {
+				continue
+			}
+			// Sadece açık olan bir etiket kapatılır, aradakiler de kapatılır
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] == name {
+					for j := len(open) - 1; j >= i; j-- {
+						b.WriteString("</" + open[j] + ">")
+					}
+					open = open[:i]
+					break
+				}
+			}
+		}
+	}
+
+	// Kapatılmamış etiketleri kapat
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i] + ">")
+	}
+	return b.String()
+}
+
+// PlainText HTML'den etiketleri ayıklayıp düz metin döndürür
+func PlainText(input string) string {
+	z := nethtml.NewTokenizer(strings.NewReader(input))
+	var b strings.Builder
+	skipDepth := 0
+	for {
+		tt := z.Next()
+		if tt == nethtml.ErrorToken {
+			break
+		}
+		token := z.Token()
+		switch tt {
+		case nethtml.TextToken:
+			if skipDepth == 0 {
+				b.WriteString(token.Data)
+			}
+		case nethtml.StartTagToken, nethtml.SelfClosingTagToken:
+			if droppedTags[token.Data] && tt == nethtml.StartTagToken {
+				skipDepth++
+			}
+			// Blok etiketleri kelimelerin birleşmesini engellemek için boşluk olur
+			if blockTags[token.Data] {
+				b.WriteString(" ")
+			}
+		case nethtml.EndTagToken:
+			if droppedTags[token.Data] && skipDepth > 0 {
+				skipDepth--
+			}
+			if blockTags[token.Data] {
+				b.WriteString(" ")
+			}
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,653 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"kozan/db"
+	"kozan/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// Günlük rota planı. Her teknisyenin o günkü randevuları, dükkândan
+// (iletişim bilgilerindeki enlem/boylam) çıkıp dönen tek araçlı, zaman
+// pencereli bir rota problemi olarak çözülür: önce en ucuz ekleme ile
+// başlangıç rotası kurulur, sonra taşıma ve 2-opt ile iyileştirilir.
+// Mesafeler kuş uçuşu × ROUTE_ROAD_FACTOR olduğundan harita servisi gerekmez.
+const (
+	// Randevunun bitişi yoksa varış penceresi
+	routeDefaultWindow = 2 * time.Hour
+	// Bir dakika gecikme bu kadar dakikalık yola eşdeğer sayılır
+	routeLatePenalty   = 100
+	routeMaxIterations = 200
+)
+
+// routeServiceDuration bir işte geçen ortalama süre (ROUTE_SERVICE_MINUTES, varsayılan 45)
+func routeServiceDuration() time.Duration {
+	return time.Duration(envFloat("ROUTE_SERVICE_MINUTES", 45) * float64(time.Minute))
+}
+
+// routeDayStart depodan en erken çıkış saati (ROUTE_DAY_START, varsayılan 08:00)
+func routeDayStart() string {
+	if v := os.Getenv("ROUTE_DAY_START"); v != "" {
+		return v
+	}
+	return "08:00"
+}
+
+type routeJob struct {
+	wo       models.WorkOrder
+	lat, lng float64
+	open     *time.Time
+	close    *time.Time
+}
+
+func newRouteJob(wo models.WorkOrder) routeJob {
+	j := routeJob{wo: wo, lat: *wo.Latitude, lng: *wo.Longitude, open: wo.ScheduledStart, close: wo.ScheduledEnd}
+	if j.close == nil && j.open != nil {
+		end := j.open.Add(routeDefaultWindow)
+		j.close = &end
+	}
+	return j
+}
+
+// routeEval bir ziyaret sırasının zaman çizelgesi ve maliyeti
+type routeEval struct {
+	stops    []models.RouteStop
+	startAt  time.Time
+	returnAt time.Time
+	km       float64
+	travel   time.Duration
+	late     time.Duration
+	cost     float64
+}
+
+func roundMinutes(d time.Duration) int {
+	return int(math.Round(d.Minutes()))
+}
+
+// simulateRoute sırayı depodan başlayıp depoda biten bir gün olarak yürütür.
+// Erken varışta pencerenin açılması beklenir, geç varış cezalandırılır.
+func simulateRoute(depotLat, depotLng float64, shiftStart time.Time, jobs []routeJob, order []int) routeEval {
+	ev := routeEval{startAt: shiftStart, stops: make([]models.RouteStop, 0, len(order))}
+	service := routeServiceDuration()
+
+	// İlk işte beklememek için çıkış ertelenir
+	if len(order) > 0 {
+		first := jobs[order[0]]
+		if first.open != nil {
+			if leave := first.open.Add(-travelTime(depotLat, depotLng, first.lat, first.lng)); leave.After(shiftStart) {
+				ev.startAt = leave
+			}
+		}
+	}
+
+	t := ev.startAt
+	lat, lng := depotLat, depotLng
+	for i, idx := range order {
+		j := jobs[idx]
+		km := haversineKm(lat, lng, j.lat, j.lng) * roadFactor()
+		drive := travelTime(lat, lng, j.lat, j.lng)
+		arrival := t.Add(drive)
+		start := arrival
+		if j.open != nil && start.Before(*j.open) {
+			start = *j.open
+		}
+		var late time.Duration
+		if j.close != nil && start.After(*j.close) {
+			late = start.Sub(*j.close)
+		}
+		t = start.Add(service)
+
+		ev.km += km
+		ev.travel += drive
+		ev.late += late
+		ev.stops = append(ev.stops, models.RouteStop{
+			Position: i + 1, WorkOrderID: j.wo.ID, Title: j.wo.Title, Address: j.wo.Address,
+			Latitude: j.lat, Longitude: j.lng, WindowStart: j.open, WindowEnd: j.close,
+			ArrivalAt: arrival, DepartureAt: t, TravelKm: math.Round(km*10) / 10,
+			TravelMinutes: roundMinutes(drive), WaitMinutes: roundMinutes(start.Sub(arrival)), LateMinutes: roundMinutes(late),
+		})
+		lat, lng = j.lat, j.lng
+	}
+
+	drive := travelTime(lat, lng, depotLat, depotLng)
+	ev.km += haversineKm(lat, lng, depotLat, depotLng) * roadFactor()
+	ev.travel += drive
+	ev.returnAt = t.Add(drive)
+	ev.cost = ev.late.Minutes()*routeLatePenalty + ev.travel.Minutes()
+	return ev
+}
+
+// optimizeRoute en ucuz ekleme ve yerel arama ile ziyaret sırasını bulur.
+// Sonuç aynı girdi için her zaman aynıdır.
+func optimizeRoute(depotLat, depotLng float64, shiftStart time.Time, jobs []routeJob) []int {
+	cost := func(order []int) float64 {
+		return simulateRoute(depotLat, depotLng, shiftStart, jobs, order).cost
+	}
+
+	// Pencere kapanışı erken olan işler önce eklenir
+	candidates := make([]int, len(jobs))
+	for i := range candidates {
+		candidates[i] = i
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		ja, jb := jobs[candidates[a]], jobs[candidates[b]]
+		switch {
+		case ja.close == nil || jb.close == nil:
+			return ja.close != nil && jb.close == nil
+		case !ja.close.Equal(*jb.close):
+			return ja.close.Before(*jb.close)
+		}
+		return ja.wo.ID < jb.wo.ID
+	})
+
+	order := []int{}
+	for _, idx := range candidates {
+		var best []int
+		bestCost := math.Inf(1)
+		for pos := 0; pos <= len(order); pos++ {
+			trial := insertAt(order, pos, idx)
+			if c := cost(trial); c < bestCost {
+				best, bestCost = trial, c
+			}
+		}
+		order = best
+	}
+
+	current := cost(order)
+	for iter := 0; iter < routeMaxIterations; iter++ {
+		improved := false
+		// Taşıma: bir durağı başka bir sıraya al
+		for i := 0; i < len(order) && !improved; i++ {
+			rest := append(append([]int{}, order[:i]...), order[i+1:]...)
+			for pos := 0; pos <= len(rest); pos++ {
+				if pos == i {
+					continue
+				}
+				trial := insertAt(rest, pos, order[i])
+				if c := cost(trial); c < current-1e-9 {
+					order, current, improved = trial, c, true
+					break
+				}
+			}
+		}
+		// 2-opt: bir aralığı ters çevir
+		for i := 0; i < len(order)-1 && !improved; i++ {
+			for k := i + 1; k < len(order); k++ {
+				trial := append([]int{}, order...)
+				for a, b := i, k; a < b; a, b = a+1, b-1 {
+					trial[a], trial[b] = trial[b], trial[a]
+				}
+				if c := cost(trial); c < current-1e-9 {
+					order, current, improved = trial, c, true
+					break
+				}
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+	return order
+}
+
+func insertAt(order []int, pos, value int) []int {
+	out := make([]int, 0, len(order)+1)
+	out = append(out, order[:pos]...)
+	out = append(out, value)
+	return append(out, order[pos:]...)
+}
+
+// routeDepot dükkânın konumu
+func routeDepot() (float64, float64, error) {
+	contact, err := getContact()
+	if err != nil || !validCoordinates(contact.Latitude, contact.Longitude) {
+		return 0, 0, fmt.Errorf("Dükkân konumu tanımlı değil (iletişim bilgilerinde enlem/boylam)")
+	}
+	return contact.Latitude, contact.Longitude, nil
+}
+
+// parseRouteDay tarihi ve en erken çıkış saatini işletme saat diliminde yorumlar
+func parseRouteDay(date, startTime string) (time.Time, time.Time, error) {
+	day, err := time.ParseInLocation("2006-01-02", date, businessLocation)
+	if err != nil {
+		return day, day, fmt.Errorf("Tarih YYYY-AA-GG biçiminde olmalı")
+	}
+	if startTime == "" {
+		startTime = routeDayStart()
+	}
+	clock, err := time.Parse("15:04", startTime)
+	if err != nil {
+		return day, day, fmt.Errorf("Başlangıç saati SS:DD biçiminde olmalı")
+	}
+	return day, day.Add(time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute), nil
+}
+
+// routeJobsForDay teknisyenin o gün randevusu olan açık işleri
+func routeJobsForDay(technicianID int, day time.Time) ([]models.WorkOrder, error) {
+	rows, err := db.DB.Query(
+		"SELECT "+workOrderColumns+` FROM work_orders
+		WHERE technician_id = $1 AND scheduled_start >= $2 AND scheduled_start < $3 AND status NOT IN ('completed', 'cancelled')
+		ORDER BY scheduled_start, id`,
+		technicianID, day, day.AddDate(0, 0, 1),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.WorkOrder
+	for rows.Next() {
+		wo, err := scanWorkOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, wo)
+	}
+	return list, rows.Err()
+}
+
+// buildRoutePlan işleri verilen sırayla (order nil ise optimize ederek) planlar
+func buildRoutePlan(technicianID int, day, shiftStart time.Time, depotLat, depotLng float64, orders []models.WorkOrder, order []int) models.RoutePlan {
+	plan := models.RoutePlan{
+		TechnicianID: technicianID, Date: day.Format("2006-01-02"), DepotLat: depotLat, DepotLng: depotLng,
+		ShiftStart: shiftStart, Manual: order != nil, Unlocated: []int{},
+	}
+	var jobs []routeJob
+	for _, wo := range orders {
+		if wo.Latitude == nil || wo.Longitude == nil || !validCoordinates(*wo.Latitude, *wo.Longitude) {
+			plan.Unlocated = append(plan.Unlocated, wo.ID)
+			continue
+		}
+		jobs = append(jobs, newRouteJob(wo))
+	}
+	if order == nil {
+		order = optimizeRoute(depotLat, depotLng, shiftStart, jobs)
+	}
+
+	ev := simulateRoute(depotLat, depotLng, shiftStart, jobs, order)
+	plan.Stops = ev.stops
+	plan.StartAt, plan.ReturnAt = ev.startAt, ev.returnAt
+	plan.TotalKm = math.Round(ev.km*10) / 10
+	plan.TravelMinutes, plan.LateMinutes = roundMinutes(ev.travel), roundMinutes(ev.late)
+	return plan
+}
+
+// saveRoutePlan teknisyenin o günkü planını değiştirir
+func saveRoutePlan(tx *sql.Tx, plan *models.RoutePlan, userID int) error {
+	err := tx.QueryRow(
+		`INSERT INTO route_plans (technician_id, plan_date, depot_lat, depot_lng, shift_start, start_at, return_at,
+			total_km, travel_minutes, late_minutes, manual, unlocated, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (technician_id, plan_date) DO UPDATE SET
+			depot_lat = EXCLUDED.depot_lat, depot_lng = EXCLUDED.depot_lng, shift_start = EXCLUDED.shift_start,
+			start_at = EXCLUDED.start_at, return_at = EXCLUDED.return_at, total_km = EXCLUDED.total_km,
+			travel_minutes = EXCLUDED.travel_minutes, late_minutes = EXCLUDED.late_minutes, manual = EXCLUDED.manual,
+			unlocated = EXCLUDED.unlocated, created_by = EXCLUDED.created_by, updated_at = NOW()
+		RETURNING id, created_at, updated_at`,
+		plan.TechnicianID, plan.Date, plan.DepotLat, plan.DepotLng, plan.ShiftStart, plan.StartAt, plan.ReturnAt,
+		plan.TotalKm, plan.TravelMinutes, plan.LateMinutes, plan.Manual, pq.Array(plan.Unlocated), userID,
+	).Scan(&plan.ID, &plan.CreatedAt, &plan.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	plan.CreatedBy = userID
+
+	if _, err := tx.Exec("DELETE FROM route_plan_stops WHERE plan_id = $1", plan.ID); err != nil {
+		return err
+	}
+	for _, s := range plan.Stops {
+		_, err := tx.Exec(
+			`INSERT INTO route_plan_stops (plan_id, position, work_order_id, window_start, window_end, arrival_at, departure_at,
+				travel_km, travel_minutes, wait_minutes, late_minutes)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+			plan.ID, s.Position, s.WorkOrderID, s.WindowStart, s.WindowEnd, s.ArrivalAt, s.DepartureAt,
+			s.TravelKm, s.TravelMinutes, s.WaitMinutes, s.LateMinutes,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const routePlanColumns = `id, technician_id, plan_date, depot_lat, depot_lng, shift_start, start_at, return_at,
+	total_km, travel_minutes, late_minutes, manual, unlocated, created_by, created_at, updated_at`
+
+func scanRoutePlan(scanner interface{ Scan(...interface{}) error }) (models.RoutePlan, error) {
+	var p models.RoutePlan
+	var date time.Time
+	var unlocated pq.Int64Array
+	err := scanner.Scan(&p.ID, &p.TechnicianID, &date, &p.DepotLat, &p.DepotLng, &p.ShiftStart, &p.StartAt, &p.ReturnAt,
+		&p.TotalKm, &p.TravelMinutes, &p.LateMinutes, &p.Manual, &unlocated, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt)
+	p.Date = date.Format("2006-01-02")
+	p.Unlocated = make([]int, len(unlocated))
+	for i, id := range unlocated {
+		p.Unlocated[i] = int(id)
+	}
+	return p, err
+}
+
+func loadRouteStops(plan *models.RoutePlan) error {
+	rows, err := db.DB.Query(
+		`SELECT s.position, s.work_order_id, w.title, w.address, COALESCE(w.latitude, 0), COALESCE(w.longitude, 0),
+			s.window_start, s.window_end, s.arrival_at, s.departure_at, s.travel_km, s.travel_minutes, s.wait_minutes, s.late_minutes
+		FROM route_plan_stops s JOIN work_orders w ON w.id = s.work_order_id
+		WHERE s.plan_id = $1 ORDER BY s.position`,
+		plan.ID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	plan.Stops = []models.RouteStop{}
+	for rows.Next() {
+		var s models.RouteStop
+		var windowStart, windowEnd sql.NullTime
+		if err := rows.Scan(&s.Position, &s.WorkOrderID, &s.Title, &s.Address, &s.Latitude, &s.Longitude,
+			&windowStart, &windowEnd, &s.ArrivalAt, &s.DepartureAt, &s.TravelKm, &s.TravelMinutes, &s.WaitMinutes, &s.LateMinutes); err != nil {
+			return err
+		}
+		if windowStart.Valid {
+			s.WindowStart = &windowStart.Time
+		}
+		if windowEnd.Valid {
+			s.WindowEnd = &windowEnd.Time
+		}
+		plan.Stops = append(plan.Stops, s)
+	}
+	return rows.Err()
+}
+
+func getRoutePlan(query string, args ...interface{}) (models.RoutePlan, error) {
+	plan, err := scanRoutePlan(db.DB.QueryRow("SELECT "+routePlanColumns+" FROM route_plans "+query, args...))
+	if err != nil {
+		return plan, err
+	}
+	return plan, loadRouteStops(&plan)
+}
+
+// optimizeRoutesHandler günün rotalarını hesaplar ve kaydeder. technician_id
+// verilmezse o gün randevusu olan tüm teknisyenler için çalışır. Elle
+// değiştirilmiş planların üzerine yazılır.
+func optimizeRoutesHandler(c *gin.Context) {
+	var input struct {
+		Date         string `json:"date"`
+		TechnicianID int    `json:"technician_id"`
+		StartTime    string `json:"start_time"` // SS:DD
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	day, shiftStart, err := parseRouteDay(input.Date, input.StartTime)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.TechnicianID != 0 && !isTechnician(input.TechnicianID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Teknisyen bulunamadı"})
+		return
+	}
+	depotLat, depotLng, err := routeDepot()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	technicians := []int{input.TechnicianID}
+	if input.TechnicianID == 0 {
+		technicians, err = selectIDs(db.DB,
+			`SELECT DISTINCT technician_id FROM work_orders
+			WHERE technician_id IS NOT NULL AND scheduled_start >= $1 AND scheduled_start < $2 AND status NOT IN ('completed', 'cancelled')
+			ORDER BY technician_id`,
+			day, day.AddDate(0, 0, 1),
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	plans := []models.RoutePlan{}
+	for _, technicianID := range technicians {
+		orders, err := routeJobsForDay(technicianID, day)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		plans = append(plans, buildRoutePlan(technicianID, day, shiftStart, depotLat, depotLng, orders, nil))
+	}
+
+	// Planlar tek işlemde kaydedilir; biri yazılamazsa hiçbiri değişmez
+	tx, err := db.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	actor := requestActor(c)
+	for i := range plans {
+		plan := &plans[i]
+		if err := saveRoutePlan(tx, plan, currentUserID(c)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		err := insertAudit(tx, actor, "route_plan", plan.ID, auditCreate, nil, gin.H{
+			"technician_id": plan.TechnicianID, "date": plan.Date, "order": routeOrder(*plan),
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"plans": plans})
+}
+
+func routeOrder(plan models.RoutePlan) []int {
+	ids := make([]int, len(plan.Stops))
+	for i, s := range plan.Stops {
+		ids[i] = s.WorkOrderID
+	}
+	return ids
+}
+
+func getRoutePlansHandler(c *gin.Context) {
+	date := c.Query("date")
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tarih YYYY-AA-GG biçiminde olmalı"})
+		return
+	}
+	query := "SELECT " + routePlanColumns + " FROM route_plans WHERE plan_date = $1"
+	args := []interface{}{date}
+	if v := c.Query("technician_id"); v != "" {
+		technicianID, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+			return
+		}
+		query += " AND technician_id = $2"
+		args = append(args, technicianID)
+	}
+	rows, err := db.DB.Query(query+" ORDER BY technician_id", args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	plans := []models.RoutePlan{}
+	for rows.Next() {
+		plan, err := scanRoutePlan(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		plans = append(plans, plan)
+	}
+	rows.Close()
+	for i := range plans {
+		if err := loadRouteStops(&plans[i]); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, plans)
+}
+
+func getRoutePlanHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	plan, err := getRoutePlan("WHERE id = $1", id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rota planı bulunamadı"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
+
+// routeJobConflict iş emri plan hesaplandıktan sonra teknisyenden alınmış, başka
+// güne taşınmış veya kapanmışsa nedenini döndürür
+func routeJobConflict(wo models.WorkOrder, technicianID int, day time.Time) string {
+	switch {
+	case wo.TechnicianID == nil || *wo.TechnicianID != technicianID:
+		return "başka teknisyene verilmiş"
+	case wo.ScheduledStart == nil || wo.ScheduledStart.Before(day) || !wo.ScheduledStart.Before(day.AddDate(0, 0, 1)):
+		return "artık bu güne planlı değil"
+	case wo.Status == "completed" || wo.Status == "cancelled":
+		return "kapanmış"
+	case wo.Latitude == nil || wo.Longitude == nil || !validCoordinates(*wo.Latitude, *wo.Longitude):
+		return "konumsuz"
+	}
+	return ""
+}
+
+// reorderRoutePlanHandler planı verilen sırayla yeniden hesaplar. Sıra,
+// plandaki iş emirlerinin tamamını içermelidir.
+func reorderRoutePlanHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	var input struct {
+		WorkOrderIDs []int `json:"work_order_ids"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	before, err := getRoutePlan("WHERE id = $1", id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rota planı bulunamadı"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Yeni sıra, plandaki durakların bir permütasyonu olmalı
+	positions := make(map[int]int, len(before.Stops))
+	for i, s := range before.Stops {
+		positions[s.WorkOrderID] = i
+	}
+	seen := make(map[int]bool, len(input.WorkOrderIDs))
+	for _, woID := range input.WorkOrderIDs {
+		if _, ok := positions[woID]; !ok || seen[woID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Sıra, plandaki iş emirlerini birer kez içermeli"})
+			return
+		}
+		seen[woID] = true
+	}
+	if len(seen) != len(positions) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Sıra, plandaki iş emirlerini birer kez içermeli"})
+		return
+	}
+
+	// Randevu saatleri, atamalar veya konumlar değişmiş olabilir; güncel iş emirleriyle hesaplanır
+	day, _ := time.ParseInLocation("2006-01-02", before.Date, businessLocation)
+	orders := make([]models.WorkOrder, 0, len(input.WorkOrderIDs))
+	order := make([]int, 0, len(input.WorkOrderIDs))
+	for _, woID := range input.WorkOrderIDs {
+		wo, err := getWorkOrder(woID)
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("İş emri #%d artık yok, rotayı yeniden hesaplayın", woID)})
+			return
+		}
+		if reason := routeJobConflict(wo, before.TechnicianID, day); reason != "" {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("İş emri #%d %s, rotayı yeniden hesaplayın", woID, reason)})
+			return
+		}
+		order = append(order, len(orders))
+		orders = append(orders, wo)
+	}
+	plan := buildRoutePlan(before.TechnicianID, day, before.ShiftStart, before.DepotLat, before.DepotLng, orders, order)
+	plan.Unlocated = before.Unlocated
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+	if err := saveRoutePlan(tx, &plan, currentUserID(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	err = insertAudit(tx, requestActor(c), "route_plan", plan.ID, auditUpdate, gin.H{"order": routeOrder(before)}, gin.H{"order": routeOrder(plan)})
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
+
+// getTechnicianRouteHandler teknisyenin o günkü kayıtlı rotası
+func getTechnicianRouteHandler(c *gin.Context) {
+	date := c.Query("date")
+	if date == "" {
+		date = time.Now().In(businessLocation).Format("2006-01-02")
+	}
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tarih YYYY-AA-GG biçiminde olmalı"})
+		return
+	}
+	plan, err := getRoutePlan("WHERE technician_id = $1 AND plan_date = $2", currentUserID(c), date)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Bu gün için rota planı yok"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
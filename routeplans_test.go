@@ -0,0 +1,196 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"kozan/models"
+)
+
+const testDepotLat, testDepotLng = 41.0, 29.0
+
+func testRouteJob(id int, lat, lng float64, open, close *time.Time) routeJob {
+	wo := models.WorkOrder{ID: id, Latitude: &lat, Longitude: &lng, ScheduledStart: open, ScheduledEnd: close}
+	return newRouteJob(wo)
+}
+
+func clockAt(hour, minute int) *time.Time {
+	t := time.Date(2026, 3, 10, hour, minute, 0, 0, businessLocation)
+	return &t
+}
+
+func TestSimulateRoute(t *testing.T) {
+	shift := *clockAt(8, 0)
+	service := routeServiceDuration()
+	near := testRouteJob(1, 41.0, 29.05, nil, nil)
+	drive := travelTime(testDepotLat, testDepotLng, near.lat, near.lng)
+
+	t.Run("boş rota", func(t *testing.T) {
+		ev := simulateRoute(testDepotLat, testDepotLng, shift, nil, nil)
+		if !ev.startAt.Equal(shift) || !ev.returnAt.Equal(shift) || ev.km != 0 || ev.cost != 0 || len(ev.stops) != 0 {
+			t.Errorf("boş rota = %+v", ev)
+		}
+	})
+
+	t.Run("tek iş", func(t *testing.T) {
+		ev := simulateRoute(testDepotLat, testDepotLng, shift, []routeJob{near}, []int{0})
+		stop := ev.stops[0]
+		if !stop.ArrivalAt.Equal(shift.Add(drive)) || !stop.DepartureAt.Equal(shift.Add(drive+service)) {
+			t.Errorf("varış %v, çıkış %v", stop.ArrivalAt, stop.DepartureAt)
+		}
+		if !ev.returnAt.Equal(shift.Add(2*drive + service)) {
+			t.Errorf("dönüş = %v, want %v", ev.returnAt, shift.Add(2*drive+service))
+		}
+		wantKm := 2 * haversineKm(testDepotLat, testDepotLng, near.lat, near.lng) * roadFactor()
+		if math.Abs(ev.km-wantKm) > 1e-9 {
+			t.Errorf("km = %v, want %v", ev.km, wantKm)
+		}
+		if ev.late != 0 || stop.WaitMinutes != 0 {
+			t.Errorf("gecikme %v, bekleme %d", ev.late, stop.WaitMinutes)
+		}
+	})
+
+	t.Run("ilk iş için çıkış ertelenir", func(t *testing.T) {
+		job := testRouteJob(1, near.lat, near.lng, clockAt(10, 0), clockAt(12, 0))
+		ev := simulateRoute(testDepotLat, testDepotLng, shift, []routeJob{job}, []int{0})
+		if !ev.startAt.Equal(clockAt(10, 0).Add(-drive)) || !ev.stops[0].ArrivalAt.Equal(*clockAt(10, 0)) {
+			t.Errorf("çıkış %v, varış %v", ev.startAt, ev.stops[0].ArrivalAt)
+		}
+	})
+
+	t.Run("pencere açılana kadar beklenir", func(t *testing.T) {
+		second := testRouteJob(2, near.lat, near.lng, clockAt(11, 0), clockAt(13, 0))
+		ev := simulateRoute(testDepotLat, testDepotLng, shift, []routeJob{near, second}, []int{0, 1})
+		if !ev.stops[1].DepartureAt.Equal(clockAt(11, 0).Add(service)) || ev.stops[1].WaitMinutes <= 0 {
+			t.Errorf("ikinci durak = %+v", ev.stops[1])
+		}
+	})
+
+	t.Run("geç varış cezalandırılır", func(t *testing.T) {
+		job := testRouteJob(1, near.lat, near.lng, clockAt(7, 0), clockAt(8, 0))
+		ev := simulateRoute(testDepotLat, testDepotLng, shift, []routeJob{job}, []int{0})
+		if ev.late != drive || ev.cost != drive.Minutes()*routeLatePenalty+ev.travel.Minutes() {
+			t.Errorf("gecikme %v, maliyet %v", ev.late, ev.cost)
+		}
+	})
+}
+
+// permutations küçük girdiler için tüm sıraları üretir
+func permutations(n int) [][]int {
+	if n == 0 {
+		return [][]int{{}}
+	}
+	var out [][]int
+	for _, p := range permutations(n - 1) {
+		for pos := 0; pos <= len(p); pos++ {
+			out = append(out, insertAt(p, pos, n-1))
+		}
+	}
+	return out
+}
+
+func TestOptimizeRoute(t *testing.T) {
+	shift := *clockAt(8, 0)
+	tests := []struct {
+		name string
+		jobs []routeJob
+		want []int // nil ise sadece en düşük maliyet kontrol edilir
+	}{
+		{"boş", nil, []int{}},
+		{"tek iş", []routeJob{testRouteJob(1, 41.01, 29.01, nil, nil)}, []int{0}},
+		{"pencere uzaktaki işi öne alır", []routeJob{
+			testRouteJob(1, 41.0, 29.01, clockAt(13, 0), clockAt(15, 0)),
+			testRouteJob(2, 41.0, 29.2, clockAt(8, 0), clockAt(9, 0)),
+		}, []int{1, 0}},
+		{"pencere sırası", []routeJob{
+			testRouteJob(1, 41.02, 29.0, clockAt(14, 0), nil),
+			testRouteJob(2, 41.04, 29.0, clockAt(12, 0), nil),
+			testRouteJob(3, 41.06, 29.0, clockAt(10, 0), nil),
+		}, []int{2, 1, 0}},
+		{"penceresiz dağınık işler", []routeJob{
+			testRouteJob(1, 41.05, 29.0, nil, nil),
+			testRouteJob(2, 41.0, 29.06, nil, nil),
+			testRouteJob(3, 40.96, 29.01, nil, nil),
+			testRouteJob(4, 41.02, 28.95, nil, nil),
+			testRouteJob(5, 41.03, 29.04, nil, nil),
+		}, nil},
+	}
+	for _, tt := range tests {
+		order := optimizeRoute(testDepotLat, testDepotLng, shift, tt.jobs)
+		if len(order) != len(tt.jobs) {
+			t.Errorf("%s: %d iş için sıra %v", tt.name, len(tt.jobs), order)
+			continue
+		}
+		if tt.want != nil {
+			for i := range tt.want {
+				if order[i] != tt.want[i] {
+					t.Errorf("%s: sıra = %v, want %v", tt.name, order, tt.want)
+					break
+				}
+			}
+			continue
+		}
+		got := simulateRoute(testDepotLat, testDepotLng, shift, tt.jobs, order).cost
+		for _, p := range permutations(len(tt.jobs)) {
+			if c := simulateRoute(testDepotLat, testDepotLng, shift, tt.jobs, p).cost; c < got-1e-9 {
+				t.Errorf("%s: sıra %v maliyeti %v, %v daha ucuz (%v)", tt.name, order, got, p, c)
+				break
+			}
+		}
+	}
+}
+
+func TestOptimizeRouteDeterministic(t *testing.T) {
+	shift := *clockAt(8, 0)
+	jobs := []routeJob{
+		testRouteJob(1, 41.05, 29.0, nil, nil),
+		testRouteJob(2, 41.0, 29.06, clockAt(9, 0), nil),
+		testRouteJob(3, 40.96, 29.01, nil, nil),
+		testRouteJob(4, 41.02, 28.95, clockAt(11, 0), clockAt(12, 0)),
+	}
+	first := optimizeRoute(testDepotLat, testDepotLng, shift, jobs)
+	for i := 0; i < 5; i++ {
+		again := optimizeRoute(testDepotLat, testDepotLng, shift, jobs)
+		for k := range first {
+			if again[k] != first[k] {
+				t.Fatalf("sıra değişti: %v, %v", first, again)
+			}
+		}
+	}
+}
+
+func TestRouteJobConflict(t *testing.T) {
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, businessLocation)
+	technician, other := 7, 8
+	lat, lng := 41.0, 29.05
+	job := func(edit func(*models.WorkOrder)) models.WorkOrder {
+		wo := models.WorkOrder{ID: 1, Status: "scheduled", TechnicianID: &technician,
+			ScheduledStart: clockAt(10, 0), Latitude: &lat, Longitude: &lng}
+		if edit != nil {
+			edit(&wo)
+		}
+		return wo
+	}
+	nextDay := day.AddDate(0, 0, 1)
+
+	tests := []struct {
+		name string
+		wo   models.WorkOrder
+		want string
+	}{
+		{"geçerli", job(nil), ""},
+		{"günün ilk anı", job(func(wo *models.WorkOrder) { wo.ScheduledStart = &day }), ""},
+		{"başka teknisyen", job(func(wo *models.WorkOrder) { wo.TechnicianID = &other }), "başka teknisyene verilmiş"},
+		{"atama kaldırılmış", job(func(wo *models.WorkOrder) { wo.TechnicianID = nil }), "başka teknisyene verilmiş"},
+		{"ertesi güne taşınmış", job(func(wo *models.WorkOrder) { wo.ScheduledStart = &nextDay }), "artık bu güne planlı değil"},
+		{"randevu kaldırılmış", job(func(wo *models.WorkOrder) { wo.ScheduledStart = nil }), "artık bu güne planlı değil"},
+		{"iptal edilmiş", job(func(wo *models.WorkOrder) { wo.Status = "cancelled" }), "kapanmış"},
+		{"konumsuz", job(func(wo *models.WorkOrder) { wo.Latitude = nil }), "konumsuz"},
+	}
+	for _, tt := range tests {
+		if got := routeJobConflict(tt.wo, technician, day); got != tt.want {
+			t.Errorf("%s: routeJobConflict = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
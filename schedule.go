@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"kozan/db"
+	"kozan/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Zamanlanmış yayın: publish_schedule tablosundaki her satır için run_at
+// zamanında çalışan bir content.schedule işi kuyruğa eklenir. İş satırı
+// FOR UPDATE ile kilitler ve sadece hâlâ bekliyorsa çalıştırır; böylece iptal
+// edilen veya yenisiyle değiştirilen zamanlamalar sessizce atlanır.
+const (
+	schedulePublish   = "publish"
+	scheduleUnpublish = "unpublish"
+)
+
+// Zamanlanabilen içerik tipleri
+var schedulableEntities = []string{"product", "service", "hero", "campaign"}
+
+func isSchedulable(entityType string) bool {
+	for _, e := range schedulableEntities {
+		if e == entityType {
+			return true
+		}
+	}
+	return false
+}
+
+type publishSchedulePayload struct {
+	ScheduleID int `json:"schedule_id"`
+}
+
+// enqueueSchedule zamanlama satırının işini kayıtla aynı transaction içinde ekler
+func enqueueSchedule(q queryRower, scheduleID int, runAt time.Time) error {
+	_, err := enqueueJob(q, jobPublishSchedule, publishSchedulePayload{ScheduleID: scheduleID}, jobOptions{
+		RunAt:     runAt,
+		UniqueKey: "schedule:" + strconv.Itoa(scheduleID),
+	})
+	return err
+}
+
+// enqueuePendingSchedules işi olmayan bekleyen zamanlamaları kuyruğa ekler.
+// Zamanlamalar iş kuyruğuna taşınmadan önce oluşturulan satırlar içindir;
+// unique_key sayesinde tekrar çalıştırılması güvenlidir.
+func enqueuePendingSchedules() error {
+	rows, err := db.DB.Query(
+		`SELECT id, run_at FROM publish_schedule s WHERE status = 'pending'
+		AND NOT EXISTS (SELECT 1 FROM jobs WHERE unique_key = 'schedule:' || s.id)`,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var runAt time.Time
+		if err := rows.Scan(&id, &runAt); err != nil {
+			return err
+		}
+		if err := enqueueSchedule(db.DB, id, runAt); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// publishScheduleJob zamanı gelen yayın işini çalıştırır. Başarısız yayın
+// publish_schedule'a "failed" olarak yazılır ve iş tekrar denenmeden dead olur;
+// panelden tekrar denendiğinde satır yeniden bekler duruma alınır.
+func publishScheduleJob(ctx context.Context, job models.Job, payload publishSchedulePayload) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var entityID int
+	var entityType, action, status string
+	err = tx.QueryRowContext(ctx,
+		"SELECT entity_type, entity_id, action, status FROM publish_schedule WHERE id = $1 FOR UPDATE",
+		payload.ScheduleID,
+	).Scan(&entityType, &entityID, &action, &status)
+	if err == sql.ErrNoRows || (err == nil && status != "pending") {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Başarısız yayın sadece kendi değişikliklerini geri alır; durum satırı
+	// aynı işlemde "failed" olarak yazılır
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT schedule_action"); err != nil {
+		return err
+	}
+	status, errMsg := "done", ""
+	execErr := executeSchedule(tx, entityType, entityID, action)
+	if execErr != nil {
+		status, errMsg = "failed", execErr.Error()
+		log.Printf("scheduler: %s %s %d failed: %v", action, entityType, entityID, execErr)
+		if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT schedule_action"); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE publish_schedule SET status = $1, error = $2, executed_at = NOW() WHERE id = $3",
+		status, errMsg, payload.ScheduleID,
+	)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if execErr != nil {
+		return permanentJobError(execErr)
+	}
+	return nil
+}
+
+// executeSchedule yayına alma ise taslağın o anki halini yayınlar. Yayın ve
+// audit kaydı zamanlama satırıyla aynı işlemde yazılır.
+func executeSchedule(tx *sql.Tx, entityType string, entityID int, action string) error {
+	actor := systemActor("scheduler")
+	switch action {
+	case schedulePublish:
+		data, err := revisionEntities[entityType].load(entityID)
+		if err != nil {
+			return fmt.Errorf("kayıt bulunamadı")
+		}
+		published, err := publishedData(tx, entityType, entityID)
+		if err != nil {
+			return err
+		}
+		if err := publishEntity(tx, 0, entityType, entityID, data); err != nil {
+			return err
+		}
+		if err := publishEvents(tx, entityType, published, data); err != nil {
+			return err
+		}
+		return insertAudit(tx, actor, entityType, entityID, auditPublish, nil, data)
+	case scheduleUnpublish:
+		if err := unpublishEntity(tx, entityType, entityID); err != nil {
+			return err
+		}
+		return insertAudit(tx, actor, entityType, entityID, auditUnpublish, nil, nil)
+	default:
+		return fmt.Errorf("bilinmeyen aksiyon: %s", action)
+	}
+}
+
+// cancelSchedules silinen bir kaydın bekleyen işlerini iptal eder
+func cancelSchedules(entityType string, entityID int) error {
+	_, err := db.DB.Exec(
+		"UPDATE publish_schedule SET status = 'cancelled' WHERE entity_type = $1 AND entity_id = $2 AND status = 'pending'",
+		entityType, entityID,
+	)
+	return err
+}
+
+// Zamanlama işlemleri
+func setScheduleHandler(c *gin.Context) {
+	entityType, entity, id, ok := revisionParams(c, false)
+	if !ok {
+		return
+	}
+	if !isSchedulable(entityType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bu içerik tipi zamanlanamaz"})
+		return
+	}
+	if _, err := entity.load(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kayıt bulunamadı"})
+		return
+	}
+
+	var input struct {
+		PublishAt   *time.Time `json:"publish_at"`
+		UnpublishAt *time.Time `json:"unpublish_at"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.PublishAt != nil && input.UnpublishAt != nil && !input.UnpublishAt.After(*input.PublishAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Yayından kaldırma zamanı yayın zamanından sonra olmalı"})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	// Yeni zamanlama bekleyen eskilerin yerine geçer
+	_, err = tx.Exec(
+		"UPDATE publish_schedule SET status = 'cancelled' WHERE entity_type = $1 AND entity_id = $2 AND status = 'pending'",
+		entityType, id,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for action, at := range map[string]*time.Time{schedulePublish: input.PublishAt, scheduleUnpublish: input.UnpublishAt} {
+		if at == nil {
+			continue
+		}
+		var scheduleID int
+		err = tx.QueryRow(
+			"INSERT INTO publish_schedule (entity_type, entity_id, action, run_at, created_by) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+			entityType, id, action, *at, currentUserID(c),
+		).Scan(&scheduleID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := enqueueSchedule(tx, scheduleID, *at); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, entityType, id, auditSchedule, nil, input)
+	c.JSON(http.StatusOK, input)
+}
+
+// scheduleTimelineHandler hangi içeriğin ne zaman değişeceğini gösterir
+func scheduleTimelineHandler(c *gin.Context) {
+	from := time.Now().Add(-7 * 24 * time.Hour)
+	to := time.Now().Add(90 * 24 * time.Hour)
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz tarih: from"})
+			return
+		}
+		from = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz tarih: to"})
+			return
+		}
+		to = t
+	}
+
+	rows, err := db.DB.Query(`
+		SELECT s.id, s.entity_type, s.entity_id, s.action, s.run_at, s.status, COALESCE(s.error, ''), s.executed_at,
+			COALESCE(
+				(SELECT name FROM products WHERE s.entity_type = 'product' AND id = s.entity_id),
+				(SELECT title FROM services WHERE s.entity_type = 'service' AND id = s.entity_id),
+				(SELECT heading FROM hero WHERE s.entity_type = 'hero' AND id = s.entity_id),
+				(SELECT title FROM campaigns WHERE s.entity_type = 'campaign' AND id = s.entity_id),
+				''
+			)
+		FROM publish_schedule s
+		WHERE s.run_at >= $1 AND s.run_at < $2 AND s.status <> 'cancelled'
+		ORDER BY s.run_at, s.id`, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	entries := []models.ScheduleEntry{}
+	for rows.Next() {
+		var e models.ScheduleEntry
+		var executedAt sql.NullTime
+		if err := rows.Scan(&e.ID, &e.EntityType, &e.EntityID, &e.Action, &e.RunAt, &e.Status, &e.Error, &executedAt, &e.Label); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if executedAt.Valid {
+			e.ExecutedAt = &executedAt.Time
+		}
+		entries = append(entries, e)
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
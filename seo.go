@@ -0,0 +1,363 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"kozan/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// SEO: ürün ve hizmetler Türkçe karakterleri sadeleştirilmiş benzersiz
+// slug'larla adreslenir. Slug değişince eski slug slug_redirects tablosunda
+// kalır ve public API yeni adrese 301 ile yönlendirir.
+type slugSource struct {
+	table  string
+	column string // slug boşsa üretilecek alan
+}
+
+var sluggedEntities = map[string]slugSource{
+	"product": {"products", "name"},
+	"service": {"services", "title"},
+}
+
+// Sitedeki detay sayfalarının yolları
+var sitePaths = map[string]string{
+	"product": "/urunler/",
+	"service": "/hizmetler/",
+}
+
+// Ürün ve hizmet sorgularında models.SEO alanlarının sütunları
+const seoColumns = "COALESCE(slug, ''), seo_title, seo_description, og_image"
+
+const maxSlugLength = 80
+
+var turkishASCII = strings.NewReplacer(
+	"ş", "s", "Ş", "s", "ğ", "g", "Ğ", "g", "ı", "i", "İ", "i",
+	"ç", "c", "Ç", "c", "ö", "o", "Ö", "o", "ü", "u", "Ü", "u",
+	"â", "a", "Â", "a", "î", "i", "Î", "i", "û", "u", "Û", "u",
+)
+
+// slugify "Samsung Wind-Free 18000 BTU" → "samsung-wind-free-18000-btu"
+func slugify(s string) string {
+	s = strings.ToLower(turkishASCII.Replace(s))
+	var b strings.Builder
+	dash := false
+	for _, r := range s {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	slug := strings.Trim(b.String(), "-")
+	if len(slug) > maxSlugLength {
+		slug = slug[:maxSlugLength]
+		if i := strings.LastIndex(slug, "-"); i > maxSlugLength/2 {
+			slug = slug[:i]
+		}
+	}
+	return slug
+}
+
+// prepareSlug istenen slug'ı (boşsa kaynak alanı) normalleştirir ve
+// tablodaki diğer kayıtlarla çakışıyorsa sonuna sayı ekler
+func prepareSlug(q queryRower, entityType string, id int, slug *string, source string) error {
+	table := sluggedEntities[entityType].table
+	base := slugify(*slug)
+	if base == "" {
+		base = slugify(source)
+	}
+	if base == "" {
+		base = entityType
+	}
+
+	candidate := base
+	for n := 2; ; n++ {
+		var exists bool
+		err := q.QueryRow("SELECT EXISTS (SELECT 1 FROM "+table+" WHERE slug = $1 AND id <> $2)", candidate, id).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			break
+		}
+		candidate = fmt.Sprintf("%s-%d", base, n)
+	}
+	*slug = candidate
+	return nil
+}
+
+// maxSlugAttempts eşzamanlı kayıtlarla slug çakışmasında deneme sayısı
+const maxSlugAttempts = 5
+
+// writeWithSlug slug'ı hazırlayıp kaydı işlem içinde yazar. Seçilen slug'ı
+// aynı anda başka bir kayıt aldıysa yazma savepoint'e geri alınır ve sıradaki
+// boş slug ile tekrar denenir.
+func writeWithSlug(tx *sql.Tx, entityType string, id int, slug *string, source string, write func() error) error {
+	requested := *slug
+	for attempt := 1; ; attempt++ {
+		*slug = requested
+		if err := prepareSlug(tx, entityType, id, slug, source); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("SAVEPOINT slug_write"); err != nil {
+			return err
+		}
+		err := write()
+		if err == nil {
+			_, err = tx.Exec("RELEASE SAVEPOINT slug_write")
+			return err
+		}
+		if !isSlugViolation(entityType, err) || attempt == maxSlugAttempts {
+			return err
+		}
+		if _, err := tx.Exec("ROLLBACK TO SAVEPOINT slug_write"); err != nil {
+			return err
+		}
+	}
+}
+
+// isSlugViolation hatanın tablonun slug benzersizlik indeksinden geldiğini kontrol eder
+func isSlugViolation(entityType string, err error) bool {
+	var pqErr *pq.Error
+	return isUniqueViolation(err) && errors.As(err, &pqErr) &&
+		pqErr.Constraint == sluggedEntities[entityType].table+"_slug_key"
+}
+
+// updateSlugRedirects slug değiştiyse eski slug'ı kayda yönlendirir.
+// Yeni slug daha önce yönlendirme olarak kullanılıyorsa o kayıt silinir.
+func updateSlugRedirects(q execer, entityType string, id int, oldSlug, newSlug string) error {
+	_, err := q.Exec("DELETE FROM slug_redirects WHERE entity_type = $1 AND old_slug = $2", entityType, newSlug)
+	if err == nil && oldSlug != "" && oldSlug != newSlug {
+		_, err = q.Exec(
+			`INSERT INTO slug_redirects (entity_type, old_slug, entity_id) VALUES ($1, $2, $3)
+			ON CONFLICT (entity_type, old_slug) DO UPDATE SET entity_id = EXCLUDED.entity_id, created_at = NOW()`,
+			entityType, oldSlug, id,
+		)
+	}
+	return err
+}
+
+func deleteSlugRedirects(entityType string, id int) {
+	if _, err := db.DB.Exec("DELETE FROM slug_redirects WHERE entity_type = $1 AND entity_id = $2", entityType, id); err != nil {
+		log.Printf("slug redirect delete failed (%s %d): %v", entityType, id, err)
+	}
+}
+
+// backfillSlugs slug'ı olmayan eski kayıtlara slug üretir ve yayındaki
+// hallerine de ekler
+func backfillSlugs() error {
+	for entityType, src := range sluggedEntities {
+		rows, err := db.DB.Query("SELECT id, " + src.column + " FROM " + src.table + " WHERE slug IS NULL OR slug = '' ORDER BY id")
+		if err != nil {
+			return err
+		}
+		type pending struct {
+			id     int
+			source string
+		}
+		var list []pending
+		for rows.Next() {
+			var p pending
+			if err := rows.Scan(&p.id, &p.source); err != nil {
+				rows.Close()
+				return err
+			}
+			list = append(list, p)
+		}
+		rows.Close()
+
+		for _, p := range list {
+			var slug string
+			if err := prepareSlug(db.DB, entityType, p.id, &slug, p.source); err != nil {
+				return err
+			}
+			if _, err := db.DB.Exec("UPDATE "+src.table+" SET slug = $1 WHERE id = $2", slug, p.id); err != nil {
+				return err
+			}
+			_, err := db.DB.Exec(
+				`UPDATE published_content SET data = data || jsonb_build_object('slug', $1::text)
+				WHERE entity_type = $2 AND entity_id = $3 AND COALESCE(data->>'slug', '') = ''`,
+				slug, entityType, p.id,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applySEODefaults boş SEO alanlarını kaydın kendi alanlarıyla doldurur
+func applySEODefaults(entityType string, items []map[string]interface{}) {
+	if _, ok := sluggedEntities[entityType]; !ok {
+		return
+	}
+	titleField := sluggedEntities[entityType].column
+	for _, item := range items {
+		if v, _ := item["seoTitle"].(string); v == "" {
+			item["seoTitle"] = item[titleField]
+		}
+		if v, _ := item["seoDescription"].(string); v == "" {
+			item["seoDescription"] = item["descriptionExcerpt"]
+		}
+		if v, _ := item["ogImage"].(string); v == "" {
+			item["ogImage"] = item["image"]
+		}
+	}
+}
+
+// servePublicBySlug tek bir ürün veya hizmeti slug ile döndürür.
+// Eski bir slug istenirse güncel adrese kalıcı yönlendirme yapılır.
+func servePublicBySlug(c *gin.Context, entityType string) {
+	slug := c.Param("slug")
+	items, err := publicItems(c, entityType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, item := range items {
+		if item["slug"] == slug {
+			found := []map[string]interface{}{item}
+			localize(c, entityType, found)
+			renderRichText(entityType, found)
+			applySEODefaults(entityType, found)
+			c.JSON(http.StatusOK, item)
+			return
+		}
+	}
+
+	var id int
+	if db.DB.QueryRow("SELECT entity_id FROM slug_redirects WHERE entity_type = $1 AND old_slug = $2", entityType, slug).Scan(&id) == nil {
+		for _, item := range items {
+			current, _ := item["slug"].(string)
+			if itemID, _ := item["id"].(float64); int(itemID) == id && current != "" {
+				location := strings.TrimSuffix(c.Request.URL.Path, slug) + current
+				if c.Request.URL.RawQuery != "" {
+					location += "?" + c.Request.URL.RawQuery
+				}
+				c.Redirect(http.StatusMovedPermanently, location)
+				return
+			}
+		}
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{"error": "Kayıt bulunamadı"})
+}
+
+func getPublicProductHandler(c *gin.Context) {
+	servePublicBySlug(c, "product")
+}
+
+func getPublicServiceHandler(c *gin.Context) {
+	servePublicBySlug(c, "service")
+}
+
+// siteURL public sitenin adresidir (sitemap ve robots.txt için)
+func siteURL() string {
+	if v := os.Getenv("SITE_URL"); v != "" {
+		return strings.TrimRight(v, "/")
+	}
+	return "http://localhost:5173"
+}
+
+type sitemapAlternate struct {
+	Rel      string `xml:"rel,attr"`
+	Hreflang string `xml:"hreflang,attr"`
+	Href     string `xml:"href,attr"`
+}
+
+type sitemapURL struct {
+	Loc        string             `xml:"loc"`
+	LastMod    string             `xml:"lastmod,omitempty"`
+	Alternates []sitemapAlternate `xml:"xhtml:link"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	XHTML   string       `xml:"xmlns:xhtml,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+// sitemapEntry her dil için hreflang alternatifleri ekler
+func sitemapEntry(path string, lastMod time.Time) sitemapURL {
+	loc := siteURL() + path
+	entry := sitemapURL{Loc: loc}
+	if !lastMod.IsZero() {
+		entry.LastMod = lastMod.UTC().Format("2006-01-02")
+	}
+	for _, l := range supportedLocales {
+		href := loc
+		if l.Code != defaultLocale {
+			href += "?lang=" + l.Code
+		}
+		entry.Alternates = append(entry.Alternates, sitemapAlternate{Rel: "alternate", Hreflang: l.Code, Href: href})
+	}
+	return entry
+}
+
+// sitemapHandler yayındaki ürün ve hizmetlerden sitemap.xml üretir
+func sitemapHandler(c *gin.Context) {
+	rows, err := db.DB.Query(
+		`SELECT entity_type, data->>'slug', published_at FROM published_content
+		WHERE entity_type IN ('product', 'service') AND COALESCE(data->>'slug', '') <> ''
+		ORDER BY entity_type, entity_id`,
+	)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "sitemap unavailable")
+		return
+	}
+	defer rows.Close()
+
+	var latest time.Time
+	var pages []sitemapURL
+	for rows.Next() {
+		var entityType, slug string
+		var publishedAt time.Time
+		if err := rows.Scan(&entityType, &slug, &publishedAt); err != nil {
+			c.String(http.StatusInternalServerError, "sitemap unavailable")
+			return
+		}
+		if publishedAt.After(latest) {
+			latest = publishedAt
+		}
+		pages = append(pages, sitemapEntry(sitePaths[entityType]+slug, publishedAt))
+	}
+
+	set := sitemapURLSet{
+		Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9",
+		XHTML: "http://www.w3.org/1999/xhtml",
+		URLs:  append([]sitemapURL{sitemapEntry("/", latest)}, pages...),
+	}
+	body, err := xml.MarshalIndent(set, "", "  ")
+	if err != nil {
+		c.String(http.StatusInternalServerError, "sitemap unavailable")
+		return
+	}
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", append([]byte(xml.Header), body...))
+}
+
+func robotsHandler(c *gin.Context) {
+	robots := "User-agent: *\n" +
+		"Disallow: /admin\n" +
+		"Disallow: /login\n" +
+		"Disallow: /takip/\n" +
+		"Disallow: /api/admin/\n" +
+		"Allow: /\n\n" +
+		"Sitemap: " + siteURL() + "/sitemap.xml\n"
+	c.String(http.StatusOK, robots)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Samsung Wind-Free 18000 BTU", "samsung-wind-free-18000-btu"},
+		{"Kombi Bakımı ve Onarımı", "kombi-bakimi-ve-onarimi"},
+		{"İŞÇİ ÇĞÖÜ şığ", "isci-cgou-sig"},
+		{"Klima & Isıtma — Servis!", "klima-isitma-servis"},
+		{"  --baştaki ve sondaki--  ", "bastaki-ve-sondaki"},
+		{"Âlâ Îmâr", "ala-imar"},
+		{"a///b___c", "a-b-c"},
+		{"!!!", ""},
+		{"", ""},
+		{strings.Repeat("kelime ", 20), strings.TrimSuffix(strings.Repeat("kelime-", 11), "-")},
+		{strings.Repeat("a", 100), strings.Repeat("a", 80)},
+	}
+	for _, tt := range tests {
+		if got := slugify(tt.in); got != tt.want {
+			t.Errorf("slugify(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
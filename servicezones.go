@@ -0,0 +1,508 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"kozan/db"
+	"kozan/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Hizmet bölgeleri ve yol ücreti. Bölgeler öncelik sırasıyla denenir, ilk
+// eşleşen geçerlidir; böylece örneğin 10 km ücretsiz, 40 km ücretli gibi iç
+// içe bölgeler tanımlanabilir. Koordinat verilmezse sadece ilçe/mahalle
+// listeli bölgeler adresteki isimlerle eşleştirilir.
+const (
+	zoneRadius  = "radius"
+	zonePolygon = "polygon"
+	zoneArea    = "area"
+)
+
+var zoneKinds = map[string]bool{zoneRadius: true, zonePolygon: true, zoneArea: true}
+
+var allWeekdays = []int{1, 2, 3, 4, 5, 6, 7}
+
+// errServiceDay randevu günü bölgenin servis günlerinden biri değil
+var errServiceDay = errors.New("Bu bölgeye randevu gününde servis verilmiyor")
+
+// errOutsideServiceArea adres tanımlı bölgelerin hiçbirine düşmüyor
+var errOutsideServiceArea = errors.New("Adres hizmet bölgelerinin dışında; yol ücretini (call_out_fee) girin veya outside_service_area ile onaylayın")
+
+// Adreslerde ismin ardından gelen ve eşleşmede yok sayılan kelimeler
+var areaSuffixes = map[string]bool{"mahallesi": true, "mah": true, "mh": true, "koyu": true, "ilcesi": true}
+
+var areaFold = strings.NewReplacer("ç", "c", "ğ", "g", "ı", "i", "ö", "o", "ş", "s", "ü", "u", "â", "a", "î", "i", "û", "u")
+
+// normalizeArea ismi Türkçe küçük harfe çevirir, aksanları kaldırır ve
+// harf/rakam dışındaki karakterleri boşluğa çevirir ("İmamoğlu" → "imamoglu")
+func normalizeArea(s string) string {
+	s = areaFold.Replace(strings.ToLowerSpecial(unicode.TurkishCase, s))
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// normalizeAreaName listedeki ismi "Gaffaruşağı Mahallesi" → "gaffarusagi" biçimine getirir
+func normalizeAreaName(s string) string {
+	words := strings.Fields(normalizeArea(s))
+	for len(words) > 1 && areaSuffixes[words[len(words)-1]] {
+		words = words[:len(words)-1]
+	}
+	return strings.Join(words, " ")
+}
+
+// mentionsAny metinde isimlerden biri tam kelime olarak geçiyor mu
+func mentionsAny(text string, names []string) bool {
+	text = " " + text + " "
+	for _, name := range names {
+		if n := normalizeAreaName(name); n != "" && strings.Contains(text, " "+n+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// pointInPolygon ışın yöntemi; bölgeler küçük olduğu için düzlem kabul edilir
+func pointInPolygon(lat, lng float64, polygon [][2]float64) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		latI, lngI := polygon[i][0], polygon[i][1]
+		latJ, lngJ := polygon[j][0], polygon[j][1]
+		if (lngI > lng) != (lngJ > lng) && lat < (latJ-latI)*(lng-lngI)/(lngJ-lngI)+latI {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// serviceLocation bölge kontrolü için adres ve/veya koordinat
+type serviceLocation struct {
+	Address       string   `json:"address"`
+	District      string   `json:"district"`
+	Neighbourhood string   `json:"neighbourhood"`
+	Latitude      *float64 `json:"latitude"`
+	Longitude     *float64 `json:"longitude"`
+}
+
+func (l serviceLocation) hasCoordinates() bool {
+	return l.Latitude != nil && l.Longitude != nil && validCoordinates(*l.Latitude, *l.Longitude)
+}
+
+// zoneMatches konum bölgenin içinde mi. Mahalle listesi olan bölgede ilçe
+// listesi de varsa adres bu ilçelerden birini içermelidir; aynı isimli
+// mahalleler farklı ilçelerde bulunabilir.
+func zoneMatches(z models.ServiceZone, loc serviceLocation, shopLat, shopLng float64, shopOK bool) bool {
+	switch z.Kind {
+	case zoneRadius:
+		if !loc.hasCoordinates() {
+			return false
+		}
+		lat, lng := shopLat, shopLng
+		if z.CenterLat != nil && z.CenterLng != nil {
+			lat, lng = *z.CenterLat, *z.CenterLng
+		} else if !shopOK {
+			return false
+		}
+		return haversineKm(lat, lng, *loc.Latitude, *loc.Longitude) <= z.RadiusKm
+	case zonePolygon:
+		return loc.hasCoordinates() && pointInPolygon(*loc.Latitude, *loc.Longitude, z.Polygon)
+	case zoneArea:
+		text := normalizeArea(loc.District + " " + loc.Neighbourhood + " " + loc.Address)
+		if len(z.Neighbourhoods) > 0 {
+			return mentionsAny(text, z.Neighbourhoods) && (len(z.Districts) == 0 || mentionsAny(text, z.Districts))
+		}
+		return mentionsAny(text, z.Districts)
+	}
+	return false
+}
+
+const serviceZoneColumns = `id, name, kind, center_lat, center_lng, radius_km, polygon, districts, neighbourhoods,
+	call_out_fee, days, priority, active, created_at, updated_at`
+
+func scanServiceZone(scanner interface{ Scan(...interface{}) error }) (models.ServiceZone, error) {
+	var z models.ServiceZone
+	var centerLat, centerLng sql.NullFloat64
+	var polygon, districts, neighbourhoods, days []byte
+	err := scanner.Scan(&z.ID, &z.Name, &z.Kind, &centerLat, &centerLng, &z.RadiusKm, &polygon, &districts, &neighbourhoods,
+		&z.CallOutFee, &days, &z.Priority, &z.Active, &z.CreatedAt, &z.UpdatedAt)
+	if err != nil {
+		return z, err
+	}
+	if centerLat.Valid && centerLng.Valid {
+		z.CenterLat, z.CenterLng = &centerLat.Float64, &centerLng.Float64
+	}
+	json.Unmarshal(polygon, &z.Polygon)
+	json.Unmarshal(districts, &z.Districts)
+	json.Unmarshal(neighbourhoods, &z.Neighbourhoods)
+	json.Unmarshal(days, &z.Days)
+	return z, nil
+}
+
+func getServiceZones(activeOnly bool) ([]models.ServiceZone, error) {
+	query := "SELECT " + serviceZoneColumns + " FROM service_zones"
+	if activeOnly {
+		query += " WHERE active"
+	}
+	rows, err := db.DB.Query(query + " ORDER BY priority, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	zones := []models.ServiceZone{}
+	for rows.Next() {
+		z, err := scanServiceZone(rows)
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, z)
+	}
+	return zones, rows.Err()
+}
+
+func getServiceZone(id int) (models.ServiceZone, error) {
+	return scanServiceZone(db.DB.QueryRow("SELECT "+serviceZoneColumns+" FROM service_zones WHERE id = $1", id))
+}
+
+// findServiceZone konumun düştüğü ilk aktif bölge; bulunamazsa nil
+func findServiceZone(loc serviceLocation) (*models.ServiceZone, error) {
+	zones, err := getServiceZones(true)
+	if err != nil {
+		return nil, err
+	}
+	shopLat, shopLng, shopErr := routeDepot()
+	for _, z := range zones {
+		if zoneMatches(z, loc, shopLat, shopLng, shopErr == nil) {
+			return &z, nil
+		}
+	}
+	return nil, nil
+}
+
+func zoneServesDay(z models.ServiceZone, t time.Time) bool {
+	weekday := isoWeekday(t.In(businessLocation))
+	for _, d := range z.Days {
+		if d == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceZoneFee konumun bölgesi ve yol ücreti; bölge yoksa nil ve 0
+func serviceZoneFee(loc serviceLocation) (*int, float64, error) {
+	zone, err := findServiceZone(loc)
+	if err != nil || zone == nil {
+		return nil, 0, err
+	}
+	return &zone.ID, zone.CallOutFee, nil
+}
+
+// applyServiceZone iş emrinin bölgesini ve yol ücretini adres/koordinata göre belirler
+func applyServiceZone(wo *models.WorkOrder) error {
+	var err error
+	wo.ServiceZoneID, wo.CallOutFee, err = serviceZoneFee(serviceLocation{Address: wo.Address, Latitude: wo.Latitude, Longitude: wo.Longitude})
+	return err
+}
+
+// checkServiceDay randevu, bölgenin servis günlerinden birine düşmeli
+func checkServiceDay(wo models.WorkOrder) error {
+	if wo.ServiceZoneID == nil || wo.ScheduledStart == nil {
+		return nil
+	}
+	zone, err := getServiceZone(*wo.ServiceZoneID)
+	if err != nil {
+		return err
+	}
+	if !zoneServesDay(zone, *wo.ScheduledStart) {
+		return fmt.Errorf("%w (%s: %s)", errServiceDay, zone.Name, formatWeekdays(zone.Days))
+	}
+	return nil
+}
+
+// checkServiceArea aktif bölge varken hiçbirine düşmeyen adresi reddeder.
+// Adres ve konum yoksa veya bölge dışı açıkça onaylandıysa kontrol yapılmaz.
+func checkServiceArea(wo models.WorkOrder, allowOutside bool) error {
+	return checkZoneCoverage(wo.ServiceZoneID, wo.Address, wo.Latitude, allowOutside)
+}
+
+func checkZoneCoverage(zoneID *int, address string, lat *float64, allowOutside bool) error {
+	if zoneID != nil || allowOutside || (strings.TrimSpace(address) == "" && lat == nil) {
+		return nil
+	}
+	var zones bool
+	if err := db.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM service_zones WHERE active)").Scan(&zones); err != nil {
+		return err
+	}
+	if zones {
+		return errOutsideServiceArea
+	}
+	return nil
+}
+
+// respondZoneError bölge kurallarına uymayan kayıtları 400, diğer hataları 500 olarak döndürür
+func respondZoneError(c *gin.Context, err error) {
+	if errors.Is(err, errServiceDay) || errors.Is(err, errOutsideServiceArea) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+func formatWeekdays(days []int) string {
+	names := make([]string, 0, len(days))
+	for _, d := range days {
+		names = append(names, weekdayNames[d])
+	}
+	return strings.Join(names, ", ")
+}
+
+// nextServiceDate verilen günden itibaren bölgeye servis verilen ilk gün
+func nextServiceDate(z models.ServiceZone, from time.Time) (time.Time, bool) {
+	for i := 0; i < 7; i++ {
+		day := from.AddDate(0, 0, i)
+		if zoneServesDay(z, day) {
+			return day, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// checkServiceAreaHandler adres veya koordinat için hizmet verilip verilmediğini ve yol ücretini döndürür
+func checkServiceAreaHandler(c *gin.Context) {
+	var input struct {
+		serviceLocation
+		Date string `json:"date"` // YYYY-AA-GG, isteğe bağlı
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	loc := input.serviceLocation
+	if (loc.Latitude != nil || loc.Longitude != nil) && !loc.hasCoordinates() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz koordinat"})
+		return
+	}
+	if !loc.hasCoordinates() && strings.TrimSpace(loc.Address+loc.District+loc.Neighbourhood) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Adres veya koordinat zorunludur"})
+		return
+	}
+	from := time.Now().In(businessLocation)
+	if input.Date != "" {
+		d, err := time.ParseInLocation("2006-01-02", input.Date, businessLocation)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Tarih YYYY-AA-GG biçiminde olmalı"})
+			return
+		}
+		from = d
+	}
+
+	zone, err := findServiceZone(loc)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if zone == nil {
+		resp := gin.H{"served": false}
+		// Adres isimle eşleşmediyse koordinatla tekrar denenebilir
+		if !loc.hasCoordinates() {
+			resp["needs_coordinates"] = true
+		}
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	// available: istenen gün (verilmezse bugün) servis günü mü
+	resp := gin.H{
+		"served":              true,
+		"zone":                gin.H{"id": zone.ID, "name": zone.Name},
+		"call_out_fee":        zone.CallOutFee,
+		"currency":            priceCurrency,
+		"days":                zone.Days,
+		"days_text":           formatWeekdays(zone.Days),
+		"available":           zoneServesDay(*zone, from),
+		"next_available_date": nil,
+	}
+	if next, ok := nextServiceDate(*zone, from); ok {
+		resp["next_available_date"] = next.Format("2006-01-02")
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func validateServiceZone(z *models.ServiceZone) error {
+	z.Name = strings.TrimSpace(z.Name)
+	if z.Name == "" {
+		return fmt.Errorf("Bölge adı zorunludur")
+	}
+	if !zoneKinds[z.Kind] {
+		return fmt.Errorf("Bölge türü radius, polygon veya area olmalı")
+	}
+	if z.CallOutFee < 0 {
+		return fmt.Errorf("Yol ücreti negatif olamaz")
+	}
+	switch z.Kind {
+	case zoneRadius:
+		if z.RadiusKm <= 0 {
+			return fmt.Errorf("Yarıçap (km) pozitif olmalı")
+		}
+		if (z.CenterLat == nil) != (z.CenterLng == nil) || (z.CenterLat != nil && !validCoordinates(*z.CenterLat, *z.CenterLng)) {
+			return fmt.Errorf("Geçersiz merkez koordinatı")
+		}
+		z.Polygon, z.Districts, z.Neighbourhoods = nil, nil, nil
+	case zonePolygon:
+		if len(z.Polygon) < 3 {
+			return fmt.Errorf("Çokgen en az 3 noktadan oluşmalı")
+		}
+		for _, p := range z.Polygon {
+			if !validCoordinates(p[0], p[1]) {
+				return fmt.Errorf("Çokgende geçersiz koordinat")
+			}
+		}
+		z.CenterLat, z.CenterLng, z.RadiusKm, z.Districts, z.Neighbourhoods = nil, nil, 0, nil, nil
+	case zoneArea:
+		z.Districts, z.Neighbourhoods = cleanNames(z.Districts), cleanNames(z.Neighbourhoods)
+		if len(z.Districts) == 0 && len(z.Neighbourhoods) == 0 {
+			return fmt.Errorf("En az bir ilçe veya mahalle girilmeli")
+		}
+		z.CenterLat, z.CenterLng, z.RadiusKm, z.Polygon = nil, nil, 0, nil
+	}
+
+	if len(z.Days) == 0 {
+		z.Days = allWeekdays
+	}
+	seen := make(map[int]bool)
+	days := []int{}
+	for _, d := range z.Days {
+		if d < 1 || d > 7 {
+			return fmt.Errorf("Günler 1 (Pazartesi) ile 7 (Pazar) arasında olmalı")
+		}
+		if !seen[d] {
+			seen[d] = true
+			days = append(days, d)
+		}
+	}
+	z.Days = days
+	return nil
+}
+
+func cleanNames(names []string) []string {
+	out := []string{}
+	for _, n := range names {
+		if n = strings.TrimSpace(n); n != "" {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func getServiceZonesHandler(c *gin.Context) {
+	zones, err := getServiceZones(false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, zones)
+}
+
+func createServiceZoneHandler(c *gin.Context) {
+	z := models.ServiceZone{Active: true}
+	if err := c.ShouldBindJSON(&z); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateServiceZone(&z); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	polygon, _ := json.Marshal(z.Polygon)
+	districts, _ := json.Marshal(z.Districts)
+	neighbourhoods, _ := json.Marshal(z.Neighbourhoods)
+	days, _ := json.Marshal(z.Days)
+	err := db.DB.QueryRow(
+		`INSERT INTO service_zones (name, kind, center_lat, center_lng, radius_km, polygon, districts, neighbourhoods, call_out_fee, days, priority, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id, created_at, updated_at`,
+		z.Name, z.Kind, z.CenterLat, z.CenterLng, z.RadiusKm, string(polygon), string(districts), string(neighbourhoods),
+		z.CallOutFee, string(days), z.Priority, z.Active,
+	).Scan(&z.ID, &z.CreatedAt, &z.UpdatedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, "service_zone", z.ID, auditCreate, nil, z)
+	c.JSON(http.StatusCreated, z)
+}
+
+// updateServiceZoneHandler bölgeyi tamamen değiştirir. Mevcut iş emirlerinin
+// yol ücreti değişmez; adresleri güncellendiğinde yeniden hesaplanır.
+func updateServiceZoneHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	before, err := getServiceZone(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Bölge bulunamadı"})
+		return
+	}
+	z := before
+	if err := c.ShouldBindJSON(&z); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	z.ID = id
+	if err := validateServiceZone(&z); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	polygon, _ := json.Marshal(z.Polygon)
+	districts, _ := json.Marshal(z.Districts)
+	neighbourhoods, _ := json.Marshal(z.Neighbourhoods)
+	days, _ := json.Marshal(z.Days)
+	err = db.DB.QueryRow(
+		`UPDATE service_zones SET name = $1, kind = $2, center_lat = $3, center_lng = $4, radius_km = $5, polygon = $6,
+			districts = $7, neighbourhoods = $8, call_out_fee = $9, days = $10, priority = $11, active = $12, updated_at = NOW()
+		WHERE id = $13 RETURNING updated_at`,
+		z.Name, z.Kind, z.CenterLat, z.CenterLng, z.RadiusKm, string(polygon), string(districts), string(neighbourhoods),
+		z.CallOutFee, string(days), z.Priority, z.Active, id,
+	).Scan(&z.UpdatedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, "service_zone", id, auditUpdate, before, z)
+	c.JSON(http.StatusOK, z)
+}
+
+func deleteServiceZoneHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	before, err := getServiceZone(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Bölge bulunamadı"})
+		return
+	}
+	if _, err := db.DB.Exec("DELETE FROM service_zones WHERE id = $1", id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, "service_zone", id, auditDelete, before, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Bölge silindi"})
+}
@@ -0,0 +1,51 @@
+package main
+
+import "testing"
+
+func TestPointInPolygon(t *testing.T) {
+	square := [][2]float64{{37.0, 35.0}, {37.0, 36.0}, {38.0, 36.0}, {38.0, 35.0}}
+	// Sağ üst köşesi içe doğru kesik "L" biçimi
+	lShape := [][2]float64{{37.0, 35.0}, {37.0, 36.0}, {37.5, 36.0}, {37.5, 35.5}, {38.0, 35.5}, {38.0, 35.0}}
+	triangle := [][2]float64{{37.0, 35.0}, {37.0, 36.0}, {38.0, 35.5}}
+	tests := []struct {
+		name     string
+		lat, lng float64
+		polygon  [][2]float64
+		want     bool
+	}{
+		{"kare içi", 37.5, 35.5, square, true},
+		{"kare dışı kuzey", 38.5, 35.5, square, false},
+		{"kare dışı doğu", 37.5, 36.5, square, false},
+		{"kare dışı batı", 37.5, 34.5, square, false},
+		{"L içi alt", 37.2, 35.8, lShape, true},
+		{"L içi sol", 37.8, 35.2, lShape, true},
+		{"L kesik köşe", 37.8, 35.8, lShape, false},
+		{"üçgen içi", 37.3, 35.5, triangle, true},
+		{"üçgen dışı köşe yanı", 37.9, 35.1, triangle, false},
+		{"boş çokgen", 37.5, 35.5, nil, false},
+		{"iki nokta", 37.5, 35.5, [][2]float64{{37.0, 35.0}, {38.0, 36.0}}, false},
+	}
+	for _, tt := range tests {
+		if got := pointInPolygon(tt.lat, tt.lng, tt.polygon); got != tt.want {
+			t.Errorf("%s: pointInPolygon(%v, %v) = %v, want %v", tt.name, tt.lat, tt.lng, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeAreaName(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Gaffaruşağı Mahallesi", "gaffarusagi"},
+		{"İMAMOĞLU", "imamoglu"},
+		{"Tufanpaşa Mah.", "tufanpasa"},
+		{"Yeşilyurt Köyü", "yesilyurt"},
+		{"Mahallesi", "mahallesi"},
+		{"  ", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeAreaName(tt.in); got != tt.want {
+			t.Errorf("normalizeAreaName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,545 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"kozan/db"
+	"kozan/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Teknisyen cihazları için çevrimdışı eşitleme. Cihaz bağlantı yokken yaptığı
+// değişiklikleri kendi ürettiği ID ve zamanla kuyruğa alır, bağlantı gelince
+// toplu gönderir (push) ve son imleçten bu yana değişen işleri çeker (pull).
+//
+// Her değişiklik (user_id, id) ile sync_mutations tablosuna yazılır; aynı
+// değişiklik tekrar gelirse yeniden uygulanmaz, kayıtlı sonuç döner. Çakışmalar
+// sabit kurallarla çözülür:
+//   - durum geçişleri sadece ileri gider; iş zaten o durumda veya ilerisindeyse
+//     sunucudaki durum geçerli kalır (already_applied)
+//   - iş iptal edildiyse veya değişiklik iş tamamlandıktan sonra yapıldıysa
+//     uygulanmaz (job_closed); iş başka teknisyene verildiyse not_assigned
+//   - not, kontrol listesi ve imzada son yazan kazanır: cihaz zamanı sunucudaki
+//     son değişiklikten eskiyse uygulanmaz (stale)
+//   - parça ve fotoğraflar client_id ile bir kez eklenir, silme tekrarlanabilir
+const (
+	syncMaxMutations = 100
+	syncMaxBody      = 64 << 20
+	syncRetention    = 30 * 24 * time.Hour
+	// İlk eşitlemede bu süreden önce tamamlanmış işler gönderilmez
+	syncCompletedWindow = 7 * 24 * time.Hour
+)
+
+// Değişiklik türleri
+const (
+	mutationStartTravel     = "work_order.start_travel"
+	mutationArrive          = "work_order.arrive"
+	mutationFinish          = "work_order.finish"
+	mutationNotes           = "work_order.notes"
+	mutationPartAdd         = "part.add"
+	mutationPartRemove      = "part.remove"
+	mutationPhotoAdd        = "photo.add"
+	mutationPhotoRemove     = "photo.remove"
+	mutationChecklistUpdate = "checklist.update"
+	mutationSignature       = "signature.save"
+)
+
+// Değişiklik sonuçları. retry dışındakiler kaydedilir ve kesindir; cihaz
+// değişikliği kuyruktan silebilir.
+const (
+	syncApplied  = "applied"
+	syncConflict = "conflict"
+	syncRejected = "rejected"
+	syncRetry    = "retry"
+)
+
+// Çakışma nedenleri
+const (
+	conflictNotAssigned       = "not_assigned"
+	conflictJobClosed         = "job_closed"
+	conflictAlreadyApplied    = "already_applied"
+	conflictInvalidTransition = "invalid_transition"
+	conflictStale             = "stale"
+)
+
+// workOrderStatusRank durum geçişlerinin sırası
+var workOrderStatusRank = map[string]int{
+	"new": 0, "scheduled": 1, "on_the_way": 2, "in_progress": 3, "completed": 4,
+}
+
+type transition struct {
+	status string
+	apply  jobTransition
+}
+
+var syncTransitions = map[string]transition{
+	mutationStartTravel: {"on_the_way", startTravel},
+	mutationArrive:      {"in_progress", arriveAtJob},
+	mutationFinish:      {"completed", finishJob},
+}
+
+type syncMutation struct {
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	WorkOrderID int             `json:"work_order_id"`
+	ClientTime  *time.Time      `json:"client_time"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+type syncResult struct {
+	ID        string      `json:"id"`
+	Status    string      `json:"status"`
+	Conflict  string      `json:"conflict,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	Data      interface{} `json:"data,omitempty"`
+	Duplicate bool        `json:"duplicate,omitempty"`
+}
+
+type syncRequest struct {
+	DeviceID  string         `json:"device_id"`
+	Cursor    string         `json:"cursor"`
+	Mutations []syncMutation `json:"mutations"`
+}
+
+type syncPull struct {
+	Cursor     string      `json:"cursor"`
+	Full       bool        `json:"full"`
+	Jobs       []jobDetail `json:"jobs"`
+	Removed    []int       `json:"removed"`
+	ServerTime time.Time   `json:"server_time"`
+}
+
+func conflictResult(m syncMutation, reason string, data interface{}) syncResult {
+	return syncResult{ID: m.ID, Status: syncConflict, Conflict: reason, Data: data}
+}
+
+func rejectedResult(m syncMutation, message string) syncResult {
+	return syncResult{ID: m.ID, Status: syncRejected, Error: message}
+}
+
+// findMutation daha önce uygulanmış değişikliğin kayıtlı sonucunu döndürür
+func findMutation(q queryRower, userID int, mutationID string) (syncResult, error) {
+	var raw []byte
+	err := q.QueryRow("SELECT result FROM sync_mutations WHERE user_id = $1 AND mutation_id = $2", userID, mutationID).Scan(&raw)
+	if err != nil {
+		return syncResult{}, err
+	}
+	var result syncResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return syncResult{}, err
+	}
+	result.Duplicate = true
+	return result, nil
+}
+
+// processMutation değişikliği bir kez uygular. Kayıt önce sahiplenilir; aynı
+// değişiklik eşzamanlı gelirse ikinci istek satır kilidinde bekler ve ilk
+// isteğin sonucunu döndürür. Dönen hata geçicidir; sahiplenme geri alınır ve
+// cihaz tekrar gönderir.
+func processMutation(c *gin.Context, deviceID string, m syncMutation) (syncResult, error) {
+	userID := currentUserID(c)
+	if m.ID == "" || len(m.ID) > 64 {
+		return rejectedResult(m, "Değişiklik ID'si zorunludur (en fazla 64 karakter)"), nil
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return syncResult{}, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		`INSERT INTO sync_mutations (user_id, mutation_id, device_id, type, work_order_id, client_time, status, result)
+		VALUES ($1, $2, $3, $4, $5, $6, 'pending', '{}') ON CONFLICT (user_id, mutation_id) DO NOTHING`,
+		userID, m.ID, deviceID, m.Type, m.WorkOrderID, m.ClientTime,
+	)
+	if err != nil {
+		return syncResult{}, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return syncResult{}, err
+	}
+	if n == 0 {
+		return findMutation(tx, userID, m.ID)
+	}
+
+	// Değişiklik kaydıyla aynı işlemde uygulanır; uygulanmayan değişikliğin
+	// yarım kalan yazımları kayıt noktasına geri alınır
+	if _, err := tx.Exec("SAVEPOINT apply_mutation"); err != nil {
+		return syncResult{}, err
+	}
+	var effects commitEffects
+	result, err := applyMutation(c, tx, &effects, m)
+	if err != nil {
+		effects.rolledBack()
+		return syncResult{}, err
+	}
+	if result.Status != syncApplied {
+		effects.rolledBack()
+		effects = commitEffects{}
+		if _, err := tx.Exec("ROLLBACK TO SAVEPOINT apply_mutation"); err != nil {
+			return syncResult{}, err
+		}
+	}
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return syncResult{}, err
+	}
+	_, err = tx.Exec(
+		"UPDATE sync_mutations SET status = $1, result = $2 WHERE user_id = $3 AND mutation_id = $4",
+		result.Status, string(raw), userID, m.ID,
+	)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		effects.rolledBack()
+		return syncResult{}, err
+	}
+	effects.committed()
+	return result, nil
+}
+
+// jobClosedAt değişiklik cihazda yapıldığında iş kapanmış mıydı
+func jobClosedAt(wo models.WorkOrder, at time.Time) bool {
+	if wo.Status == "cancelled" {
+		return true
+	}
+	return wo.Status == "completed" && wo.CompletedAt != nil && at.After(*wo.CompletedAt)
+}
+
+// transitionConflict geçişin neden uygulanamayacağını döndürür. Durum sadece
+// ileri gider; iş zaten hedef durumda veya ilerisindeyse sunucudaki durum geçerlidir.
+func transitionConflict(wo models.WorkOrder, target string) string {
+	if wo.Status == "cancelled" {
+		return conflictJobClosed
+	}
+	if workOrderStatusRank[wo.Status] >= workOrderStatusRank[target] {
+		return conflictAlreadyApplied
+	}
+	return ""
+}
+
+// staleAt cihazdaki değişiklik sunucudaki son değişiklikten eski mi (son yazan kazanır)
+func staleAt(last *time.Time, at time.Time) bool {
+	return last != nil && last.After(at)
+}
+
+func applyMutation(c *gin.Context, tx *sql.Tx, effects *commitEffects, m syncMutation) (syncResult, error) {
+	wo, err := getWorkOrder(m.WorkOrderID)
+	if err == sql.ErrNoRows {
+		return rejectedResult(m, "İş bulunamadı"), nil
+	}
+	if err != nil {
+		return syncResult{}, err
+	}
+	if wo.TechnicianID == nil || *wo.TechnicianID != currentUserID(c) {
+		return conflictResult(m, conflictNotAssigned, nil), nil
+	}
+	at := checkpointInput{RecordedAt: m.ClientTime}.at()
+	decode := func(v interface{}) bool {
+		return len(m.Payload) == 0 || json.Unmarshal(m.Payload, v) == nil
+	}
+
+	if t, ok := syncTransitions[m.Type]; ok {
+		var input checkpointInput
+		if !decode(&input) {
+			return rejectedResult(m, "Geçersiz içerik"), nil
+		}
+		if input.RecordedAt == nil {
+			input.RecordedAt = &at
+		}
+		if reason := transitionConflict(wo, t.status); reason != "" {
+			return conflictResult(m, reason, wo), nil
+		}
+		updated, err := t.apply(c, tx, effects, wo, input)
+		if errors.Is(err, errWorkOrderState) {
+			return conflictResult(m, conflictInvalidTransition, wo), nil
+		}
+		if err != nil {
+			return syncResult{}, err
+		}
+		return syncResult{ID: m.ID, Status: syncApplied, Data: updated}, nil
+	}
+
+	if jobClosedAt(wo, at) {
+		return conflictResult(m, conflictJobClosed, nil), nil
+	}
+
+	switch m.Type {
+	case mutationNotes:
+		var input struct {
+			Notes string `json:"notes"`
+		}
+		if !decode(&input) {
+			return rejectedResult(m, "Geçersiz içerik"), nil
+		}
+		result, err := tx.Exec(
+			`UPDATE work_orders SET technician_notes = $1, technician_notes_at = $2, updated_at = NOW()
+			WHERE id = $3 AND (technician_notes_at IS NULL OR technician_notes_at <= $2)`,
+			strings.TrimSpace(input.Notes), at, wo.ID,
+		)
+		if err != nil {
+			return syncResult{}, err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return conflictResult(m, conflictStale, nil), nil
+		}
+		return syncResult{ID: m.ID, Status: syncApplied}, nil
+
+	case mutationPartAdd:
+		var input partInput
+		if !decode(&input) {
+			return rejectedResult(m, "Geçersiz içerik"), nil
+		}
+		if input.ClientID == "" {
+			input.ClientID = m.ID
+		}
+		part, err := addWorkOrderPart(c, tx, effects, wo, input)
+		if errors.Is(err, errInvalidStock) {
+			return rejectedResult(m, err.Error()), nil
+		}
+		if err != nil {
+			return syncResult{}, err
+		}
+		return syncResult{ID: m.ID, Status: syncApplied, Data: part}, nil
+
+	case mutationPartRemove, mutationPhotoRemove:
+		var input struct {
+			ID       int    `json:"id"`
+			ClientID string `json:"client_id"`
+		}
+		if !decode(&input) || (input.ID == 0 && input.ClientID == "") {
+			return rejectedResult(m, "id veya client_id zorunludur"), nil
+		}
+		if m.Type == mutationPartRemove {
+			_, err = removeWorkOrderPart(c, tx, wo.ID, input.ID, input.ClientID)
+		} else {
+			_, err = removeWorkOrderPhoto(c, tx, effects, wo.ID, input.ID, input.ClientID)
+		}
+		if errors.Is(err, errInvalidStock) {
+			return rejectedResult(m, err.Error()), nil
+		}
+		if err != nil {
+			return syncResult{}, err
+		}
+		// Zaten silinmiş kayıt da başarılı sayılır
+		return syncResult{ID: m.ID, Status: syncApplied}, nil
+
+	case mutationPhotoAdd:
+		var input struct {
+			photoInput
+			Data string `json:"data"`
+		}
+		if !decode(&input) {
+			return rejectedResult(m, "Geçersiz içerik"), nil
+		}
+		if input.ClientID == "" {
+			input.ClientID = m.ID
+		}
+		data, err := base64.StdEncoding.DecodeString(input.Data)
+		if err != nil {
+			return rejectedResult(m, "Fotoğraf base64 olmalı"), nil
+		}
+		photo, err := addWorkOrderPhoto(c, tx, effects, wo, input.photoInput, data)
+		if errors.Is(err, errInvalidPhoto) {
+			return rejectedResult(m, err.Error()), nil
+		}
+		if err != nil {
+			return syncResult{}, err
+		}
+		return syncResult{ID: m.ID, Status: syncApplied, Data: photo}, nil
+
+	case mutationChecklistUpdate:
+		var input struct {
+			ItemID int `json:"item_id"`
+			checklistInput
+		}
+		if !decode(&input) {
+			return rejectedResult(m, "Geçersiz içerik"), nil
+		}
+		input.RecordedAt = &at
+		item, err := updateChecklistItem(c, tx, wo, input.ItemID, input.checklistInput)
+		if err == sql.ErrNoRows {
+			var exists bool
+			if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM work_order_checklist WHERE id = $1 AND work_order_id = $2)", input.ItemID, wo.ID).Scan(&exists); err != nil {
+				return syncResult{}, err
+			}
+			if !exists {
+				return rejectedResult(m, "Madde bulunamadı"), nil
+			}
+			return conflictResult(m, conflictStale, nil), nil
+		}
+		if err != nil {
+			return syncResult{}, err
+		}
+		return syncResult{ID: m.ID, Status: syncApplied, Data: item}, nil
+
+	case mutationSignature:
+		var input signatureInput
+		if !decode(&input) {
+			return rejectedResult(m, "Geçersiz içerik"), nil
+		}
+		input.RecordedAt = &at
+		if staleAt(wo.SignedAt, at) {
+			return conflictResult(m, conflictStale, nil), nil
+		}
+		if _, err := saveSignature(c, tx, effects, wo, input); errors.Is(err, errInvalidSignature) {
+			return rejectedResult(m, err.Error()), nil
+		} else if err != nil {
+			return syncResult{}, err
+		}
+		return syncResult{ID: m.ID, Status: syncApplied}, nil
+	}
+
+	return rejectedResult(m, "Bilinmeyen değişiklik türü: "+m.Type), nil
+}
+
+// İmleç "<txid>.<unix zamanı>" biçimindedir. txid, imleç üretildiğinde hâlâ
+// açık olan en eski işlemdir; bu sayede imleçten önce başlayıp sonra biten
+// işlemlerin değişiklikleri kaçırılmaz.
+func encodeSyncCursor(xmin int64, at time.Time) string {
+	return strconv.FormatInt(xmin, 10) + "." + strconv.FormatInt(at.Unix(), 10)
+}
+
+func decodeSyncCursor(cursor string) (int64, time.Time, bool) {
+	xminPart, atPart, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return 0, time.Time{}, false
+	}
+	xmin, err1 := strconv.ParseInt(xminPart, 10, 64)
+	unix, err2 := strconv.ParseInt(atPart, 10, 64)
+	if err1 != nil || err2 != nil {
+		return 0, time.Time{}, false
+	}
+	return xmin, time.Unix(unix, 0), true
+}
+
+// pullChanges imleçten bu yana teknisyenin işlerinde değişenleri tam haliyle
+// döndürür. İmleç yoksa, geçersizse veya saklama süresinden eskiyse tüm
+// güncel işler gönderilir (full) ve cihaz yerel verisini bunlarla değiştirir.
+func pullChanges(userID int, cursor string) (syncPull, error) {
+	pull := syncPull{Jobs: []jobDetail{}, Removed: []int{}, ServerTime: time.Now()}
+	var xmin int64
+	if err := db.DB.QueryRow("SELECT txid_snapshot_xmin(txid_current_snapshot())").Scan(&xmin); err != nil {
+		return pull, err
+	}
+	pull.Cursor = encodeSyncCursor(xmin, pull.ServerTime)
+
+	var ids []int
+	var err error
+	since, issuedAt, ok := decodeSyncCursor(cursor)
+	if !ok || time.Since(issuedAt) > syncRetention {
+		pull.Full = true
+		ids, err = selectIDs(db.DB,
+			`SELECT id FROM work_orders WHERE technician_id = $1 AND status <> 'cancelled'
+			AND (status <> 'completed' OR completed_at > $2) ORDER BY scheduled_start NULLS LAST, id`,
+			userID, time.Now().Add(-syncCompletedWindow),
+		)
+	} else {
+		ids, err = selectIDs(db.DB,
+			`SELECT DISTINCT work_order_id FROM sync_changes WHERE technician_id = $1 AND txid >= $2 AND txid < $3`,
+			userID, since, xmin,
+		)
+	}
+	if err != nil {
+		return pull, err
+	}
+
+	for _, id := range ids {
+		wo, err := getWorkOrder(id)
+		if err != nil && err != sql.ErrNoRows {
+			return pull, err
+		}
+		if err == sql.ErrNoRows || wo.TechnicianID == nil || *wo.TechnicianID != userID || wo.Status == "cancelled" {
+			if !pull.Full {
+				pull.Removed = append(pull.Removed, id)
+			}
+			continue
+		}
+		detail, err := loadJobDetail(wo)
+		if err != nil {
+			return pull, err
+		}
+		pull.Jobs = append(pull.Jobs, detail)
+	}
+	return pull, nil
+}
+
+// syncHandler değişiklikleri gönderildiği sırayla uygular, ardından imleçten
+// bu yana değişen işleri döndürür. Geçici bir hata olursa o ve sonraki
+// değişiklikler "retry" olarak döner; sıra bozulmasın diye uygulanmaz.
+func syncHandler(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, syncMaxBody)
+	var req syncRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Mutations) > syncMaxMutations {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Bir istekte en fazla %d değişiklik gönderilebilir", syncMaxMutations)})
+		return
+	}
+	if req.DeviceID == "" {
+		req.DeviceID = c.GetHeader("X-Device-ID")
+	}
+	if len(req.DeviceID) > 64 {
+		req.DeviceID = req.DeviceID[:64]
+	}
+
+	results := make([]syncResult, 0, len(req.Mutations))
+	failed := false
+	for _, m := range req.Mutations {
+		if failed {
+			results = append(results, syncResult{ID: m.ID, Status: syncRetry})
+			continue
+		}
+		result, err := processMutation(c, req.DeviceID, m)
+		if err != nil {
+			log.Printf("sync: mutation %s (%s) failed: %v", m.ID, m.Type, err)
+			failed = true
+			result = syncResult{ID: m.ID, Status: syncRetry, Error: "Geçici hata, tekrar gönderin"}
+		}
+		results = append(results, result)
+	}
+
+	pull, err := pullChanges(currentUserID(c), req.Cursor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "results": results})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"results": results, "cursor": pull.Cursor, "full": pull.Full,
+		"jobs": pull.Jobs, "removed": pull.Removed, "server_time": pull.ServerTime,
+	})
+}
+
+func pullSyncHandler(c *gin.Context) {
+	pull, err := pullChanges(currentUserID(c), c.Query("cursor"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pull)
+}
+
+func cleanupSyncLog(ctx context.Context) error {
+	cutoff := time.Now().Add(-syncRetention)
+	if _, err := db.DB.ExecContext(ctx, "DELETE FROM sync_changes WHERE created_at < $1", cutoff); err != nil {
+		return err
+	}
+	_, err := db.DB.ExecContext(ctx, "DELETE FROM sync_mutations WHERE created_at < $1", cutoff)
+	return err
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"kozan/models"
+)
+
+func TestDecodeSyncCursor(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+		xmin   int64
+		at     int64
+		ok     bool
+	}{
+		{"geçerli", "12345.1760000000", 12345, 1760000000, true},
+		{"sıfır", "0.0", 0, 0, true},
+		{"boş", "", 0, 0, false},
+		{"nokta yok", "12345", 0, 0, false},
+		{"txid sayı değil", "abc.1760000000", 0, 0, false},
+		{"zaman sayı değil", "12345.abc", 0, 0, false},
+		{"zaman eksik", "12345.", 0, 0, false},
+		{"fazla nokta", "1.2.3", 0, 0, false},
+	}
+	for _, tt := range tests {
+		xmin, at, ok := decodeSyncCursor(tt.cursor)
+		if ok != tt.ok {
+			t.Errorf("%s: decodeSyncCursor(%q) ok = %v, want %v", tt.name, tt.cursor, ok, tt.ok)
+			continue
+		}
+		if ok && (xmin != tt.xmin || at.Unix() != tt.at) {
+			t.Errorf("%s: decodeSyncCursor(%q) = %d, %d, want %d, %d", tt.name, tt.cursor, xmin, at.Unix(), tt.xmin, tt.at)
+		}
+	}
+}
+
+func TestSyncCursorRoundTrip(t *testing.T) {
+	at := time.Date(2026, 3, 10, 14, 30, 15, 500, time.UTC)
+	xmin, got, ok := decodeSyncCursor(encodeSyncCursor(987654, at))
+	if !ok || xmin != 987654 || !got.Equal(at.Truncate(time.Second)) {
+		t.Errorf("round trip = %d, %v, %v; want 987654, %v, true", xmin, got, ok, at.Truncate(time.Second))
+	}
+}
+
+func TestJobClosedAt(t *testing.T) {
+	completed := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		wo   models.WorkOrder
+		at   time.Time
+		want bool
+	}{
+		{"açık iş", models.WorkOrder{Status: "in_progress"}, completed, false},
+		{"iptal edilmiş", models.WorkOrder{Status: "cancelled"}, completed.Add(-time.Hour), true},
+		{"tamamlanmadan önce", models.WorkOrder{Status: "completed", CompletedAt: &completed}, completed.Add(-time.Minute), false},
+		{"tamamlandığı anda", models.WorkOrder{Status: "completed", CompletedAt: &completed}, completed, false},
+		{"tamamlandıktan sonra", models.WorkOrder{Status: "completed", CompletedAt: &completed}, completed.Add(time.Minute), true},
+		{"bitiş zamanı yok", models.WorkOrder{Status: "completed"}, completed, false},
+	}
+	for _, tt := range tests {
+		if got := jobClosedAt(tt.wo, tt.at); got != tt.want {
+			t.Errorf("%s: jobClosedAt(%s, %v) = %v, want %v", tt.name, tt.wo.Status, tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestTransitionConflict(t *testing.T) {
+	tests := []struct {
+		status string
+		target string
+		want   string
+	}{
+		{"new", "on_the_way", ""},
+		{"scheduled", "in_progress", ""},
+		{"on_the_way", "in_progress", ""},
+		{"in_progress", "completed", ""},
+		{"on_the_way", "on_the_way", conflictAlreadyApplied},
+		{"in_progress", "on_the_way", conflictAlreadyApplied},
+		{"completed", "in_progress", conflictAlreadyApplied},
+		{"completed", "completed", conflictAlreadyApplied},
+		{"cancelled", "on_the_way", conflictJobClosed},
+		{"cancelled", "completed", conflictJobClosed},
+	}
+	for _, tt := range tests {
+		if got := transitionConflict(models.WorkOrder{Status: tt.status}, tt.target); got != tt.want {
+			t.Errorf("transitionConflict(%s, %s) = %q, want %q", tt.status, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestStaleAt(t *testing.T) {
+	last := time.Date(2026, 10, 19, 9, 40, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		last *time.Time
+		at   time.Time
+		want bool
+	}{
+		{"önceki değişiklik yok", nil, last, false},
+		{"cihaz daha yeni", &last, last.Add(time.Minute), false},
+		{"aynı an", &last, last, false},
+		{"cihaz daha eski", &last, last.Add(-time.Minute), true},
+	}
+	for _, tt := range tests {
+		if got := staleAt(tt.last, tt.at); got != tt.want {
+			t.Errorf("%s: staleAt(%v) = %v, want %v", tt.name, tt.at, got, tt.want)
+		}
+	}
+}
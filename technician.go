@@ -0,0 +1,1220 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"kozan/db"
+	"kozan/models"
+	"kozan/pdf"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// Teknisyen mobil API'si (/api/tech). Teknisyen sadece kendisine atanmış iş
+// emirlerini görür. Yola çıkış, varış ve bitiş GPS konumu ve zamanıyla
+// kaydedilir; fotoğraflar, kullanılan parçalar, kontrol listesi ve müşteri
+// imzası servis raporuna (PDF) girer.
+const (
+	checkpointTravel = "travel_started"
+	checkpointArrive = "arrived"
+	checkpointFinish = "finished"
+
+	photoMaxSize     = 10 << 20
+	signatureMaxSize = 1 << 20
+
+	// Telefon saati sunucudan biraz ileride olabilir
+	clockSkewTolerance = 5 * time.Minute
+	// Çevrimdışı kaydedilen işlemler en fazla bu kadar geriye tarihlenebilir
+	maxBackdate = 7 * 24 * time.Hour
+)
+
+var photoKinds = map[string]bool{"before": true, "after": true}
+
+var photoExtensions = map[string]string{"image/jpeg": ".jpg", "image/png": ".png", "image/webp": ".webp"}
+
+// defaultChecklist varışta kontrol listesi boşsa eklenir
+var defaultChecklist = []string{
+	"Cihaz ve çevresi kontrol edildi",
+	"Arıza tespit edildi ve müşteriye açıklandı",
+	"Yapılan işlem test edildi",
+	"Çalışma alanı temizlendi",
+	"Müşteriye kullanım bilgisi verildi",
+}
+
+// checkpointInput yola çıkış, konum, varış ve bitiş istekleri. recorded_at
+// çevrimdışı kaydedilen işlemin telefondaki zamanıdır.
+type checkpointInput struct {
+	Latitude   float64    `json:"latitude"`
+	Longitude  float64    `json:"longitude"`
+	Accuracy   float64    `json:"accuracy"`
+	ETAMinutes int        `json:"eta_minutes"`
+	RecordedAt *time.Time `json:"recorded_at"`
+	Notes      string     `json:"notes"`
+}
+
+func (in checkpointInput) hasLocation() bool {
+	return validCoordinates(in.Latitude, in.Longitude)
+}
+
+// at işlemin zamanı: telefonda kaydedilen zaman makulse o, değilse şimdi
+func (in checkpointInput) at() time.Time {
+	now := time.Now()
+	if in.RecordedAt == nil || in.RecordedAt.Before(now.Add(-maxBackdate)) || in.RecordedAt.After(now.Add(clockSkewTolerance)) {
+		return now
+	}
+	if in.RecordedAt.After(now) {
+		return now
+	}
+	return *in.RecordedAt
+}
+
+// resolveTime zamanı bir kez belirler; sonraki at() çağrıları aynı değeri döndürür
+func (in *checkpointInput) resolveTime() time.Time {
+	t := in.at()
+	in.RecordedAt = &t
+	return t
+}
+
+func recordCheckpoint(q execer, workOrderID, userID int, kind string, in checkpointInput) error {
+	var lat, lng, accuracy interface{}
+	if in.hasLocation() {
+		lat, lng = in.Latitude, in.Longitude
+		if in.Accuracy > 0 {
+			accuracy = in.Accuracy
+		}
+	}
+	_, err := q.Exec(
+		`INSERT INTO work_order_checkpoints (work_order_id, user_id, kind, latitude, longitude, accuracy_m, recorded_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		workOrderID, userID, kind, lat, lng, accuracy, in.at(),
+	)
+	return err
+}
+
+// arriveAtJob teknisyen adrese vardığında işi başlatır. Durum, varış
+// noktası ve kontrol listesi tek işlemde yazılır.
+func arriveAtJob(c *gin.Context, tx *sql.Tx, effects *commitEffects, wo models.WorkOrder, input checkpointInput) (models.WorkOrder, error) {
+	before := wo
+	at := input.resolveTime()
+	err := tx.QueryRow(
+		`UPDATE work_orders SET status = 'in_progress', arrived_at = $1, eta_at = NULL, updated_at = NOW()
+		WHERE id = $2 AND status IN ('new', 'scheduled', 'on_the_way') RETURNING status, arrived_at, updated_at`,
+		at, wo.ID,
+	).Scan(&wo.Status, &wo.ArrivedAt, &wo.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return wo, fmt.Errorf("%w: %s", errWorkOrderState, wo.Status)
+	}
+	if err != nil {
+		return wo, err
+	}
+	wo.ETA = nil
+	if err := recordCheckpoint(tx, wo.ID, currentUserID(c), checkpointArrive, input); err != nil {
+		return wo, err
+	}
+	if err := seedChecklist(tx, wo.ID); err != nil {
+		return wo, err
+	}
+	return wo, workOrderChanged(c, tx, effects, before, wo)
+}
+
+// finishJob işi tamamlar. Takip bağlantısı faturaya erişilebilsin diye uzatılır.
+func finishJob(c *gin.Context, tx *sql.Tx, effects *commitEffects, wo models.WorkOrder, input checkpointInput) (models.WorkOrder, error) {
+	before := wo
+	at := input.resolveTime()
+	err := tx.QueryRow(
+		`UPDATE work_orders SET status = 'completed', completed_at = $1, updated_at = NOW(),
+			technician_notes = CASE WHEN $2 <> '' THEN $2 ELSE technician_notes END,
+			technician_notes_at = CASE WHEN $2 <> '' THEN $1 ELSE technician_notes_at END,
+			tracking_expires_at = GREATEST(tracking_expires_at, $3)
+		WHERE id = $4 AND status = 'in_progress' RETURNING status, completed_at, technician_notes, updated_at`,
+		at, strings.TrimSpace(input.Notes), time.Now().Add(trackingLinkTTL()), wo.ID,
+	).Scan(&wo.Status, &wo.CompletedAt, &wo.TechnicianNotes, &wo.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return wo, fmt.Errorf("%w: %s", errWorkOrderState, wo.Status)
+	}
+	if err != nil {
+		return wo, err
+	}
+	if err := recordCheckpoint(tx, wo.ID, currentUserID(c), checkpointFinish, input); err != nil {
+		return wo, err
+	}
+	return wo, workOrderChanged(c, tx, effects, before, wo)
+}
+
+func seedChecklist(q execer, workOrderID int) error {
+	_, err := q.Exec(
+		`INSERT INTO work_order_checklist (work_order_id, position, label)
+		SELECT $1, ord, label FROM unnest($2::text[]) WITH ORDINALITY AS t(label, ord)
+		WHERE NOT EXISTS (SELECT 1 FROM work_order_checklist WHERE work_order_id = $1)`,
+		workOrderID, pq.Array(defaultChecklist),
+	)
+	return err
+}
+
+// jobEditable kapanmış işte fotoğraf, parça, kontrol listesi ve imza değiştirilemez
+func jobEditable(wo models.WorkOrder) error {
+	if wo.Status == "completed" || wo.Status == "cancelled" {
+		return fmt.Errorf("%w: %s", errWorkOrderState, wo.Status)
+	}
+	return nil
+}
+
+// technicianJob URL'deki işi yükler; iş teknisyene atanmamışsa 404 döner
+func technicianJob(c *gin.Context) (models.WorkOrder, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return models.WorkOrder{}, false
+	}
+	wo, err := getWorkOrder(id)
+	if err != nil || wo.TechnicianID == nil || *wo.TechnicianID != currentUserID(c) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "İş bulunamadı"})
+		return models.WorkOrder{}, false
+	}
+	return wo, true
+}
+
+// respondJobError durum hatalarını 409, diğerlerini 500 olarak döndürür
+func respondJobError(c *gin.Context, err error) {
+	if errors.Is(err, errWorkOrderState) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// getTechnicianJobsHandler günün işleri randevu sırasına göre. Bugün için
+// yolda veya devam eden işler randevu günü farklı olsa da listelenir.
+func getTechnicianJobsHandler(c *gin.Context) {
+	now := time.Now().In(businessLocation)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, businessLocation)
+	day := today
+	if v := c.Query("date"); v != "" {
+		d, err := time.ParseInLocation("2006-01-02", v, businessLocation)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Tarih YYYY-AA-GG biçiminde olmalı"})
+			return
+		}
+		day = d
+	}
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, businessLocation)
+	end := start.AddDate(0, 0, 1)
+	isToday := start.Equal(today)
+
+	rows, err := db.DB.Query(
+		"SELECT "+workOrderColumns+` FROM work_orders
+		WHERE technician_id = $1 AND status <> 'cancelled'
+			AND ((scheduled_start >= $2 AND scheduled_start < $3) OR ($4 AND status IN ('on_the_way', 'in_progress')))
+		ORDER BY scheduled_start NULLS LAST, id`,
+		currentUserID(c), start, end, isToday,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	jobs := []models.WorkOrder{}
+	for rows.Next() {
+		wo, err := scanWorkOrder(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		jobs = append(jobs, wo)
+	}
+	rows.Close()
+	for i := range jobs {
+		if customer, err := getCustomer(jobs[i].CustomerID); err == nil {
+			jobs[i].Customer = &customer
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"date": start.Format("2006-01-02"), "jobs": jobs})
+}
+
+// jobDetail iş emri ve sahada toplanan veriler
+type jobDetail struct {
+	models.WorkOrder
+	Checklist   []models.ChecklistItem       `json:"checklist"`
+	Parts       []models.WorkOrderPart       `json:"parts"`
+	Photos      []models.WorkOrderPhoto      `json:"photos"`
+	Checkpoints []models.WorkOrderCheckpoint `json:"checkpoints"`
+}
+
+func loadJobDetail(wo models.WorkOrder) (jobDetail, error) {
+	d := jobDetail{WorkOrder: wo}
+	if customer, err := getCustomer(wo.CustomerID); err == nil {
+		d.Customer = &customer
+	}
+	var err error
+	if d.Checklist, err = getChecklist(wo.ID); err != nil {
+		return d, err
+	}
+	if d.Parts, err = getWorkOrderParts(wo.ID); err != nil {
+		return d, err
+	}
+	if d.Photos, err = getWorkOrderPhotos(wo.ID); err != nil {
+		return d, err
+	}
+	d.Checkpoints, err = getCheckpoints(wo.ID)
+	return d, err
+}
+
+func getChecklist(workOrderID int) ([]models.ChecklistItem, error) {
+	rows, err := db.DB.Query(
+		"SELECT id, work_order_id, position, label, done, note, done_by, done_at FROM work_order_checklist WHERE work_order_id = $1 ORDER BY position, id",
+		workOrderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.ChecklistItem{}
+	for rows.Next() {
+		var item models.ChecklistItem
+		var doneBy sql.NullInt64
+		var doneAt sql.NullTime
+		if err := rows.Scan(&item.ID, &item.WorkOrderID, &item.Position, &item.Label, &item.Done, &item.Note, &doneBy, &doneAt); err != nil {
+			return nil, err
+		}
+		if doneBy.Valid {
+			v := int(doneBy.Int64)
+			item.DoneBy = &v
+		}
+		if doneAt.Valid {
+			item.DoneAt = &doneAt.Time
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+const workOrderPartColumns = "id, COALESCE(client_id, ''), work_order_id, part_id, location_id, name, quantity, unit, unit_price, note, created_by, created_at"
+
+func scanWorkOrderPart(scanner interface{ Scan(...interface{}) error }) (models.WorkOrderPart, error) {
+	var p models.WorkOrderPart
+	var partID, locationID sql.NullInt64
+	err := scanner.Scan(&p.ID, &p.ClientID, &p.WorkOrderID, &partID, &locationID, &p.Name, &p.Quantity, &p.Unit, &p.UnitPrice, &p.Note, &p.CreatedBy, &p.CreatedAt)
+	if partID.Valid {
+		v := int(partID.Int64)
+		p.PartID = &v
+	}
+	if locationID.Valid {
+		v := int(locationID.Int64)
+		p.LocationID = &v
+	}
+	return p, err
+}
+
+func getWorkOrderParts(workOrderID int) ([]models.WorkOrderPart, error) {
+	rows, err := db.DB.Query("SELECT "+workOrderPartColumns+" FROM work_order_parts WHERE work_order_id = $1 ORDER BY id", workOrderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	parts := []models.WorkOrderPart{}
+	for rows.Next() {
+		p, err := scanWorkOrderPart(rows)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, p)
+	}
+	return parts, rows.Err()
+}
+
+func getWorkOrderPhotos(workOrderID int) ([]models.WorkOrderPhoto, error) {
+	rows, err := db.DB.Query(
+		"SELECT id, COALESCE(client_id, ''), work_order_id, kind, content_type, caption, uploaded_by, created_at FROM work_order_photos WHERE work_order_id = $1 ORDER BY id",
+		workOrderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	photos := []models.WorkOrderPhoto{}
+	for rows.Next() {
+		var p models.WorkOrderPhoto
+		if err := rows.Scan(&p.ID, &p.ClientID, &p.WorkOrderID, &p.Kind, &p.ContentType, &p.Caption, &p.UploadedBy, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		photos = append(photos, p)
+	}
+	return photos, rows.Err()
+}
+
+func getCheckpoints(workOrderID int) ([]models.WorkOrderCheckpoint, error) {
+	rows, err := db.DB.Query(
+		`SELECT id, work_order_id, user_id, kind, latitude, longitude, accuracy_m, recorded_at, created_at
+		FROM work_order_checkpoints WHERE work_order_id = $1 ORDER BY recorded_at, id`,
+		workOrderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checkpoints := []models.WorkOrderCheckpoint{}
+	for rows.Next() {
+		var cp models.WorkOrderCheckpoint
+		var lat, lng, accuracy sql.NullFloat64
+		if err := rows.Scan(&cp.ID, &cp.WorkOrderID, &cp.UserID, &cp.Kind, &lat, &lng, &accuracy, &cp.RecordedAt, &cp.CreatedAt); err != nil {
+			return nil, err
+		}
+		if lat.Valid && lng.Valid {
+			cp.Latitude, cp.Longitude = &lat.Float64, &lng.Float64
+		}
+		if accuracy.Valid {
+			cp.Accuracy = &accuracy.Float64
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+	return checkpoints, rows.Err()
+}
+
+func getTechnicianJobHandler(c *gin.Context) {
+	wo, ok := technicianJob(c)
+	if !ok {
+		return
+	}
+	detail, err := loadJobDetail(wo)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, detail)
+}
+
+// getWorkOrderFieldDataHandler yönetim paneli için sahadan gelen veriler
+func getWorkOrderFieldDataHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	wo, err := getWorkOrder(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "İş emri bulunamadı"})
+		return
+	}
+	detail, err := loadJobDetail(wo)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, detail)
+}
+
+// jobTransition iş emri üzerinde çağıranın işleminde çalışan bir geçiş
+type jobTransition func(c *gin.Context, tx *sql.Tx, effects *commitEffects, wo models.WorkOrder, input checkpointInput) (models.WorkOrder, error)
+
+// runTransition geçişi kendi işleminde çalıştırır
+func runTransition(c *gin.Context, transition jobTransition, wo models.WorkOrder, input checkpointInput) (models.WorkOrder, error) {
+	return withTx(func(tx *sql.Tx, effects *commitEffects) (models.WorkOrder, error) {
+		return transition(c, tx, effects, wo, input)
+	})
+}
+
+// jobTransitionHandler yola çıkış, konum, varış ve bitiş için ortak işleyici
+func jobTransitionHandler(transition jobTransition) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		wo, ok := technicianJob(c)
+		if !ok {
+			return
+		}
+		var input checkpointInput
+		if err := c.ShouldBindJSON(&input); err != nil && err != io.EOF {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		wo, err := runTransition(c, transition, wo, input)
+		if err != nil {
+			respondJobError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, wo)
+	}
+}
+
+// updateJobLocation konum güncellemesi; jobTransitionHandler ile aynı imzada
+func updateJobLocation(c *gin.Context, tx *sql.Tx, effects *commitEffects, wo models.WorkOrder, input checkpointInput) (models.WorkOrder, error) {
+	return updateTravelLocation(c, tx, wo, input)
+}
+
+// Servis fotoğrafları, imzalar ve faturalar gibi herkese açık olmayan bir dizinde tutulur
+func serviceMediaDir() string {
+	if dir := os.Getenv("SERVICE_MEDIA_DIR"); dir != "" {
+		return dir
+	}
+	return "service-media"
+}
+
+// saveServiceMedia dosyayı iş emrinin dizinine yazar ve göreli yolunu döndürür
+func saveServiceMedia(workOrderID int, prefix, ext string, data []byte) (string, error) {
+	dir := filepath.Join(serviceMediaDir(), strconv.Itoa(workOrderID))
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%s_%d%s", prefix, time.Now().UnixNano(), ext)
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0640); err != nil {
+		return "", err
+	}
+	return filepath.Join(strconv.Itoa(workOrderID), name), nil
+}
+
+var errInvalidPhoto = errors.New("geçersiz fotoğraf")
+
+type photoInput struct {
+	ClientID string `json:"client_id"`
+	Kind     string `json:"kind"`
+	Caption  string `json:"caption"`
+}
+
+// addWorkOrderPhoto fotoğrafı doğrular ve kaydeder. client_id verilmişse aynı
+// fotoğrafın ikinci kez yüklenmesi mevcut kaydı döndürür.
+func addWorkOrderPhoto(c *gin.Context, tx *sql.Tx, effects *commitEffects, wo models.WorkOrder, input photoInput, data []byte) (models.WorkOrderPhoto, error) {
+	photo := models.WorkOrderPhoto{
+		ClientID: strings.TrimSpace(input.ClientID), WorkOrderID: wo.ID, Kind: input.Kind,
+		Caption: strings.TrimSpace(input.Caption), UploadedBy: currentUserID(c),
+	}
+	if !photoKinds[photo.Kind] {
+		return photo, fmt.Errorf("%w: tür before veya after olmalı", errInvalidPhoto)
+	}
+	if len(data) > photoMaxSize {
+		return photo, fmt.Errorf("%w: en fazla 10 MB olabilir", errInvalidPhoto)
+	}
+	photo.ContentType = http.DetectContentType(data)
+	ext, ok := photoExtensions[photo.ContentType]
+	if !ok {
+		return photo, fmt.Errorf("%w: JPEG, PNG veya WebP olmalı", errInvalidPhoto)
+	}
+	if photo.ClientID != "" {
+		if existing, err := getPhotoByClientID(tx, wo.ID, photo.ClientID); err == nil {
+			return existing, nil
+		}
+	}
+
+	file, err := saveServiceMedia(wo.ID, photo.Kind, ext, data)
+	if err != nil {
+		return photo, err
+	}
+	// İşlem geri alınırsa kayıtsız kalan dosya silinir
+	effects.afterRollback(func() { os.Remove(filepath.Join(serviceMediaDir(), file)) })
+	err = tx.QueryRow(
+		`INSERT INTO work_order_photos (work_order_id, client_id, kind, file, content_type, caption, uploaded_by)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7) ON CONFLICT (work_order_id, client_id) DO NOTHING
+		RETURNING id, created_at`,
+		photo.WorkOrderID, photo.ClientID, photo.Kind, file, photo.ContentType, photo.Caption, photo.UploadedBy,
+	).Scan(&photo.ID, &photo.CreatedAt)
+	if err == sql.ErrNoRows {
+		// Aynı client_id ile eşzamanlı yükleme
+		os.Remove(filepath.Join(serviceMediaDir(), file))
+		return getPhotoByClientID(tx, wo.ID, photo.ClientID)
+	}
+	if err != nil {
+		return photo, err
+	}
+	return photo, insertAudit(tx, requestActor(c), "work_order_photo", photo.ID, auditCreate, nil, photo)
+}
+
+func getPhotoByClientID(q queryRower, workOrderID int, clientID string) (models.WorkOrderPhoto, error) {
+	var p models.WorkOrderPhoto
+	err := q.QueryRow(
+		`SELECT id, client_id, work_order_id, kind, content_type, caption, uploaded_by, created_at
+		FROM work_order_photos WHERE work_order_id = $1 AND client_id = $2`,
+		workOrderID, clientID,
+	).Scan(&p.ID, &p.ClientID, &p.WorkOrderID, &p.Kind, &p.ContentType, &p.Caption, &p.UploadedBy, &p.CreatedAt)
+	return p, err
+}
+
+func uploadJobPhotoHandler(c *gin.Context) {
+	wo, ok := technicianJob(c)
+	if !ok {
+		return
+	}
+	if err := jobEditable(wo); err != nil {
+		respondJobError(c, err)
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dosya yüklenemedi"})
+		return
+	}
+	if header.Size > photoMaxSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Fotoğraf en fazla 10 MB olabilir"})
+		return
+	}
+	f, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dosya yüklenemedi"})
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(f, photoMaxSize+1))
+	f.Close()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dosya yüklenemedi"})
+		return
+	}
+
+	input := photoInput{Kind: c.PostForm("kind"), Caption: c.PostForm("caption"), ClientID: c.PostForm("client_id")}
+	photo, err := withTx(func(tx *sql.Tx, effects *commitEffects) (models.WorkOrderPhoto, error) {
+		return addWorkOrderPhoto(c, tx, effects, wo, input, data)
+	})
+	if errors.Is(err, errInvalidPhoto) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, photo)
+}
+
+func servePhoto(c *gin.Context, workOrderID int) {
+	photoID, err := strconv.Atoi(c.Param("photoId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	var file, contentType string
+	err = db.DB.QueryRow("SELECT file, content_type FROM work_order_photos WHERE id = $1 AND work_order_id = $2", photoID, workOrderID).
+		Scan(&file, &contentType)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Fotoğraf bulunamadı"})
+		return
+	}
+	c.Header("Cache-Control", "private, max-age=3600")
+	c.Header("Content-Type", contentType)
+	c.File(filepath.Join(serviceMediaDir(), file))
+}
+
+func getJobPhotoHandler(c *gin.Context) {
+	wo, ok := technicianJob(c)
+	if !ok {
+		return
+	}
+	servePhoto(c, wo.ID)
+}
+
+func getWorkOrderPhotoHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	servePhoto(c, id)
+}
+
+func deleteJobPhotoHandler(c *gin.Context) {
+	wo, ok := technicianJob(c)
+	if !ok {
+		return
+	}
+	if err := jobEditable(wo); err != nil {
+		respondJobError(c, err)
+		return
+	}
+	photoID, err := strconv.Atoi(c.Param("photoId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	removed, err := withTx(func(tx *sql.Tx, effects *commitEffects) (bool, error) {
+		return removeWorkOrderPhoto(c, tx, effects, wo.ID, photoID, "")
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Fotoğraf bulunamadı"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Fotoğraf silindi"})
+}
+
+// removeWorkOrderPhoto fotoğrafı id veya client_id ile siler. Silinen kayıt yoksa false döner.
+// Dosya işlem commit edildikten sonra silinir.
+func removeWorkOrderPhoto(c *gin.Context, tx *sql.Tx, effects *commitEffects, workOrderID, id int, clientID string) (bool, error) {
+	rows, err := tx.Query(
+		`DELETE FROM work_order_photos WHERE work_order_id = $1 AND (id = $2 OR client_id = NULLIF($3, ''))
+		RETURNING id, COALESCE(client_id, ''), work_order_id, kind, content_type, caption, uploaded_by, created_at, file`,
+		workOrderID, id, clientID,
+	)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	var removed []models.WorkOrderPhoto
+	for rows.Next() {
+		var p models.WorkOrderPhoto
+		var file string
+		if err := rows.Scan(&p.ID, &p.ClientID, &p.WorkOrderID, &p.Kind, &p.ContentType, &p.Caption, &p.UploadedBy, &p.CreatedAt, &file); err != nil {
+			return false, err
+		}
+		effects.afterCommit(func() { os.Remove(filepath.Join(serviceMediaDir(), file)) })
+		removed = append(removed, p)
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+	rows.Close()
+	for _, p := range removed {
+		if err := insertAudit(tx, requestActor(c), "work_order_photo", p.ID, auditDelete, p, nil); err != nil {
+			return false, err
+		}
+	}
+	return len(removed) > 0, nil
+}
+
+type partInput struct {
+	ClientID   string  `json:"client_id"`
+	PartID     *int    `json:"part_id"`
+	LocationID *int    `json:"location_id"`
+	Name       string  `json:"name"`
+	Quantity   float64 `json:"quantity"`
+	Unit       string  `json:"unit"`
+	Note       string  `json:"note"`
+}
+
+// addWorkOrderPart parçayı ekler. client_id verilmişse tekrar eklenmez, mevcut kayıt döner.
+// Katalogdan seçilen parça (part_id) aynı işlemde teknisyenin aracından düşülür;
+// başka bir stok yeri verilemez. Ad, birim ve fiyat katalogdan alınır. Düşük
+// stok uyarısı işlem commit edildikten sonra yayınlanır.
+func addWorkOrderPart(c *gin.Context, tx *sql.Tx, effects *commitEffects, wo models.WorkOrder, input partInput) (models.WorkOrderPart, error) {
+	userID := currentUserID(c)
+	part := models.WorkOrderPart{
+		ClientID: strings.TrimSpace(input.ClientID), WorkOrderID: wo.ID, PartID: input.PartID, LocationID: input.LocationID,
+		Name: strings.TrimSpace(input.Name), Quantity: input.Quantity, Unit: strings.TrimSpace(input.Unit),
+		Note: strings.TrimSpace(input.Note), CreatedBy: userID,
+	}
+	if (part.Name == "" && part.PartID == nil) || part.Quantity <= 0 {
+		return part, invalidStock("parça adı veya part_id ile pozitif miktar zorunludur")
+	}
+
+	if part.PartID == nil {
+		part.LocationID = nil
+	} else {
+		err := tx.QueryRow("SELECT name, unit, sale_price FROM parts WHERE id = $1", *part.PartID).Scan(&part.Name, &part.Unit, &part.UnitPrice)
+		if err == sql.ErrNoRows {
+			return part, invalidStock("parça bulunamadı")
+		}
+		if err != nil {
+			return part, err
+		}
+		van, err := technicianVan(tx, userID)
+		if err == sql.ErrNoRows {
+			return part, invalidStock("araç stoku tanımlı değil")
+		}
+		if err != nil {
+			return part, err
+		}
+		if part.LocationID != nil && *part.LocationID != van {
+			return part, invalidStock("parça yalnızca kendi aracınızın stokundan düşülebilir")
+		}
+		part.LocationID = &van
+	}
+	if part.Unit == "" {
+		part.Unit = "adet"
+	}
+
+	err := tx.QueryRow(
+		`INSERT INTO work_order_parts (work_order_id, client_id, part_id, location_id, name, quantity, unit, unit_price, note, created_by)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8, $9, $10) ON CONFLICT (work_order_id, client_id) DO NOTHING
+		RETURNING id, created_at`,
+		part.WorkOrderID, part.ClientID, part.PartID, part.LocationID, part.Name, part.Quantity, part.Unit, part.UnitPrice, part.Note, part.CreatedBy,
+	).Scan(&part.ID, &part.CreatedAt)
+	if err == sql.ErrNoRows {
+		// Tekrar gönderim: stok ilk eklemede düşülmüştür
+		part, err = scanWorkOrderPart(tx.QueryRow(
+			"SELECT "+workOrderPartColumns+" FROM work_order_parts WHERE work_order_id = $1 AND client_id = $2", wo.ID, part.ClientID,
+		))
+		return part, err
+	}
+	if err != nil {
+		return part, err
+	}
+
+	if part.PartID != nil {
+		alert, err := recordStockMovement(tx, &models.StockMovement{
+			PartID: *part.PartID, Kind: movementUse, Quantity: part.Quantity, FromLocationID: part.LocationID,
+			WorkOrderID: &wo.ID, Note: part.Note, CreatedBy: userID,
+		})
+		if err != nil {
+			return part, err
+		}
+		effects.afterCommit(func() { publishLowStock(c, alert) })
+	}
+	return part, insertAudit(tx, requestActor(c), "work_order_part", part.ID, auditCreate, nil, part)
+}
+
+// removeWorkOrderPart parçayı iş emrinden siler; stoktan düşülmüşse aynı yere
+// iade hareketi yazılır. Silinen kayıt yoksa false döner.
+func removeWorkOrderPart(c *gin.Context, tx *sql.Tx, workOrderID, id int, clientID string) (bool, error) {
+	part, err := scanWorkOrderPart(tx.QueryRow(
+		`DELETE FROM work_order_parts WHERE work_order_id = $1 AND (id = $2 OR client_id = NULLIF($3, ''))
+		RETURNING `+workOrderPartColumns,
+		workOrderID, id, clientID,
+	))
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if part.PartID != nil && part.LocationID != nil {
+		_, err := recordStockMovement(tx, &models.StockMovement{
+			PartID: *part.PartID, Kind: movementReturn, Quantity: part.Quantity, ToLocationID: part.LocationID,
+			WorkOrderID: &workOrderID, Note: "İş emrinden çıkarıldı", CreatedBy: currentUserID(c),
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+	if err := insertAudit(tx, requestActor(c), "work_order_part", part.ID, auditDelete, part, nil); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func addJobPartHandler(c *gin.Context) {
+	wo, ok := technicianJob(c)
+	if !ok {
+		return
+	}
+	if err := jobEditable(wo); err != nil {
+		respondJobError(c, err)
+		return
+	}
+	var input partInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	part, err := withTx(func(tx *sql.Tx, effects *commitEffects) (models.WorkOrderPart, error) {
+		return addWorkOrderPart(c, tx, effects, wo, input)
+	})
+	if err != nil {
+		respondStockError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, part)
+}
+
+func deleteJobPartHandler(c *gin.Context) {
+	wo, ok := technicianJob(c)
+	if !ok {
+		return
+	}
+	if err := jobEditable(wo); err != nil {
+		respondJobError(c, err)
+		return
+	}
+	partID, err := strconv.Atoi(c.Param("partId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	removed, err := withTx(func(tx *sql.Tx, effects *commitEffects) (bool, error) {
+		return removeWorkOrderPart(c, tx, wo.ID, partID, "")
+	})
+	if err != nil {
+		respondStockError(c, err)
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Parça bulunamadı"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Parça silindi"})
+}
+
+func addChecklistItemHandler(c *gin.Context) {
+	wo, ok := technicianJob(c)
+	if !ok {
+		return
+	}
+	if err := jobEditable(wo); err != nil {
+		respondJobError(c, err)
+		return
+	}
+	var input struct {
+		Label string `json:"label"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || strings.TrimSpace(input.Label) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Madde metni zorunludur"})
+		return
+	}
+
+	item := models.ChecklistItem{WorkOrderID: wo.ID, Label: strings.TrimSpace(input.Label)}
+	err := db.DB.QueryRow(
+		`INSERT INTO work_order_checklist (work_order_id, position, label)
+		VALUES ($1, (SELECT COALESCE(MAX(position), 0) + 1 FROM work_order_checklist WHERE work_order_id = $1), $2)
+		RETURNING id, position`,
+		item.WorkOrderID, item.Label,
+	).Scan(&item.ID, &item.Position)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(c, "work_order_checklist", item.ID, auditCreate, nil, item)
+	c.JSON(http.StatusCreated, item)
+}
+
+type checklistInput struct {
+	Done       *bool      `json:"done"`
+	Note       *string    `json:"note"`
+	RecordedAt *time.Time `json:"recorded_at"`
+}
+
+// updateChecklistItem maddeyi günceller. Madde daha yeni bir zamanla
+// güncellenmişse değişiklik uygulanmaz ve sql.ErrNoRows döner (son yazan kazanır).
+func updateChecklistItem(c *gin.Context, tx *sql.Tx, wo models.WorkOrder, itemID int, input checklistInput) (models.ChecklistItem, error) {
+	var item models.ChecklistItem
+	var doneBy sql.NullInt64
+	var doneAt sql.NullTime
+	before, err := getChecklistItem(wo.ID, itemID)
+	if err != nil {
+		return item, err
+	}
+	at := checkpointInput{RecordedAt: input.RecordedAt}.at()
+	err = tx.QueryRow(
+		`UPDATE work_order_checklist SET
+			done = COALESCE($1, done),
+			note = COALESCE($2, note),
+			done_by = CASE WHEN $1 IS NULL THEN done_by WHEN $1 THEN $3 ELSE NULL END,
+			done_at = CASE WHEN $1 IS NULL THEN done_at WHEN $1 THEN $4 ELSE NULL END,
+			updated_at = $4
+		WHERE id = $5 AND work_order_id = $6 AND (updated_at IS NULL OR updated_at <= $4)
+		RETURNING id, work_order_id, position, label, done, note, done_by, done_at`,
+		input.Done, input.Note, currentUserID(c), at, itemID, wo.ID,
+	).Scan(&item.ID, &item.WorkOrderID, &item.Position, &item.Label, &item.Done, &item.Note, &doneBy, &doneAt)
+	if err != nil {
+		return item, err
+	}
+	if doneBy.Valid {
+		v := int(doneBy.Int64)
+		item.DoneBy = &v
+	}
+	if doneAt.Valid {
+		item.DoneAt = &doneAt.Time
+	}
+	return item, insertAudit(tx, requestActor(c), "work_order_checklist", item.ID, auditUpdate, before, item)
+}
+
+func getChecklistItem(workOrderID, itemID int) (models.ChecklistItem, error) {
+	items, err := getChecklist(workOrderID)
+	if err != nil {
+		return models.ChecklistItem{}, err
+	}
+	for _, item := range items {
+		if item.ID == itemID {
+			return item, nil
+		}
+	}
+	return models.ChecklistItem{}, sql.ErrNoRows
+}
+
+func updateChecklistItemHandler(c *gin.Context) {
+	wo, ok := technicianJob(c)
+	if !ok {
+		return
+	}
+	if err := jobEditable(wo); err != nil {
+		respondJobError(c, err)
+		return
+	}
+	itemID, err := strconv.Atoi(c.Param("itemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	var input checklistInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := withTx(func(tx *sql.Tx, effects *commitEffects) (models.ChecklistItem, error) {
+		return updateChecklistItem(c, tx, wo, itemID, input)
+	})
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Madde bulunamadı"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+var errInvalidSignature = errors.New("geçersiz imza")
+
+type signatureInput struct {
+	// PNG, base64 veya data:image/png;base64,... biçiminde (canvas.toDataURL)
+	Image      string     `json:"image"`
+	SignerName string     `json:"signer_name"`
+	RecordedAt *time.Time `json:"recorded_at"`
+}
+
+// saveSignature müşteri imzasını kaydeder; önceki imza değiştirilir. Önceki
+// dosya işlem commit edildikten sonra, yeni dosya işlem geri alınırsa silinir.
+func saveSignature(c *gin.Context, tx *sql.Tx, effects *commitEffects, wo models.WorkOrder, input signatureInput) (models.WorkOrder, error) {
+	encoded := input.Image
+	if i := strings.Index(encoded, ","); strings.HasPrefix(encoded, "data:") && i > 0 {
+		encoded = encoded[i+1:]
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(data) > signatureMaxSize {
+		return wo, fmt.Errorf("%w: en fazla 1 MB, base64 PNG olmalı", errInvalidSignature)
+	}
+	if _, err := png.DecodeConfig(bytes.NewReader(data)); err != nil {
+		return wo, fmt.Errorf("%w: PNG olmalı", errInvalidSignature)
+	}
+	signer := strings.TrimSpace(input.SignerName)
+	if signer == "" {
+		return wo, fmt.Errorf("%w: imzalayan kişinin adı zorunludur", errInvalidSignature)
+	}
+
+	file, err := saveServiceMedia(wo.ID, "signature", ".png", data)
+	if err != nil {
+		return wo, err
+	}
+	effects.afterRollback(func() { os.Remove(filepath.Join(serviceMediaDir(), file)) })
+	var previous string
+	signedAt := checkpointInput{RecordedAt: input.RecordedAt}.at()
+	err = tx.QueryRow(
+		`UPDATE work_orders w SET signature_file = $1, signer_name = $2, signed_at = $3, updated_at = NOW()
+		FROM (SELECT signature_file FROM work_orders WHERE id = $4) old
+		WHERE w.id = $4 RETURNING old.signature_file`,
+		file, signer, signedAt, wo.ID,
+	).Scan(&previous)
+	if err != nil {
+		return wo, err
+	}
+	if previous != "" {
+		effects.afterCommit(func() { os.Remove(filepath.Join(serviceMediaDir(), previous)) })
+	}
+	before := wo
+	wo.SignerName, wo.SignedAt = signer, &signedAt
+	return wo, insertAudit(tx, requestActor(c), "work_order", wo.ID, auditUpdate, before, wo)
+}
+
+func saveSignatureHandler(c *gin.Context) {
+	wo, ok := technicianJob(c)
+	if !ok {
+		return
+	}
+	if err := jobEditable(wo); err != nil {
+		respondJobError(c, err)
+		return
+	}
+	var input signatureInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	wo, err := withTx(func(tx *sql.Tx, effects *commitEffects) (models.WorkOrder, error) {
+		return saveSignature(c, tx, effects, wo, input)
+	})
+	if errors.Is(err, errInvalidSignature) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"signer_name": wo.SignerName, "signed_at": wo.SignedAt})
+}
+
+func userFullName(userID int) string {
+	var name, username string
+	if err := db.DB.QueryRow("SELECT full_name, username FROM users WHERE id = $1", userID).Scan(&name, &username); err != nil {
+		return ""
+	}
+	if name == "" {
+		return username
+	}
+	return name
+}
+
+var checkpointLabels = map[string]string{
+	checkpointTravel: "Yola çıkış",
+	checkpointArrive: "Varış",
+	checkpointFinish: "Bitiş",
+}
+
+// serviceReportPDF sahada toplanan verilerden servis raporu üretir
+func serviceReportPDF(d jobDetail) []byte {
+	doc := pdf.New()
+	doc.Heading(fmt.Sprintf("Servis Raporu #%d", d.ID))
+	if contact, err := getContact(); err == nil {
+		doc.Text(strings.TrimSpace(contact.Title + "  " + contact.Phone))
+	}
+
+	doc.Section("Müşteri")
+	if d.Customer != nil {
+		doc.Text(d.Customer.Name)
+		if d.Customer.Phone != "" {
+			doc.Text("Telefon: " + d.Customer.Phone)
+		}
+	}
+	if d.Address != "" {
+		doc.Text("Adres: " + d.Address)
+	}
+
+	doc.Section("İş")
+	doc.Text(d.Title)
+	if d.Description != "" {
+		doc.Text(d.Description)
+	}
+	if appointment := formatAppointment(d.ScheduledStart, d.ScheduledEnd); appointment != "" {
+		doc.Text("Randevu: " + appointment)
+	}
+	if d.TechnicianID != nil {
+		doc.Text("Teknisyen: " + userFullName(*d.TechnicianID))
+	}
+
+	if len(d.Checkpoints) > 0 {
+		doc.Section("Zaman Çizelgesi")
+		for _, cp := range d.Checkpoints {
+			text := checkpointLabels[cp.Kind] + ": " + formatLocalTime(cp.RecordedAt)
+			if cp.Latitude != nil {
+				text += fmt.Sprintf(" (%.5f, %.5f)", *cp.Latitude, *cp.Longitude)
+			}
+			doc.Text(text)
+		}
+	}
+
+	if len(d.Checklist) > 0 {
+		doc.Section("Kontrol Listesi")
+		for _, item := range d.Checklist {
+			mark := "[ ]"
+			if item.Done {
+				mark = "[x]"
+			}
+			text := mark + " " + item.Label
+			if item.Note != "" {
+				text += " - " + item.Note
+			}
+			doc.Text(text)
+		}
+	}
+
+	if len(d.Parts) > 0 {
+		doc.Section("Kullanılan Parçalar")
+		for _, p := range d.Parts {
+			text := fmt.Sprintf("%s: %s %s", p.Name, strconv.FormatFloat(p.Quantity, 'f', -1, 64), p.Unit)
+			if p.Note != "" {
+				text += " (" + p.Note + ")"
+			}
+			doc.Text(text)
+		}
+	}
+
+	if len(d.Photos) > 0 {
+		counts := map[string]int{}
+		for _, p := range d.Photos {
+			counts[p.Kind]++
+		}
+		doc.Section("Fotoğraflar")
+		doc.Text(fmt.Sprintf("İşlem öncesi: %d, işlem sonrası: %d", counts["before"], counts["after"]))
+		for _, p := range d.Photos {
+			if p.Caption != "" {
+				doc.Text("- " + p.Caption)
+			}
+		}
+	}
+
+	if d.TechnicianNotes != "" {
+		doc.Section("Teknisyen Notu")
+		doc.Text(d.TechnicianNotes)
+	}
+
+	doc.Section("Müşteri Onayı")
+	if d.SignedAt == nil {
+		doc.Text("Müşteri imzası alınmadı.")
+		return doc.Bytes()
+	}
+	if data := signatureImage(d.ID); data != nil {
+		if img, err := png.Decode(bytes.NewReader(data)); err == nil {
+			doc.Image(img, 180)
+		}
+	}
+	doc.Text(fmt.Sprintf("İmzalayan: %s, %s", d.SignerName, formatLocalTime(*d.SignedAt)))
+	return doc.Bytes()
+}
+
+// signatureImage iş emrindeki imza görüntüsünü okur; imza yoksa nil döner
+func signatureImage(workOrderID int) []byte {
+	var file string
+	if err := db.DB.QueryRow("SELECT signature_file FROM work_orders WHERE id = $1", workOrderID).Scan(&file); err != nil || file == "" {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(serviceMediaDir(), file))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func sendServiceReport(c *gin.Context, wo models.WorkOrder) {
+	detail, err := loadJobDetail(wo)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="servis-raporu-%d.pdf"`, wo.ID))
+	c.Data(http.StatusOK, "application/pdf", serviceReportPDF(detail))
+}
+
+func getJobReportHandler(c *gin.Context) {
+	wo, ok := technicianJob(c)
+	if !ok {
+		return
+	}
+	sendServiceReport(c, wo)
+}
+
+func getWorkOrderReportHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	wo, err := getWorkOrder(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "İş emri bulunamadı"})
+		return
+	}
+	sendServiceReport(c, wo)
+}
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"kozan/db"
+	"kozan/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Müşteri takip bağlantısı: her iş emri için tahmin edilemez, süreli bir token
+// üretilir. Müşteri /api/track/:token ile durumu, randevu aralığını, teknisyenin
+// adını, yoldaysa tahmini varış süresini ve iş bitince faturayı görür.
+const invoiceMaxSize = 10 << 20
+
+// errWorkOrderState iş emrinin mevcut durumunda yapılamayan geçişler için (409)
+var errWorkOrderState = errors.New("İş emrinin durumu bu işleme uygun değil")
+
+var trackingStatusLabels = map[string]string{
+	"new":         "Talebiniz alındı",
+	"scheduled":   "Randevu planlandı",
+	"on_the_way":  "Teknisyen yolda",
+	"in_progress": "Servis devam ediyor",
+	"completed":   "Tamamlandı",
+	"cancelled":   "İptal edildi",
+}
+
+// trackingLinkTTL bağlantının geçerlilik süresi (TRACKING_LINK_DAYS, varsayılan 30).
+// İş tamamlandığında süre faturaya erişilebilsin diye tekrar uzatılır.
+func trackingLinkTTL() time.Duration {
+	days := 30
+	if v, err := strconv.Atoi(os.Getenv("TRACKING_LINK_DAYS")); err == nil && v > 0 {
+		days = v
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// newTrackingToken 192 bit rastgele, URL'de kullanılabilen token
+func newTrackingToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func trackingURL(token string) string {
+	return siteURL() + "/takip/" + token
+}
+
+// issueTrackingToken iş emrine yeni takip token'ı atar; önceki bağlantı geçersiz olur
+func issueTrackingToken(q queryRower, workOrderID int) (string, time.Time, error) {
+	token, err := newTrackingToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	var expiresAt time.Time
+	err = q.QueryRow(
+		"UPDATE work_orders SET tracking_token = $1, tracking_expires_at = $2 WHERE id = $3 RETURNING tracking_expires_at",
+		token, time.Now().Add(trackingLinkTTL()), workOrderID,
+	).Scan(&expiresAt)
+	return token, expiresAt, err
+}
+
+// trackingTokenActive token verilmiş ve süresi dolmamışsa true döner
+func trackingTokenActive(token sql.NullString, expiresAt sql.NullTime, now time.Time) bool {
+	return token.Valid && token.String != "" && expiresAt.Valid && expiresAt.Time.After(now)
+}
+
+// workOrderTrackingURL geçerli bağlantıyı döndürür, yoksa veya süresi dolmuşsa yenisini üretir
+func workOrderTrackingURL(workOrderID int) (string, error) {
+	var token sql.NullString
+	var expiresAt sql.NullTime
+	err := db.DB.QueryRow("SELECT tracking_token, tracking_expires_at FROM work_orders WHERE id = $1", workOrderID).
+		Scan(&token, &expiresAt)
+	if err != nil {
+		return "", err
+	}
+	if trackingTokenActive(token, expiresAt, time.Now()) {
+		return trackingURL(token.String), nil
+	}
+	newToken, _, err := issueTrackingToken(db.DB, workOrderID)
+	if err != nil {
+		return "", err
+	}
+	return trackingURL(newToken), nil
+}
+
+// technicianFirstName müşteriye teknisyenin sadece ilk adı gösterilir
+func technicianFirstName(userID int) string {
+	var fullName string
+	if err := db.DB.QueryRow("SELECT full_name FROM users WHERE id = $1", userID).Scan(&fullName); err != nil {
+		return ""
+	}
+	if fields := strings.Fields(fullName); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+func formatAppointment(start, end *time.Time) string {
+	if start == nil {
+		return ""
+	}
+	s := formatLocalTime(*start)
+	if end != nil {
+		s += " - " + end.In(businessLocation).Format("15:04")
+	}
+	return s
+}
+
+// workOrderVars bildirim şablonları için iş emri değişkenleri
+func workOrderVars(wo models.WorkOrder) map[string]string {
+	vars := map[string]string{
+		"work_order_id":    strconv.Itoa(wo.ID),
+		"work_order_title": wo.Title,
+		"address":          wo.Address,
+		"appointment":      formatAppointment(wo.ScheduledStart, wo.ScheduledEnd),
+	}
+	if wo.TechnicianID != nil {
+		vars["technician_name"] = technicianFirstName(*wo.TechnicianID)
+	}
+	if wo.ETA != nil {
+		vars["eta"] = wo.ETA.In(businessLocation).Format("15:04")
+	}
+	if url, err := workOrderTrackingURL(wo.ID); err == nil {
+		vars["tracking_url"] = url
+	} else {
+		log.Printf("tracking link for work order %d: %v", wo.ID, err)
+	}
+	return vars
+}
+
+func getWorkOrderByTrackingToken(token string) (models.WorkOrder, error) {
+	return scanWorkOrder(db.DB.QueryRow(
+		"SELECT "+workOrderColumns+" FROM work_orders WHERE tracking_token = $1 AND tracking_expires_at > NOW()", token,
+	))
+}
+
+// getTrackingHandler müşteriye açık takip sayfası verisi
+func getTrackingHandler(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Robots-Tag", "noindex")
+
+	wo, err := getWorkOrderByTrackingToken(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Takip bağlantısı geçersiz veya süresi dolmuş"})
+		return
+	}
+
+	resp := gin.H{
+		"work_order_id": wo.ID,
+		"title":         wo.Title,
+		"status":        wo.Status,
+		"status_label":  trackingStatusLabels[wo.Status],
+		"updated_at":    wo.UpdatedAt,
+	}
+	if wo.ScheduledStart != nil {
+		resp["appointment"] = gin.H{"start": wo.ScheduledStart, "end": wo.ScheduledEnd}
+	}
+	if wo.TechnicianID != nil {
+		if name := technicianFirstName(*wo.TechnicianID); name != "" {
+			resp["technician"] = gin.H{"first_name": name}
+		}
+	}
+	if wo.Status == "on_the_way" && wo.ETA != nil {
+		minutes := int(math.Ceil(time.Until(*wo.ETA).Minutes()))
+		if minutes < 0 {
+			minutes = 0
+		}
+		resp["on_the_way"] = gin.H{"started_at": wo.TravelStartedAt, "eta": wo.ETA, "eta_minutes": minutes}
+	}
+	if wo.Status == "completed" && wo.HasInvoice {
+		resp["invoice_url"] = "/api/track/" + c.Param("token") + "/invoice"
+	}
+	if contact, err := getContact(); err == nil {
+		resp["company_phone"] = contact.Phone
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// getTrackingInvoiceHandler iş tamamlandıysa faturayı indirir
+func getTrackingInvoiceHandler(c *gin.Context) {
+	wo, err := getWorkOrderByTrackingToken(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Takip bağlantısı geçersiz veya süresi dolmuş"})
+		return
+	}
+	if wo.Status != "completed" || !wo.HasInvoice {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Fatura henüz hazır değil"})
+		return
+	}
+	serveInvoice(c, wo.ID)
+}
+
+// Faturalar /uploads altında değil, herkese açık olmayan bir dizinde tutulur
+func invoiceDir() string {
+	if dir := os.Getenv("INVOICE_DIR"); dir != "" {
+		return dir
+	}
+	return "invoices"
+}
+
+func serveInvoice(c *gin.Context, workOrderID int) {
+	var file string
+	if err := db.DB.QueryRow("SELECT invoice_file FROM work_orders WHERE id = $1", workOrderID).Scan(&file); err != nil || file == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Fatura bulunamadı"})
+		return
+	}
+	c.Header("Cache-Control", "private, no-store")
+	c.FileAttachment(filepath.Join(invoiceDir(), file), fmt.Sprintf("fatura-%d.pdf", workOrderID))
+}
+
+// uploadInvoiceHandler iş emrine PDF fatura ekler (önceki fatura değiştirilir)
+func uploadInvoiceHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	var previous string
+	if err := db.DB.QueryRow("SELECT invoice_file FROM work_orders WHERE id = $1", id).Scan(&previous); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "İş emri bulunamadı"})
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dosya yüklenemedi"})
+		return
+	}
+	if header.Size > invoiceMaxSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Fatura en fazla 10 MB olabilir"})
+		return
+	}
+	f, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dosya yüklenemedi"})
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(f, invoiceMaxSize+1))
+	f.Close()
+	if err != nil || !bytes.HasPrefix(data, []byte("%PDF-")) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Fatura PDF olmalı"})
+		return
+	}
+
+	if err := os.MkdirAll(invoiceDir(), 0750); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	name := fmt.Sprintf("%d_%d.pdf", id, time.Now().UnixNano())
+	if err := os.WriteFile(filepath.Join(invoiceDir(), name), data, 0640); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Dosya kaydedilemedi"})
+		return
+	}
+	if _, err := db.DB.Exec("UPDATE work_orders SET invoice_file = $1, updated_at = NOW() WHERE id = $2", name, id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if previous != "" {
+		os.Remove(filepath.Join(invoiceDir(), previous))
+	}
+
+	recordAudit(c, "work_order", id, auditUpdate, gin.H{"invoice_file": previous}, gin.H{"invoice_file": name})
+	c.JSON(http.StatusOK, gin.H{"message": "Fatura yüklendi"})
+}
+
+func getInvoiceHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	serveInvoice(c, id)
+}
+
+// rotateTrackingLinkHandler yeni takip bağlantısı üretir (eskisi geçersiz olur)
+func rotateTrackingLinkHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	token, expiresAt, err := issueTrackingToken(db.DB, id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "İş emri bulunamadı"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, "work_order", id, auditUpdate, nil, gin.H{"tracking_link": "rotated"})
+	c.JSON(http.StatusOK, gin.H{"url": trackingURL(token), "token": token, "expires_at": expiresAt})
+}
+
+// estimateETA teknisyenin konumundan iş adresine tahmini varış zamanı.
+// İş emrinin koordinatı yoksa eta_minutes kullanılır.
+func estimateETA(wo models.WorkOrder, input checkpointInput) *time.Time {
+	var eta time.Time
+	switch {
+	case wo.Latitude != nil && wo.Longitude != nil && input.hasLocation():
+		eta = input.at().Add(travelTime(input.Latitude, input.Longitude, *wo.Latitude, *wo.Longitude))
+	case input.ETAMinutes > 0:
+		eta = input.at().Add(time.Duration(input.ETAMinutes) * time.Minute)
+	default:
+		return nil
+	}
+	eta = eta.Truncate(time.Minute)
+	return &eta
+}
+
+// startTravel iş emrini "yolda" durumuna alır, tahmini varışı hesaplar ve
+// müşteriye "teknisyen yolda" bildirimi gönderir
+func startTravel(c *gin.Context, tx *sql.Tx, effects *commitEffects, wo models.WorkOrder, input checkpointInput) (models.WorkOrder, error) {
+	before := wo
+	at := input.resolveTime()
+	wo.ETA = estimateETA(wo, input)
+	err := tx.QueryRow(
+		`UPDATE work_orders SET status = 'on_the_way', travel_started_at = $1, eta_at = $2, updated_at = NOW()
+		WHERE id = $3 AND status IN ('new', 'scheduled') RETURNING status, travel_started_at, updated_at`,
+		at, wo.ETA, wo.ID,
+	).Scan(&wo.Status, &wo.TravelStartedAt, &wo.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return wo, fmt.Errorf("%w: %s", errWorkOrderState, wo.Status)
+	}
+	if err != nil {
+		return wo, err
+	}
+	if err := recordCheckpoint(tx, wo.ID, currentUserID(c), checkpointTravel, input); err != nil {
+		return wo, err
+	}
+	return wo, workOrderChanged(c, tx, effects, before, wo)
+}
+
+// updateTravelLocation yoldaki teknisyenin konumuyla tahmini varışı günceller.
+// İş bu arada varmış veya iptal edilmişse tahmin yazılmaz (409). Konum audit
+// kaydına yazılmaz, sadece tahmini varışın değişimi kaydedilir.
+func updateTravelLocation(c *gin.Context, tx *sql.Tx, wo models.WorkOrder, input checkpointInput) (models.WorkOrder, error) {
+	if wo.Status != "on_the_way" {
+		return wo, fmt.Errorf("%w: %s", errWorkOrderState, wo.Status)
+	}
+	eta := estimateETA(wo, input)
+	if eta == nil {
+		return wo, nil
+	}
+	previous := wo.ETA
+	err := tx.QueryRow(
+		"UPDATE work_orders SET eta_at = $1 WHERE id = $2 AND status = 'on_the_way' RETURNING eta_at", eta, wo.ID,
+	).Scan(&wo.ETA)
+	if err == sql.ErrNoRows {
+		return wo, errWorkOrderState
+	}
+	if err != nil {
+		return wo, err
+	}
+	if err := insertAudit(tx, requestActor(c), "work_order", wo.ID, auditUpdate,
+		gin.H{"eta_at": previous}, gin.H{"eta_at": wo.ETA}); err != nil {
+		return wo, err
+	}
+	return wo, nil
+}
+
+func startTravelHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	var input checkpointInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	wo, err := getWorkOrder(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "İş emri bulunamadı"})
+		return
+	}
+
+	wo, err = runTransition(c, startTravel, wo, input)
+	if errors.Is(err, errWorkOrderState) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, wo)
+}
+
+func updateTravelLocationHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	var input checkpointInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	wo, err := getWorkOrder(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "İş emri bulunamadı"})
+		return
+	}
+
+	wo, err = runTransition(c, updateJobLocation, wo, input)
+	if errors.Is(err, errWorkOrderState) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"eta": wo.ETA})
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"kozan/models"
+)
+
+func TestTrackingLinkTTL(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 30 * 24 * time.Hour},
+		{"7", 7 * 24 * time.Hour},
+		{"0", 30 * 24 * time.Hour},
+		{"-3", 30 * 24 * time.Hour},
+		{"bir hafta", 30 * 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("TRACKING_LINK_DAYS", tt.value)
+		if got := trackingLinkTTL(); got != tt.want {
+			t.Errorf("trackingLinkTTL(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestTrackingTokenActive(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	token := sql.NullString{String: "abc", Valid: true}
+	expires := func(d time.Duration) sql.NullTime { return sql.NullTime{Time: now.Add(d), Valid: true} }
+
+	tests := []struct {
+		name      string
+		token     sql.NullString
+		expiresAt sql.NullTime
+		want      bool
+	}{
+		{"geçerli", token, expires(time.Hour), true},
+		{"süresi dolmuş", token, expires(-time.Second), false},
+		{"tam bitiş anında", token, expires(0), false},
+		{"bitiş zamanı yok", token, sql.NullTime{}, false},
+		{"token yok", sql.NullString{}, expires(time.Hour), false},
+		{"boş token", sql.NullString{Valid: true}, expires(time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		if got := trackingTokenActive(tt.token, tt.expiresAt, now); got != tt.want {
+			t.Errorf("%s: trackingTokenActive = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNewTrackingToken(t *testing.T) {
+	urlSafe := regexp.MustCompile(`^[A-Za-z0-9_-]{32}$`)
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		token, err := newTrackingToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !urlSafe.MatchString(token) {
+			t.Fatalf("newTrackingToken() = %q, want 32 URL-safe characters", token)
+		}
+		if seen[token] {
+			t.Fatalf("duplicate token %q", token)
+		}
+		seen[token] = true
+	}
+}
+
+func TestEstimateETA(t *testing.T) {
+	// Kuş uçuşu mesafe yol mesafesi sayılır, hız 60 km/s: 0.5 derece enlem ≈ 55.6 km ≈ 55 dk 36 sn
+	t.Setenv("ROUTE_ROAD_FACTOR", "1")
+	t.Setenv("ROUTE_AVG_SPEED_KMH", "60")
+	at := time.Now().Add(-10 * time.Minute).Truncate(time.Minute)
+	lat, lng := 37.0, 35.8
+	located := models.WorkOrder{Latitude: &lat, Longitude: &lng}
+
+	tests := []struct {
+		name  string
+		wo    models.WorkOrder
+		input checkpointInput
+		want  *time.Time
+	}{
+		{"konumdan", located, checkpointInput{Latitude: 37.5, Longitude: 35.8, RecordedAt: &at}, ptrTime(at.Add(55 * time.Minute))},
+		{"konum varken eta_minutes yok sayılır", located, checkpointInput{Latitude: 37.5, Longitude: 35.8, ETAMinutes: 5, RecordedAt: &at}, ptrTime(at.Add(55 * time.Minute))},
+		{"iş emrinin koordinatı yok", models.WorkOrder{}, checkpointInput{Latitude: 37.5, Longitude: 35.8, ETAMinutes: 20, RecordedAt: &at}, ptrTime(at.Add(20 * time.Minute))},
+		{"teknisyen konumu yok", located, checkpointInput{ETAMinutes: 15, RecordedAt: &at}, ptrTime(at.Add(15 * time.Minute))},
+		{"tahmin için veri yok", located, checkpointInput{RecordedAt: &at}, nil},
+		{"koordinatı olmayan iş, süre yok", models.WorkOrder{}, checkpointInput{Latitude: 37.5, Longitude: 35.8, RecordedAt: &at}, nil},
+	}
+
+	for _, tt := range tests {
+		if got := estimateETA(tt.wo, tt.input); !equalTimePtr(got, tt.want) {
+			t.Errorf("%s: estimateETA = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,628 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"kozan/db"
+	"kozan/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// Giden webhook'lar: olaylar abonelere JSON olarak gönderilir ve
+// X-Kozan-Signature başlığında zaman damgasıyla birlikte HMAC-SHA256 imzası
+// bulunur. Gönderim iş kuyruğu üzerinden yapılır ve başarısızlıkta tekrar denenir.
+const (
+	eventProductCreated      = "product.created"
+	eventProductUpdated      = "product.updated"
+	eventProductDeleted      = "product.deleted"
+	eventProductPriceChanged = "product.price_changed"
+	eventServiceCreated      = "service.created"
+	eventServiceUpdated      = "service.updated"
+	eventServiceDeleted      = "service.deleted"
+	eventLeadCreated         = "lead.created"
+	eventWorkOrderCreated    = "work_order.created"
+	eventWorkOrderUpdated    = "work_order.updated"
+	eventWorkOrderCompleted  = "work_order.completed"
+	eventPartLowStock        = "part.low_stock"
+	eventPing                = "ping"
+)
+
+var webhookEvents = []string{
+	eventProductCreated, eventProductUpdated, eventProductDeleted, eventProductPriceChanged,
+	eventServiceCreated, eventServiceUpdated, eventServiceDeleted,
+	eventLeadCreated,
+	eventWorkOrderCreated, eventWorkOrderUpdated, eventWorkOrderCompleted,
+	eventPartLowStock,
+}
+
+const (
+	webhookMaxAttempts = 8
+	// Art arda bu kadar teslimat tüm denemelerden sonra başarısız olursa webhook kapatılır
+	webhookDisableAfter = 10
+	webhookTimeout      = 10 * time.Second
+	webhookResponseMax  = 2048
+)
+
+// webhookEvent aboneye gönderilen zarf
+type webhookEvent struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+func isWebhookEvent(event string) bool {
+	if event == "*" {
+		return true
+	}
+	for _, e := range webhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// signWebhook Stripe'a benzer imza üretir: t=<unix>,v1=hex(HMAC(secret, "<unix>.<body>"))
+func signWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// emitEvent olayı abone olan aktif webhook'lar için kuyruğa ekler.
+// Hata isteği bozmaz, sadece loglanır.
+func emitEvent(eventType string, data interface{}) {
+	if err := enqueueEvent(eventType, data, 0); err != nil {
+		log.Printf("webhooks: %s could not be queued: %v", eventType, err)
+	}
+}
+
+// enqueueEvent webhookID verilirse olayı sadece o webhook'a gönderir (test için)
+func enqueueEvent(eventType string, data interface{}, webhookID int) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := queueEvent(tx, eventType, data, webhookID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// queueEvent olayın teslimatlarını verilen işlem içinde kuyruğa ekler; olay
+// ancak değişikliğin kendisiyle birlikte commit edilirse gönderilir
+func queueEvent(tx *sql.Tx, eventType string, data interface{}, webhookID int) error {
+	eventID, err := randomHex(12)
+	if err != nil {
+		return err
+	}
+	event := webhookEvent{ID: "evt_" + eventID, Type: eventType, CreatedAt: time.Now().UTC(), Data: data}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	var ids []int
+	if webhookID != 0 {
+		ids = []int{webhookID}
+	} else {
+		ids, err = selectIDs(tx, "SELECT id FROM webhooks WHERE active AND ($1 = ANY(events) OR '*' = ANY(events))", eventType)
+		if err != nil {
+			return err
+		}
+	}
+	for _, id := range ids {
+		if err := insertDelivery(tx, id, event.ID, eventType, payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertDelivery(tx *sql.Tx, webhookID int, eventID, eventType string, payload []byte) error {
+	var deliveryID int64
+	err := tx.QueryRow(
+		"INSERT INTO webhook_deliveries (webhook_id, event_id, event, payload) VALUES ($1, $2, $3, $4) RETURNING id",
+		webhookID, eventID, eventType, string(payload),
+	).Scan(&deliveryID)
+	if err != nil {
+		return err
+	}
+	_, err = enqueueJob(tx, jobWebhookDeliver, webhookDeliveryPayload{DeliveryID: deliveryID}, jobOptions{
+		MaxAttempts: webhookMaxAttempts,
+		UniqueKey:   "webhook-delivery:" + strconv.FormatInt(deliveryID, 10),
+	})
+	return err
+}
+
+type webhookDeliveryPayload struct {
+	DeliveryID int64 `json:"delivery_id"`
+}
+
+var webhookClient = &http.Client{
+	Timeout: webhookTimeout,
+	// Yönlendirmeler takip edilmez; abone doğru adresi vermeli
+	CheckRedirect: func(req *http.Request, via []*http.Request) error { return http.ErrUseLastResponse },
+	// Proxy kullanılmaz ve bağlanılan IP her bağlantıda tekrar kontrol edilir;
+	// böylece oluşturmadan sonra iç ağa çözümlenen (DNS rebinding) adreslere gidilmez
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: webhookTimeout,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				return checkWebhookIP(net.ParseIP(host))
+			},
+		}).DialContext,
+		TLSHandshakeTimeout: webhookTimeout,
+		MaxIdleConnsPerHost: 2,
+	},
+}
+
+// cgnatRange taşıyıcı NAT adresleri (100.64.0.0/10) de iç ağ sayılır
+var cgnatRange = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// checkWebhookIP iç ağ, loopback ve link-local adreslere gönderimi engeller
+func checkWebhookIP(ip net.IP) error {
+	if ip == nil {
+		return fmt.Errorf("Geçersiz IP adresi")
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || cgnatRange.Contains(ip) {
+		return fmt.Errorf("İç ağ adreslerine webhook gönderilemez: %s", ip)
+	}
+	return nil
+}
+
+// deliverWebhookJob tek bir teslimatı gönderir. 2xx dışı yanıtlar hata sayılır
+// ve iş kuyruğu tarafından üstel beklemeyle tekrar denenir.
+func deliverWebhookJob(ctx context.Context, job models.Job, payload webhookDeliveryPayload) error {
+	var webhookID int
+	var event, status, targetURL, secret string
+	var body []byte
+	var active bool
+	err := db.DB.QueryRow(
+		`SELECT d.webhook_id, d.event, d.payload, d.status, w.url, w.secret, w.active
+		FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id WHERE d.id = $1`,
+		payload.DeliveryID,
+	).Scan(&webhookID, &event, &body, &status, &targetURL, &secret, &active)
+	if err == sql.ErrNoRows || (err == nil && status != "pending") {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !active && event != eventPing {
+		db.DB.Exec("UPDATE webhook_deliveries SET status = 'failed', error = 'webhook kapalı' WHERE id = $1", payload.DeliveryID)
+		return nil
+	}
+	// https zorunluluğundan önce oluşturulmuş webhook'lar
+	if !strings.HasPrefix(targetURL, "https://") {
+		db.DB.Exec("UPDATE webhook_deliveries SET status = 'failed', error = 'adres https değil' WHERE id = $1", payload.DeliveryID)
+		return permanentJobError(fmt.Errorf("webhook adresi https değil"))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, targetURL, bytes.NewReader(body))
+	if err != nil {
+		return permanentJobError(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Kozan-Webhooks/1.0")
+	req.Header.Set("X-Kozan-Event", event)
+	req.Header.Set("X-Kozan-Delivery", strconv.FormatInt(payload.DeliveryID, 10))
+	req.Header.Set("X-Kozan-Signature", signWebhook(secret, time.Now().Unix(), body))
+
+	start := time.Now()
+	resp, sendErr := webhookClient.Do(req)
+	duration := time.Since(start).Milliseconds()
+	// Yanıt gövdesi saklanmaz, sadece bağlantı tekrar kullanılabilsin diye okunur
+	var responseStatus int
+	if sendErr == nil {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, webhookResponseMax))
+		resp.Body.Close()
+		responseStatus = resp.StatusCode
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			sendErr = fmt.Errorf("HTTP %d", resp.StatusCode)
+		}
+	}
+
+	if sendErr == nil {
+		_, err = db.DB.Exec(
+			`UPDATE webhook_deliveries SET status = 'success', attempts = $1, response_status = $2,
+				error = '', duration_ms = $3, delivered_at = NOW() WHERE id = $4`,
+			job.Attempts, responseStatus, duration, payload.DeliveryID,
+		)
+		if err == nil {
+			_, err = db.DB.Exec("UPDATE webhooks SET failure_count = 0 WHERE id = $1", webhookID)
+		}
+		return err
+	}
+
+	status = "pending"
+	if job.Attempts >= job.MaxAttempts {
+		status = "failed"
+	}
+	db.DB.Exec(
+		`UPDATE webhook_deliveries SET status = $1, attempts = $2, response_status = $3,
+			error = $4, duration_ms = $5 WHERE id = $6`,
+		status, job.Attempts, responseStatus, sendErr.Error(), duration, payload.DeliveryID,
+	)
+	if status == "failed" {
+		recordWebhookFailure(webhookID)
+	}
+	return sendErr
+}
+
+// webhookDeliveryRetention teslimat kayıtlarının saklanma süresidir
+// (WEBHOOK_DELIVERY_RETENTION_DAYS, varsayılan 30). Gövdeler kişisel veri
+// içerebildiği için süresiz tutulmaz.
+func webhookDeliveryRetention() time.Duration {
+	return time.Duration(jobEnvInt("WEBHOOK_DELIVERY_RETENTION_DAYS", 30)) * 24 * time.Hour
+}
+
+// cleanupWebhookDeliveries süresi dolan ve sonuçlanmış teslimatları siler
+func cleanupWebhookDeliveries(ctx context.Context) error {
+	res, err := db.DB.ExecContext(ctx,
+		"DELETE FROM webhook_deliveries WHERE status <> 'pending' AND created_at < $1",
+		time.Now().Add(-webhookDeliveryRetention()),
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		log.Printf("webhooks: %d old deliveries deleted", n)
+	}
+	return nil
+}
+
+// recordWebhookFailure art arda başarısız teslimatları sayar ve sınırda webhook'u kapatır
+func recordWebhookFailure(webhookID int) {
+	var failures int
+	var active bool
+	err := db.DB.QueryRow(
+		"UPDATE webhooks SET failure_count = failure_count + 1 WHERE id = $1 RETURNING failure_count, active", webhookID,
+	).Scan(&failures, &active)
+	if err != nil || !active || failures < webhookDisableAfter {
+		return
+	}
+
+	reason := fmt.Sprintf("Art arda %d teslimat başarısız oldu", failures)
+	if _, err := db.DB.Exec("UPDATE webhooks SET active = FALSE, disabled_at = NOW(), disabled_reason = $1 WHERE id = $2", reason, webhookID); err != nil {
+		log.Printf("webhooks: could not disable %d: %v", webhookID, err)
+		return
+	}
+	log.Printf("webhooks: %d disabled after %d failed deliveries", webhookID, failures)
+	writeAudit(systemActor("webhooks"), "webhook", webhookID, auditUpdate,
+		gin.H{"active": true}, gin.H{"active": false, "disabled_reason": reason})
+}
+
+const webhookColumns = "id, url, description, events, active, failure_count, disabled_at, disabled_reason, created_by, created_at, updated_at"
+
+func scanWebhook(scanner interface{ Scan(...interface{}) error }) (models.Webhook, error) {
+	var w models.Webhook
+	var disabledAt sql.NullTime
+	err := scanner.Scan(&w.ID, &w.URL, &w.Description, pq.Array(&w.Events), &w.Active, &w.FailureCount,
+		&disabledAt, &w.DisabledReason, &w.CreatedBy, &w.CreatedAt, &w.UpdatedAt)
+	if disabledAt.Valid {
+		w.DisabledAt = &disabledAt.Time
+	}
+	return w, err
+}
+
+func getWebhook(id int) (models.Webhook, error) {
+	return scanWebhook(db.DB.QueryRow("SELECT "+webhookColumns+" FROM webhooks WHERE id = $1", id))
+}
+
+func validateWebhook(w *models.Webhook) error {
+	u, err := url.Parse(strings.TrimSpace(w.URL))
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return fmt.Errorf("Geçersiz adres: https olmalı")
+	}
+	if err := checkWebhookHost(u.Hostname()); err != nil {
+		return err
+	}
+	w.URL = u.String()
+	if len(w.Events) == 0 {
+		return fmt.Errorf("En az bir olay seçilmeli")
+	}
+	for _, e := range w.Events {
+		if !isWebhookEvent(e) {
+			return fmt.Errorf("Geçersiz olay: %s", e)
+		}
+	}
+	return nil
+}
+
+// checkWebhookHost adresin çözümlendiği tüm IP'lerin dış ağda olduğunu doğrular
+func checkWebhookHost(host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		return checkWebhookIP(ip)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("Adres çözümlenemedi: %s", host)
+	}
+	for _, addr := range addrs {
+		if err := checkWebhookIP(addr.IP); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Webhook işlemleri
+func getWebhooksHandler(c *gin.Context) {
+	rows, err := db.DB.Query("SELECT " + webhookColumns + " FROM webhooks ORDER BY id")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		webhooks = append(webhooks, w)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": webhooks, "events": webhookEvents})
+}
+
+// createWebhookHandler imza anahtarı sadece oluşturma yanıtında gösterilir
+func createWebhookHandler(c *gin.Context) {
+	var w models.Webhook
+	if err := c.BindJSON(&w); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateWebhook(&w); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	secret, err := randomHex(24)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	w.Secret = "whsec_" + secret
+	w.Active = true
+	w.CreatedBy = currentUserID(c)
+	err = db.DB.QueryRow(
+		`INSERT INTO webhooks (url, description, events, secret, created_by) VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at`,
+		w.URL, w.Description, pq.Array(w.Events), w.Secret, w.CreatedBy,
+	).Scan(&w.ID, &w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// İmza anahtarı denetim kaydına yazılmaz
+	audited := w
+	audited.Secret = ""
+	recordAudit(c, "webhook", w.ID, auditCreate, nil, audited)
+	c.JSON(http.StatusCreated, w)
+}
+
+// updateWebhookHandler tekrar etkinleştirmede hata sayacı sıfırlanır
+func updateWebhookHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var w models.Webhook
+	if err := c.BindJSON(&w); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateWebhook(&w); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before, err := getWebhook(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook bulunamadı"})
+		return
+	}
+
+	_, err = db.DB.Exec(
+		`UPDATE webhooks SET url = $1, description = $2, events = $3, active = $4, updated_at = NOW(),
+			failure_count = CASE WHEN $4 AND NOT active THEN 0 ELSE failure_count END,
+			disabled_at = CASE WHEN $4 THEN NULL ELSE disabled_at END,
+			disabled_reason = CASE WHEN $4 THEN '' ELSE disabled_reason END
+		WHERE id = $5`,
+		w.URL, w.Description, pq.Array(w.Events), w.Active, id,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	after, _ := getWebhook(id)
+	recordAudit(c, "webhook", id, auditUpdate, before, after)
+	c.JSON(http.StatusOK, after)
+}
+
+func deleteWebhookHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	before, err := getWebhook(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook bulunamadı"})
+		return
+	}
+	if _, err := db.DB.Exec("DELETE FROM webhooks WHERE id = $1", id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, "webhook", id, auditDelete, before, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook silindi"})
+}
+
+// testWebhookHandler webhook'a ping olayı gönderir
+func testWebhookHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	if _, err := getWebhook(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook bulunamadı"})
+		return
+	}
+	if err := enqueueEvent(eventPing, gin.H{"webhook_id": id}, id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Test olayı kuyruğa eklendi"})
+}
+
+const deliveryColumns = "id, webhook_id, event_id, event, payload, status, attempts, response_status, error, duration_ms, redelivery_of, created_at, delivered_at"
+
+func scanDelivery(scanner interface{ Scan(...interface{}) error }) (models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var payload []byte
+	var redeliveryOf sql.NullInt64
+	var deliveredAt sql.NullTime
+	err := scanner.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.Event, &payload, &d.Status, &d.Attempts, &d.ResponseStatus,
+		&d.Error, &d.DurationMS, &redeliveryOf, &d.CreatedAt, &deliveredAt)
+	d.Payload = json.RawMessage(payload)
+	if redeliveryOf.Valid {
+		d.RedeliveryOf = &redeliveryOf.Int64
+	}
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+	return d, err
+}
+
+// getWebhookDeliveriesHandler teslimat günlüğü
+func getWebhookDeliveriesHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	query := "SELECT " + deliveryColumns + " FROM webhook_deliveries WHERE webhook_id = $1"
+	args := []interface{}{id}
+	if v := c.Query("status"); v != "" {
+		args = append(args, v)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if offset < 0 {
+		offset = 0
+	}
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT %d OFFSET %d", limit, offset)
+
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// redeliverWebhookHandler aynı olayı (aynı event_id ile) yeni bir teslimat olarak gönderir
+func redeliverWebhookHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("deliveryId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	original, err := scanDelivery(db.DB.QueryRow("SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE id = $1", id))
+	if err != nil || strconv.Itoa(original.WebhookID) != c.Param("id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Teslimat bulunamadı"})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	var deliveryID int64
+	err = tx.QueryRow(
+		"INSERT INTO webhook_deliveries (webhook_id, event_id, event, payload, redelivery_of) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		original.WebhookID, original.EventID, original.Event, string(original.Payload), original.ID,
+	).Scan(&deliveryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	_, err = enqueueJob(tx, jobWebhookDeliver, webhookDeliveryPayload{DeliveryID: deliveryID}, jobOptions{
+		MaxAttempts: webhookMaxAttempts,
+		UniqueKey:   "webhook-delivery:" + strconv.FormatInt(deliveryID, 10),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	delivery, _ := scanDelivery(db.DB.QueryRow("SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE id = $1", deliveryID))
+	recordAudit(c, "webhook_delivery", int(deliveryID), auditCreate, nil, gin.H{"redelivery_of": original.ID, "event_id": original.EventID})
+	c.JSON(http.StatusAccepted, delivery)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"testing"
+
+	"kozan/models"
+)
+
+func TestSignWebhook(t *testing.T) {
+	body := []byte(`{"id":"evt_1","type":"ping"}`)
+	mac := hmac.New(sha256.New, []byte("whsec_test"))
+	mac.Write([]byte("1700000000." + string(body)))
+	want := "t=1700000000,v1=" + hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		body      []byte
+		match     bool
+	}{
+		{"aynı girdi", "whsec_test", 1700000000, body, true},
+		{"farklı anahtar", "whsec_other", 1700000000, body, false},
+		{"farklı zaman", "whsec_test", 1700000001, body, false},
+		{"farklı gövde", "whsec_test", 1700000000, []byte(`{"id":"evt_2","type":"ping"}`), false},
+	}
+	for _, tt := range tests {
+		got := signWebhook(tt.secret, tt.timestamp, tt.body)
+		if (got == want) != tt.match {
+			t.Errorf("%s: signWebhook = %q, want match = %v with %q", tt.name, got, tt.match, want)
+		}
+	}
+}
+
+func TestCheckWebhookIP(t *testing.T) {
+	tests := []struct {
+		ip string
+		ok bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.10", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::1", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"224.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if err := checkWebhookIP(net.ParseIP(tt.ip)); (err == nil) != tt.ok {
+			t.Errorf("checkWebhookIP(%s) error = %v, want ok = %v", tt.ip, err, tt.ok)
+		}
+	}
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{"https://93.184.216.34/hook", true},
+		{"http://93.184.216.34/hook", false},
+		{"https://127.0.0.1/hook", false},
+		{"https://[::1]:8443/hook", false},
+		{"https://169.254.169.254/latest/meta-data", false},
+		{"ftp://93.184.216.34/hook", false},
+		{"https:///hook", false},
+	}
+	for _, tt := range tests {
+		w := models.Webhook{URL: tt.url, Events: []string{"*"}}
+		if err := validateWebhook(&w); (err == nil) != tt.ok {
+			t.Errorf("validateWebhook(%q) error = %v, want ok = %v", tt.url, err, tt.ok)
+		}
+	}
+}
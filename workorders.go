@@ -0,0 +1,534 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"kozan/db"
+	"kozan/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// İş emirleri: müşteriye ait servis işleri. Müşteriler telefon (yoksa
+// e-posta) ile eşleştirilir, aynı kişi için ikinci kayıt açılmaz.
+var workOrderStatuses = map[string]bool{
+	"new": true, "scheduled": true, "on_the_way": true, "in_progress": true, "completed": true, "cancelled": true,
+}
+
+// queryRower *sql.DB ve *sql.Tx için ortak arayüz
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// execer *sql.DB ve *sql.Tx için ortak arayüz
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// commitEffects işlemin sonucuna bağlı yan etkileri toplar: olaylar commit'ten
+// sonra yayınlanır, işlem geri alınırsa yazılmış dosyalar silinir
+type commitEffects struct {
+	onCommit   []func()
+	onRollback []func()
+}
+
+func (e *commitEffects) afterCommit(f func())   { e.onCommit = append(e.onCommit, f) }
+func (e *commitEffects) afterRollback(f func()) { e.onRollback = append(e.onRollback, f) }
+
+func (e *commitEffects) committed() {
+	for _, f := range e.onCommit {
+		f()
+	}
+}
+
+func (e *commitEffects) rolledBack() {
+	for _, f := range e.onRollback {
+		f()
+	}
+}
+
+// withTx fn'i tek bir işlemde çalıştırır ve işlemin sonucuna göre fn'in
+// bıraktığı yan etkileri uygular
+func withTx[T any](fn func(tx *sql.Tx, effects *commitEffects) (T, error)) (T, error) {
+	var zero T
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return zero, err
+	}
+	defer tx.Rollback()
+
+	var effects commitEffects
+	result, err := fn(tx, &effects)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		effects.rolledBack()
+		return result, err
+	}
+	effects.committed()
+	return result, nil
+}
+
+const workOrderColumns = `id, customer_id, title, description, address, status, source_message_id,
+	scheduled_start, scheduled_end, technician_id, latitude, longitude, travel_started_at, eta_at, arrived_at, completed_at,
+	technician_notes, signer_name, signed_at, invoice_file <> '', service_zone_id, call_out_fee, created_by, created_at, updated_at`
+
+func scanWorkOrder(scanner interface{ Scan(...interface{}) error }) (models.WorkOrder, error) {
+	var wo models.WorkOrder
+	var sourceMessageID, technicianID, serviceZoneID sql.NullInt64
+	var scheduledStart, scheduledEnd, travelStartedAt, eta, arrivedAt, completedAt, signedAt sql.NullTime
+	var lat, lng sql.NullFloat64
+	err := scanner.Scan(&wo.ID, &wo.CustomerID, &wo.Title, &wo.Description, &wo.Address, &wo.Status,
+		&sourceMessageID, &scheduledStart, &scheduledEnd, &technicianID, &lat, &lng, &travelStartedAt, &eta, &arrivedAt, &completedAt,
+		&wo.TechnicianNotes, &wo.SignerName, &signedAt, &wo.HasInvoice, &serviceZoneID, &wo.CallOutFee, &wo.CreatedBy, &wo.CreatedAt, &wo.UpdatedAt)
+	if sourceMessageID.Valid {
+		v := int(sourceMessageID.Int64)
+		wo.SourceMessageID = &v
+	}
+	if technicianID.Valid {
+		v := int(technicianID.Int64)
+		wo.TechnicianID = &v
+	}
+	if serviceZoneID.Valid {
+		v := int(serviceZoneID.Int64)
+		wo.ServiceZoneID = &v
+	}
+	if lat.Valid && lng.Valid {
+		wo.Latitude, wo.Longitude = &lat.Float64, &lng.Float64
+	}
+	for _, t := range []struct {
+		src sql.NullTime
+		dst **time.Time
+	}{
+		{scheduledStart, &wo.ScheduledStart}, {scheduledEnd, &wo.ScheduledEnd},
+		{travelStartedAt, &wo.TravelStartedAt}, {eta, &wo.ETA}, {arrivedAt, &wo.ArrivedAt},
+		{completedAt, &wo.CompletedAt}, {signedAt, &wo.SignedAt},
+	} {
+		if t.src.Valid {
+			v := t.src.Time
+			*t.dst = &v
+		}
+	}
+	return wo, err
+}
+
+// isTechnician kullanıcının var olduğunu ve teknisyen rolünde olduğunu kontrol eder
+func isTechnician(userID int) bool {
+	var exists bool
+	err := db.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND role_id = $2)", userID, roleTechnician).Scan(&exists)
+	return err == nil && exists
+}
+
+// validateWorkOrderSchedule randevu aralığını, teknisyeni ve koordinatları kontrol eder
+func validateWorkOrderSchedule(wo models.WorkOrder) error {
+	if wo.ScheduledEnd != nil && (wo.ScheduledStart == nil || !wo.ScheduledEnd.After(*wo.ScheduledStart)) {
+		return fmt.Errorf("Randevu bitişi başlangıçtan sonra olmalı")
+	}
+	if wo.TechnicianID != nil && !isTechnician(*wo.TechnicianID) {
+		return fmt.Errorf("Teknisyen bulunamadı")
+	}
+	if (wo.Latitude == nil) != (wo.Longitude == nil) ||
+		(wo.Latitude != nil && !validCoordinates(*wo.Latitude, *wo.Longitude)) {
+		return fmt.Errorf("Geçersiz koordinat")
+	}
+	return nil
+}
+
+func getWorkOrder(id int) (models.WorkOrder, error) {
+	return scanWorkOrder(db.DB.QueryRow("SELECT "+workOrderColumns+" FROM work_orders WHERE id = $1", id))
+}
+
+func getCustomer(id int) (models.Customer, error) {
+	var customer models.Customer
+	err := db.DB.QueryRow(
+		"SELECT id, name, COALESCE(phone, ''), COALESCE(email, ''), COALESCE(address, ''), created_at FROM customers WHERE id = $1", id,
+	).Scan(&customer.ID, &customer.Name, &customer.Phone, &customer.Email, &customer.Address, &customer.CreatedAt)
+	return customer, err
+}
+
+// findOrCreateCustomer müşteriyi telefon veya e-postayla bulur, yoksa oluşturur.
+// İş emriyle aynı işlemde çalışır; aynı telefon veya e-posta için eşzamanlı
+// istekler advisory lock ile sıraya girer, böylece çift müşteri oluşmaz.
+func findOrCreateCustomer(tx *sql.Tx, customer models.Customer) (int, error) {
+	// Kilitler her zaman aynı sırayla (önce telefon) alınır
+	for _, key := range []string{"phone:" + customer.Phone, "email:" + strings.ToLower(customer.Email)} {
+		if strings.HasSuffix(key, ":") {
+			continue
+		}
+		if _, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext('customer:' || $1))", key); err != nil {
+			return 0, err
+		}
+	}
+
+	var id int
+	err := sql.ErrNoRows
+	if customer.Phone != "" || customer.Email != "" {
+		// Telefonu eşleşen müşteri e-postası eşleşene tercih edilir
+		err = tx.QueryRow(
+			`SELECT id FROM customers
+			WHERE (phone = NULLIF($1, '')) OR (lower(email) = lower(NULLIF($2, '')))
+			ORDER BY (phone = NULLIF($1, '')) IS TRUE DESC, id LIMIT 1`,
+			customer.Phone, customer.Email,
+		).Scan(&id)
+	}
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	err = tx.QueryRow(
+		"INSERT INTO customers (name, phone, email, address) VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, '')) RETURNING id",
+		customer.Name, customer.Phone, customer.Email, customer.Address,
+	).Scan(&id)
+	return id, err
+}
+
+// insertWorkOrder iş emrini müşteri takip bağlantısıyla birlikte oluşturur
+func insertWorkOrder(q queryRower, wo *models.WorkOrder) error {
+	if wo.Status == "" {
+		wo.Status = "new"
+	}
+	token, err := newTrackingToken()
+	if err != nil {
+		return err
+	}
+	return q.QueryRow(
+		`INSERT INTO work_orders (customer_id, title, description, address, status, source_message_id, created_by,
+			scheduled_start, scheduled_end, technician_id, latitude, longitude, service_zone_id, call_out_fee, tracking_token, tracking_expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16) RETURNING id, created_at, updated_at`,
+		wo.CustomerID, wo.Title, wo.Description, wo.Address, wo.Status, wo.SourceMessageID, wo.CreatedBy,
+		wo.ScheduledStart, wo.ScheduledEnd, wo.TechnicianID, wo.Latitude, wo.Longitude, wo.ServiceZoneID, wo.CallOutFee,
+		token, time.Now().Add(trackingLinkTTL()),
+	).Scan(&wo.ID, &wo.CreatedAt, &wo.UpdatedAt)
+}
+
+// İş emri işlemleri
+func getWorkOrdersHandler(c *gin.Context) {
+	query := "SELECT " + workOrderColumns + " FROM work_orders WHERE 1=1"
+	var args []interface{}
+	if v := c.Query("status"); v != "" {
+		args = append(args, v)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	if v := c.Query("customer_id"); v != "" {
+		args = append(args, v)
+		query += fmt.Sprintf(" AND customer_id = $%d", len(args))
+	}
+	query += " ORDER BY id DESC"
+
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	workOrders := []models.WorkOrder{}
+	for rows.Next() {
+		wo, err := scanWorkOrder(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		workOrders = append(workOrders, wo)
+	}
+
+	c.JSON(http.StatusOK, workOrders)
+}
+
+func getWorkOrderHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	wo, err := getWorkOrder(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "İş emri bulunamadı"})
+		return
+	}
+	if customer, err := getCustomer(wo.CustomerID); err == nil {
+		wo.Customer = &customer
+	}
+
+	c.JSON(http.StatusOK, wo)
+}
+
+func createWorkOrderHandler(c *gin.Context) {
+	var input struct {
+		models.WorkOrder
+		// Hiçbir hizmet bölgesine düşmeyen adresi onaylar
+		OutsideServiceArea bool `json:"outside_service_area"`
+	}
+	if err := c.BindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	wo := input.WorkOrder
+	if wo.Title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Başlık zorunludur"})
+		return
+	}
+	if wo.Status != "" && !workOrderStatuses[wo.Status] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz durum: " + wo.Status})
+		return
+	}
+	if err := validateWorkOrderSchedule(wo); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Müşteri ya customer_id ile ya da customer bilgileriyle verilir
+	if wo.CustomerID == 0 {
+		if wo.Customer == nil || strings.TrimSpace(wo.Customer.Name) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Müşteri bilgisi zorunludur"})
+			return
+		}
+		if wo.Customer.Phone != "" {
+			phone, err := normalizePhone(wo.Customer.Phone)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			wo.Customer.Phone = phone
+		}
+	} else if _, err := getCustomer(wo.CustomerID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Müşteri bulunamadı"})
+		return
+	}
+
+	// Yol ücreti verilmezse hizmet bölgesinden alınır
+	fee := wo.CallOutFee
+	if err := applyServiceZone(&wo); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if fee > 0 {
+		wo.CallOutFee = fee
+	}
+	if err := checkServiceArea(wo, fee > 0 || input.OutsideServiceArea); err != nil {
+		respondZoneError(c, err)
+		return
+	}
+	if err := checkServiceDay(wo); err != nil {
+		respondZoneError(c, err)
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	// Yeni müşteri iş emriyle birlikte oluşturulur; iş emri kaydedilemezse müşteri de kalmaz
+	if wo.CustomerID == 0 {
+		if wo.CustomerID, err = findOrCreateCustomer(tx, *wo.Customer); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	wo.Customer = nil
+	wo.SourceMessageID = nil
+	wo.TravelStartedAt, wo.ETA, wo.ArrivedAt, wo.CompletedAt, wo.HasInvoice = nil, nil, nil, nil, false
+	wo.TechnicianNotes, wo.SignerName, wo.SignedAt = "", "", nil
+	wo.CreatedBy = currentUserID(c)
+	if err := insertWorkOrder(tx, &wo); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, "work_order", wo.ID, auditCreate, nil, wo)
+	emitEvent(eventWorkOrderCreated, wo)
+	c.JSON(http.StatusCreated, wo)
+}
+
+func updateWorkOrderHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var input struct {
+		Title          *string    `json:"title"`
+		Description    *string    `json:"description"`
+		Address        *string    `json:"address"`
+		Status         *string    `json:"status"`
+		ScheduledStart *time.Time `json:"scheduled_start"`
+		ScheduledEnd   *time.Time `json:"scheduled_end"`
+		// 0 teknisyen atamasını kaldırır
+		TechnicianID *int     `json:"technician_id"`
+		Latitude     *float64 `json:"latitude"`
+		Longitude    *float64 `json:"longitude"`
+		// Verilmezse adres veya konum değiştiğinde hizmet bölgesinden yeniden hesaplanır
+		CallOutFee         *float64 `json:"call_out_fee"`
+		OutsideServiceArea bool     `json:"outside_service_area"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before, err := getWorkOrder(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "İş emri bulunamadı"})
+		return
+	}
+
+	wo := before
+	if input.Title != nil {
+		wo.Title = *input.Title
+	}
+	if input.Description != nil {
+		wo.Description = *input.Description
+	}
+	if input.Address != nil {
+		wo.Address = *input.Address
+	}
+	if input.ScheduledStart != nil {
+		wo.ScheduledStart = input.ScheduledStart
+	}
+	if input.ScheduledEnd != nil {
+		wo.ScheduledEnd = input.ScheduledEnd
+	}
+	if input.TechnicianID != nil {
+		wo.TechnicianID = input.TechnicianID
+		if *input.TechnicianID == 0 {
+			wo.TechnicianID = nil
+		}
+	}
+	if input.Latitude != nil || input.Longitude != nil {
+		wo.Latitude, wo.Longitude = input.Latitude, input.Longitude
+	}
+	if input.Status != nil {
+		if !workOrderStatuses[*input.Status] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz durum: " + *input.Status})
+			return
+		}
+		wo.Status = *input.Status
+	}
+	if wo.Title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Başlık zorunludur"})
+		return
+	}
+	if err := validateWorkOrderSchedule(wo); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	locationChanged := input.Address != nil || input.Latitude != nil || input.Longitude != nil
+	if locationChanged {
+		if err := applyServiceZone(&wo); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if input.CallOutFee != nil {
+		if *input.CallOutFee < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Yol ücreti negatif olamaz"})
+			return
+		}
+		wo.CallOutFee = *input.CallOutFee
+	}
+	if locationChanged {
+		if err := checkServiceArea(wo, input.CallOutFee != nil || input.OutsideServiceArea); err != nil {
+			respondZoneError(c, err)
+			return
+		}
+	}
+	if locationChanged || input.ScheduledStart != nil {
+		if err := checkServiceDay(wo); err != nil {
+			respondZoneError(c, err)
+			return
+		}
+	}
+	if wo.Status == "completed" && before.Status != "completed" {
+		now := time.Now()
+		wo.CompletedAt = &now
+	}
+
+	// Tamamlanan işin takip bağlantısı faturaya erişilebilsin diye uzatılır
+	err = db.DB.QueryRow(
+		`UPDATE work_orders SET title = $1, description = $2, address = $3, status = $4, scheduled_start = $5, scheduled_end = $6,
+			technician_id = $7, latitude = $8, longitude = $9, completed_at = $10, service_zone_id = $11, call_out_fee = $12, updated_at = NOW(),
+			tracking_expires_at = CASE WHEN $4 = 'completed' AND status <> 'completed' THEN GREATEST(tracking_expires_at, $13) ELSE tracking_expires_at END
+		WHERE id = $14 RETURNING updated_at`,
+		wo.Title, wo.Description, wo.Address, wo.Status, wo.ScheduledStart, wo.ScheduledEnd,
+		wo.TechnicianID, wo.Latitude, wo.Longitude, wo.CompletedAt, wo.ServiceZoneID, wo.CallOutFee, time.Now().Add(trackingLinkTTL()), id,
+	).Scan(&wo.UpdatedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	workOrderUpdated(c, before, wo)
+	c.JSON(http.StatusOK, wo)
+}
+
+// statusNotifications duruma geçildiğinde müşteriye otomatik gönderilen şablon
+var statusNotifications = map[string]string{
+	"scheduled":  templateAppointmentConfirmation,
+	"on_the_way": templateTechnicianOnTheWay,
+}
+
+// workOrderUpdated güncellemeyi audit kaydına yazar; durum değiştiyse panel
+// olayını, webhook'ları ve müşteri bildirimini tetikler
+func workOrderUpdated(c *gin.Context, before, wo models.WorkOrder) {
+	recordAudit(c, "work_order", wo.ID, auditUpdate, before, wo)
+	workOrderEvents(c, before, wo)
+	if err := queueStatusNotification(db.DB, before, wo); err != nil {
+		log.Printf("status notification failed (work order %d): %v", wo.ID, err)
+	}
+}
+
+// workOrderChanged workOrderUpdated'ın işlem içindeki karşılığıdır: audit kaydı
+// ve müşteri bildirimi değişiklikle birlikte yazılır, olaylar commit'ten sonra yayınlanır
+func workOrderChanged(c *gin.Context, tx *sql.Tx, effects *commitEffects, before, wo models.WorkOrder) error {
+	if err := insertAudit(tx, requestActor(c), "work_order", wo.ID, auditUpdate, before, wo); err != nil {
+		return err
+	}
+	if err := queueStatusNotification(tx, before, wo); err != nil {
+		return err
+	}
+	effects.afterCommit(func() { workOrderEvents(c, before, wo) })
+	return nil
+}
+
+func workOrderEvents(c *gin.Context, before, wo models.WorkOrder) {
+	emitEvent(eventWorkOrderUpdated, wo)
+	if wo.Status == before.Status {
+		return
+	}
+	publishAdminEvent(c, eventWorkOrderStatusChanged, "work_order", wo.ID, gin.H{"work_order": wo, "previous_status": before.Status})
+	if wo.Status == "completed" {
+		emitEvent(eventWorkOrderCompleted, wo)
+	}
+}
+
+// queueStatusNotification yeni durumun müşteri bildirimini kuyruğa ekler
+func queueStatusNotification(q queryRower, before, wo models.WorkOrder) error {
+	template, ok := statusNotifications[wo.Status]
+	if !ok || wo.Status == before.Status {
+		return nil
+	}
+	payload := workOrderNotifyPayload{WorkOrderID: wo.ID, Template: template}
+	if _, err := enqueueJob(q, jobWorkOrderNotify, payload, jobOptions{}); err != nil {
+		return fmt.Errorf("%s: %w", template, err)
+	}
+	return nil
+}